@@ -1,18 +1,158 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	_ "time/tzdata"
 
 	"github.com/touchsung/maxion-server/internal/config"
+	"github.com/touchsung/maxion-server/internal/core/services"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/migrations"
 	"github.com/touchsung/maxion-server/internal/server"
+	"github.com/touchsung/maxion-server/internal/tracing"
+	"gorm.io/gorm"
 )
 
 func main() {
-	db, err := config.GetDatabaseConnection()
+	demo := flag.Bool("demo", false, "run on in-memory storage and an embedded Redis, seeded with demo stocks")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: main [--demo]")
+		fmt.Fprintln(os.Stderr, "       main migrate <command>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	cfg, err := config.Load(func(c *config.Config) {
+		if *demo {
+			c.Demo = true
+		}
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load configuration:", err)
+		os.Exit(1)
+	}
+
+	logger, err := logging.New(cfg.Log.Level, cfg.Log.Format, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to set up logging:", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+	logger.Info("loaded configuration", "config", cfg.Redacted())
+
+	if args := flag.Args(); len(args) > 0 {
+		if args[0] != "migrate" {
+			flag.Usage()
+			os.Exit(2)
+		}
+		if cfg.Demo {
+			fatal(logger, "migration failed", errors.New("demo mode has no database to migrate"))
+		}
+		err := runMigrate(context.Background(), cfg, logger, args[1:])
+		if errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			os.Exit(2)
+		}
+		if err != nil {
+			fatal(logger, "migration failed", err)
+		}
+		return
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		fatal(logger, "failed to set up tracing", err)
+	}
+
+	calendar, err := services.LoadMarketCalendar(cfg.Market.CalendarFile)
+	if err != nil {
+		fatal(logger, "failed to load market calendar", err)
+	}
+
+	var srv *server.Server
+	if cfg.Demo {
+		logger.Warn("running in demo mode; data is kept in memory and lost on exit")
+		srv, err = server.NewDemoServer(cfg, calendar, logger)
+		if err != nil {
+			fatal(logger, "failed to start demo mode", err)
+		}
+	} else {
+		srv = server.NewServer(cfg, openDatabase(cfg, logger), calendar, logger)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Start(ctx)
+	}()
+
+	select {
+	case err := <-errCh:
+		fatal(logger, "server stopped unexpectedly", err)
+	case <-ctx.Done():
 	}
+	stop()
+
+	logger.Info("shutting down, draining pending writes", "timeout", cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	report, err := srv.Shutdown(shutdownCtx)
+	if err != nil {
+		logger.Error("shutdown error", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("failed to flush traces", "error", err)
+	}
+
+	logger.Info("drained pending writes", "flushed", report.Synced, "failed", len(report.Failures))
+	for _, failure := range report.Failures {
+		logger.Error("unflushed write",
+			"key", failure.Key,
+			"error", failure.Err,
+			"recorded", failure.Recorded,
+			"payload", failure.Payload,
+		)
+	}
+
+	if err != nil || len(report.Failures) > 0 {
+		cancel()
+		os.Exit(1)
+	}
+}
+
+// openDatabase connects to the database and checks that its schema is up to
+// date.
+func openDatabase(cfg *config.Config, logger *slog.Logger) *gorm.DB {
+	db, err := config.GetDatabaseConnection(cfg.Database)
+	if err != nil {
+		fatal(logger, "failed to connect to database", err)
+	}
+
+	migrator, err := migrations.NewMigrator(db, cfg.Database.Driver, logger)
+	if err != nil {
+		fatal(logger, "failed to load migrations", err)
+	}
+	if err := migrator.Check(context.Background()); err != nil {
+		fatal(logger, "refusing to start against an outdated database schema", err)
+	}
+	return db
+}
 
-	srv := server.NewServer(db)
-	log.Fatal(srv.Start(":3000"))
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/config"
+	"github.com/touchsung/maxion-server/internal/migrations"
+)
+
+const migrateUsage = `usage: main migrate <command>
+
+commands:
+  up                  apply all pending migrations
+  down [steps]        revert the last steps migrations (default 1)
+  status              list migrations and when they were applied
+  baseline <version>  record migrations up to version as applied without
+                      running them, for databases created by the old init.sql`
+
+var errUsage = errors.New("invalid migrate command")
+
+type migrateCommand struct {
+	name string
+	// arg is the steps of down or the version of baseline.
+	arg int64
+}
+
+func parseMigrateArgs(args []string) (migrateCommand, error) {
+	if len(args) == 0 {
+		return migrateCommand{}, errUsage
+	}
+
+	cmd := migrateCommand{name: args[0]}
+	switch {
+	case (cmd.name == "up" || cmd.name == "status") && len(args) == 1:
+		return cmd, nil
+	case cmd.name == "down" && len(args) == 1:
+		cmd.arg = 1
+		return cmd, nil
+	case (cmd.name == "down" || cmd.name == "baseline") && len(args) == 2:
+		arg, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || arg <= 0 {
+			return migrateCommand{}, errUsage
+		}
+		cmd.arg = arg
+		return cmd, nil
+	default:
+		return migrateCommand{}, errUsage
+	}
+}
+
+func runMigrate(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) error {
+	cmd, err := parseMigrateArgs(args)
+	if err != nil {
+		return err
+	}
+
+	db, err := config.GetDatabaseConnection(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	migrator, err := migrations.NewMigrator(db, cfg.Database.Driver, logger)
+	if err != nil {
+		return err
+	}
+
+	switch cmd.name {
+	case "up":
+		count, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Info("migrated database", "applied", count, "version", migrator.Latest())
+		return nil
+	case "down":
+		count, err := migrator.Down(ctx, int(cmd.arg))
+		if err != nil {
+			return err
+		}
+		logger.Info("reverted migrations", "reverted", count)
+		return nil
+	case "baseline":
+		return migrator.Baseline(ctx, cmd.arg)
+	default:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return printStatus(os.Stdout, statuses)
+	}
+}
+
+func printStatus(out io.Writer, statuses []migrations.Status) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, status := range statuses {
+		applied := "pending"
+		if status.AppliedAt != nil {
+			applied = status.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		if status.Unknown {
+			applied += " (unknown to this server)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, applied)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMigrateArgs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected migrateCommand
+		valid    bool
+	}{
+		{name: "Up", args: []string{"up"}, expected: migrateCommand{name: "up"}, valid: true},
+		{name: "Status", args: []string{"status"}, expected: migrateCommand{name: "status"}, valid: true},
+		{name: "Down one step", args: []string{"down"}, expected: migrateCommand{name: "down", arg: 1}, valid: true},
+		{name: "Down steps", args: []string{"down", "3"}, expected: migrateCommand{name: "down", arg: 3}, valid: true},
+		{name: "Baseline", args: []string{"baseline", "1"}, expected: migrateCommand{name: "baseline", arg: 1}, valid: true},
+		{name: "No command", args: nil},
+		{name: "Unknown command", args: []string{"sideways"}},
+		{name: "Up with argument", args: []string{"up", "2"}},
+		{name: "Baseline without version", args: []string{"baseline"}},
+		{name: "Zero steps", args: []string{"down", "0"}},
+		{name: "Malformed steps", args: []string{"down", "all"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd, err := parseMigrateArgs(tc.args)
+			if !tc.valid {
+				assert.ErrorIs(t, err, errUsage)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, cmd)
+		})
+	}
+}
@@ -0,0 +1,696 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "******"
+
+type Config struct {
+	// Demo runs the server on in-memory storage and an embedded Redis, so
+	// the database and Redis settings are ignored.
+	Demo      bool            `yaml:"demo"`
+	Server    ServerConfig    `yaml:"server"`
+	GRPC      GRPCConfig      `yaml:"grpc"`
+	FIX       FIXConfig       `yaml:"fix"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Redis     RedisConfig     `yaml:"redis"`
+	Cache     CacheConfig     `yaml:"cache"`
+	Updater   UpdaterConfig   `yaml:"updater"`
+	Leader    LeaderConfig    `yaml:"leader"`
+	Outbox    OutboxConfig    `yaml:"outbox"`
+	Webhooks  WebhooksConfig  `yaml:"webhooks"`
+	Market    MarketConfig    `yaml:"market"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Log       LogConfig       `yaml:"log"`
+}
+
+type ServerConfig struct {
+	Addr            string        `yaml:"addr"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// RequestTimeout bounds the database and Redis work done for a request.
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// ProxyHeader names the header in which reverse proxies pass the client
+	// address, e.g. X-Forwarded-For. It is only believed for requests from
+	// TrustedProxies, so both must be set to rate limit clients behind a
+	// load balancer by their own address.
+	ProxyHeader string `yaml:"proxy_header"`
+	// TrustedProxies lists the proxies' IP addresses or CIDR ranges.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+type GRPCConfig struct {
+	// Addr is the listen address of the gRPC API; empty disables it.
+	Addr string `yaml:"addr"`
+	// StreamInterval is how often new alerts are polled for the alert
+	// streams, and how soon a failed quote read is retried.
+	StreamInterval time.Duration `yaml:"stream_interval"`
+	// Reflection registers the server reflection service, which lets tools
+	// such as grpcurl list and call methods without the proto file.
+	Reflection bool `yaml:"reflection"`
+}
+
+type FIXConfig struct {
+	// Addr is the listen address of the FIX gateway; empty disables it.
+	Addr         string `yaml:"addr"`
+	SenderCompID string `yaml:"sender_comp_id"`
+	// TargetCompIDs lists the counterparties allowed to log on; empty
+	// allows any.
+	TargetCompIDs []string `yaml:"target_comp_ids"`
+	// PollInterval is how long a session waits before catching up on its
+	// orders when order updates are interrupted.
+	PollInterval time.Duration `yaml:"poll_interval"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// APIKeys are the keys clients may send in X-API-Key to be limited by
+	// key instead of by IP address.
+	APIKeys []string `yaml:"api_keys"`
+	// Default applies to routes without an entry in Routes.
+	Default RateLimitRule    `yaml:"default"`
+	Routes  []RouteRateLimit `yaml:"routes"`
+}
+
+// RateLimitRule allows PerIP or PerAPIKey requests per Window. A zero limit
+// is unlimited.
+type RateLimitRule struct {
+	PerIP     int           `yaml:"per_ip"`
+	PerAPIKey int           `yaml:"per_api_key"`
+	Window    time.Duration `yaml:"window"`
+}
+
+type RouteRateLimit struct {
+	Method string `yaml:"method"`
+	// Path is the route pattern, e.g. "/v1/transactions/:id/status".
+	Path          string `yaml:"path"`
+	RateLimitRule `yaml:",inline"`
+}
+
+type DatabaseConfig struct {
+	// Driver is "sqlserver", "postgres" or "sqlite".
+	Driver string `yaml:"driver"`
+	// Path is the database file of the sqlite driver, or ":memory:".
+	Path     string `yaml:"path"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	// SSLMode is the sslmode of the postgres driver.
+	SSLMode           string        `yaml:"sslmode"`
+	ConnectionTimeout time.Duration `yaml:"connection_timeout"`
+}
+
+type RedisConfig struct {
+	// Mode is standalone, cluster or sentinel.
+	Mode string `yaml:"mode"`
+	// Host and Port address a standalone server.
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Password string `yaml:"password"`
+	// DB is not supported by Redis Cluster.
+	DB int `yaml:"db"`
+	// Addrs are the seed nodes of a cluster, or the sentinels, as host:port.
+	Addrs []string `yaml:"addrs"`
+	// MasterName is the master set monitored by the sentinels.
+	MasterName       string `yaml:"master_name"`
+	SentinelPassword string `yaml:"sentinel_password"`
+}
+
+type CacheConfig struct {
+	// Duration is the TTL of cached reads and of pending writes awaiting sync.
+	Duration     time.Duration `yaml:"duration"`
+	SyncInterval time.Duration `yaml:"sync_interval"`
+}
+
+type UpdaterConfig struct {
+	Interval time.Duration `yaml:"interval"`
+}
+
+// LeaderConfig configures the election of the instance that runs the
+// background jobs: the stock updater, cache sync, outbox relay and webhook
+// dispatcher.
+type LeaderConfig struct {
+	// LeaseTTL is how long the leader's lease lasts without renewal, and so
+	// how long a failed leader's jobs go unrun.
+	LeaseTTL time.Duration `yaml:"lease_ttl"`
+}
+
+// OutboxConfig configures the relay that publishes order events. Events are
+// published to every sink configured.
+type OutboxConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+	// RedisStream is the Redis stream events are added to; empty disables it.
+	RedisStream string `yaml:"redis_stream"`
+	// WebhookURL is POSTed every event; empty disables it.
+	WebhookURL string `yaml:"webhook_url"`
+}
+
+// WebhooksConfig configures the dispatcher that sends webhook deliveries to
+// subscribers.
+type WebhooksConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// Timeout is how long a subscriber has to answer a delivery.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type MarketConfig struct {
+	CalendarFile string `yaml:"calendar_file"`
+}
+
+type TracingConfig struct {
+	// Exporter is "none" or "otlp". The OTLP endpoint is configured with the
+	// standard OTEL_EXPORTER_OTLP_* environment variables.
+	Exporter    string  `yaml:"exporter"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:            ":3000",
+			ShutdownTimeout: 30 * time.Second,
+			RequestTimeout:  10 * time.Second,
+		},
+		GRPC: GRPCConfig{
+			Addr:           ":9090",
+			StreamInterval: time.Second,
+		},
+		FIX: FIXConfig{
+			SenderCompID: "MAXION",
+			PollInterval: time.Second,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Default: RateLimitRule{PerIP: 300, PerAPIKey: 3000, Window: time.Minute},
+			Routes: []RouteRateLimit{
+				{Method: "POST", Path: "/v1/transactions", RateLimitRule: RateLimitRule{PerIP: 60, PerAPIKey: 600, Window: time.Minute}},
+				{Method: "POST", Path: "/v1/order-groups", RateLimitRule: RateLimitRule{PerIP: 60, PerAPIKey: 600, Window: time.Minute}},
+				{Method: "POST", Path: "/transactions", RateLimitRule: RateLimitRule{PerIP: 60, PerAPIKey: 600, Window: time.Minute}},
+			},
+		},
+		Database: DatabaseConfig{
+			Driver:            DriverSQLServer,
+			SSLMode:           "disable",
+			ConnectionTimeout: 30 * time.Second,
+		},
+		Redis: RedisConfig{
+			Mode: RedisStandalone,
+			Host: "localhost",
+			Port: 6379,
+		},
+		Cache: CacheConfig{
+			Duration:     30 * time.Second,
+			SyncInterval: 15 * time.Second,
+		},
+		Updater: UpdaterConfig{
+			Interval: 2 * time.Second,
+		},
+		Leader: LeaderConfig{
+			LeaseTTL: 15 * time.Second,
+		},
+		Outbox: OutboxConfig{
+			PollInterval: time.Second,
+			BatchSize:    100,
+			RedisStream:  "order_events",
+		},
+		Webhooks: WebhooksConfig{
+			PollInterval: time.Second,
+			Timeout:      10 * time.Second,
+		},
+		Market: MarketConfig{
+			CalendarFile: "config/calendar.yaml",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "maxion-server",
+			SampleRatio: 1,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+	}
+}
+
+// Load builds the configuration from defaults, the optional YAML or TOML file
+// named by CONFIG_FILE and finally environment variables, which take
+// precedence.
+// Overrides, such as those from command-line flags, are applied last, before
+// the result is validated.
+func Load(overrides ...func(*Config)) (*Config, error) {
+	cfg := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	for _, override := range overrides {
+		override(&cfg)
+	}
+
+	if cfg.Database.Port == 0 {
+		cfg.Database.Port = defaultPorts[cfg.Database.Driver]
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// loadFile reads a config file, which is TOML if its extension is .toml and
+// YAML otherwise.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		if data, err = tomlToYAML(data); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// tomlToYAML re-encodes a TOML document as YAML, so that both formats are
+// read with the same keys and value parsing.
+func tomlToYAML(data []byte) ([]byte, error) {
+	var doc map[string]any
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
+
+func (c *Config) loadEnv() error {
+	var errs []error
+
+	errs = append(errs, envBool(&c.Demo, "DEMO"))
+
+	envString(&c.Server.Addr, "SERVER_ADDR")
+	errs = append(errs, envDuration(&c.Server.ShutdownTimeout, "SERVER_SHUTDOWN_TIMEOUT"))
+	errs = append(errs, envDuration(&c.Server.RequestTimeout, "SERVER_REQUEST_TIMEOUT"))
+	envString(&c.Server.ProxyHeader, "SERVER_PROXY_HEADER")
+	envList(&c.Server.TrustedProxies, "SERVER_TRUSTED_PROXIES")
+
+	envString(&c.GRPC.Addr, "GRPC_ADDR")
+	errs = append(errs, envDuration(&c.GRPC.StreamInterval, "GRPC_STREAM_INTERVAL"))
+	errs = append(errs, envBool(&c.GRPC.Reflection, "GRPC_REFLECTION"))
+
+	envString(&c.FIX.Addr, "FIX_ADDR")
+	envString(&c.FIX.SenderCompID, "FIX_SENDER_COMP_ID")
+	envList(&c.FIX.TargetCompIDs, "FIX_TARGET_COMP_IDS")
+	errs = append(errs, envDuration(&c.FIX.PollInterval, "FIX_POLL_INTERVAL"))
+
+	errs = append(errs, envBool(&c.RateLimit.Enabled, "RATE_LIMIT_ENABLED"))
+	envList(&c.RateLimit.APIKeys, "RATE_LIMIT_API_KEYS")
+	errs = append(errs, envInt(&c.RateLimit.Default.PerIP, "RATE_LIMIT_PER_IP"))
+	errs = append(errs, envInt(&c.RateLimit.Default.PerAPIKey, "RATE_LIMIT_PER_API_KEY"))
+	errs = append(errs, envDuration(&c.RateLimit.Default.Window, "RATE_LIMIT_WINDOW"))
+
+	envString(&c.Database.Driver, "DB_DRIVER")
+	envString(&c.Database.Path, "DB_PATH")
+	envString(&c.Database.Host, "DB_HOST")
+	errs = append(errs, envInt(&c.Database.Port, "DB_PORT"))
+	envString(&c.Database.User, "DB_USER")
+	envString(&c.Database.Password, "DB_PASSWORD")
+	envString(&c.Database.Name, "DB_NAME")
+	envString(&c.Database.SSLMode, "DB_SSLMODE")
+	errs = append(errs, envDuration(&c.Database.ConnectionTimeout, "DB_CONNECTION_TIMEOUT"))
+
+	envString(&c.Redis.Mode, "REDIS_MODE")
+	envString(&c.Redis.Host, "REDIS_HOST")
+	errs = append(errs, envInt(&c.Redis.Port, "REDIS_PORT"))
+	envString(&c.Redis.Password, "REDIS_PASSWORD")
+	errs = append(errs, envInt(&c.Redis.DB, "REDIS_DB"))
+	envList(&c.Redis.Addrs, "REDIS_ADDRS")
+	envString(&c.Redis.MasterName, "REDIS_MASTER_NAME")
+	envString(&c.Redis.SentinelPassword, "REDIS_SENTINEL_PASSWORD")
+
+	errs = append(errs, envDuration(&c.Cache.Duration, "CACHE_DURATION"))
+	errs = append(errs, envDuration(&c.Cache.SyncInterval, "SYNC_INTERVAL"))
+
+	errs = append(errs, envDuration(&c.Updater.Interval, "STOCK_UPDATE_INTERVAL"))
+
+	errs = append(errs, envDuration(&c.Leader.LeaseTTL, "LEADER_LEASE_TTL"))
+
+	errs = append(errs, envDuration(&c.Outbox.PollInterval, "OUTBOX_POLL_INTERVAL"))
+	errs = append(errs, envInt(&c.Outbox.BatchSize, "OUTBOX_BATCH_SIZE"))
+	envString(&c.Outbox.RedisStream, "OUTBOX_REDIS_STREAM")
+	envString(&c.Outbox.WebhookURL, "OUTBOX_WEBHOOK_URL")
+
+	errs = append(errs, envDuration(&c.Webhooks.PollInterval, "WEBHOOK_POLL_INTERVAL"))
+	errs = append(errs, envDuration(&c.Webhooks.Timeout, "WEBHOOK_TIMEOUT"))
+
+	envString(&c.Market.CalendarFile, "MARKET_CALENDAR_FILE")
+
+	envString(&c.Tracing.Exporter, "TRACING_EXPORTER")
+	envString(&c.Tracing.ServiceName, "OTEL_SERVICE_NAME")
+	errs = append(errs, envFloat(&c.Tracing.SampleRatio, "TRACING_SAMPLE_RATIO"))
+
+	envString(&c.Log.Level, "LOG_LEVEL")
+	envString(&c.Log.Format, "LOG_FORMAT")
+
+	return errors.Join(errs...)
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr is required"))
+	}
+	errs = append(errs, validatePositive("server.shutdown_timeout", c.Server.ShutdownTimeout))
+	errs = append(errs, validatePositive("server.request_timeout", c.Server.RequestTimeout))
+	if c.Server.ProxyHeader != "" && len(c.Server.TrustedProxies) == 0 {
+		errs = append(errs, errors.New("server.trusted_proxies is required with server.proxy_header"))
+	}
+	for i, proxy := range c.Server.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err != nil {
+			if _, err := netip.ParseAddr(proxy); err != nil {
+				errs = append(errs, fmt.Errorf("server.trusted_proxies[%d]: %q is not an IP address or CIDR range", i, proxy))
+			}
+		}
+	}
+
+	if c.GRPC.Addr != "" {
+		if c.GRPC.Addr == c.Server.Addr {
+			errs = append(errs, errors.New("grpc.addr must differ from server.addr"))
+		}
+		errs = append(errs, validatePositive("grpc.stream_interval", c.GRPC.StreamInterval))
+		// Order update streams follow the events published to the stream.
+		if c.Outbox.RedisStream == "" {
+			errs = append(errs, errors.New("outbox.redis_stream is required with grpc.addr"))
+		}
+	}
+
+	if c.FIX.Addr != "" {
+		if c.FIX.Addr == c.Server.Addr || c.FIX.Addr == c.GRPC.Addr {
+			errs = append(errs, errors.New("fix.addr must differ from server.addr and grpc.addr"))
+		}
+		if c.FIX.SenderCompID == "" {
+			errs = append(errs, errors.New("fix.sender_comp_id is required"))
+		}
+		errs = append(errs, validatePositive("fix.poll_interval", c.FIX.PollInterval))
+		// So do execution reports.
+		if c.Outbox.RedisStream == "" {
+			errs = append(errs, errors.New("outbox.redis_stream is required with fix.addr"))
+		}
+	}
+
+	if c.RateLimit.Enabled {
+		errs = append(errs, c.RateLimit.Default.validate("rate_limit.default"))
+		for i, route := range c.RateLimit.Routes {
+			name := fmt.Sprintf("rate_limit.routes[%d]", i)
+			if route.Method == "" || route.Path == "" {
+				errs = append(errs, fmt.Errorf("%s: method and path are required", name))
+			}
+			errs = append(errs, route.validate(name))
+		}
+	}
+
+	if !c.Demo {
+		errs = append(errs, c.validateStorage()...)
+	}
+
+	errs = append(errs, validatePositive("cache.duration", c.Cache.Duration))
+	errs = append(errs, validatePositive("cache.sync_interval", c.Cache.SyncInterval))
+	// Pending writes expire after cache.duration, so they must outlive at
+	// least one sync cycle or they are dropped before reaching the database.
+	if c.Cache.SyncInterval >= c.Cache.Duration {
+		errs = append(errs, errors.New("cache.sync_interval must be shorter than cache.duration"))
+	}
+
+	errs = append(errs, validatePositive("updater.interval", c.Updater.Interval))
+	errs = append(errs, validatePositive("leader.lease_ttl", c.Leader.LeaseTTL))
+
+	errs = append(errs, validatePositive("outbox.poll_interval", c.Outbox.PollInterval))
+	if c.Outbox.BatchSize <= 0 {
+		errs = append(errs, errors.New("outbox.batch_size must be positive"))
+	}
+	if c.Outbox.WebhookURL != "" {
+		if u, err := url.Parse(c.Outbox.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("outbox.webhook_url must be an http or https URL, got %q", c.Outbox.WebhookURL))
+		}
+	}
+
+	errs = append(errs, validatePositive("webhooks.poll_interval", c.Webhooks.PollInterval))
+	errs = append(errs, validatePositive("webhooks.timeout", c.Webhooks.Timeout))
+
+	if c.Market.CalendarFile == "" {
+		errs = append(errs, errors.New("market.calendar_file is required"))
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "otlp":
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter must be none or otlp, got %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sample_ratio must be between 0 and 1"))
+	}
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level must be debug, info, warn or error, got %q", c.Log.Level))
+	}
+	switch c.Log.Format {
+	case "json", "text":
+	default:
+		errs = append(errs, fmt.Errorf("log.format must be json or text, got %q", c.Log.Format))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	return nil
+}
+
+// validateStorage checks the database and Redis settings, which demo mode
+// does not use.
+func (c *Config) validateStorage() []error {
+	var errs []error
+
+	switch c.Database.Driver {
+	case DriverSQLServer, DriverPostgres:
+		if c.Database.Host == "" {
+			errs = append(errs, errors.New("database.host is required"))
+		}
+		if c.Database.User == "" {
+			errs = append(errs, errors.New("database.user is required"))
+		}
+		if c.Database.Name == "" {
+			errs = append(errs, errors.New("database.name is required"))
+		}
+		errs = append(errs, validatePort("database.port", c.Database.Port))
+	case DriverSQLite:
+		if c.Database.Path == "" {
+			errs = append(errs, errors.New("database.path is required for sqlite"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("database.driver must be sqlserver, postgres or sqlite, got %q", c.Database.Driver))
+	}
+	errs = append(errs, validatePositive("database.connection_timeout", c.Database.ConnectionTimeout))
+
+	switch c.Redis.Mode {
+	case RedisStandalone:
+		if c.Redis.Host == "" {
+			errs = append(errs, errors.New("redis.host is required"))
+		}
+		errs = append(errs, validatePort("redis.port", c.Redis.Port))
+	case RedisCluster:
+		if len(c.Redis.Addrs) == 0 {
+			errs = append(errs, errors.New("redis.addrs is required for cluster"))
+		}
+		if c.Redis.DB != 0 {
+			errs = append(errs, errors.New("redis.db must be 0 for cluster"))
+		}
+	case RedisSentinel:
+		if len(c.Redis.Addrs) == 0 {
+			errs = append(errs, errors.New("redis.addrs is required for sentinel"))
+		}
+		if c.Redis.MasterName == "" {
+			errs = append(errs, errors.New("redis.master_name is required for sentinel"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("redis.mode must be standalone, cluster or sentinel, got %q", c.Redis.Mode))
+	}
+	if c.Redis.DB < 0 {
+		errs = append(errs, errors.New("redis.db must not be negative"))
+	}
+
+	return errs
+}
+
+func (r RateLimitRule) validate(name string) error {
+	if r.PerIP < 0 || r.PerAPIKey < 0 {
+		return fmt.Errorf("%s: limits must not be negative", name)
+	}
+	return validatePositive(name+".window", r.Window)
+}
+
+// Rule returns the rate limit of the route registered as method and path.
+func (c RateLimitConfig) Rule(method string, path string) RateLimitRule {
+	for _, route := range c.Routes {
+		if strings.EqualFold(route.Method, method) && route.Path == path {
+			return route.RateLimitRule
+		}
+	}
+	return c.Default
+}
+
+// Redacted returns a copy of the configuration with secrets masked, suitable
+// for logging.
+func (c Config) Redacted() Config {
+	if c.Database.Password != "" {
+		c.Database.Password = redacted
+	}
+	if c.Redis.Password != "" {
+		c.Redis.Password = redacted
+	}
+	if c.Redis.SentinelPassword != "" {
+		c.Redis.SentinelPassword = redacted
+	}
+	if len(c.RateLimit.APIKeys) > 0 {
+		keys := make([]string, len(c.RateLimit.APIKeys))
+		for i := range keys {
+			keys[i] = redacted
+		}
+		c.RateLimit.APIKeys = keys
+	}
+	return c
+}
+
+func (c Config) String() string {
+	out, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return fmt.Sprintf("<unprintable config: %v>", err)
+	}
+	return string(out)
+}
+
+func (c RedisConfig) Addr() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+func envString(dst *string, key string) {
+	if value, ok := os.LookupEnv(key); ok {
+		*dst = value
+	}
+}
+
+// envList reads a comma-separated list, dropping empty items.
+func envList(dst *[]string, key string) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+func envBool(dst *bool, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%s: invalid boolean %q", key, value)
+	}
+	*dst = parsed
+	return nil
+}
+
+func envInt(dst *int, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%s: invalid integer %q", key, value)
+	}
+	*dst = parsed
+	return nil
+}
+
+func envDuration(dst *time.Duration, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%s: invalid duration %q", key, value)
+	}
+	*dst = parsed
+	return nil
+}
+
+func envFloat(dst *float64, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("%s: invalid number %q", key, value)
+	}
+	*dst = parsed
+	return nil
+}
+
+func validatePort(name string, port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("%s must be between 1 and 65535", name)
+	}
+	return nil
+}
+
+func validatePositive(name string, d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("%s must be positive", name)
+	}
+	return nil
+}
@@ -0,0 +1,374 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("DB_HOST", "mssql")
+	t.Setenv("DB_USER", "sa")
+	t.Setenv("DB_PASSWORD", "YourStrong@Passw0rd")
+	t.Setenv("DB_NAME", "TradingBot")
+}
+
+func TestLoad_DefaultsAndEnv(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("REDIS_HOST", "redis")
+	t.Setenv("SYNC_INTERVAL", "5s")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, ":3000", cfg.Server.Addr)
+	assert.Equal(t, "mssql", cfg.Database.Host)
+	assert.Equal(t, 1433, cfg.Database.Port)
+	assert.Equal(t, "redis:6379", cfg.Redis.Addr())
+	assert.Equal(t, 30*time.Second, cfg.Cache.Duration)
+	assert.Equal(t, 5*time.Second, cfg.Cache.SyncInterval)
+	assert.Equal(t, 2*time.Second, cfg.Updater.Interval)
+	assert.Equal(t, 15*time.Second, cfg.Leader.LeaseTTL)
+	assert.Equal(t, "order_events", cfg.Outbox.RedisStream)
+	assert.Empty(t, cfg.Outbox.WebhookURL)
+	assert.Equal(t, 10*time.Second, cfg.Webhooks.Timeout)
+	assert.False(t, cfg.GRPC.Reflection)
+}
+
+func TestLoad_FileWithEnvOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	err := os.WriteFile(path, []byte(`
+server:
+  addr: ":8080"
+database:
+  host: filehost
+  user: app
+  name: Trading
+cache:
+  duration: 1m
+  sync_interval: 10s
+updater:
+  interval: 500ms
+`), 0o600)
+	require.NoError(t, err)
+
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("DB_HOST", "envhost")
+	t.Setenv("GRPC_REFLECTION", "true")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, ":8080", cfg.Server.Addr)
+	assert.Equal(t, "envhost", cfg.Database.Host)
+	assert.Equal(t, "app", cfg.Database.User)
+	assert.Equal(t, time.Minute, cfg.Cache.Duration)
+	assert.Equal(t, 500*time.Millisecond, cfg.Updater.Interval)
+	assert.True(t, cfg.GRPC.Reflection)
+}
+
+func TestLoad_TOMLFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.toml")
+	err := os.WriteFile(path, []byte(`
+[server]
+addr = ":8080"
+trusted_proxies = ["10.0.0.0/8"]
+proxy_header = "X-Forwarded-For"
+
+[database]
+host = "filehost"
+port = 1444
+user = "app"
+name = "Trading"
+
+[cache]
+duration = "1m"
+
+[[rate_limit.routes]]
+method = "POST"
+path = "/v1/transactions"
+per_ip = 10
+window = "1m"
+`), 0o600)
+	require.NoError(t, err)
+
+	t.Setenv("CONFIG_FILE", path)
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, ":8080", cfg.Server.Addr)
+	assert.Equal(t, []string{"10.0.0.0/8"}, cfg.Server.TrustedProxies)
+	assert.Equal(t, "filehost", cfg.Database.Host)
+	assert.Equal(t, 1444, cfg.Database.Port)
+	assert.Equal(t, time.Minute, cfg.Cache.Duration)
+	assert.Equal(t, Default().Updater.Interval, cfg.Updater.Interval)
+	if assert.Len(t, cfg.RateLimit.Routes, 1) {
+		assert.Equal(t, 10, cfg.RateLimit.Routes[0].PerIP)
+		assert.Equal(t, time.Minute, cfg.RateLimit.Routes[0].Window)
+	}
+}
+
+func TestLoad_MalformedTOMLFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.toml")
+	require.NoError(t, os.WriteFile(path, []byte("[server\naddr = 1"), 0o600))
+	t.Setenv("CONFIG_FILE", path)
+
+	_, err := Load()
+	assert.ErrorContains(t, err, "failed to parse config file")
+}
+
+func TestLoad_FIXTargetCompIDs(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("FIX_ADDR", ":9878")
+	t.Setenv("FIX_TARGET_COMP_IDS", "DESK1, DESK2,,")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, "MAXION", cfg.FIX.SenderCompID)
+	assert.Equal(t, []string{"DESK1", "DESK2"}, cfg.FIX.TargetCompIDs)
+}
+
+func TestLoad_TrustedProxies(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("SERVER_PROXY_HEADER", "X-Forwarded-For")
+	t.Setenv("SERVER_TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.5")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, "X-Forwarded-For", cfg.Server.ProxyHeader)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.5"}, cfg.Server.TrustedProxies)
+}
+
+func TestLoad_DatabaseDrivers(t *testing.T) {
+	t.Run("Postgres default port", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("DB_DRIVER", "postgres")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+
+		assert.Equal(t, 5432, cfg.Database.Port)
+		assert.Equal(t, "disable", cfg.Database.SSLMode)
+	})
+
+	t.Run("SQLite without server settings", func(t *testing.T) {
+		t.Setenv("DB_DRIVER", "sqlite")
+		t.Setenv("DB_PATH", "/var/lib/maxion/trading.db")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+
+		assert.Equal(t, "/var/lib/maxion/trading.db", cfg.Database.Path)
+		assert.Equal(t, 0, cfg.Database.Port)
+	})
+}
+
+func TestLoad_RedisModes(t *testing.T) {
+	t.Run("Cluster", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("REDIS_MODE", "cluster")
+		t.Setenv("REDIS_ADDRS", "redis-0:6379, redis-1:6379,redis-2:6379")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"redis-0:6379", "redis-1:6379", "redis-2:6379"}, cfg.Redis.Addrs)
+	})
+
+	t.Run("Sentinel", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("REDIS_MODE", "sentinel")
+		t.Setenv("REDIS_ADDRS", "sentinel-0:26379,sentinel-1:26379")
+		t.Setenv("REDIS_MASTER_NAME", "maxion")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+
+		assert.Equal(t, "maxion", cfg.Redis.MasterName)
+		assert.Len(t, cfg.Redis.Addrs, 2)
+	})
+}
+
+func TestLoad_DemoSkipsStorage(t *testing.T) {
+	t.Setenv("DB_HOST", "")
+	t.Setenv("REDIS_HOST", "")
+
+	_, err := Load()
+	assert.Error(t, err)
+
+	cfg, err := Load(func(c *Config) { c.Demo = true })
+	require.NoError(t, err)
+	assert.True(t, cfg.Demo)
+
+	t.Setenv("DEMO", "true")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.True(t, cfg.Demo)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		env  map[string]string
+	}{
+		{
+			name: "Missing database host",
+			env:  map[string]string{"DB_HOST": ""},
+		},
+		{
+			name: "Unknown database driver",
+			env:  map[string]string{"DB_DRIVER": "mysql"},
+		},
+		{
+			name: "SQLite without path",
+			env:  map[string]string{"DB_DRIVER": "sqlite"},
+		},
+		{
+			name: "Malformed duration",
+			env:  map[string]string{"CACHE_DURATION": "soon"},
+		},
+		{
+			name: "Port out of range",
+			env:  map[string]string{"REDIS_PORT": "70000"},
+		},
+		{
+			name: "Unknown Redis mode",
+			env:  map[string]string{"REDIS_MODE": "replica"},
+		},
+		{
+			name: "Cluster without addresses",
+			env:  map[string]string{"REDIS_MODE": "cluster"},
+		},
+		{
+			name: "Cluster with a database number",
+			env:  map[string]string{"REDIS_MODE": "cluster", "REDIS_ADDRS": "redis-0:6379", "REDIS_DB": "1"},
+		},
+		{
+			name: "Sentinel without master name",
+			env:  map[string]string{"REDIS_MODE": "sentinel", "REDIS_ADDRS": "sentinel-0:26379"},
+		},
+		{
+			name: "Sync slower than pending write TTL",
+			env:  map[string]string{"CACHE_DURATION": "10s", "SYNC_INTERVAL": "15s"},
+		},
+		{
+			name: "Proxy header without trusted proxies",
+			env:  map[string]string{"SERVER_PROXY_HEADER": "X-Forwarded-For"},
+		},
+		{
+			name: "Malformed trusted proxy",
+			env:  map[string]string{"SERVER_PROXY_HEADER": "X-Forwarded-For", "SERVER_TRUSTED_PROXIES": "10.0.0.0/33"},
+		},
+		{
+			name: "gRPC on the HTTP port",
+			env:  map[string]string{"GRPC_ADDR": ":3000"},
+		},
+		{
+			name: "gRPC without order event stream",
+			env:  map[string]string{"GRPC_ADDR": ":9090", "OUTBOX_REDIS_STREAM": ""},
+		},
+		{
+			name: "FIX on the gRPC port",
+			env:  map[string]string{"FIX_ADDR": ":9090"},
+		},
+		{
+			name: "FIX without order event stream",
+			env:  map[string]string{"FIX_ADDR": ":9878", "OUTBOX_REDIS_STREAM": ""},
+		},
+		{
+			name: "FIX without SenderCompID",
+			env:  map[string]string{"FIX_ADDR": ":9878", "FIX_SENDER_COMP_ID": ""},
+		},
+		{
+			name: "Malformed boolean",
+			env:  map[string]string{"RATE_LIMIT_ENABLED": "sometimes"},
+		},
+		{
+			name: "Rate limit without window",
+			env:  map[string]string{"RATE_LIMIT_WINDOW": "0s"},
+		},
+		{
+			name: "Leader lease without TTL",
+			env:  map[string]string{"LEADER_LEASE_TTL": "0s"},
+		},
+		{
+			name: "Outbox without batch size",
+			env:  map[string]string{"OUTBOX_BATCH_SIZE": "0"},
+		},
+		{
+			name: "Outbox webhook without scheme",
+			env:  map[string]string{"OUTBOX_WEBHOOK_URL": "bookkeeping.internal/events"},
+		},
+		{
+			name: "Webhooks without timeout",
+			env:  map[string]string{"WEBHOOK_TIMEOUT": "0s"},
+		},
+		{
+			name: "Unknown log level",
+			env:  map[string]string{"LOG_LEVEL": "verbose"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			for key, value := range tc.env {
+				t.Setenv(key, value)
+			}
+
+			_, err := Load()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestConfig_StringRedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "YourStrong@Passw0rd"
+	cfg.Redis.Password = "hunter2"
+	cfg.Redis.SentinelPassword = "sentinel-secret"
+	cfg.RateLimit.APIKeys = []string{"desk-key-1"}
+
+	dump := cfg.String()
+
+	assert.NotContains(t, dump, "YourStrong@Passw0rd")
+	assert.NotContains(t, dump, "hunter2")
+	assert.NotContains(t, dump, "sentinel-secret")
+	assert.NotContains(t, dump, "desk-key-1")
+	assert.Contains(t, dump, redacted)
+	assert.Equal(t, "YourStrong@Passw0rd", cfg.Database.Password)
+	assert.Equal(t, []string{"desk-key-1"}, cfg.RateLimit.APIKeys)
+}
+
+func TestRateLimitConfig_Rule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	err := os.WriteFile(path, []byte(`
+rate_limit:
+  routes:
+    - method: put
+      path: /v1/transactions/:id/status
+      per_ip: 10
+      per_api_key: 100
+      window: 30s
+`), 0o600)
+	require.NoError(t, err)
+
+	setRequiredEnv(t)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("RATE_LIMIT_PER_IP", "50")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, RateLimitRule{PerIP: 10, PerAPIKey: 100, Window: 30 * time.Second},
+		cfg.RateLimit.Rule("PUT", "/v1/transactions/:id/status"))
+	assert.Equal(t, RateLimitRule{PerIP: 50, PerAPIKey: 3000, Window: time.Minute},
+		cfg.RateLimit.Rule("POST", "/v1/transactions"))
+}
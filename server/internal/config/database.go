@@ -2,21 +2,91 @@ package config
 
 import (
 	"fmt"
-	"os"
+	"net/url"
+	"strconv"
 
+	"github.com/touchsung/maxion-server/internal/tracing"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/driver/sqlserver"
 	"gorm.io/gorm"
 )
 
-func GetDatabaseConnection() (*gorm.DB, error) {
-	dbHost := os.Getenv("DB_HOST")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-	
-	dsn := fmt.Sprintf("sqlserver://%s:%s@%s:1433?database=%s&connection+timeout=30&encrypt=DISABLE",
-		dbUser, dbPassword, dbHost, dbName)
-	
-	return gorm.Open(sqlserver.Open(dsn), &gorm.Config{})
+// Database drivers. Each has its own schema under sql/.
+const (
+	DriverSQLServer = "sqlserver"
+	DriverPostgres  = "postgres"
+	DriverSQLite    = "sqlite"
+)
+
+var defaultPorts = map[string]int{
+	DriverSQLServer: 1433,
+	DriverPostgres:  5432,
+}
+
+func GetDatabaseConnection(cfg DatabaseConfig) (*gorm.DB, error) {
+	dialector, err := dialectorFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Driver == DriverSQLite {
+		// SQLite allows a single writer; queuing writes on one connection
+		// avoids "database is locked" errors, and keeps in-memory databases
+		// from being opened once per connection.
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+
+	if err := db.Use(tracing.GormPlugin()); err != nil {
+		return nil, fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
+
+	return db, nil
+}
+
+// sqlServerDSN escapes the credentials and database name, which may contain
+// characters that are special in a URL.
+func sqlServerDSN(cfg DatabaseConfig, timeout int) string {
+	query := url.Values{}
+	query.Set("database", cfg.Name)
+	query.Set("connection timeout", strconv.Itoa(timeout))
+	query.Set("encrypt", "DISABLE")
+	return (&url.URL{
+		Scheme:   "sqlserver",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		RawQuery: query.Encode(),
+	}).String()
 }
 
+func dialectorFor(cfg DatabaseConfig) (gorm.Dialector, error) {
+	timeout := int(cfg.ConnectionTimeout.Seconds())
+
+	switch cfg.Driver {
+	case DriverSQLServer:
+		return sqlserver.Open(sqlServerDSN(cfg, timeout)), nil
+	case DriverPostgres:
+		dsn := (&url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(cfg.User, cfg.Password),
+			Host:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+			Path:     cfg.Name,
+			RawQuery: fmt.Sprintf("sslmode=%s&connect_timeout=%d", cfg.SSLMode, timeout),
+		}).String()
+		return postgres.Open(dsn), nil
+	case DriverSQLite:
+		dsn := fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=%d", cfg.Path, timeout*1000)
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLServerDSN_EscapesCredentials(t *testing.T) {
+	cfg := DatabaseConfig{
+		Host:     "db.internal",
+		Port:     1433,
+		User:     "app@corp",
+		Password: "p@ss:w/rd?#1",
+		Name:     "Trading&Co",
+	}
+
+	dsn, err := url.Parse(sqlServerDSN(cfg, 30))
+	require.NoError(t, err)
+
+	assert.Equal(t, "sqlserver", dsn.Scheme)
+	assert.Equal(t, "db.internal:1433", dsn.Host)
+	assert.Equal(t, "app@corp", dsn.User.Username())
+	password, _ := dsn.User.Password()
+	assert.Equal(t, "p@ss:w/rd?#1", password)
+	query := dsn.Query()
+	assert.Equal(t, "Trading&Co", query.Get("database"))
+	assert.Equal(t, "30", query.Get("connection timeout"))
+	assert.Equal(t, "DISABLE", query.Get("encrypt"))
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis deployment modes.
+const (
+	RedisStandalone = "standalone"
+	RedisCluster    = "cluster"
+	RedisSentinel   = "sentinel"
+)
+
+// NewRedisClient returns a client for the Redis deployment described by cfg.
+// In sentinel mode it follows the master through failovers.
+func NewRedisClient(cfg RedisConfig) redis.UniversalClient {
+	switch cfg.Mode {
+	case RedisCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    cfg.Addrs,
+			Password: cfg.Password,
+		})
+	case RedisSentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.MasterName,
+			SentinelAddrs:    cfg.Addrs,
+			SentinelPassword: cfg.SentinelPassword,
+			Password:         cfg.Password,
+			DB:               cfg.DB,
+		})
+	default:
+		return redis.NewClient(&redis.Options{
+			Addr:     cfg.Addr(),
+			Password: cfg.Password,
+			DB:       cfg.DB,
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// AlertRuleType is what an alert rule watches.
+type AlertRuleType string
+
+const (
+	// ThresholdRule fires when a price crosses Value.
+	ThresholdRule AlertRuleType = "THRESHOLD"
+	// PercentMoveRule fires when a price has moved Value percent from the
+	// rule's reference price.
+	PercentMoveRule AlertRuleType = "PERCENT_MOVE"
+	// VolumeRule fires when a volume crosses Value.
+	VolumeRule AlertRuleType = "VOLUME"
+)
+
+// QuoteSide is the side of a quote an alert rule watches.
+type QuoteSide string
+
+const (
+	BidSide QuoteSide = "BID"
+	AskSide QuoteSide = "ASK"
+)
+
+// AlertDirection is whether an alert rule fires on a rise or a fall.
+type AlertDirection string
+
+const (
+	Above AlertDirection = "ABOVE"
+	Below AlertDirection = "BELOW"
+)
+
+// AlertRule fires an alert when the quotes of Symbol meet its condition, at
+// most once per CooldownSeconds. A percent move is measured from ReferencePrice,
+// which is the price when the rule was created and then when it last fired.
+type AlertRule struct {
+	RuleID         int64          `gorm:"column:RuleId;primaryKey;autoIncrement"`
+	Symbol         string         `gorm:"column:Symbol"`
+	Type           AlertRuleType  `gorm:"column:Type"`
+	Side           QuoteSide      `gorm:"column:Side"`
+	Direction      AlertDirection `gorm:"column:Direction"`
+	Value          float64        `gorm:"column:Value"`
+	ReferencePrice *float64       `gorm:"column:ReferencePrice"`
+	// CooldownSeconds is how long the rule stays quiet after firing.
+	CooldownSeconds int        `gorm:"column:CooldownSeconds"`
+	LastFiredAt     *time.Time `gorm:"column:LastFiredAt"`
+	CreatedAt       time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (AlertRule) TableName() string {
+	return "AlertRules"
+}
+
+// CoolingDown reports whether the rule fired too recently to fire at now.
+func (r AlertRule) CoolingDown(now time.Time) bool {
+	return r.LastFiredAt != nil && now.Before(r.LastFiredAt.Add(time.Duration(r.CooldownSeconds)*time.Second))
+}
+
+// Observe returns the figure of stock the rule compares with its Value: a
+// price, a percent move or a volume.
+func (r AlertRule) Observe(stock Stock) float64 {
+	price, volume := stock.BidPrice, stock.BidVolume
+	if r.Side == AskSide {
+		price, volume = stock.AskPrice, stock.AskVolume
+	}
+
+	switch r.Type {
+	case VolumeRule:
+		return float64(volume)
+	case PercentMoveRule:
+		if r.ReferencePrice == nil || *r.ReferencePrice == 0 {
+			return 0
+		}
+		return (price - *r.ReferencePrice) / *r.ReferencePrice * 100
+	default:
+		return price
+	}
+}
+
+// Met reports whether stock meets the rule's condition, and the figure it
+// was judged on.
+func (r AlertRule) Met(stock Stock) (float64, bool) {
+	observed := r.Observe(stock)
+	if r.Type == PercentMoveRule {
+		if r.Direction == Above {
+			return observed, observed >= r.Value
+		}
+		return observed, observed <= -r.Value
+	}
+	if r.Direction == Above {
+		return observed, observed > r.Value
+	}
+	return observed, observed < r.Value
+}
+
+// Price returns the price of stock on the rule's side.
+func (r AlertRule) Price(stock Stock) float64 {
+	if r.Side == AskSide {
+		return stock.AskPrice
+	}
+	return stock.BidPrice
+}
+
+// Describe returns a message for an alert of the rule, given the figure it
+// fired on.
+func (r AlertRule) Describe(observed float64) string {
+	side := "bid"
+	if r.Side == AskSide {
+		side = "ask"
+	}
+	verb := "rose above"
+	if r.Direction == Below {
+		verb = "fell below"
+	}
+
+	switch r.Type {
+	case VolumeRule:
+		return fmt.Sprintf("%s %s volume %s %g (%.0f)", r.Symbol, side, verb, r.Value, observed)
+	case PercentMoveRule:
+		verb = "rose"
+		if r.Direction == Below {
+			verb = "fell"
+		}
+		return fmt.Sprintf("%s %s %s %.2f%%", r.Symbol, side, verb, math.Abs(observed))
+	default:
+		return fmt.Sprintf("%s %s %s %g (%.2f)", r.Symbol, side, verb, r.Value, observed)
+	}
+}
+
+// Alert is a firing of an alert rule, kept as its history.
+type Alert struct {
+	AlertID     int64         `gorm:"column:AlertId;primaryKey;autoIncrement"`
+	RuleID      int64         `gorm:"column:RuleId"`
+	Symbol      string        `gorm:"column:Symbol"`
+	Type        AlertRuleType `gorm:"column:Type"`
+	Observed    float64       `gorm:"column:Observed"`
+	Message     string        `gorm:"column:Message"`
+	TriggeredAt time.Time     `gorm:"column:TriggeredAt"`
+}
+
+func (Alert) TableName() string {
+	return "Alerts"
+}
@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"errors"
+)
+
+// ErrorKind classifies domain errors so that adapters can map them onto
+// their own status codes without inspecting messages.
+type ErrorKind string
+
+const (
+	KindNotFound    ErrorKind = "NOT_FOUND"
+	KindValidation  ErrorKind = "VALIDATION"
+	KindConflict    ErrorKind = "CONFLICT"
+	KindRejected    ErrorKind = "REJECTED"
+	KindUnavailable ErrorKind = "UNAVAILABLE"
+	KindRateLimited ErrorKind = "RATE_LIMITED"
+)
+
+// Error is a failure the API can explain to its caller. Code is a stable,
+// machine-readable identifier such as "stock_not_found"; Message is safe to
+// show to clients. Validation errors list the offending fields in Fields.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NewNotFoundError(code string, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+func NewValidationError(code string, message string) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message}
+}
+
+func NewConflictError(code string, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+// NewRejectedError reports an order that is well formed but refused by a
+// trading or risk rule, such as the market being closed.
+func NewRejectedError(code string, message string) *Error {
+	return &Error{Kind: KindRejected, Code: code, Message: message}
+}
+
+// NewUnavailableError reports a dependency outage; err is the underlying
+// cause and is not shown to clients.
+func NewUnavailableError(code string, message string, err error) *Error {
+	return &Error{Kind: KindUnavailable, Code: code, Message: message, Err: err}
+}
+
+// ErrorKindOf returns the kind of the first domain error in err's chain.
+func ErrorKindOf(err error) (ErrorKind, bool) {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Kind, true
+	}
+	return "", false
+}
+
+// NewRateLimitedError reports a caller that has used up its request quota.
+func NewRateLimitedError(code string, message string) *Error {
+	return &Error{Kind: KindRateLimited, Code: code, Message: message}
+}
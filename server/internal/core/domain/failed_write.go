@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"time"
+)
+
+// FailedWrite is a cached create or update that could not be written to the
+// database and was moved out of Redis so it is not lost when its key expires.
+type FailedWrite struct {
+	FailedWriteID int64     `gorm:"column:FailedWriteId;primaryKey;autoIncrement"`
+	CacheKey      string    `gorm:"column:CacheKey"`
+	Operation     string    `gorm:"column:Operation"`
+	Payload       string    `gorm:"column:Payload"`
+	Error         string    `gorm:"column:Error"`
+	FailedAt      time.Time `gorm:"column:FailedAt"`
+}
+
+func (FailedWrite) TableName() string {
+	return "FailedWrites"
+}
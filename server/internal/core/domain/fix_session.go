@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"time"
+)
+
+// FIXSession holds the sequence numbers of a FIX session, which outlive the
+// connection so that a counterparty can log back on without resetting them.
+type FIXSession struct {
+	SessionID string `gorm:"column:SessionId;primaryKey"`
+	// NextSenderSeqNum is the MsgSeqNum of the next message we send.
+	NextSenderSeqNum int `gorm:"column:NextSenderSeqNum"`
+	// NextTargetSeqNum is the MsgSeqNum expected from the counterparty.
+	NextTargetSeqNum int       `gorm:"column:NextTargetSeqNum"`
+	UpdatedAt        time.Time `gorm:"column:UpdatedAt"`
+}
+
+func (FIXSession) TableName() string {
+	return "FixSessions"
+}
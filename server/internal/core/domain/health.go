@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"time"
+)
+
+type HealthState string
+
+const (
+	Healthy     HealthState = "ok"
+	Degraded    HealthState = "degraded"
+	Unavailable HealthState = "unavailable"
+)
+
+// DependencyHealth is the result of probing an external dependency.
+type DependencyHealth struct {
+	Status    HealthState `json:"status"`
+	LatencyMs int64       `json:"latencyMs"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// WorkerHealth reports how recently a background worker last succeeded.
+// Standby workers run on another instance, the leader.
+type WorkerHealth struct {
+	Status      HealthState `json:"status"`
+	Standby     bool        `json:"standby,omitempty"`
+	LastSuccess *time.Time  `json:"lastSuccess,omitempty"`
+	AgeSeconds  *float64    `json:"ageSeconds,omitempty"`
+}
+
+type Readiness struct {
+	Status        HealthState                 `json:"status"`
+	Timestamp     time.Time                   `json:"timestamp"`
+	Dependencies  map[string]DependencyHealth `json:"dependencies"`
+	Workers       map[string]WorkerHealth     `json:"workers"`
+	PendingWrites *int                        `json:"pendingWrites"`
+}
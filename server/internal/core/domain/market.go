@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"time"
+)
+
+var ErrMarketClosed = NewRejectedError("market_closed", "market is closed for order entry")
+
+type MarketSession string
+
+const (
+	PreMarket  MarketSession = "PRE_MARKET"
+	Regular    MarketSession = "REGULAR"
+	PostMarket MarketSession = "POST_MARKET"
+	Closed     MarketSession = "CLOSED"
+)
+
+// MarketStatus describes the state of an exchange at a point in time.
+type MarketStatus struct {
+	Exchange      string        `json:"exchange"`
+	Timezone      string        `json:"timezone"`
+	Session       MarketSession `json:"session"`
+	IsOpen        bool          `json:"isOpen"`
+	AcceptsOrders bool          `json:"acceptsOrders"`
+	Reason        string        `json:"reason,omitempty"`
+	Timestamp     time.Time     `json:"timestamp"`
+	SessionEnds   *time.Time    `json:"sessionEnds,omitempty"`
+	NextOpen      *time.Time    `json:"nextOpen,omitempty"`
+}
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+)
+
+// OrderLeg is the part an order plays in its group.
+type OrderLeg string
+
+const (
+	EntryLeg      OrderLeg = "ENTRY"
+	TakeProfitLeg OrderLeg = "TAKE_PROFIT"
+	StopLossLeg   OrderLeg = "STOP_LOSS"
+)
+
+type OrderGroupType string
+
+const (
+	// BracketGroup is an entry order with take-profit and stop-loss legs,
+	// which are held until the entry fills.
+	BracketGroup OrderGroupType = "BRACKET"
+	// OCOGroup is a take-profit and a stop-loss leg closing a position
+	// already held.
+	OCOGroup OrderGroupType = "OCO"
+)
+
+// OrderGroup is a bracket or OCO group to place. Side is the side of the
+// position: the entry order takes it and the exit legs the other side. The
+// exit legs of a group are one-cancels-other: when one fills, the others
+// are cancelled.
+type OrderGroup struct {
+	ID              string
+	Type            OrderGroupType
+	Symbol          string
+	Side            TransactionType
+	Quantity        int
+	TakeProfitPrice *float64
+	StopLossPrice   *float64
+	Notes           *string
+	// Orders are the orders placed for the group, entry first.
+	Orders []Transaction
+}
+
+// Opposite returns the side that closes a position of side t.
+func (t TransactionType) Opposite() TransactionType {
+	if t == Buy {
+		return Sell
+	}
+	return Buy
+}
+
+// Final reports whether an order in status s can no longer change.
+func (s TransactionStatus) Final() bool {
+	return s == Completed || s == Cancelled || s == Failed
+}
+
+func (t Transaction) IsEntry() bool {
+	return t.Leg != nil && *t.Leg == EntryLeg
+}
+
+// CheckGroupTransition returns a conflict error if t, an order of a group,
+// cannot move to status: a final order cannot change, as its group has
+// already acted on it, and a held leg can only be cancelled.
+func (t Transaction) CheckGroupTransition(status TransactionStatus) error {
+	switch {
+	case t.Status.Final():
+		return NewConflictError("order_closed",
+			fmt.Sprintf("order %d is already %s", t.TransactionID, t.Status))
+	case t.Status == Held && status != Cancelled:
+		return NewConflictError("order_held",
+			fmt.Sprintf("order %d is held until its entry order fills", t.TransactionID))
+	}
+	return nil
+}
+
+// ApplyGroupStatus moves order id of group to status and applies the changes
+// GroupTransitions makes to the rest of group, as the repositories do, or
+// returns the error CheckGroupTransition refuses it with. Repeating the
+// status of an order changes nothing.
+func ApplyGroupStatus(group []Transaction, id int64, status TransactionStatus) error {
+	i := slices.IndexFunc(group, func(tx Transaction) bool { return tx.TransactionID == id })
+	if i < 0 || group[i].Status == status {
+		return nil
+	}
+	order := group[i]
+	if err := order.CheckGroupTransition(status); err != nil {
+		return err
+	}
+
+	changes := GroupTransitions(order, status, group)
+	group[i].Status = status
+	for j := range group {
+		if next, ok := changes[group[j].TransactionID]; ok {
+			group[j].Status = next
+		}
+	}
+	return nil
+}
+
+// GroupTransitions returns the status changes that order moving to status
+// makes to the other orders of its group, by transaction ID:
+//   - the entry filling activates the held legs
+//   - the entry being cancelled or failing cancels them
+//   - an exit leg filling cancels the other exit legs
+func GroupTransitions(order Transaction, status TransactionStatus, group []Transaction) map[int64]TransactionStatus {
+	changes := make(map[int64]TransactionStatus)
+	for _, other := range group {
+		if other.TransactionID == order.TransactionID || other.Status.Final() {
+			continue
+		}
+
+		switch {
+		case order.IsEntry() && other.Status == Held && status == Completed:
+			changes[other.TransactionID] = Pending
+		case order.IsEntry() && other.Status == Held && (status == Cancelled || status == Failed):
+			changes[other.TransactionID] = Cancelled
+		case !order.IsEntry() && !other.IsEntry() && status == Completed:
+			changes[other.TransactionID] = Cancelled
+		}
+	}
+	return changes
+}
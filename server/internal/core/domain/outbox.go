@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Order event types.
+const (
+	OrderCreated       = "order.created"
+	OrderStatusChanged = "order.status_changed"
+)
+
+// OutboxEvent is an event written in the same database transaction as the
+// change it describes, and kept until it has been published. AggregateID is
+// the transaction the event is about; events of one transaction are
+// published in OutboxID order.
+type OutboxEvent struct {
+	OutboxID    int64     `gorm:"column:OutboxId;primaryKey;autoIncrement"`
+	AggregateID int64     `gorm:"column:AggregateId"`
+	EventType   string    `gorm:"column:EventType"`
+	Payload     string    `gorm:"column:Payload"`
+	CreatedAt   time.Time `gorm:"column:CreatedAt"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "OutboxEvents"
+}
+
+// OrderEvent is the payload of order events: the order as it was after the
+// change. Status changes also carry the status before it.
+type OrderEvent struct {
+	TransactionID  int64      `json:"transactionId"`
+	Symbol         string     `json:"symbol"`
+	Type           string     `json:"type"`
+	Status         string     `json:"status"`
+	PreviousStatus string     `json:"previousStatus,omitempty"`
+	Quantity       int        `json:"quantity"`
+	Price          float64    `json:"price"`
+	TotalAmount    float64    `json:"totalAmount"`
+	OrderTime      time.Time  `json:"orderTime"`
+	ExecutionTime  *time.Time `json:"executionTime,omitempty"`
+	Notes          *string    `json:"notes,omitempty"`
+	ClientOrderID  *string    `json:"clientOrderId,omitempty"`
+	GroupID        *string    `json:"groupId,omitempty"`
+	Leg            *OrderLeg  `json:"leg,omitempty"`
+}
+
+// NewOrderEvent returns an outbox event of eventType for tx.
+func NewOrderEvent(eventType string, tx *Transaction) (*OutboxEvent, error) {
+	return newOrderEvent(eventType, tx, "")
+}
+
+// NewStatusChangedEvent returns the order.status_changed event of tx, whose
+// status was previous.
+func NewStatusChangedEvent(tx *Transaction, previous TransactionStatus) (*OutboxEvent, error) {
+	return newOrderEvent(OrderStatusChanged, tx, previous.String())
+}
+
+func newOrderEvent(eventType string, tx *Transaction, previous string) (*OutboxEvent, error) {
+	payload, err := json.Marshal(OrderEvent{
+		TransactionID:  tx.TransactionID,
+		PreviousStatus: previous,
+		Symbol:         tx.Symbol,
+		Type:           tx.Type.String(),
+		Status:         tx.Status.String(),
+		Quantity:       tx.Quantity,
+		Price:          tx.Price,
+		TotalAmount:    tx.TotalAmount,
+		OrderTime:      tx.OrderTime,
+		ExecutionTime:  tx.ExecutionTime,
+		Notes:          tx.Notes,
+		ClientOrderID:  tx.ClientOrderID,
+		GroupID:        tx.GroupID,
+		Leg:            tx.Leg,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxEvent{
+		AggregateID: tx.TransactionID,
+		EventType:   eventType,
+		Payload:     string(payload),
+		CreatedAt:   time.Now().UTC(),
+	}, nil
+}
+
+// Transaction returns the order the event describes.
+func (e OrderEvent) Transaction() Transaction {
+	txType, _ := ParseTransactionType(e.Type)
+	status, _ := ParseTransactionStatus(e.Status)
+	return Transaction{
+		TransactionID: e.TransactionID,
+		Symbol:        e.Symbol,
+		Type:          txType,
+		Status:        status,
+		Quantity:      e.Quantity,
+		Price:         e.Price,
+		TotalAmount:   e.TotalAmount,
+		OrderTime:     e.OrderTime,
+		ExecutionTime: e.ExecutionTime,
+		Notes:         e.Notes,
+		ClientOrderID: e.ClientOrderID,
+		GroupID:       e.GroupID,
+		Leg:           e.Leg,
+	}
+}
+
+// OrderChange is a published order event as delivered to the subscribers
+// of an order feed.
+type OrderChange struct {
+	EventType string
+	Order     OrderEvent
+}
@@ -28,20 +28,81 @@ const (
 	Completed TransactionStatus = 2
 	Cancelled TransactionStatus = 3
 	Failed    TransactionStatus = 4
+	// Held is the status of the exit legs of a bracket until their entry
+	// order fills.
+	Held TransactionStatus = 5
 )
 
 type Transaction struct {
-	TransactionID  int64             `gorm:"column:TransactionId;primaryKey;autoIncrement"`
+	TransactionID int64             `gorm:"column:TransactionId;primaryKey;autoIncrement"`
 	Symbol        string            `gorm:"column:Symbol"`
 	Type          TransactionType   `gorm:"column:TypeId"`
 	Status        TransactionStatus `gorm:"column:StatusId"`
-	Quantity      int              `gorm:"column:Quantity"`
-	Price         float64          `gorm:"column:Price"`
-	TotalAmount   float64          `gorm:"column:TotalAmount"`
-	OrderTime     time.Time        `gorm:"column:OrderTime"`
-	ExecutionTime *time.Time       `gorm:"column:ExecutionTime"`
-	Notes         *string          `gorm:"column:Notes"`
-	Stock         Stock            `gorm:"foreignKey:Symbol;references:Symbol"`
+	Quantity      int               `gorm:"column:Quantity"`
+	Price         float64           `gorm:"column:Price"`
+	TotalAmount   float64           `gorm:"column:TotalAmount"`
+	OrderTime     time.Time         `gorm:"column:OrderTime"`
+	ExecutionTime *time.Time        `gorm:"column:ExecutionTime"`
+	Notes         *string           `gorm:"column:Notes"`
+	// ClientOrderID is the order's ID at the gateway it was entered through,
+	// prefixed with the gateway session, e.g. "CLIENT/ord-1". It lets the
+	// gateway find the order once its asynchronous insert has completed.
+	ClientOrderID *string `gorm:"column:ClientOrderId"`
+	// GroupID links the orders of a bracket or OCO group, and Leg is this
+	// order's part in it; both are nil for orders placed on their own. The
+	// group is named when it is placed, as its orders only get their IDs
+	// once inserted.
+	GroupID *string   `gorm:"column:GroupId"`
+	Leg     *OrderLeg `gorm:"column:Leg"`
+	Stock   Stock     `gorm:"foreignKey:Symbol;references:Symbol"`
+}
+
+func (t TransactionType) String() string {
+	switch t {
+	case Buy:
+		return "BUY"
+	case Sell:
+		return "SELL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+func (s TransactionStatus) String() string {
+	switch s {
+	case Pending:
+		return "PENDING"
+	case Completed:
+		return "COMPLETED"
+	case Cancelled:
+		return "CANCELLED"
+	case Failed:
+		return "FAILED"
+	case Held:
+		return "HELD"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ParseTransactionType is the inverse of TransactionType.String.
+func ParseTransactionType(s string) (TransactionType, bool) {
+	for _, t := range []TransactionType{Buy, Sell} {
+		if t.String() == s {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// ParseTransactionStatus is the inverse of TransactionStatus.String.
+func ParseTransactionStatus(s string) (TransactionStatus, bool) {
+	for _, status := range []TransactionStatus{Pending, Completed, Cancelled, Failed, Held} {
+		if status.String() == s {
+			return status, true
+		}
+	}
+	return 0, false
 }
 
 func (Stock) TableName() string {
@@ -51,4 +112,3 @@ func (Stock) TableName() string {
 func (Transaction) TableName() string {
 	return "Transactions"
 }
-
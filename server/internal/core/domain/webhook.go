@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"time"
+)
+
+// Webhook event types, sent when an order reaches a final status.
+const (
+	OrderCompleted = "order.completed"
+	OrderCancelled = "order.cancelled"
+	OrderFailed    = "order.failed"
+)
+
+// WebhookEvents are the event types a subscription can receive.
+var WebhookEvents = []string{OrderCompleted, OrderCancelled, OrderFailed}
+
+// WebhookEventFor returns the webhook event type for a change to status, if
+// there is one.
+func WebhookEventFor(status TransactionStatus) (string, bool) {
+	switch status {
+	case Completed:
+		return OrderCompleted, true
+	case Cancelled:
+		return OrderCancelled, true
+	case Failed:
+		return OrderFailed, true
+	default:
+		return "", false
+	}
+}
+
+// WebhookSubscription is a URL that is called back with the events it
+// subscribes to, signed with its secret.
+type WebhookSubscription struct {
+	SubscriptionID int64     `gorm:"column:SubscriptionId;primaryKey;autoIncrement"`
+	URL            string    `gorm:"column:Url"`
+	Secret         string    `gorm:"column:Secret"`
+	Events         []string  `gorm:"column:Events;serializer:json"`
+	Active         bool      `gorm:"column:Active"`
+	CreatedAt      time.Time `gorm:"column:CreatedAt"`
+}
+
+// Subscribes reports whether the subscription receives eventType.
+func (s WebhookSubscription) Subscribes(eventType string) bool {
+	for _, event := range s.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (WebhookSubscription) TableName() string {
+	return "WebhookSubscriptions"
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "PENDING"
+	DeliverySucceeded WebhookDeliveryStatus = "SUCCEEDED"
+	DeliveryFailed    WebhookDeliveryStatus = "FAILED"
+)
+
+// WebhookDelivery is an event to be sent to a subscription, and the outcome
+// of the attempts so far. A pending delivery is next attempted at
+// NextAttemptAt. Redelivering an event creates a new delivery with the same
+// EventID.
+type WebhookDelivery struct {
+	DeliveryID     int64                 `gorm:"column:DeliveryId;primaryKey;autoIncrement"`
+	SubscriptionID int64                 `gorm:"column:SubscriptionId"`
+	EventID        string                `gorm:"column:EventId"`
+	EventType      string                `gorm:"column:EventType"`
+	Payload        string                `gorm:"column:Payload"`
+	Status         WebhookDeliveryStatus `gorm:"column:Status"`
+	Attempts       int                   `gorm:"column:Attempts"`
+	NextAttemptAt  *time.Time            `gorm:"column:NextAttemptAt"`
+	ResponseStatus *int                  `gorm:"column:ResponseStatus"`
+	LastError      *string               `gorm:"column:LastError"`
+	CreatedAt      time.Time             `gorm:"column:CreatedAt"`
+	DeliveredAt    *time.Time            `gorm:"column:DeliveredAt"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "WebhookDeliveries"
+}
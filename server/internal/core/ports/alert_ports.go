@@ -0,0 +1,45 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+// AlertRepository stores alert rules and the alerts they fire. Getting or
+// deleting an unknown rule returns a not found error.
+type AlertRepository interface {
+	CreateAlertRule(ctx context.Context, rule *domain.AlertRule) error
+	GetAlertRules(ctx context.Context) ([]domain.AlertRule, error)
+	GetAlertRule(ctx context.Context, id int64) (*domain.AlertRule, error)
+	// DeleteAlertRule deletes the rule and its alerts.
+	DeleteAlertRule(ctx context.Context, id int64) error
+
+	// RecordAlert stores alert and saves the LastFiredAt and ReferencePrice
+	// of rule, in one database transaction.
+	RecordAlert(ctx context.Context, rule *domain.AlertRule, alert *domain.Alert) error
+	// GetAlerts returns up to limit alerts, newest first; those of one rule
+	// if ruleID is not 0.
+	GetAlerts(ctx context.Context, ruleID int64, limit int) ([]domain.Alert, error)
+	// GetAlertsAfter returns up to limit alerts with an ID above afterID,
+	// oldest first.
+	GetAlertsAfter(ctx context.Context, afterID int64, limit int) ([]domain.Alert, error)
+}
+
+// QuoteListener is given each batch of quotes the stock updater saves.
+type QuoteListener interface {
+	QuotesUpdated(ctx context.Context, quotes []domain.Stock) error
+}
+
+type AlertService interface {
+	CreateRule(ctx context.Context, rule *domain.AlertRule) error
+	GetRules(ctx context.Context) ([]domain.AlertRule, error)
+	GetRule(ctx context.Context, id int64) (*domain.AlertRule, error)
+	DeleteRule(ctx context.Context, id int64) error
+	// GetAlerts returns the latest alerts, newest first; those of one rule
+	// if ruleID is not 0, which must then exist.
+	GetAlerts(ctx context.Context, ruleID int64) ([]domain.Alert, error)
+	// GetAlertsAfter returns the alerts fired after afterID, oldest first,
+	// for streaming.
+	GetAlertsAfter(ctx context.Context, afterID int64) ([]domain.Alert, error)
+}
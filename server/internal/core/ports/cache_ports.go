@@ -0,0 +1,53 @@
+package ports
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+// ErrCacheMiss is returned by Cache.Get for keys that are missing or have
+// expired.
+var ErrCacheMiss = errors.New("cache miss")
+
+// Cache is a key-value store whose entries expire. Besides cached reads, it
+// queues pending writes under a common key prefix until they are synced to
+// the database.
+type Cache interface {
+	Get(ctx context.Context, key string) (string, error)
+	// Set stores value under key for ttl, or without expiry if ttl is zero.
+	Set(ctx context.Context, key string, value string, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	// Keys returns the keys that start with prefix, in no particular order.
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	// TTL returns how long key has left to live, or a negative duration if
+	// it has no expiry or does not exist.
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	Ping(ctx context.Context) error
+}
+
+// QuoteStore holds the latest quote of each stock where every server
+// instance can read it, and tells them when quotes change.
+type QuoteStore interface {
+	// SaveQuotes stores stocks and notifies subscribers of their symbols.
+	SaveQuotes(ctx context.Context, stocks []domain.Stock) error
+	// GetQuote returns ErrCacheMiss for a symbol without a stored quote.
+	GetQuote(ctx context.Context, symbol string) (*domain.Stock, error)
+	// GetQuotes returns every stored quote, ordered by stock ID.
+	GetQuotes(ctx context.Context) ([]domain.Stock, error)
+	// Subscribe calls onChange with the symbols of every SaveQuotes, from
+	// any instance, until ctx is done. An empty list means any quote may
+	// have changed, as when the subscription is first made or re-made after
+	// a lost connection and notifications may have been missed.
+	Subscribe(ctx context.Context, onChange func(symbols []string)) error
+}
+
+// QuoteWatcher tells in-process watchers when quotes change.
+type QuoteWatcher interface {
+	// WatchQuotes calls onChange with the symbols of changed quotes, an
+	// empty list meaning any may have changed, until stop is called.
+	// onChange must not block.
+	WatchQuotes(onChange func(symbols []string)) (stop func())
+}
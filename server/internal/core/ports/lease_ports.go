@@ -0,0 +1,16 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// Leases grants named leases, each held by at most one holder at a time,
+// that expire unless renewed.
+type Leases interface {
+	// AcquireLease takes the lease for holder for ttl, or extends it if
+	// holder already has it, and reports whether holder has it.
+	AcquireLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error)
+	// ReleaseLease gives up the lease if holder has it.
+	ReleaseLease(ctx context.Context, name string, holder string) error
+}
@@ -0,0 +1,44 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+// OutboxRepository reads the events that repositories write alongside each
+// transaction insert and status update.
+type OutboxRepository interface {
+	// GetOutboxEvents returns up to limit unpublished events after the
+	// event afterID, oldest first.
+	GetOutboxEvents(ctx context.Context, afterID int64, limit int) ([]domain.OutboxEvent, error)
+	// DeleteOutboxEvents removes events once they have been published.
+	DeleteOutboxEvents(ctx context.Context, ids []int64) error
+}
+
+// EventSink publishes outbox events to another system. Publish returns once
+// the system has accepted the event. An event may be published more than
+// once, so consumers should ignore event IDs they have already seen.
+type EventSink interface {
+	Name() string
+	Publish(ctx context.Context, event domain.OutboxEvent) error
+}
+
+// EventSource delivers the events an EventSink published, on every
+// instance.
+type EventSource interface {
+	// Subscribe calls onEvent with each event published after it was
+	// called, in order, until ctx is done.
+	Subscribe(ctx context.Context, onEvent func(domain.OutboxEvent)) error
+}
+
+// OrderFeed hands the order events published through the outbox to
+// in-process subscribers, so that they follow committed order changes
+// without polling the transactions.
+type OrderFeed interface {
+	// SubscribeOrders returns the order changes published from now on and
+	// a function that ends the subscription. The channel is closed if the
+	// subscriber falls behind or the feed stops, after which the
+	// subscriber has to catch up by reading the orders.
+	SubscribeOrders() (<-chan domain.OrderChange, func())
+}
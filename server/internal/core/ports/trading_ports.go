@@ -1,24 +1,70 @@
 package ports
 
 import (
+	"context"
+	"time"
+
 	"github.com/touchsung/maxion-server/internal/core/domain"
 )
 
+// StockReader reads stock quotes. Getting an unknown symbol returns a not
+// found error.
+type StockReader interface {
+	GetAllStocks(ctx context.Context) ([]domain.Stock, error)
+	GetStockBySymbol(ctx context.Context, symbol string) (*domain.Stock, error)
+}
+
 type StockRepository interface {
-	GetAllStocks() ([]domain.Stock, error)
-	GetStockBySymbol(symbol string) (*domain.Stock, error)
-	UpdateStock(stock *domain.Stock) error
+	StockReader
+	UpdateStock(ctx context.Context, stock *domain.Stock) error
 }
 
 type TransactionRepository interface {
-	GetAllTransactions() ([]domain.Transaction, error)
-	CreateTransaction(tx *domain.Transaction) error
-	UpdateTransactionStatus(id int64, status domain.TransactionStatus) error
+	GetAllTransactions(ctx context.Context) ([]domain.Transaction, error)
+	CreateTransaction(ctx context.Context, tx *domain.Transaction) error
+	// CreateOrderGroup inserts the orders of a bracket or OCO group, all or
+	// none, and sets their IDs.
+	CreateOrderGroup(ctx context.Context, orders []domain.Transaction) error
+	// UpdateTransactionStatus applies the changes domain.GroupTransitions
+	// makes to the rest of an order's group in the same database
+	// transaction. Changes domain.CheckGroupTransition refuses return a
+	// conflict error; repeating the status of a grouped order does nothing.
+	UpdateTransactionStatus(ctx context.Context, id int64, status domain.TransactionStatus) error
+	// GetTransactionGroup returns the orders of the group of transaction id
+	// by transaction ID, or only that transaction if it isn't grouped.
+	// Getting an unknown transaction returns a not found error.
+	GetTransactionGroup(ctx context.Context, id int64) ([]domain.Transaction, error)
+}
+
+type FailedWriteRepository interface {
+	RecordFailedWrite(ctx context.Context, fw *domain.FailedWrite) error
+}
+
+// FIXSessionRepository persists FIX session sequence numbers. Getting an
+// unknown session returns a not found error.
+type FIXSessionRepository interface {
+	GetFIXSession(ctx context.Context, sessionID string) (*domain.FIXSession, error)
+	SaveFIXSession(ctx context.Context, session *domain.FIXSession) error
 }
 
 type TradingService interface {
-	GetAllStocks() ([]domain.Stock, error)
-	GetAllTransactions() ([]domain.Transaction, error)
-	CreateTransaction(tx *domain.Transaction) error
-	UpdateTransactionStatus(id int64, status domain.TransactionStatus) error
-} 
\ No newline at end of file
+	GetAllStocks(ctx context.Context) ([]domain.Stock, error)
+	GetAllTransactions(ctx context.Context) ([]domain.Transaction, error)
+	CreateTransaction(ctx context.Context, tx *domain.Transaction) error
+	// CreateOrderGroup places the orders of group and sets its ID and
+	// Orders.
+	CreateOrderGroup(ctx context.Context, group *domain.OrderGroup) error
+	UpdateTransactionStatus(ctx context.Context, id int64, status domain.TransactionStatus) error
+}
+
+type MarketCalendar interface {
+	Status(at time.Time) domain.MarketStatus
+}
+
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type HealthService interface {
+	Readiness(ctx context.Context) domain.Readiness
+}
@@ -0,0 +1,52 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+// WebhookRepository stores webhook subscriptions and their deliveries.
+// Getting, updating or deleting an unknown subscription or delivery returns
+// a not found error.
+type WebhookRepository interface {
+	CreateWebhookSubscription(ctx context.Context, sub *domain.WebhookSubscription) error
+	GetWebhookSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error)
+	GetWebhookSubscription(ctx context.Context, id int64) (*domain.WebhookSubscription, error)
+	UpdateWebhookSubscription(ctx context.Context, sub *domain.WebhookSubscription) error
+	// DeleteWebhookSubscription deletes the subscription and its deliveries.
+	DeleteWebhookSubscription(ctx context.Context, id int64) error
+
+	CreateWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	// CreateWebhookDeliveries inserts deliveries, all or none, and sets
+	// their IDs.
+	CreateWebhookDeliveries(ctx context.Context, deliveries []domain.WebhookDelivery) error
+	// GetWebhookDeliveries returns up to limit deliveries of a subscription,
+	// newest first.
+	GetWebhookDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]domain.WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, id int64) (*domain.WebhookDelivery, error)
+	// GetDueWebhookDeliveries returns up to limit pending deliveries due by
+	// now, oldest first.
+	GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+}
+
+// WebhookSender POSTs a signed webhook payload. It returns the response
+// status, or 0 if there was no response, and an error unless the receiver
+// answered with a 2xx status.
+type WebhookSender interface {
+	Send(ctx context.Context, sub domain.WebhookSubscription, delivery domain.WebhookDelivery) (int, error)
+}
+
+type WebhookService interface {
+	CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error
+	GetSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id int64) (*domain.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id int64) error
+	GetDeliveries(ctx context.Context, subscriptionID int64) ([]domain.WebhookDelivery, error)
+	// Redeliver queues the event of a delivery to be sent again, as a new
+	// delivery.
+	Redeliver(ctx context.Context, subscriptionID int64, deliveryID int64) (*domain.WebhookDelivery, error)
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/metrics"
+)
+
+// AlertEvaluator checks every alert rule against each batch of quotes from
+// the stock updater. A rule fires when its condition becomes met, rather
+// than on every quote that meets it, and not again within its cooldown, so
+// that a price oscillating around a threshold doesn't flood subscribers.
+//
+// Whether each rule's condition was met by the previous quotes is kept in
+// memory, so after a restart or a change of leader a rule whose condition is
+// already met fires once more if its cooldown has passed. QuotesUpdated is
+// called from the stock updater's goroutine only.
+type AlertEvaluator struct {
+	repo   ports.AlertRepository
+	logger *slog.Logger
+	now    func() time.Time
+	// met holds the rules whose condition the previous quotes met.
+	met map[int64]bool
+}
+
+func NewAlertEvaluator(repo ports.AlertRepository, logger *slog.Logger) *AlertEvaluator {
+	return &AlertEvaluator{
+		repo:   repo,
+		logger: logger,
+		now:    time.Now,
+		met:    make(map[int64]bool),
+	}
+}
+
+// QuotesUpdated fires the alerts quotes trigger. A rule whose alert could
+// not be recorded is tried again with the next quotes.
+func (e *AlertEvaluator) QuotesUpdated(ctx context.Context, quotes []domain.Stock) error {
+	rules, err := e.repo.GetAlertRules(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load alert rules: %w", err)
+	}
+
+	bySymbol := make(map[string]domain.Stock, len(quotes))
+	for _, quote := range quotes {
+		bySymbol[quote.Symbol] = quote
+	}
+
+	// Rebuilt on each call, so that deleted rules are forgotten.
+	met := make(map[int64]bool, len(rules))
+	var errs []error
+	for i := range rules {
+		rule := &rules[i]
+		stock, ok := bySymbol[rule.Symbol]
+		if !ok {
+			met[rule.RuleID] = e.met[rule.RuleID]
+			continue
+		}
+
+		observed, isMet := rule.Met(stock)
+		met[rule.RuleID] = isMet
+		if !isMet || e.met[rule.RuleID] {
+			continue
+		}
+
+		now := e.now().UTC()
+		if rule.CoolingDown(now) {
+			metrics.AlertsSuppressed.Inc()
+			continue
+		}
+		if err := e.fire(ctx, rule, stock, observed, now); err != nil {
+			met[rule.RuleID] = false
+			errs = append(errs, err)
+		}
+	}
+
+	e.met = met
+	return errors.Join(errs...)
+}
+
+func (e *AlertEvaluator) fire(ctx context.Context, rule *domain.AlertRule, stock domain.Stock, observed float64, now time.Time) error {
+	rule.LastFiredAt = &now
+	if rule.Type == domain.PercentMoveRule {
+		// The next move is measured from here.
+		price := rule.Price(stock)
+		rule.ReferencePrice = &price
+	}
+
+	alert := &domain.Alert{
+		RuleID:      rule.RuleID,
+		Symbol:      rule.Symbol,
+		Type:        rule.Type,
+		Observed:    observed,
+		Message:     rule.Describe(observed),
+		TriggeredAt: now,
+	}
+	if err := e.repo.RecordAlert(ctx, rule, alert); err != nil {
+		return fmt.Errorf("failed to record alert of rule %d: %w", rule.RuleID, err)
+	}
+
+	metrics.AlertsFired.WithLabelValues(string(rule.Type)).Inc()
+	e.logger.InfoContext(ctx, "alert fired",
+		"rule_id", rule.RuleID,
+		"alert_id", alert.AlertID,
+		"symbol", rule.Symbol,
+		"message", alert.Message,
+	)
+	return nil
+}
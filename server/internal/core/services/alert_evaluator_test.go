@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/repositories"
+)
+
+// alertTest evaluates quotes of AAPL against the rules it creates, on a
+// clock that moves a second per quote.
+type alertTest struct {
+	t         *testing.T
+	repo      ports.AlertRepository
+	service   *alertService
+	evaluator *AlertEvaluator
+	now       time.Time
+}
+
+func newAlertTest(t *testing.T) *alertTest {
+	repo := repositories.NewMemoryRepository([]domain.Stock{
+		{Symbol: "AAPL", BidPrice: 169.85, BidVolume: 500, AskPrice: 170.15, AskVolume: 300},
+	})
+	at := &alertTest{
+		t:         t,
+		repo:      repo,
+		service:   NewAlertService(repo, repo, logging.Discard()),
+		evaluator: NewAlertEvaluator(repo, logging.Discard()),
+		now:       time.Date(2024, 1, 1, 15, 0, 0, 0, time.UTC),
+	}
+	at.evaluator.now = func() time.Time { return at.now }
+	return at
+}
+
+func (at *alertTest) createRule(rule domain.AlertRule) *domain.AlertRule {
+	rule.Symbol = "AAPL"
+	require.NoError(at.t, at.service.CreateRule(context.Background(), &rule))
+	return &rule
+}
+
+// quote evaluates an AAPL quote a second after the previous one and returns
+// the messages of the alerts it fired.
+func (at *alertTest) quote(askPrice float64, askVolume int) []string {
+	at.now = at.now.Add(time.Second)
+	before, err := at.repo.GetAlerts(context.Background(), 0, 1000)
+	require.NoError(at.t, err)
+
+	require.NoError(at.t, at.evaluator.QuotesUpdated(context.Background(), []domain.Stock{
+		{Symbol: "AAPL", BidPrice: askPrice - 0.30, BidVolume: 500, AskPrice: askPrice, AskVolume: askVolume},
+	}))
+
+	after, err := at.repo.GetAlerts(context.Background(), 0, 1000)
+	require.NoError(at.t, err)
+	var messages []string
+	for _, alert := range after[:len(after)-len(before)] {
+		messages = append([]string{alert.Message}, messages...)
+	}
+	return messages
+}
+
+func TestAlertEvaluator_Threshold(t *testing.T) {
+	at := newAlertTest(t)
+	at.createRule(domain.AlertRule{Type: domain.ThresholdRule, Side: domain.AskSide, Direction: domain.Below, Value: 165, CooldownSeconds: 60})
+
+	assert.Empty(t, at.quote(166, 300))
+	assert.Equal(t, []string{"AAPL ask fell below 165 (164.80)"}, at.quote(164.8, 300))
+	// Staying below is not a new crossing.
+	assert.Empty(t, at.quote(164.5, 300))
+}
+
+func TestAlertEvaluator_CooldownSuppressesOscillation(t *testing.T) {
+	at := newAlertTest(t)
+	rule := at.createRule(domain.AlertRule{Type: domain.ThresholdRule, Side: domain.AskSide, Direction: domain.Below, Value: 165, CooldownSeconds: 60})
+
+	fired := 0
+	for i := 0; i < 10; i++ {
+		fired += len(at.quote(164.9, 300))
+		fired += len(at.quote(165.1, 300))
+	}
+	assert.Equal(t, 1, fired)
+
+	// Once the cooldown has passed the next crossing fires.
+	at.now = at.now.Add(time.Minute)
+	assert.Len(t, at.quote(164.9, 300), 1)
+
+	alerts, err := at.service.GetAlerts(context.Background(), rule.RuleID)
+	require.NoError(t, err)
+	assert.Len(t, alerts, 2)
+}
+
+func TestAlertEvaluator_PercentMove(t *testing.T) {
+	at := newAlertTest(t)
+	rule := at.createRule(domain.AlertRule{Type: domain.PercentMoveRule, Side: domain.AskSide, Direction: domain.Above, Value: 2, CooldownSeconds: 1})
+	require.NotNil(t, rule.ReferencePrice)
+	assert.Equal(t, 170.15, *rule.ReferencePrice)
+
+	assert.Empty(t, at.quote(173, 300))
+	assert.Equal(t, []string{"AAPL ask rose 2.03%"}, at.quote(173.6, 300))
+
+	// The next move is measured from the price it fired at.
+	got, err := at.service.GetRule(context.Background(), rule.RuleID)
+	require.NoError(t, err)
+	assert.Equal(t, 173.6, *got.ReferencePrice)
+	assert.Empty(t, at.quote(175, 300))
+	assert.Len(t, at.quote(177.2, 300), 1)
+}
+
+func TestAlertEvaluator_Volume(t *testing.T) {
+	at := newAlertTest(t)
+	at.createRule(domain.AlertRule{Type: domain.VolumeRule, Side: domain.AskSide, Direction: domain.Above, Value: 1000})
+
+	assert.Empty(t, at.quote(170, 900))
+	assert.Equal(t, []string{"AAPL ask volume rose above 1000 (1200)"}, at.quote(170, 1200))
+}
+
+func TestAlertEvaluator_DeletedRule(t *testing.T) {
+	at := newAlertTest(t)
+	rule := at.createRule(domain.AlertRule{Type: domain.ThresholdRule, Side: domain.AskSide, Direction: domain.Below, Value: 165})
+
+	require.NoError(t, at.service.DeleteRule(context.Background(), rule.RuleID))
+	assert.Empty(t, at.quote(164, 300))
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+)
+
+const (
+	// defaultAlertCooldown is how long a rule stays quiet after firing, if
+	// it doesn't say.
+	defaultAlertCooldown = 5 * time.Minute
+	// alertHistorySize is how many of the latest alerts are listed or
+	// streamed at a time.
+	alertHistorySize = 100
+)
+
+type alertService struct {
+	repo   ports.AlertRepository
+	stocks ports.StockReader
+	logger *slog.Logger
+}
+
+// NewAlertService manages alert rules and reads the alerts they fired. The
+// rules are evaluated by an AlertEvaluator.
+func NewAlertService(repo ports.AlertRepository, stocks ports.StockReader, logger *slog.Logger) *alertService {
+	return &alertService{repo: repo, stocks: stocks, logger: logger}
+}
+
+// CreateRule checks that the rule's stock exists. A percent move rule is
+// measured from the current price.
+func (s *alertService) CreateRule(ctx context.Context, rule *domain.AlertRule) error {
+	stock, err := s.stocks.GetStockBySymbol(ctx, rule.Symbol)
+	if err != nil {
+		return err
+	}
+
+	rule.ReferencePrice = nil
+	if rule.Type == domain.PercentMoveRule {
+		price := rule.Price(*stock)
+		rule.ReferencePrice = &price
+	}
+	if rule.CooldownSeconds == 0 {
+		rule.CooldownSeconds = int(defaultAlertCooldown.Seconds())
+	}
+	rule.LastFiredAt = nil
+	rule.CreatedAt = time.Now().UTC()
+	return s.repo.CreateAlertRule(ctx, rule)
+}
+
+func (s *alertService) GetRules(ctx context.Context) ([]domain.AlertRule, error) {
+	return s.repo.GetAlertRules(ctx)
+}
+
+func (s *alertService) GetRule(ctx context.Context, id int64) (*domain.AlertRule, error) {
+	return s.repo.GetAlertRule(ctx, id)
+}
+
+func (s *alertService) DeleteRule(ctx context.Context, id int64) error {
+	return s.repo.DeleteAlertRule(ctx, id)
+}
+
+func (s *alertService) GetAlerts(ctx context.Context, ruleID int64) ([]domain.Alert, error) {
+	if ruleID != 0 {
+		if _, err := s.repo.GetAlertRule(ctx, ruleID); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.GetAlerts(ctx, ruleID, alertHistorySize)
+}
+
+func (s *alertService) GetAlertsAfter(ctx context.Context, afterID int64) ([]domain.Alert, error) {
+	return s.repo.GetAlertsAfter(ctx, afterID, alertHistorySize)
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/repositories"
+)
+
+func TestAlertService_CreateRule(t *testing.T) {
+	repo := repositories.NewMemoryRepository(repositories.DemoStocks())
+	service := NewAlertService(repo, repo, logging.Discard())
+	ctx := context.Background()
+
+	rule := &domain.AlertRule{Symbol: "AAPL", Type: domain.ThresholdRule, Side: domain.AskSide, Direction: domain.Below, Value: 165}
+	require.NoError(t, service.CreateRule(ctx, rule))
+	assert.NotZero(t, rule.RuleID)
+	assert.Equal(t, 300, rule.CooldownSeconds)
+	assert.Nil(t, rule.ReferencePrice)
+	assert.False(t, rule.CreatedAt.IsZero())
+
+	percent := &domain.AlertRule{Symbol: "AAPL", Type: domain.PercentMoveRule, Side: domain.BidSide, Direction: domain.Below, Value: 1, CooldownSeconds: 30}
+	require.NoError(t, service.CreateRule(ctx, percent))
+	require.NotNil(t, percent.ReferencePrice)
+	assert.Equal(t, 169.85, *percent.ReferencePrice)
+	assert.Equal(t, 30, percent.CooldownSeconds)
+
+	rules, err := service.GetRules(ctx)
+	require.NoError(t, err)
+	assert.Len(t, rules, 2)
+
+	err = service.CreateRule(ctx, &domain.AlertRule{Symbol: "ZZZZ", Type: domain.VolumeRule, Side: domain.BidSide, Direction: domain.Above, Value: 1})
+	kind, _ := domain.ErrorKindOf(err)
+	assert.Equal(t, domain.KindNotFound, kind)
+}
+
+func TestAlertService_GetAlerts_UnknownRule(t *testing.T) {
+	repo := repositories.NewMemoryRepository(nil)
+	service := NewAlertService(repo, repo, logging.Discard())
+
+	_, err := service.GetAlerts(context.Background(), 7)
+	kind, _ := domain.ErrorKindOf(err)
+	assert.Equal(t, domain.KindNotFound, kind)
+
+	alerts, err := service.GetAlerts(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Empty(t, alerts)
+}
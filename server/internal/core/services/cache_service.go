@@ -3,181 +3,625 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sort"
+	"sync"
 	"time"
 
-	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
 	"github.com/touchsung/maxion-server/internal/core/domain"
 	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/metrics"
+	"github.com/touchsung/maxion-server/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	CACHE_DURATION        = 30 * time.Second
-	SYNC_INTERVAL         = 15 * time.Second
 	PENDING_CREATE_PREFIX = "pending_create_tx:"
 	PENDING_UPDATE_PREFIX = "pending_update_tx:"
 	ALL_TRANSACTIONS_KEY  = "all_transactions"
+	// GROUP_UPDATES_PREFIX keys the index of the pending updates of each
+	// order group.
+	GROUP_UPDATES_PREFIX = "group_updates:"
 )
 
+// groupLockStripes is the number of locks order groups are spread over.
+const groupLockStripes = 64
+
+// CacheService caches transaction reads and queues transaction writes in
+// the cache until Sync writes them to the database. Syncs are run in the
+// background by a SyncWorker.
 type CacheService struct {
-	redis *redis.Client
-	db    ports.TransactionRepository
+	cache        ports.Cache
+	db           ports.TransactionRepository
+	failures     ports.FailedWriteRepository
+	logger       *slog.Logger
+	ttl          time.Duration
+	syncInterval time.Duration
+	// groupLocks serialise checking and caching the status updates of each
+	// order group, so that two updates racing on one group can't both pass
+	// its rules. Groups whose IDs hash alike share a lock.
+	groupLocks [groupLockStripes]sync.Mutex
+}
+
+// SyncFailure is a pending write that could not be written to the database.
+// Recorded reports whether it was saved to the failed writes table.
+type SyncFailure struct {
+	Key      string
+	Payload  string
+	Err      error
+	Recorded bool
+}
+
+type FlushReport struct {
+	Synced   int
+	Failures []SyncFailure
+}
+
+// pendingCreate is the cached payload of a transaction awaiting insert. Trace
+// carries the span context of the request that created it, under a key
+// that cannot clash with the transaction's own fields. Group holds the
+// other orders of its order group, which are inserted with it.
+type pendingCreate struct {
+	domain.Transaction
+	Group []domain.Transaction `json:"_group,omitempty"`
+	Trace map[string]string    `json:"_trace,omitempty"`
+}
+
+// pendingUpdate is the cached payload of a status update awaiting sync.
+// Trace is keyed as in pendingCreate.
+type pendingUpdate struct {
+	ID     int64                    `json:"id"`
+	Status domain.TransactionStatus `json:"status"`
+	Trace  map[string]string        `json:"_trace,omitempty"`
+}
+
+// groupUpdate is an entry of the index of a group's pending updates. Key is
+// the pending update's cache key.
+type groupUpdate struct {
+	Key      string                   `json:"key"`
+	ID       int64                    `json:"id"`
+	Status   domain.TransactionStatus `json:"status"`
+	CachedAt time.Time                `json:"cachedAt"`
 }
 
 type syncResult struct {
-	key string
-	err error
+	operation string
+	key       string
+	payload   string
+	err       error
+	// recorded is set once a failed write has been moved to the failed
+	// writes table.
+	recorded bool
 }
 
+// errUndecodable marks pending writes that can never be synced, so there is
+// no point retrying them.
+var errUndecodable = errors.New("undecodable pending write")
+
 func generateCacheKey(key string, txID string) string {
 	return fmt.Sprintf("%s_%s", key, txID)
 }
 
-func NewCacheService(redisClient *redis.Client, db ports.TransactionRepository) *CacheService {
-	service := &CacheService{
-		redis: redisClient,
-		db:    db,
-	}
+// pendingWriteIDFormat has a fixed width, so that IDs sort in time order.
+const pendingWriteIDFormat = "2006-01-02T15:04:05.000000000Z"
 
-	go service.startBackgroundSync()
+// pendingWriteID is time-ordered, so that writes are synced in the order
+// they were cached, with a random suffix so writes cached within the same
+// instant don't overwrite each other.
+func pendingWriteID() string {
+	return time.Now().UTC().Format(pendingWriteIDFormat) + "-" + uuid.NewString()[:8]
+}
 
-	return service
+func NewCacheService(
+	cache ports.Cache,
+	db ports.TransactionRepository,
+	failures ports.FailedWriteRepository,
+	ttl time.Duration,
+	syncInterval time.Duration,
+	logger *slog.Logger,
+) *CacheService {
+	return &CacheService{
+		cache:        cache,
+		db:           db,
+		failures:     failures,
+		logger:       logger,
+		ttl:          ttl,
+		syncInterval: syncInterval,
+	}
 }
 
-func (s *CacheService) CacheTransaction(tx *domain.Transaction) error {
-	txJSON, err := json.Marshal(tx)
+func (s *CacheService) CacheTransaction(ctx context.Context, tx *domain.Transaction) error {
+	ctx, span := tracing.Tracer().Start(ctx, "CacheService.CacheTransaction")
+	defer span.End()
+
+	txJSON, err := json.Marshal(pendingCreate{
+		Transaction: *tx,
+		Trace:       tracing.Inject(ctx),
+	})
 	if err != nil {
 		return fmt.Errorf("failed to marshal transaction: %w", err)
 	}
 
-	key := generateCacheKey(PENDING_CREATE_PREFIX, time.Now().UTC().Format(time.RFC3339))
-	if err := s.setCache(context.Background(), key, txJSON); err != nil {
-		return err
+	key := generateCacheKey(PENDING_CREATE_PREFIX, pendingWriteID())
+	if err := s.setCache(ctx, key, txJSON); err != nil {
+		tracing.RecordError(span, err)
+		return domain.NewUnavailableError("order_cache_unavailable", "orders cannot be accepted right now", err)
 	}
 
-	s.redis.Del(context.Background(), ALL_TRANSACTIONS_KEY)
+	s.invalidateTransactions(ctx)
 
 	return nil
 }
 
-func (s *CacheService) CacheTransactionUpdate(id int64, status domain.TransactionStatus) error {
-	update := struct {
-		ID     int64                    `json:"id"`
-		Status domain.TransactionStatus `json:"status"`
-	}{
-		ID:     id,
-		Status: status,
+// CacheOrderGroup queues the orders of a group as a single write, so that
+// they are inserted together.
+func (s *CacheService) CacheOrderGroup(ctx context.Context, orders []domain.Transaction) error {
+	ctx, span := tracing.Tracer().Start(ctx, "CacheService.CacheOrderGroup")
+	defer span.End()
+
+	txJSON, err := json.Marshal(pendingCreate{
+		Transaction: orders[0],
+		Group:       orders[1:],
+		Trace:       tracing.Inject(ctx),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal order group: %w", err)
+	}
+
+	key := generateCacheKey(PENDING_CREATE_PREFIX, pendingWriteID())
+	if err := s.setCache(ctx, key, txJSON); err != nil {
+		tracing.RecordError(span, err)
+		return domain.NewUnavailableError("order_cache_unavailable", "orders cannot be accepted right now", err)
 	}
 
-	updateJSON, err := json.Marshal(update)
+	s.invalidateTransactions(ctx)
+
+	return nil
+}
+
+// CacheTransactionUpdate queues a status update. An update the rules of the
+// order's group refuse returns the conflict error its sync would fail with.
+func (s *CacheService) CacheTransactionUpdate(ctx context.Context, id int64, status domain.TransactionStatus) error {
+	ctx, span := tracing.Tracer().Start(ctx, "CacheService.CacheTransactionUpdate",
+		trace.WithAttributes(attribute.Int64("transaction.id", id)))
+	defer span.End()
+
+	// Orders outside a group, the common case, need no lock or check.
+	group := s.transactionGroup(ctx, id)
+	var groupID string
+	if group != nil {
+		groupID = *group[0].GroupID
+		unlock := s.lockGroup(groupID)
+		defer unlock()
+
+		if err := s.checkGroupUpdate(ctx, group, id, status); err != nil {
+			tracing.RecordError(span, err)
+			return err
+		}
+	}
+
+	updateJSON, err := json.Marshal(pendingUpdate{
+		ID:     id,
+		Status: status,
+		Trace:  tracing.Inject(ctx),
+	})
 	if err != nil {
 		return fmt.Errorf("failed to marshal update: %w", err)
 	}
 
-	key := generateCacheKey(PENDING_UPDATE_PREFIX, time.Now().UTC().Format(time.RFC3339))
-	if err := s.setCache(context.Background(), key, updateJSON); err != nil {
-		return err
+	key := generateCacheKey(PENDING_UPDATE_PREFIX, pendingWriteID())
+	if err := s.setCache(ctx, key, updateJSON); err != nil {
+		tracing.RecordError(span, err)
+		return domain.NewUnavailableError("order_cache_unavailable", "orders cannot be updated right now", err)
+	}
+	if group != nil {
+		s.indexGroupUpdate(ctx, groupID, groupUpdate{Key: key, ID: id, Status: status, CachedAt: time.Now().UTC()})
 	}
 
-	s.redis.Del(context.Background(), ALL_TRANSACTIONS_KEY)
+	s.invalidateTransactions(ctx)
 
 	return nil
 }
 
-func (s *CacheService) handleCreateSync(key string, txJSON string) syncResult {
-	var tx domain.Transaction
-	if err := json.Unmarshal([]byte(txJSON), &tx); err != nil {
-		return syncResult{key, fmt.Errorf("failed to unmarshal transaction: %w", err)}
+// transactionGroup returns the orders of the group of order id, or nil if
+// it isn't grouped. Orders not in the database yet are left for the sync to
+// check, as is every order while the database can't be read.
+func (s *CacheService) transactionGroup(ctx context.Context, id int64) []domain.Transaction {
+	group, err := s.db.GetTransactionGroup(ctx, id)
+	if kind, _ := domain.ErrorKindOf(err); kind == domain.KindNotFound {
+		return nil
 	}
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to read order group", "transaction_id", id, "error", err)
+		return nil
+	}
+	if group[0].GroupID == nil {
+		return nil
+	}
+	return group
+}
+
+func (s *CacheService) lockGroup(groupID string) (unlock func()) {
+	h := fnv.New32a()
+	h.Write([]byte(groupID))
+	mu := &s.groupLocks[h.Sum32()%groupLockStripes]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// checkGroupUpdate applies the rules of group to order id moving to status,
+// on the group as it will be once its pending updates are synced. Indexed
+// updates stay in the index once synced, so one synced since the group was
+// read is applied twice, which changes nothing, rather than missed. Every
+// order is left for the sync to check while the index can't be read, and
+// other instances check their updates independently, so the sync still has
+// the last word.
+func (s *CacheService) checkGroupUpdate(ctx context.Context, group []domain.Transaction, id int64, status domain.TransactionStatus) error {
+	pending, err := s.groupUpdates(ctx, *group[0].GroupID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to read pending updates to check order group", "transaction_id", id, "error", err)
+		return nil
+	}
+
+	for _, update := range pending {
+		// An update the group refuses fails at sync, changing nothing.
+		_ = domain.ApplyGroupStatus(group, update.ID, update.Status)
+	}
+	return domain.ApplyGroupStatus(group, id, status)
+}
+
+// groupUpdates returns the indexed updates of a group, in the order they
+// were cached.
+func (s *CacheService) groupUpdates(ctx context.Context, groupID string) ([]groupUpdate, error) {
+	value, err := s.cache.Get(ctx, GROUP_UPDATES_PREFIX+groupID)
+	if errors.Is(err, ports.ErrCacheMiss) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var updates []groupUpdate
+	if err := json.Unmarshal([]byte(value), &updates); err != nil {
+		return nil, err
+	}
+	sort.Slice(updates, func(i, j int) bool { return updates[i].Key < updates[j].Key })
+	return updates, nil
+}
 
-	if err := s.db.CreateTransaction(&tx); err != nil {
-		return syncResult{key, fmt.Errorf("failed to create transaction: %w", err)}
+// indexGroupUpdate adds update to the index of its group, dropping entries
+// whose pending update has expired. The caller holds the group's lock.
+func (s *CacheService) indexGroupUpdate(ctx context.Context, groupID string, update groupUpdate) {
+	updates, err := s.groupUpdates(ctx, groupID)
+	if err != nil {
+		// Rather than overwrite the entries that couldn't be read.
+		s.logger.WarnContext(ctx, "failed to index pending update of order group", "group_id", groupID, "error", err)
+		return
+	}
+	kept := []groupUpdate{update}
+	for _, indexed := range updates {
+		if update.CachedAt.Sub(indexed.CachedAt) < s.ttl {
+			kept = append(kept, indexed)
+		}
 	}
 
-	return syncResult{key, nil}
+	value, err := json.Marshal(kept)
+	if err == nil {
+		err = s.setCache(ctx, GROUP_UPDATES_PREFIX+groupID, value)
+	}
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to index pending update of order group", "group_id", groupID, "error", err)
+	}
 }
 
-func (s *CacheService) handleUpdateSync(key string, updateJSON string) syncResult {
-	var update struct {
-		ID     int64                    `json:"id"`
-		Status domain.TransactionStatus `json:"status"`
+func (s *CacheService) invalidateTransactions(ctx context.Context) {
+	if err := s.cache.Delete(ctx, ALL_TRANSACTIONS_KEY); err != nil {
+		s.logger.WarnContext(ctx, "failed to invalidate cached transactions", "error", err)
 	}
+}
 
+// handleCreateSync runs outside of any request, so its span starts a new
+// trace linked to the request that cached the transaction.
+func (s *CacheService) handleCreateSync(ctx context.Context, key string, txJSON string) syncResult {
+	var pending pendingCreate
+	if err := json.Unmarshal([]byte(txJSON), &pending); err != nil {
+		return syncResult{key: key, err: fmt.Errorf("%w: failed to unmarshal transaction: %v", errUndecodable, err)}
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "CacheService.syncCreate",
+		trace.WithNewRoot(),
+		trace.WithLinks(tracing.LinkFrom(pending.Trace)),
+		trace.WithAttributes(attribute.String("cache.key", key)),
+	)
+	defer span.End()
+
+	if len(pending.Group) > 0 {
+		orders := append([]domain.Transaction{pending.Transaction}, pending.Group...)
+		if err := s.db.CreateOrderGroup(ctx, orders); err != nil {
+			tracing.RecordError(span, err)
+			return syncResult{key: key, err: fmt.Errorf("failed to create order group: %w", err)}
+		}
+		return syncResult{key: key}
+	}
+
+	tx := pending.Transaction
+	if err := s.db.CreateTransaction(ctx, &tx); err != nil {
+		tracing.RecordError(span, err)
+		return syncResult{key: key, err: fmt.Errorf("failed to create transaction: %w", err)}
+	}
+
+	return syncResult{key: key}
+}
+
+func (s *CacheService) handleUpdateSync(ctx context.Context, key string, updateJSON string) syncResult {
+	var update pendingUpdate
 	if err := json.Unmarshal([]byte(updateJSON), &update); err != nil {
-		return syncResult{key, fmt.Errorf("failed to unmarshal update: %w", err)}
+		return syncResult{key: key, err: fmt.Errorf("%w: failed to unmarshal update: %v", errUndecodable, err)}
 	}
 
-	if err := s.db.UpdateTransactionStatus(update.ID, update.Status); err != nil {
-		return syncResult{key, fmt.Errorf("failed to update transaction: %w", err)}
+	ctx, span := tracing.Tracer().Start(ctx, "CacheService.syncUpdate",
+		trace.WithNewRoot(),
+		trace.WithLinks(tracing.LinkFrom(update.Trace)),
+		trace.WithAttributes(
+			attribute.String("cache.key", key),
+			attribute.Int64("transaction.id", update.ID),
+		),
+	)
+	defer span.End()
+
+	if err := s.db.UpdateTransactionStatus(ctx, update.ID, update.Status); err != nil {
+		tracing.RecordError(span, err)
+		return syncResult{key: key, err: fmt.Errorf("failed to update transaction: %w", err)}
 	}
 
-	return syncResult{key, nil}
+	return syncResult{key: key}
 }
 
-func (s *CacheService) syncCreates(ctx context.Context) {
-	keys, err := s.redis.Keys(ctx, PENDING_CREATE_PREFIX+"*").Result()
+func (s *CacheService) syncCreates(ctx context.Context) []syncResult {
+	return s.syncPending(ctx, "create", PENDING_CREATE_PREFIX, s.handleCreateSync)
+}
+
+func (s *CacheService) syncUpdates(ctx context.Context) []syncResult {
+	return s.syncPending(ctx, "update", PENDING_UPDATE_PREFIX, s.handleUpdateSync)
+}
+
+func (s *CacheService) syncPending(
+	ctx context.Context,
+	operation string,
+	prefix string,
+	handle func(ctx context.Context, key string, value string) syncResult,
+) []syncResult {
+	keys, err := s.cache.Keys(ctx, prefix)
 	if err != nil {
-		return
+		s.logger.ErrorContext(ctx, "failed to list pending writes", "operation", operation, "error", err)
+		return []syncResult{{
+			operation: operation,
+			key:       prefix + "*",
+			err:       fmt.Errorf("failed to list pending writes: %w", err),
+		}}
 	}
+	// In the order they were cached, as the rules of order groups depend on
+	// the order of status changes.
+	sort.Strings(keys)
 
+	results := make([]syncResult, 0, len(keys))
+	remaining := len(keys)
 	for _, key := range keys {
-		txJSON, err := s.redis.Get(ctx, key).Result()
+		value, err := s.cache.Get(ctx, key)
+		if errors.Is(err, ports.ErrCacheMiss) {
+			// Expired or picked up by a concurrent sync.
+			remaining--
+			continue
+		}
 		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to read pending write", "operation", operation, "key", key, "error", err)
+			results = append(results, syncResult{
+				operation: operation,
+				key:       key,
+				err:       fmt.Errorf("failed to read pending write: %w", err),
+			})
 			continue
 		}
 
-		result := s.handleCreateSync(key, txJSON)
+		start := time.Now()
+		result := handle(ctx, key, value)
+		result.operation = operation
+		result.payload = value
+		metrics.CacheSyncDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+		if result.err == nil {
+			// Even if cancelled meanwhile, as the write has been made.
+			if err := s.cache.Delete(context.WithoutCancel(ctx), result.key); err != nil {
+				result.err = fmt.Errorf("synced but failed to remove pending write: %w", err)
+			}
+		}
+
 		if result.err == nil {
-			s.redis.Del(ctx, result.key)
+			remaining--
+			metrics.CacheSyncs.WithLabelValues(operation, "success").Inc()
+			results = append(results, result)
+			continue
+		}
+
+		metrics.CacheSyncs.WithLabelValues(operation, "failure").Inc()
+		s.logger.ErrorContext(ctx, "pending write sync failed",
+			"operation", operation,
+			"key", key,
+			"error", result.err,
+		)
+
+		// Retrying can't fix a permanent failure, and a write that expires
+		// before the next sync would be lost, so move both aside instead.
+		if isPermanentSyncError(result.err) || s.expiresBeforeNextSync(ctx, key) {
+			if s.recordFailedWrite(ctx, &result) {
+				remaining--
+			}
 		}
+		results = append(results, result)
 	}
+
+	metrics.CachePendingWrites.WithLabelValues(operation).Set(float64(remaining))
+	return results
 }
 
-func (s *CacheService) syncUpdates(ctx context.Context) {
-	keys, err := s.redis.Keys(ctx, PENDING_UPDATE_PREFIX+"*").Result()
+// isPermanentSyncError reports whether a failed write would fail again
+// however often it is retried.
+func isPermanentSyncError(err error) bool {
+	if errors.Is(err, errUndecodable) {
+		return true
+	}
+	switch kind, _ := domain.ErrorKindOf(err); kind {
+	case domain.KindNotFound, domain.KindValidation, domain.KindConflict:
+		return true
+	}
+	return false
+}
+
+func (s *CacheService) expiresBeforeNextSync(ctx context.Context, key string) bool {
+	ttl, err := s.cache.TTL(ctx, key)
 	if err != nil {
-		return
+		return false
 	}
+	// Negative values mean the key has no expiry or no longer exists.
+	return ttl >= 0 && ttl < s.syncInterval
+}
 
-	for _, key := range keys {
-		updateJSON, err := s.redis.Get(ctx, key).Result()
-		if err != nil {
-			continue
+// recordFailedWrite persists a pending write that could not be synced and
+// removes it from the cache. It reports whether the write was recorded.
+func (s *CacheService) recordFailedWrite(ctx context.Context, result *syncResult) bool {
+	failed := &domain.FailedWrite{
+		CacheKey:  result.key,
+		Operation: result.operation,
+		Payload:   result.payload,
+		Error:     result.err.Error(),
+		FailedAt:  time.Now().UTC(),
+	}
+
+	if err := s.failures.RecordFailedWrite(ctx, failed); err != nil {
+		s.logger.ErrorContext(ctx, "failed to record failed write",
+			"operation", result.operation,
+			"key", result.key,
+			"payload", result.payload,
+			"error", err,
+		)
+		return false
+	}
+
+	if err := s.cache.Delete(ctx, result.key); err != nil {
+		s.logger.WarnContext(ctx, "failed to remove recorded write from cache", "key", result.key, "error", err)
+	}
+
+	result.recorded = true
+	s.logger.WarnContext(ctx, "pending write moved to failed writes table",
+		"operation", result.operation,
+		"key", result.key,
+		"failed_write_id", failed.FailedWriteID,
+	)
+	return true
+}
+
+func (s *CacheService) setCache(ctx context.Context, key string, value []byte) error {
+	return s.cache.Set(ctx, key, string(value), s.ttl)
+}
+
+// Sync writes every pending create, then every pending update, to the
+// database. Writes that fail permanently, or would expire before the next
+// sync, are moved to the failed writes table; the rest are retried by the
+// next sync. The returned error joins every write that was not synced.
+func (s *CacheService) Sync(ctx context.Context) error {
+	var errs []error
+	for _, result := range append(s.syncCreates(ctx), s.syncUpdates(ctx)...) {
+		if result.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.key, result.err))
 		}
+	}
+	return errors.Join(errs...)
+}
 
-		result := s.handleUpdateSync(key, updateJSON)
-		if result.err == nil {
-			s.redis.Del(ctx, result.key)
+// SyncInterval is how often the pending writes are expected to be synced.
+func (s *CacheService) SyncInterval() time.Duration {
+	return s.syncInterval
+}
+
+// PendingCount returns the number of creates and updates waiting in the
+// cache to be written to the database.
+func (s *CacheService) PendingCount(ctx context.Context) (int, error) {
+	count := 0
+	for _, prefix := range []string{PENDING_CREATE_PREFIX, PENDING_UPDATE_PREFIX} {
+		keys, err := s.cache.Keys(ctx, prefix)
+		if err != nil {
+			return 0, err
 		}
+		count += len(keys)
 	}
+	return count, nil
 }
 
-func (s *CacheService) setCache(ctx context.Context, key string, value []byte) error {
-	return s.redis.Set(ctx, key, value, CACHE_DURATION).Err()
+func (s *CacheService) Ping(ctx context.Context) error {
+	return s.cache.Ping(ctx)
 }
 
-func (s *CacheService) startBackgroundSync() {
-	ticker := time.NewTicker(SYNC_INTERVAL)
-	ctx := context.Background()
+// Flush synchronously writes every pending create and update to the
+// database. Writes that still fail are moved to the failed writes table, as
+// they would otherwise expire from the cache while the server is down. The report
+// lists every write that could not be flushed.
+func (s *CacheService) Flush(ctx context.Context) FlushReport {
+	var report FlushReport
 
-	for range ticker.C {
-		s.syncCreates(ctx)
-		s.syncUpdates(ctx)
+	results := append(s.syncCreates(ctx), s.syncUpdates(ctx)...)
+	for _, result := range results {
+		if result.err == nil {
+			report.Synced++
+			continue
+		}
+
+		if !result.recorded && result.payload != "" {
+			s.recordFailedWrite(ctx, &result)
+		}
+
+		report.Failures = append(report.Failures, SyncFailure{
+			Key:      result.key,
+			Payload:  result.payload,
+			Err:      result.err,
+			Recorded: result.recorded,
+		})
 	}
+
+	return report
 }
 
 func (s *CacheService) GetAllTransactions(ctx context.Context) ([]domain.Transaction, error) {
-	txJSON, err := s.redis.Get(ctx, ALL_TRANSACTIONS_KEY).Result()
+	ctx, span := tracing.Tracer().Start(ctx, "CacheService.GetAllTransactions")
+	defer span.End()
+
+	txJSON, err := s.cache.Get(ctx, ALL_TRANSACTIONS_KEY)
 	if err == nil {
+		metrics.TransactionsCacheRequests.WithLabelValues("hit").Inc()
+		span.SetAttributes(attribute.Bool("cache.hit", true))
 		var transactions []domain.Transaction
 		if err := json.Unmarshal([]byte(txJSON), &transactions); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal cached transactions: %w", err)
 		}
 		return transactions, nil
 	}
+	// A cancelled or expired request shouldn't fall through to the database.
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		tracing.RecordError(span, ctxErr)
+		return nil, ctxErr
+	}
+	if !errors.Is(err, ports.ErrCacheMiss) {
+		s.logger.WarnContext(ctx, "failed to read cached transactions", "error", err)
+	}
+	metrics.TransactionsCacheRequests.WithLabelValues("miss").Inc()
+	span.SetAttributes(attribute.Bool("cache.hit", false))
 
-	transactions, err := s.db.GetAllTransactions()
+	transactions, err := s.db.GetAllTransactions(ctx)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 
@@ -187,7 +631,7 @@ func (s *CacheService) GetAllTransactions(ctx context.Context) ([]domain.Transac
 	}
 
 	if err := s.setCache(ctx, ALL_TRANSACTIONS_KEY, txJSONBytes); err != nil {
-		fmt.Printf("failed to cache transactions: %v\n", err)
+		s.logger.WarnContext(ctx, "failed to cache transactions", "error", err)
 	}
 
 	return transactions, nil
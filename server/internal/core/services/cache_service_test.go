@@ -2,62 +2,71 @@ package services
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
-	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/repositories"
+	"github.com/touchsung/maxion-server/internal/tracing"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const (
+	testCacheTTL     = 30 * time.Second
+	testSyncInterval = time.Second
 )
 
 type MockTransactionRepository struct {
 	mock.Mock
 }
 
-func (m *MockTransactionRepository) CreateTransaction(tx *domain.Transaction) error {
-	args := m.Called(tx)
+func (m *MockTransactionRepository) CreateTransaction(ctx context.Context, tx *domain.Transaction) error {
+	args := m.Called(ctx, tx)
 	return args.Error(0)
 }
 
-func (m *MockTransactionRepository) UpdateTransactionStatus(id int64, status domain.TransactionStatus) error {
-	args := m.Called(id, status)
+func (m *MockTransactionRepository) CreateOrderGroup(ctx context.Context, orders []domain.Transaction) error {
+	args := m.Called(ctx, orders)
 	return args.Error(0)
 }
 
-func (m *MockTransactionRepository) GetAllTransactions() ([]domain.Transaction, error) {
-	args := m.Called()
-	return args.Get(0).([]domain.Transaction), args.Error(1)
+func (m *MockTransactionRepository) UpdateTransactionStatus(ctx context.Context, id int64, status domain.TransactionStatus) error {
+	args := m.Called(ctx, id, status)
+	return args.Error(0)
 }
 
-func setupRedis(t *testing.T) *redis.Client {
-	client := redis.NewClient(&redis.Options{
-		Addr: "localhost:6379",
-		DB:   0,
-	})
-
-	_, err := client.Ping(context.Background()).Result()
-	if err != nil {
-		t.Fatalf("Failed to connect to Redis: %v", err)
+func (m *MockTransactionRepository) GetTransactionGroup(ctx context.Context, id int64) ([]domain.Transaction, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
 	}
+	return args.Get(0).([]domain.Transaction), args.Error(1)
+}
 
-	return client
+func (m *MockTransactionRepository) GetAllTransactions(ctx context.Context) ([]domain.Transaction, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.Transaction), args.Error(1)
 }
 
-func cleanupRedis(client *redis.Client, t *testing.T) {
-	err := client.FlushDB(context.Background()).Err()
-	if err != nil {
-		t.Errorf("Failed to flush Redis DB: %v", err)
-	}
-	client.Close()
+type MockFailedWriteRepository struct {
+	mock.Mock
+}
+
+func (m *MockFailedWriteRepository) RecordFailedWrite(ctx context.Context, fw *domain.FailedWrite) error {
+	args := m.Called(ctx, fw)
+	return args.Error(0)
 }
 
 func TestCacheService_CacheTransaction(t *testing.T) {
-	redisClient := setupRedis(t)
-	defer cleanupRedis(redisClient, t)
+	cache := repositories.NewMemoryCache()
 
 	mockDB := new(MockTransactionRepository)
-	cacheService := NewCacheService(redisClient, mockDB)
+	cacheService := NewCacheService(cache, mockDB, new(MockFailedWriteRepository), testCacheTTL, testSyncInterval, logging.Discard())
 
 	tx := &domain.Transaction{
 		TransactionID: 1,
@@ -70,35 +79,74 @@ func TestCacheService_CacheTransaction(t *testing.T) {
 		OrderTime:     time.Now(),
 	}
 
-	err := cacheService.CacheTransaction(tx)
+	err := cacheService.CacheTransaction(context.Background(), tx)
 	assert.NoError(t, err)
 
-	keys, err := redisClient.Keys(context.Background(), PENDING_CREATE_PREFIX+"*").Result()
+	keys, err := cache.Keys(context.Background(), PENDING_CREATE_PREFIX)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(keys))
 }
 
 func TestCacheService_CacheTransactionUpdate(t *testing.T) {
-	redisClient := setupRedis(t)
-	defer cleanupRedis(redisClient, t)
+	cache := repositories.NewMemoryCache()
 
 	mockDB := new(MockTransactionRepository)
-	cacheService := NewCacheService(redisClient, mockDB)
+	cacheService := NewCacheService(cache, mockDB, new(MockFailedWriteRepository), testCacheTTL, testSyncInterval, logging.Discard())
+
+	mockDB.On("GetTransactionGroup", mock.Anything, int64(1)).Return([]domain.Transaction{{TransactionID: 1, Status: domain.Pending}}, nil)
 
-	err := cacheService.CacheTransactionUpdate(1, domain.Completed)
+	err := cacheService.CacheTransactionUpdate(context.Background(), 1, domain.Completed)
 	assert.NoError(t, err)
 
-	keys, err := redisClient.Keys(context.Background(), PENDING_UPDATE_PREFIX+"*").Result()
+	keys, err := cache.Keys(context.Background(), PENDING_UPDATE_PREFIX)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(keys))
+
+	// Orders outside a group aren't indexed.
+	keys, err = cache.Keys(context.Background(), GROUP_UPDATES_PREFIX)
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestCacheService_CacheTransactionUpdate_Group(t *testing.T) {
+	cache := repositories.NewMemoryCache()
+
+	mockDB := new(MockTransactionRepository)
+	cacheService := NewCacheService(cache, mockDB, new(MockFailedWriteRepository), testCacheTTL, testSyncInterval, logging.Discard())
+
+	bracket := func(groupID string, firstID int64) []domain.Transaction {
+		entry, takeProfit, stopLoss := domain.EntryLeg, domain.TakeProfitLeg, domain.StopLossLeg
+		return []domain.Transaction{
+			{TransactionID: firstID, GroupID: &groupID, Leg: &entry, Status: domain.Completed},
+			{TransactionID: firstID + 1, GroupID: &groupID, Leg: &takeProfit, Status: domain.Pending},
+			{TransactionID: firstID + 2, GroupID: &groupID, Leg: &stopLoss, Status: domain.Pending},
+		}
+	}
+	for _, group := range [][]domain.Transaction{bracket("g1", 1), bracket("g2", 4)} {
+		for _, order := range group {
+			// Each read gets its own copy, as from the database.
+			mockDB.On("GetTransactionGroup", mock.Anything, order.TransactionID).Return(append([]domain.Transaction(nil), group...), nil)
+		}
+	}
+	ctx := context.Background()
+
+	// The take-profit filling cancels the stop-loss of its own group only.
+	assert.NoError(t, cacheService.CacheTransactionUpdate(ctx, 2, domain.Completed))
+	err := cacheService.CacheTransactionUpdate(ctx, 3, domain.Completed)
+	kind, _ := domain.ErrorKindOf(err)
+	assert.Equal(t, domain.KindConflict, kind)
+	assert.NoError(t, cacheService.CacheTransactionUpdate(ctx, 6, domain.Completed))
+
+	keys, err := cache.Keys(ctx, GROUP_UPDATES_PREFIX)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{GROUP_UPDATES_PREFIX + "g1", GROUP_UPDATES_PREFIX + "g2"}, keys)
 }
 
 func TestCacheService_GetAllTransactions(t *testing.T) {
-	redisClient := setupRedis(t)
-	defer cleanupRedis(redisClient, t)
+	cache := repositories.NewMemoryCache()
 
 	mockDB := new(MockTransactionRepository)
-	cacheService := NewCacheService(redisClient, mockDB)
+	cacheService := NewCacheService(cache, mockDB, new(MockFailedWriteRepository), testCacheTTL, testSyncInterval, logging.Discard())
 
 	fixedTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
 	expectedTxs := []domain.Transaction{
@@ -132,7 +180,7 @@ func TestCacheService_GetAllTransactions(t *testing.T) {
 		},
 	}
 
-	mockDB.On("GetAllTransactions").Return(expectedTxs, nil)
+	mockDB.On("GetAllTransactions", mock.Anything).Return(expectedTxs, nil)
 
 	ctx := context.Background()
 	txs, err := cacheService.GetAllTransactions(ctx)
@@ -164,42 +212,188 @@ func TestCacheService_GetAllTransactions(t *testing.T) {
 	mockDB.AssertNumberOfCalls(t, "GetAllTransactions", 1)
 }
 
-func TestCacheService_BackgroundSync(t *testing.T) {
-	redisClient := setupRedis(t)
-	defer cleanupRedis(redisClient, t)
+func TestCacheService_Flush(t *testing.T) {
+	cache := repositories.NewMemoryCache()
 
 	mockDB := new(MockTransactionRepository)
-	cacheService := NewCacheService(redisClient, mockDB)
-
-	tx := &domain.Transaction{
-		TransactionID: 1,
-		Symbol:        "AAPL",
-		Type:          domain.Buy,
-		Status:        domain.Pending,
-		Quantity:      100,
-		Price:         150.50,
-		TotalAmount:   15050.00,
-		OrderTime:     time.Now(),
+	mockFailures := new(MockFailedWriteRepository)
+	cacheService := NewCacheService(cache, mockDB, mockFailures, testCacheTTL, time.Hour, logging.Discard())
+
+	synced := &domain.Transaction{Symbol: "AAPL", Type: domain.Buy, Quantity: 100, Price: 150.50}
+	rejected := &domain.Transaction{Symbol: "MSFT", Type: domain.Sell, Quantity: 10, Price: 378.92}
+
+	mockDB.On("CreateTransaction", mock.Anything, mock.MatchedBy(func(tx *domain.Transaction) bool {
+		return tx.Symbol == "AAPL"
+	})).Return(nil)
+	mockDB.On("CreateTransaction", mock.Anything, mock.MatchedBy(func(tx *domain.Transaction) bool {
+		return tx.Symbol == "MSFT"
+	})).Return(errors.New("constraint violation"))
+	mockDB.On("UpdateTransactionStatus", mock.Anything, int64(1), domain.Completed).Return(nil)
+	mockDB.On("GetTransactionGroup", mock.Anything, int64(1)).Return([]domain.Transaction{{TransactionID: 1, Status: domain.Pending}}, nil)
+	mockFailures.On("RecordFailedWrite", mock.Anything, mock.MatchedBy(func(fw *domain.FailedWrite) bool {
+		return fw.Operation == "create" && fw.Error != ""
+	})).Return(nil)
+
+	assert.NoError(t, cacheService.CacheTransaction(context.Background(), synced))
+	assert.NoError(t, cacheService.CacheTransaction(context.Background(), rejected))
+	assert.NoError(t, cacheService.CacheTransactionUpdate(context.Background(), 1, domain.Completed))
+
+	report := cacheService.Flush(context.Background())
+
+	assert.Equal(t, 2, report.Synced)
+	if assert.Len(t, report.Failures, 1) {
+		assert.Contains(t, report.Failures[0].Payload, "MSFT")
+		assert.ErrorContains(t, report.Failures[0].Err, "constraint violation")
+		assert.True(t, report.Failures[0].Recorded)
 	}
 
-	mockDB.On("CreateTransaction", mock.AnythingOfType("*domain.Transaction")).Return(nil)
-	mockDB.On("UpdateTransactionStatus", int64(1), domain.Completed).Return(nil)
-
-	err := cacheService.CacheTransaction(tx)
+	// The rejected write was moved to the failed writes table.
+	createKeys, err := cache.Keys(context.Background(), PENDING_CREATE_PREFIX)
 	assert.NoError(t, err)
+	assert.Equal(t, 0, len(createKeys))
 
-	err = cacheService.CacheTransactionUpdate(1, domain.Completed)
-	assert.NoError(t, err)
+	mockDB.AssertExpectations(t)
+	mockFailures.AssertExpectations(t)
+}
 
-	time.Sleep(SYNC_INTERVAL + time.Second)
+func TestCacheService_CacheTransaction_SameInstant(t *testing.T) {
+	cache := repositories.NewMemoryCache()
 
-	createKeys, err := redisClient.Keys(context.Background(), PENDING_CREATE_PREFIX+"*").Result()
+	cacheService := NewCacheService(cache, new(MockTransactionRepository), new(MockFailedWriteRepository), testCacheTTL, time.Hour, logging.Discard())
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, cacheService.CacheTransaction(context.Background(), &domain.Transaction{Symbol: "AAPL", Quantity: i + 1}))
+	}
+
+	keys, err := cache.Keys(context.Background(), PENDING_CREATE_PREFIX)
 	assert.NoError(t, err)
-	assert.Equal(t, 0, len(createKeys))
+	assert.Equal(t, 10, len(keys))
+}
+
+func TestCacheService_SyncRecordsFailedWrites(t *testing.T) {
+	testCases := []struct {
+		name         string
+		payload      string
+		ttl          time.Duration
+		dbErr        error
+		recordErr    error
+		wantRecorded bool
+	}{
+		{
+			name:         "Undecodable payload",
+			payload:      "{not json",
+			ttl:          testCacheTTL,
+			wantRecorded: true,
+		},
+		{
+			name:         "Expires before next sync",
+			payload:      `{"Symbol":"AAPL"}`,
+			ttl:          500 * time.Millisecond,
+			dbErr:        errors.New("database unavailable"),
+			wantRecorded: true,
+		},
+		{
+			name:         "Permanent database error",
+			payload:      `{"Symbol":"AAPL"}`,
+			ttl:          testCacheTTL,
+			dbErr:        domain.NewConflictError("duplicate_transaction", "transaction already exists"),
+			wantRecorded: true,
+		},
+		{
+			name:         "Retried while time remains",
+			payload:      `{"Symbol":"AAPL"}`,
+			ttl:          testCacheTTL,
+			dbErr:        errors.New("database unavailable"),
+			wantRecorded: false,
+		},
+		{
+			name:         "Kept when recording fails",
+			payload:      "{not json",
+			ttl:          testCacheTTL,
+			recordErr:    errors.New("database unavailable"),
+			wantRecorded: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := repositories.NewMemoryCache()
+
+			mockDB := new(MockTransactionRepository)
+			mockDB.On("CreateTransaction", mock.Anything, mock.AnythingOfType("*domain.Transaction")).Return(tc.dbErr)
+			mockFailures := new(MockFailedWriteRepository)
+			mockFailures.On("RecordFailedWrite", mock.Anything, mock.AnythingOfType("*domain.FailedWrite")).Return(tc.recordErr)
+
+			cacheService := NewCacheService(cache, mockDB, mockFailures, testCacheTTL, testSyncInterval, logging.Discard())
+
+			key := generateCacheKey(PENDING_CREATE_PREFIX, "test")
+			assert.NoError(t, cache.Set(context.Background(), key, tc.payload, tc.ttl))
+
+			results := cacheService.syncCreates(context.Background())
+			if assert.Len(t, results, 1) {
+				assert.Error(t, results[0].err)
+				assert.Equal(t, tc.wantRecorded, results[0].recorded)
+			}
+
+			_, err := cache.Get(context.Background(), key)
+			if tc.wantRecorded {
+				assert.ErrorIs(t, err, ports.ErrCacheMiss)
+				mockFailures.AssertCalled(t, "RecordFailedWrite", mock.Anything, mock.MatchedBy(func(fw *domain.FailedWrite) bool {
+					return fw.CacheKey == key && fw.Payload == tc.payload && fw.Operation == "create"
+				}))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCacheService_SyncLinksToOriginatingSpan(t *testing.T) {
+	exporter := tracing.UseInMemoryExporter(t)
+	cache := repositories.NewMemoryCache()
+
+	mockDB := new(MockTransactionRepository)
+	mockDB.On("CreateTransaction", mock.Anything, mock.AnythingOfType("*domain.Transaction")).Return(nil)
+	mockDB.On("GetTransactionGroup", mock.Anything, int64(1)).Return([]domain.Transaction{{TransactionID: 1, Status: domain.Pending}}, nil)
+	mockDB.On("UpdateTransactionStatus", mock.Anything, int64(1), domain.Cancelled).Return(nil)
+
+	cacheService := NewCacheService(cache, mockDB, new(MockFailedWriteRepository), testCacheTTL, time.Hour, logging.Discard())
 
-	updateKeys, err := redisClient.Keys(context.Background(), PENDING_UPDATE_PREFIX+"*").Result()
+	ctx, request := tracing.Tracer().Start(context.Background(), "POST /transactions")
+	err := cacheService.CacheTransaction(ctx, &domain.Transaction{Symbol: "AAPL", Type: domain.Buy, Quantity: 1})
+	assert.NoError(t, err)
+	err = cacheService.CacheTransactionUpdate(ctx, 1, domain.Cancelled)
 	assert.NoError(t, err)
-	assert.Equal(t, 0, len(updateKeys))
+	request.End()
+
+	// Both kinds of pending write carry the span context under one key.
+	for _, prefix := range []string{PENDING_CREATE_PREFIX, PENDING_UPDATE_PREFIX} {
+		keys, err := cache.Keys(context.Background(), prefix)
+		if assert.NoError(t, err) && assert.Len(t, keys, 1) {
+			payload, err := cache.Get(context.Background(), keys[0])
+			assert.NoError(t, err)
+			assert.Contains(t, payload, `"_trace":`, prefix)
+		}
+	}
 
-	mockDB.AssertExpectations(t)
+	report := cacheService.Flush(context.Background())
+	assert.Equal(t, 2, report.Synced)
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+
+	for cached, synced := range map[string]string{
+		"CacheService.CacheTransaction":       "CacheService.syncCreate",
+		"CacheService.CacheTransactionUpdate": "CacheService.syncUpdate",
+	} {
+		cacheSpan, syncSpan := spans[cached], spans[synced]
+		assert.Equal(t, request.SpanContext().TraceID(), cacheSpan.SpanContext.TraceID())
+		assert.NotEqual(t, request.SpanContext().TraceID(), syncSpan.SpanContext.TraceID())
+		if assert.Len(t, syncSpan.Links, 1, synced) {
+			assert.Equal(t, cacheSpan.SpanContext.SpanID(), syncSpan.Links[0].SpanContext.SpanID())
+			assert.Equal(t, request.SpanContext().TraceID(), syncSpan.Links[0].SpanContext.TraceID())
+		}
+	}
 }
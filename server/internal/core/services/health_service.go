@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+)
+
+const (
+	healthCheckTimeout = 2 * time.Second
+	// A worker is degraded once it has gone this many intervals without a
+	// successful run.
+	staleIntervals = 3
+)
+
+type healthService struct {
+	database     ports.Pinger
+	cacheService *CacheService
+	cacheSync    *SyncWorker
+	stockUpdater *StockUpdater
+	elector      *LeaderElector
+	logger       *slog.Logger
+	startedAt    time.Time
+}
+
+func NewHealthService(
+	database ports.Pinger,
+	cacheService *CacheService,
+	cacheSync *SyncWorker,
+	stockUpdater *StockUpdater,
+	elector *LeaderElector,
+	logger *slog.Logger,
+) ports.HealthService {
+	return &healthService{
+		database:     database,
+		cacheService: cacheService,
+		cacheSync:    cacheSync,
+		stockUpdater: stockUpdater,
+		elector:      elector,
+		logger:       logger,
+		startedAt:    time.Now(),
+	}
+}
+
+func (s *healthService) Readiness(ctx context.Context) domain.Readiness {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	var database, redis domain.DependencyHealth
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		database = probe(ctx, s.database.Ping)
+	}()
+	go func() {
+		defer wg.Done()
+		redis = probe(ctx, s.cacheService.Ping)
+	}()
+	wg.Wait()
+
+	now := time.Now()
+	readiness := domain.Readiness{
+		Status:    domain.Healthy,
+		Timestamp: now,
+		Dependencies: map[string]domain.DependencyHealth{
+			"database": database,
+			"redis":    redis,
+		},
+		Workers: map[string]domain.WorkerHealth{
+			"stockUpdater": {Status: domain.Healthy, Standby: true},
+			"cacheSync":    {Status: domain.Healthy, Standby: true},
+		},
+	}
+	if s.elector.IsLeader() {
+		// The workers started when this instance took over as leader.
+		since := s.startedAt
+		if leading := s.elector.LeadingSince(); leading.After(since) {
+			since = leading
+		}
+		readiness.Workers["stockUpdater"] = s.workerHealth(s.stockUpdater.LastTick(), s.stockUpdater.Interval(), since, now)
+		readiness.Workers["cacheSync"] = s.workerHealth(s.cacheSync.LastSync(), s.cacheSync.Interval(), since, now)
+	}
+
+	if redis.Status == domain.Healthy {
+		if pending, err := s.cacheService.PendingCount(ctx); err == nil {
+			readiness.PendingWrites = &pending
+		}
+	}
+
+	for _, worker := range readiness.Workers {
+		if worker.Status != domain.Healthy {
+			readiness.Status = domain.Degraded
+		}
+	}
+	for name, dependency := range readiness.Dependencies {
+		if dependency.Status != domain.Healthy {
+			readiness.Status = domain.Unavailable
+			s.logger.WarnContext(ctx, "dependency unavailable", "dependency", name, "error", dependency.Error)
+		}
+	}
+
+	return readiness
+}
+
+// workerHealth reports on a worker that was started at since.
+func (s *healthService) workerHealth(last time.Time, interval time.Duration, since time.Time, now time.Time) domain.WorkerHealth {
+	stale := staleIntervals * interval
+
+	// Give workers a grace period after starting before expecting a run.
+	if last.Before(since) && now.Sub(since) < stale {
+		return domain.WorkerHealth{Status: domain.Healthy}
+	}
+	if last.IsZero() {
+		return domain.WorkerHealth{Status: domain.Degraded}
+	}
+
+	age := now.Sub(last)
+	ageSeconds := age.Seconds()
+	health := domain.WorkerHealth{
+		Status:      domain.Healthy,
+		LastSuccess: &last,
+		AgeSeconds:  &ageSeconds,
+	}
+	if age > stale {
+		health.Status = domain.Degraded
+	}
+	return health
+}
+
+func probe(ctx context.Context, ping func(context.Context) error) domain.DependencyHealth {
+	start := time.Now()
+	err := ping(ctx)
+	health := domain.DependencyHealth{
+		Status:    domain.Healthy,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		health.Status = domain.Unavailable
+		health.Error = err.Error()
+	}
+	return health
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/repositories"
+)
+
+// MockPinger mocks the Pinger interface
+type MockPinger struct {
+	mock.Mock
+}
+
+func (m *MockPinger) Ping(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func TestHealthService_Readiness(t *testing.T) {
+	cache := repositories.NewMemoryCache()
+
+	database := new(MockPinger)
+	database.On("Ping", mock.Anything).Return(nil)
+
+	mockDB := new(MockTransactionRepository)
+	mockDB.On("GetTransactionGroup", mock.Anything, int64(1)).Return([]domain.Transaction{{TransactionID: 1, Status: domain.Pending}}, nil)
+
+	cacheService := NewCacheService(cache, mockDB, new(MockFailedWriteRepository), testCacheTTL, time.Hour, logging.Discard())
+	stockUpdater := NewStockUpdater(new(MockStockRepository), repositories.NewMemoryQuoteStore(), openMarketCalendar(), nil, time.Hour, logging.Discard())
+
+	assert.NoError(t, cacheService.CacheTransactionUpdate(context.Background(), 1, domain.Completed))
+
+	healthService := NewHealthService(database, cacheService, NewSyncWorker(cacheService, logging.Discard()), stockUpdater, leadingElector(t), logging.Discard())
+	readiness := healthService.Readiness(context.Background())
+
+	assert.Equal(t, domain.Healthy, readiness.Status)
+	assert.Equal(t, domain.Healthy, readiness.Dependencies["database"].Status)
+	assert.Equal(t, domain.Healthy, readiness.Dependencies["redis"].Status)
+	assert.Equal(t, domain.Healthy, readiness.Workers["stockUpdater"].Status)
+	assert.Nil(t, readiness.Workers["stockUpdater"].LastSuccess)
+	assert.False(t, readiness.Workers["stockUpdater"].Standby)
+	require.NotNil(t, readiness.PendingWrites)
+	assert.Equal(t, 1, *readiness.PendingWrites)
+}
+
+func TestHealthService_Readiness_DatabaseDown(t *testing.T) {
+	cache := repositories.NewMemoryCache()
+
+	database := new(MockPinger)
+	database.On("Ping", mock.Anything).Return(errors.New("connection refused"))
+
+	cacheService := NewCacheService(cache, new(MockTransactionRepository), new(MockFailedWriteRepository), testCacheTTL, time.Hour, logging.Discard())
+	stockUpdater := NewStockUpdater(new(MockStockRepository), repositories.NewMemoryQuoteStore(), openMarketCalendar(), nil, time.Hour, logging.Discard())
+
+	healthService := NewHealthService(database, cacheService, NewSyncWorker(cacheService, logging.Discard()), stockUpdater, leadingElector(t), logging.Discard())
+	readiness := healthService.Readiness(context.Background())
+
+	assert.Equal(t, domain.Unavailable, readiness.Status)
+	assert.Equal(t, domain.Unavailable, readiness.Dependencies["database"].Status)
+	assert.Equal(t, "connection refused", readiness.Dependencies["database"].Error)
+	assert.Equal(t, domain.Healthy, readiness.Dependencies["redis"].Status)
+}
+
+func TestHealthService_Readiness_Standby(t *testing.T) {
+	leases := repositories.NewMemoryLeases()
+	_, err := leases.AcquireLease(context.Background(), "test", "another-instance", time.Hour)
+	require.NoError(t, err)
+
+	database := new(MockPinger)
+	database.On("Ping", mock.Anything).Return(nil)
+
+	cacheService := NewCacheService(repositories.NewMemoryCache(), new(MockTransactionRepository), new(MockFailedWriteRepository), testCacheTTL, time.Millisecond, logging.Discard())
+	stockUpdater := NewStockUpdater(new(MockStockRepository), repositories.NewMemoryQuoteStore(), openMarketCalendar(), nil, time.Millisecond, logging.Discard())
+	elector := startElector(t, leases, "instance-a")
+
+	// Long past the grace period, workers that never ran are only healthy
+	// because another instance runs them.
+	healthService := &healthService{
+		database:     database,
+		cacheService: cacheService,
+		cacheSync:    NewSyncWorker(cacheService, logging.Discard()),
+		stockUpdater: stockUpdater,
+		elector:      elector,
+		logger:       logging.Discard(),
+		startedAt:    time.Now().Add(-time.Hour),
+	}
+	readiness := healthService.Readiness(context.Background())
+
+	assert.False(t, elector.IsLeader())
+	assert.Equal(t, domain.Healthy, readiness.Status)
+	assert.True(t, readiness.Workers["stockUpdater"].Standby)
+	assert.True(t, readiness.Workers["cacheSync"].Standby)
+}
+
+func TestHealthService_WorkerHealth(t *testing.T) {
+	now := time.Now()
+	service := &healthService{}
+	started := now.Add(-time.Minute)
+
+	fresh := service.workerHealth(now.Add(-2*time.Second), time.Second, started, now)
+	assert.Equal(t, domain.Healthy, fresh.Status)
+	require.NotNil(t, fresh.AgeSeconds)
+	assert.InDelta(t, 2.0, *fresh.AgeSeconds, 0.01)
+
+	stale := service.workerHealth(now.Add(-10*time.Second), time.Second, started, now)
+	assert.Equal(t, domain.Degraded, stale.Status)
+
+	neverRan := service.workerHealth(time.Time{}, time.Second, started, now)
+	assert.Equal(t, domain.Degraded, neverRan.Status)
+
+	starting := service.workerHealth(time.Time{}, time.Hour, started, now)
+	assert.Equal(t, domain.Healthy, starting.Status)
+
+	// A run from before the worker was last started, on an earlier term as
+	// leader, counts as none.
+	takenOver := service.workerHealth(now.Add(-time.Hour), 10*time.Second, now.Add(-5*time.Second), now)
+	assert.Equal(t, domain.Healthy, takenOver.Status)
+	takenOverLongAgo := service.workerHealth(now.Add(-time.Hour), 10*time.Second, now.Add(-time.Minute), now)
+	assert.Equal(t, domain.Degraded, takenOverLongAgo.Status)
+}
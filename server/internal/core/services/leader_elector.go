@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/metrics"
+)
+
+// releaseTimeout bounds giving up the lease on Stop, which runs after the
+// elector's context is done.
+const releaseTimeout = 2 * time.Second
+
+// Job is a background job that must run on one instance at a time. It is
+// started when the instance becomes leader and stopped when it stops being
+// one, possibly many times. The context it is started with is cancelled when
+// it should stop, or when the lease it runs under expires; work that must
+// not be cut off by stopping should use runContext.
+type Job interface {
+	Start(ctx context.Context)
+	Stop()
+}
+
+// leaseKey is the context key of the context that is cancelled when the
+// lease a job was started under expires.
+type leaseKey struct{}
+
+// runContext returns the context for one run of a job started with ctx. It
+// isn't cancelled when the job is stopped, so that a run can finish the
+// writes it has started, but it is when the lease expires, as another
+// instance may be running the job by then.
+func runContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	lease, ok := ctx.Value(leaseKey{}).(context.Context)
+	if !ok {
+		return runCtx, cancel
+	}
+	stop := context.AfterFunc(lease, cancel)
+	return runCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// LeaderElector runs jobs on whichever instance holds a lease, renewing it a
+// few times per lease TTL. If the lease can't be renewed the jobs are
+// stopped before it would expire, and another instance takes over once it
+// has. Work the jobs still have in progress when the lease expires, by the
+// local clock, is cancelled, so that it doesn't overlap with the next
+// leader's.
+type LeaderElector struct {
+	leases        ports.Leases
+	name          string
+	holder        string
+	ttl           time.Duration
+	renewInterval time.Duration
+	jobs          []Job
+	logger        *slog.Logger
+	leader        atomic.Bool
+	leadingSince  atomic.Int64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	// stopJobs cancels the context the jobs were started with.
+	stopJobs context.CancelFunc
+	// lease is cancelled by expireLease, which leaseTimer calls when the
+	// lease expires unless it is renewed.
+	lease       context.Context
+	expireLease context.CancelFunc
+	leaseTimer  *time.Timer
+}
+
+// NewLeaderElector returns an elector for the lease called name. holder must
+// be unique to this instance.
+func NewLeaderElector(
+	leases ports.Leases,
+	name string,
+	holder string,
+	ttl time.Duration,
+	jobs []Job,
+	logger *slog.Logger,
+) *LeaderElector {
+	return &LeaderElector{
+		leases:        leases,
+		name:          name,
+		holder:        holder,
+		ttl:           ttl,
+		renewInterval: ttl / 3,
+		jobs:          jobs,
+		logger:        logger,
+	}
+}
+
+// Start campaigns for the lease in the background until ctx is done or Stop
+// is called.
+func (e *LeaderElector) Start(ctx context.Context) {
+	ctx, e.cancel = context.WithCancel(ctx)
+	e.done = make(chan struct{})
+
+	go func() {
+		defer close(e.done)
+		ticker := time.NewTicker(e.renewInterval)
+		defer ticker.Stop()
+
+		var expiresAt time.Time
+		for {
+			e.campaign(ctx, &expiresAt)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				if e.leader.Load() {
+					e.stepDown()
+					e.release(ctx)
+					e.logger.InfoContext(ctx, "released leader lease", "lease", e.name)
+				}
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the jobs if this instance is leading, and gives up the lease so
+// that another instance can take over without waiting for it to expire.
+func (e *LeaderElector) Stop() {
+	if e.cancel == nil {
+		return
+	}
+	e.cancel()
+	<-e.done
+}
+
+// IsLeader reports whether this instance is running the jobs.
+func (e *LeaderElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// LeadingSince returns when this instance last became leader, or the zero
+// time if it never has.
+func (e *LeaderElector) LeadingSince() time.Time {
+	if nanos := e.leadingSince.Load(); nanos != 0 {
+		return time.Unix(0, nanos)
+	}
+	return time.Time{}
+}
+
+// Exclusive runs fn while holding the lease, unless another instance holds
+// it, and reports whether fn ran. It is for one-off work that must not
+// overlap with the leader's jobs, and is meant to be called while the
+// elector is stopped. The lease is held until ctx's deadline if that is
+// later than the lease TTL.
+func (e *LeaderElector) Exclusive(ctx context.Context, fn func(ctx context.Context)) (bool, error) {
+	ttl := e.ttl
+	if deadline, ok := ctx.Deadline(); ok {
+		ttl = max(ttl, time.Until(deadline))
+	}
+
+	held, err := e.leases.AcquireLease(ctx, e.name, e.holder, ttl)
+	if err != nil || !held {
+		return false, err
+	}
+	defer e.release(ctx)
+
+	fn(ctx)
+	return true, nil
+}
+
+// campaign takes or renews the lease, starting or stopping the jobs as
+// leadership changes. expiresAt is when the lease last acquired expires, by
+// the local clock; the time is taken before asking, so it errs early.
+func (e *LeaderElector) campaign(ctx context.Context, expiresAt *time.Time) {
+	asked := time.Now()
+	held, err := e.leases.AcquireLease(ctx, e.name, e.holder, e.ttl)
+	if ctx.Err() != nil {
+		return
+	}
+
+	switch {
+	case err != nil:
+		e.logger.WarnContext(ctx, "failed to renew leader lease", "lease", e.name, "error", err)
+		// The lease may still be ours, but can't be relied on past its
+		// expiry, and stopping the jobs takes time.
+		if e.leader.Load() && !time.Now().Add(e.renewInterval).Before(*expiresAt) {
+			e.stepDown()
+			e.logger.WarnContext(ctx, "stopped leading", "lease", e.name, "reason", "lease could not be renewed")
+		}
+	case !held:
+		if e.leader.Load() {
+			e.expireLease()
+			e.stepDown()
+			e.logger.WarnContext(ctx, "stopped leading", "lease", e.name, "reason", "lease taken by another instance")
+		}
+	default:
+		*expiresAt = asked.Add(e.ttl)
+		if e.leader.Load() && e.lease.Err() != nil {
+			// Renewed too late: the jobs have been cut off, so start
+			// them again.
+			e.stepDown()
+			e.logger.WarnContext(ctx, "stopped leading", "lease", e.name, "reason", "lease expired before it was renewed")
+		}
+		if e.leader.Load() {
+			e.leaseTimer.Reset(time.Until(*expiresAt))
+		} else {
+			e.lead(ctx, *expiresAt)
+		}
+	}
+}
+
+func (e *LeaderElector) lead(ctx context.Context, expiresAt time.Time) {
+	e.logger.InfoContext(ctx, "became leader", "lease", e.name, "holder", e.holder)
+	e.lease, e.expireLease = context.WithCancel(context.WithoutCancel(ctx))
+	e.leaseTimer = time.AfterFunc(time.Until(expiresAt), e.expireLease)
+
+	jobCtx, cancel := context.WithCancel(context.WithValue(ctx, leaseKey{}, e.lease))
+	context.AfterFunc(e.lease, cancel)
+	e.stopJobs = cancel
+	for _, job := range e.jobs {
+		job.Start(jobCtx)
+	}
+	e.leadingSince.Store(time.Now().UnixNano())
+	e.leader.Store(true)
+	metrics.Leader.Set(1)
+}
+
+func (e *LeaderElector) stepDown() {
+	e.leader.Store(false)
+	metrics.Leader.Set(0)
+	e.stopJobs()
+	for _, job := range e.jobs {
+		job.Stop()
+	}
+	e.leaseTimer.Stop()
+	e.expireLease()
+}
+
+func (e *LeaderElector) release(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancel()
+
+	if err := e.leases.ReleaseLease(ctx, e.name, e.holder); err != nil {
+		e.logger.WarnContext(ctx, "failed to release leader lease", "lease", e.name, "error", err)
+	}
+}
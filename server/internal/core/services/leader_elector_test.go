@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/repositories"
+)
+
+const testLeaseTTL = 150 * time.Millisecond
+
+// countingJob counts how many times it is running.
+type countingJob struct {
+	running atomic.Int32
+	starts  atomic.Int32
+}
+
+func (j *countingJob) Start(ctx context.Context) {
+	j.starts.Add(1)
+	j.running.Add(1)
+}
+
+func (j *countingJob) Stop() {
+	j.running.Add(-1)
+}
+
+// runningJob starts a run when started, which lasts until its run context
+// is done.
+type runningJob struct {
+	cancelled chan time.Time
+}
+
+func (j *runningJob) Start(ctx context.Context) {
+	runCtx, cancel := runContext(ctx)
+	go func() {
+		defer cancel()
+		<-runCtx.Done()
+		j.cancelled <- time.Now()
+	}()
+}
+
+func (j *runningJob) Stop() {}
+
+// unreachableLeases fails every request once down is set.
+type unreachableLeases struct {
+	ports.Leases
+	down atomic.Bool
+}
+
+func (l *unreachableLeases) AcquireLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	if l.down.Load() {
+		return false, errors.New("connection refused")
+	}
+	return l.Leases.AcquireLease(ctx, name, holder, ttl)
+}
+
+// startElector starts an elector for the test lease, stopping it when the
+// test ends.
+func startElector(t *testing.T, leases ports.Leases, holder string, jobs ...Job) *LeaderElector {
+	elector := NewLeaderElector(leases, "test", holder, testLeaseTTL, jobs, logging.Discard())
+	elector.Start(context.Background())
+	t.Cleanup(elector.Stop)
+	return elector
+}
+
+// leadingElector returns an elector that has become leader.
+func leadingElector(t *testing.T, jobs ...Job) *LeaderElector {
+	elector := startElector(t, repositories.NewMemoryLeases(), "instance-a", jobs...)
+	require.Eventually(t, elector.IsLeader, time.Second, 10*time.Millisecond)
+	return elector
+}
+
+func TestLeaderElector_OneLeaderAtATime(t *testing.T) {
+	leases := repositories.NewMemoryLeases()
+	jobA, jobB := new(countingJob), new(countingJob)
+
+	first := startElector(t, leases, "instance-a", jobA)
+	require.Eventually(t, first.IsLeader, time.Second, 10*time.Millisecond)
+	second := startElector(t, leases, "instance-b", jobB)
+
+	// The second instance waits through several renewals.
+	time.Sleep(2 * testLeaseTTL)
+	assert.True(t, first.IsLeader())
+	assert.False(t, second.IsLeader())
+	assert.Equal(t, int32(1), jobA.running.Load())
+	assert.Equal(t, int32(0), jobB.starts.Load())
+
+	// Stopping the leader stops its jobs and hands over the lease.
+	first.Stop()
+	assert.False(t, first.IsLeader())
+	assert.Equal(t, int32(0), jobA.running.Load())
+
+	require.Eventually(t, second.IsLeader, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), jobB.running.Load())
+	assert.False(t, second.LeadingSince().IsZero())
+}
+
+func TestLeaderElector_Exclusive(t *testing.T) {
+	leases := repositories.NewMemoryLeases()
+	leader := startElector(t, leases, "instance-a")
+	require.Eventually(t, leader.IsLeader, time.Second, 10*time.Millisecond)
+
+	other := NewLeaderElector(leases, "test", "instance-b", testLeaseTTL, nil, logging.Discard())
+	ran := false
+	ok, err := other.Exclusive(context.Background(), func(ctx context.Context) { ran = true })
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, ran)
+
+	// Once the leader has let go, the lease is free for one-off work and is
+	// released afterwards.
+	leader.Stop()
+	ok, err = other.Exclusive(context.Background(), func(ctx context.Context) { ran = true })
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, ran)
+
+	held, err := leases.AcquireLease(context.Background(), "test", "instance-c", testLeaseTTL)
+	require.NoError(t, err)
+	assert.True(t, held)
+}
+
+func TestLeaderElector_LeaseExpiryCancelsRuns(t *testing.T) {
+	leases := &unreachableLeases{Leases: repositories.NewMemoryLeases()}
+	job := &runningJob{cancelled: make(chan time.Time, 1)}
+	elector := startElector(t, leases, "instance-a", job)
+	require.Eventually(t, elector.IsLeader, time.Second, 10*time.Millisecond)
+
+	// The run outlives stopping the job, but not the lease, which expires
+	// at most a TTL after it was last renewed.
+	leases.down.Store(true)
+	lastRenewal := time.Now()
+	select {
+	case cancelled := <-job.cancelled:
+		assert.False(t, elector.IsLeader())
+		assert.WithinDuration(t, lastRenewal, cancelled, testLeaseTTL)
+	case <-time.After(time.Second):
+		t.Fatal("run not cancelled when the lease expired")
+	}
+
+	// Only then can another instance take over, as the elector's clock
+	// errs early.
+	assert.Eventually(t, func() bool {
+		held, err := leases.Leases.AcquireLease(context.Background(), "test", "instance-b", testLeaseTTL)
+		return err == nil && held
+	}, time.Second, 10*time.Millisecond)
+}
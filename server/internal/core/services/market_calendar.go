@@ -0,0 +1,244 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	calendarDateLayout = "2006-01-02"
+	calendarTimeLayout = "15:04"
+	nextOpenLookahead  = 14
+)
+
+type calendarFile struct {
+	Exchange    string   `yaml:"exchange"`
+	Timezone    string   `yaml:"timezone"`
+	TradingDays []string `yaml:"trading_days"`
+	Sessions    []struct {
+		Name          string `yaml:"name"`
+		Start         string `yaml:"start"`
+		End           string `yaml:"end"`
+		AcceptsOrders bool   `yaml:"accepts_orders"`
+	} `yaml:"sessions"`
+	Holidays []struct {
+		Date string `yaml:"date"`
+		Name string `yaml:"name"`
+	} `yaml:"holidays"`
+	EarlyCloses []struct {
+		Date  string `yaml:"date"`
+		Close string `yaml:"close"`
+		Name  string `yaml:"name"`
+	} `yaml:"early_closes"`
+}
+
+// marketSession is a trading window expressed in minutes after local midnight.
+type marketSession struct {
+	name          domain.MarketSession
+	start         int
+	end           int
+	acceptsOrders bool
+}
+
+type earlyClose struct {
+	close int
+	name  string
+}
+
+// MarketCalendar answers whether the exchange is trading at a given instant,
+// taking trading days, holidays and early closes into account.
+type MarketCalendar struct {
+	exchange    string
+	location    *time.Location
+	tradingDays map[time.Weekday]bool
+	sessions    []marketSession
+	holidays    map[string]string
+	earlyCloses map[string]earlyClose
+}
+
+func LoadMarketCalendar(path string) (*MarketCalendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read market calendar: %w", err)
+	}
+	return ParseMarketCalendar(data)
+}
+
+func ParseMarketCalendar(data []byte) (*MarketCalendar, error) {
+	var file calendarFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse market calendar: %w", err)
+	}
+
+	location, err := time.LoadLocation(file.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid market calendar timezone %q: %w", file.Timezone, err)
+	}
+
+	calendar := &MarketCalendar{
+		exchange:    file.Exchange,
+		location:    location,
+		tradingDays: make(map[time.Weekday]bool),
+		holidays:    make(map[string]string),
+		earlyCloses: make(map[string]earlyClose),
+	}
+
+	for _, day := range file.TradingDays {
+		weekday, err := parseWeekday(day)
+		if err != nil {
+			return nil, err
+		}
+		calendar.tradingDays[weekday] = true
+	}
+
+	for _, s := range file.Sessions {
+		session := marketSession{
+			name:          domain.MarketSession(s.Name),
+			acceptsOrders: s.AcceptsOrders,
+		}
+		switch session.name {
+		case domain.PreMarket, domain.Regular, domain.PostMarket:
+		default:
+			return nil, fmt.Errorf("unknown market session %q", s.Name)
+		}
+		if session.start, err = parseClock(s.Start); err != nil {
+			return nil, err
+		}
+		if session.end, err = parseClock(s.End); err != nil {
+			return nil, err
+		}
+		if session.end <= session.start {
+			return nil, fmt.Errorf("market session %s must end after it starts", s.Name)
+		}
+		calendar.sessions = append(calendar.sessions, session)
+	}
+
+	sort.Slice(calendar.sessions, func(i, j int) bool {
+		return calendar.sessions[i].start < calendar.sessions[j].start
+	})
+	for i := 1; i < len(calendar.sessions); i++ {
+		if calendar.sessions[i].start < calendar.sessions[i-1].end {
+			return nil, fmt.Errorf("market sessions %s and %s overlap",
+				calendar.sessions[i-1].name, calendar.sessions[i].name)
+		}
+	}
+
+	for _, h := range file.Holidays {
+		if _, err := time.Parse(calendarDateLayout, h.Date); err != nil {
+			return nil, fmt.Errorf("invalid holiday date %q: %w", h.Date, err)
+		}
+		calendar.holidays[h.Date] = h.Name
+	}
+
+	for _, e := range file.EarlyCloses {
+		if _, err := time.Parse(calendarDateLayout, e.Date); err != nil {
+			return nil, fmt.Errorf("invalid early close date %q: %w", e.Date, err)
+		}
+		closeAt, err := parseClock(e.Close)
+		if err != nil {
+			return nil, err
+		}
+		calendar.earlyCloses[e.Date] = earlyClose{close: closeAt, name: e.Name}
+	}
+
+	return calendar, nil
+}
+
+func (c *MarketCalendar) Status(at time.Time) domain.MarketStatus {
+	local := at.In(c.location)
+	status := domain.MarketStatus{
+		Exchange:  c.exchange,
+		Timezone:  c.location.String(),
+		Session:   domain.Closed,
+		Timestamp: local,
+	}
+
+	sessions, reason := c.sessionsOn(local)
+	now := local.Hour()*60 + local.Minute()
+	for _, s := range sessions {
+		if now >= s.start && now < s.end {
+			ends := c.clockOn(local, s.end)
+			status.Session = s.name
+			status.IsOpen = true
+			status.AcceptsOrders = s.acceptsOrders
+			status.SessionEnds = &ends
+			return status
+		}
+	}
+
+	status.Reason = reason
+	if next, ok := c.nextOpen(local); ok {
+		status.NextOpen = &next
+	}
+	return status
+}
+
+// sessionsOn returns the sessions that trade on the given local day, along
+// with the reason to report when the exchange is closed.
+func (c *MarketCalendar) sessionsOn(day time.Time) ([]marketSession, string) {
+	if !c.tradingDays[day.Weekday()] {
+		return nil, "non-trading day"
+	}
+
+	date := day.Format(calendarDateLayout)
+	if name, ok := c.holidays[date]; ok {
+		return nil, name
+	}
+
+	early, ok := c.earlyCloses[date]
+	if !ok {
+		return c.sessions, "outside trading hours"
+	}
+
+	var sessions []marketSession
+	for _, s := range c.sessions {
+		if s.start >= early.close {
+			break
+		}
+		if s.end > early.close {
+			s.end = early.close
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, early.name
+}
+
+func (c *MarketCalendar) nextOpen(from time.Time) (time.Time, bool) {
+	for i := 0; i <= nextOpenLookahead; i++ {
+		day := from.AddDate(0, 0, i)
+		sessions, _ := c.sessionsOn(day)
+		for _, s := range sessions {
+			if start := c.clockOn(day, s.start); start.After(from) {
+				return start, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func (c *MarketCalendar) clockOn(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, c.location)
+}
+
+func parseClock(value string) (int, error) {
+	t, err := time.Parse(calendarTimeLayout, value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid market calendar time %q: %w", value, err)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func parseWeekday(value string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(value, d.String()[:3]) || strings.EqualFold(value, d.String()) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid trading day %q", value)
+}
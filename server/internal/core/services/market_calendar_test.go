@@ -0,0 +1,163 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+const testCalendarYAML = `
+exchange: TEST
+timezone: America/New_York
+trading_days: [Mon, Tue, Wed, Thu, Fri]
+sessions:
+  - { name: REGULAR, start: "09:30", end: "16:00", accepts_orders: true }
+  - { name: PRE_MARKET, start: "04:00", end: "09:30", accepts_orders: false }
+  - { name: POST_MARKET, start: "16:00", end: "20:00", accepts_orders: true }
+holidays:
+  - { date: 2026-11-26, name: Thanksgiving Day }
+early_closes:
+  - { date: 2026-11-27, close: "13:00", name: Day after Thanksgiving }
+`
+
+func TestMarketCalendar_Status(t *testing.T) {
+	calendar, err := ParseMarketCalendar([]byte(testCalendarYAML))
+	require.NoError(t, err)
+
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		at            time.Time
+		session       domain.MarketSession
+		acceptsOrders bool
+		reason        string
+	}{
+		{
+			name:    "Before pre-market",
+			at:      time.Date(2026, 10, 19, 3, 59, 0, 0, ny),
+			session: domain.Closed,
+			reason:  "outside trading hours",
+		},
+		{
+			name:    "Pre-market",
+			at:      time.Date(2026, 10, 19, 9, 29, 0, 0, ny),
+			session: domain.PreMarket,
+		},
+		{
+			name:          "Regular session open",
+			at:            time.Date(2026, 10, 19, 9, 30, 0, 0, ny),
+			session:       domain.Regular,
+			acceptsOrders: true,
+		},
+		{
+			name:          "Post-market",
+			at:            time.Date(2026, 10, 19, 16, 0, 0, 0, ny),
+			session:       domain.PostMarket,
+			acceptsOrders: true,
+		},
+		{
+			name:    "Weekend",
+			at:      time.Date(2026, 10, 18, 12, 0, 0, 0, ny),
+			session: domain.Closed,
+			reason:  "non-trading day",
+		},
+		{
+			name:    "Holiday",
+			at:      time.Date(2026, 11, 26, 12, 0, 0, 0, ny),
+			session: domain.Closed,
+			reason:  "Thanksgiving Day",
+		},
+		{
+			name:          "Early close before the bell",
+			at:            time.Date(2026, 11, 27, 12, 59, 0, 0, ny),
+			session:       domain.Regular,
+			acceptsOrders: true,
+		},
+		{
+			name:    "Early close after the bell",
+			at:      time.Date(2026, 11, 27, 13, 0, 0, 0, ny),
+			session: domain.Closed,
+			reason:  "Day after Thanksgiving",
+		},
+		{
+			name:          "Converts from UTC",
+			at:            time.Date(2026, 10, 19, 14, 0, 0, 0, time.UTC),
+			session:       domain.Regular,
+			acceptsOrders: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status := calendar.Status(tc.at)
+
+			assert.Equal(t, "TEST", status.Exchange)
+			assert.Equal(t, tc.session, status.Session)
+			assert.Equal(t, tc.session != domain.Closed, status.IsOpen)
+			assert.Equal(t, tc.acceptsOrders, status.AcceptsOrders)
+			assert.Equal(t, tc.reason, status.Reason)
+		})
+	}
+}
+
+func TestMarketCalendar_NextOpen(t *testing.T) {
+	calendar, err := ParseMarketCalendar([]byte(testCalendarYAML))
+	require.NoError(t, err)
+
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// Wednesday evening before Thanksgiving: the next session is pre-market on Friday.
+	status := calendar.Status(time.Date(2026, 11, 25, 21, 0, 0, 0, ny))
+	require.NotNil(t, status.NextOpen)
+	assert.True(t, status.NextOpen.Equal(time.Date(2026, 11, 27, 4, 0, 0, 0, ny)))
+
+	// Regular session reports when it ends, honouring the early close.
+	status = calendar.Status(time.Date(2026, 11, 27, 10, 0, 0, 0, ny))
+	require.NotNil(t, status.SessionEnds)
+	assert.True(t, status.SessionEnds.Equal(time.Date(2026, 11, 27, 13, 0, 0, 0, ny)))
+	assert.Nil(t, status.NextOpen)
+}
+
+func TestParseMarketCalendar_Invalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		yaml string
+	}{
+		{
+			name: "Unknown timezone",
+			yaml: `timezone: Mars/Olympus_Mons`,
+		},
+		{
+			name: "Unknown session",
+			yaml: "timezone: UTC\nsessions:\n  - { name: LUNCH, start: \"12:00\", end: \"13:00\" }",
+		},
+		{
+			name: "Overlapping sessions",
+			yaml: "timezone: UTC\nsessions:\n" +
+				"  - { name: REGULAR, start: \"09:00\", end: \"17:00\" }\n" +
+				"  - { name: POST_MARKET, start: \"16:00\", end: \"20:00\" }",
+		},
+		{
+			name: "Bad trading day",
+			yaml: "timezone: UTC\ntrading_days: [Funday]",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseMarketCalendar([]byte(tc.yaml))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadMarketCalendar_ShippedConfig(t *testing.T) {
+	_, err := LoadMarketCalendar("../../../config/calendar.yaml")
+	assert.NoError(t, err)
+}
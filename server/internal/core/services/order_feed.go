@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/fanout"
+)
+
+// OrderFeed follows the order events the outbox relay publishes, from
+// whichever instance holds the lease, with a single subscription and hands
+// them to every in-process subscriber, such as gRPC streams and FIX
+// sessions. Subscribers see committed changes, including those an order
+// group made, once they have been synced and relayed.
+type OrderFeed struct {
+	source ports.EventSource
+	logger *slog.Logger
+	hub    *fanout.Hub[domain.OrderChange]
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewOrderFeed(source ports.EventSource, logger *slog.Logger) *OrderFeed {
+	return &OrderFeed{
+		source: source,
+		logger: logger,
+		hub:    fanout.NewHub[domain.OrderChange](),
+	}
+}
+
+// Start subscribes to the events until ctx is done or Stop is called.
+func (f *OrderFeed) Start(ctx context.Context) {
+	ctx, f.cancel = context.WithCancel(ctx)
+	f.done = make(chan struct{})
+
+	go func() {
+		defer close(f.done)
+		// Subscribers are told the feed stopped, whatever the reason.
+		defer f.hub.Close()
+		if err := f.source.Subscribe(ctx, func(event domain.OutboxEvent) {
+			f.publish(ctx, event)
+		}); err != nil {
+			f.logger.ErrorContext(ctx, "order event subscription failed", "error", err)
+		}
+	}()
+}
+
+// Stop unsubscribes and ends every subscription.
+func (f *OrderFeed) Stop() {
+	if f.cancel == nil {
+		return
+	}
+	f.cancel()
+	<-f.done
+}
+
+func (f *OrderFeed) SubscribeOrders() (<-chan domain.OrderChange, func()) {
+	return f.hub.Subscribe()
+}
+
+func (f *OrderFeed) publish(ctx context.Context, event domain.OutboxEvent) {
+	var order domain.OrderEvent
+	if err := json.Unmarshal([]byte(event.Payload), &order); err != nil {
+		f.logger.ErrorContext(ctx, "skipping undecodable order event", "event_id", event.OutboxID, "error", err)
+		return
+	}
+	f.hub.Publish(domain.OrderChange{EventType: event.EventType, Order: order})
+}
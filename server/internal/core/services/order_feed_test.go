@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/logging"
+)
+
+// channelSource delivers the events sent on its channel.
+type channelSource chan domain.OutboxEvent
+
+func (s channelSource) Subscribe(ctx context.Context, onEvent func(domain.OutboxEvent)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-s:
+			onEvent(event)
+		}
+	}
+}
+
+func receiveChange(t *testing.T, changes <-chan domain.OrderChange) (domain.OrderChange, bool) {
+	t.Helper()
+	select {
+	case change, ok := <-changes:
+		return change, ok
+	case <-time.After(time.Second):
+		t.Fatal("no order change received")
+		return domain.OrderChange{}, false
+	}
+}
+
+func TestOrderFeed(t *testing.T) {
+	source := make(channelSource)
+	feed := NewOrderFeed(source, logging.Discard())
+	feed.Start(context.Background())
+	first, _ := feed.SubscribeOrders()
+	second, _ := feed.SubscribeOrders()
+
+	notes := "rebalance"
+	tx := &domain.Transaction{TransactionID: 7, Symbol: "AAPL", Type: domain.Buy, Status: domain.Completed, Quantity: 10, Notes: &notes}
+	event, err := domain.NewStatusChangedEvent(tx, domain.Pending)
+	require.NoError(t, err)
+	source <- domain.OutboxEvent{OutboxID: 1, EventType: domain.OrderStatusChanged, Payload: "not json"}
+	source <- *event
+
+	for _, changes := range []<-chan domain.OrderChange{first, second} {
+		change, ok := receiveChange(t, changes)
+		require.True(t, ok)
+		assert.Equal(t, domain.OrderStatusChanged, change.EventType, "undecodable events are skipped")
+		assert.Equal(t, "PENDING", change.Order.PreviousStatus)
+		assert.Equal(t, *tx, change.Order.Transaction())
+	}
+
+	feed.Stop()
+	_, ok := receiveChange(t, first)
+	assert.False(t, ok, "subscriptions end when the feed stops")
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/metrics"
+)
+
+// OutboxRelay publishes outbox events to every sink and deletes them once
+// all sinks have accepted them. An event is retried on the next run until
+// then, so sinks may see it more than once. Events of a transaction are
+// published in the order they were written: once one fails, later events of
+// the same transaction wait for the next run, while a run reads on past them
+// so that other transactions are not held up.
+//
+// Only one relay may run at a time, or events could be published out of
+// order; it is run by the leader.
+type OutboxRelay struct {
+	outbox    ports.OutboxRepository
+	sinks     []ports.EventSink
+	interval  time.Duration
+	batchSize int
+	logger    *slog.Logger
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+func NewOutboxRelay(
+	outbox ports.OutboxRepository,
+	sinks []ports.EventSink,
+	interval time.Duration,
+	batchSize int,
+	logger *slog.Logger,
+) *OutboxRelay {
+	return &OutboxRelay{
+		outbox:    outbox,
+		sinks:     sinks,
+		interval:  interval,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// Start relays events in the background until ctx is done or Stop is
+// called.
+func (r *OutboxRelay) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				// Let a run finish publishing what it has fetched, so
+				// that events are not published twice needlessly, unless
+				// the lease expires first.
+				runCtx, cancel := runContext(ctx)
+				err := r.Relay(runCtx)
+				cancel()
+				if err != nil {
+					r.logger.WarnContext(ctx, "outbox relay left events unpublished", "error", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop halts the relay and waits for an in-progress run to finish.
+func (r *OutboxRelay) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+}
+
+// Relay reads every pending event once, publishing those it can, and
+// returns the errors of those it could not.
+func (r *OutboxRelay) Relay(ctx context.Context) error {
+	// The transactions with an event that failed during this run.
+	blocked := make(map[int64]bool)
+	var lastSeen int64
+	var errs []error
+	unpublished := 0
+	for {
+		events, err := r.outbox.GetOutboxEvents(ctx, lastSeen, r.batchSize)
+		if err != nil {
+			return errors.Join(append(errs, fmt.Errorf("failed to read outbox: %w", err))...)
+		}
+		if len(events) == 0 {
+			break
+		}
+		lastSeen = events[len(events)-1].OutboxID
+
+		published, err := r.publishBatch(ctx, events, blocked)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		// Even if cancelled meanwhile, as the events have been published.
+		if deleteErr := r.outbox.DeleteOutboxEvents(context.WithoutCancel(ctx), published); deleteErr != nil {
+			// They will be published again on the next run.
+			return errors.Join(append(errs, fmt.Errorf("failed to delete published events: %w", deleteErr))...)
+		}
+		unpublished += len(events) - len(published)
+
+		if len(events) < r.batchSize {
+			break
+		}
+	}
+
+	metrics.OutboxPending.Set(float64(unpublished))
+	return errors.Join(errs...)
+}
+
+// publishBatch returns the IDs of the events that every sink accepted. The
+// events of the transactions in blocked are skipped, and a transaction
+// whose event fails is added to it.
+func (r *OutboxRelay) publishBatch(ctx context.Context, events []domain.OutboxEvent, blocked map[int64]bool) ([]int64, error) {
+	published := make([]int64, 0, len(events))
+	var errs []error
+
+	for _, event := range events {
+		if blocked[event.AggregateID] {
+			continue
+		}
+		if err := r.publish(ctx, event); err != nil {
+			blocked[event.AggregateID] = true
+			errs = append(errs, err)
+			r.logger.WarnContext(ctx, "failed to publish outbox event",
+				"event_id", event.OutboxID,
+				"event_type", event.EventType,
+				"transaction_id", event.AggregateID,
+				"error", err,
+			)
+			continue
+		}
+		published = append(published, event.OutboxID)
+	}
+
+	return published, errors.Join(errs...)
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, event domain.OutboxEvent) error {
+	for _, sink := range r.sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			metrics.OutboxPublished.WithLabelValues(sink.Name(), "failure").Inc()
+			return fmt.Errorf("%s: %w", sink.Name(), err)
+		}
+		metrics.OutboxPublished.WithLabelValues(sink.Name(), "success").Inc()
+	}
+	return nil
+}
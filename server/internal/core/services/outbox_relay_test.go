@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/repositories"
+)
+
+// recordingSink records published events, and fails those of the
+// transactions in failing.
+type recordingSink struct {
+	mu        sync.Mutex
+	published []domain.OutboxEvent
+	failing   map[int64]bool
+}
+
+func (s *recordingSink) Name() string {
+	return "recording"
+}
+
+func (s *recordingSink) Publish(ctx context.Context, event domain.OutboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failing[event.AggregateID] {
+		return errors.New("sink unavailable")
+	}
+	s.published = append(s.published, event)
+	return nil
+}
+
+func (s *recordingSink) Published() []domain.OutboxEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]domain.OutboxEvent(nil), s.published...)
+}
+
+// createOrders creates n orders and completes each, queueing two events per
+// order.
+func createOrders(t *testing.T, repo ports.TransactionRepository, n int) {
+	for i := 0; i < n; i++ {
+		tx := &domain.Transaction{Symbol: "AAPL", Type: domain.Buy, Status: domain.Pending, Quantity: 1, Price: 150, TotalAmount: 150}
+		require.NoError(t, repo.CreateTransaction(context.Background(), tx))
+		require.NoError(t, repo.UpdateTransactionStatus(context.Background(), tx.TransactionID, domain.Completed))
+	}
+}
+
+func TestOutboxRelay_Relay(t *testing.T) {
+	repo := repositories.NewMemoryRepository(repositories.DemoStocks())
+	createOrders(t, repo, 3)
+
+	sink := &recordingSink{}
+	// A batch smaller than the backlog is drained over several reads.
+	relay := NewOutboxRelay(repo, []ports.EventSink{sink}, time.Hour, 4, logging.Discard())
+
+	require.NoError(t, relay.Relay(context.Background()))
+
+	published := sink.Published()
+	require.Len(t, published, 6)
+	for i, event := range published {
+		assert.Equal(t, int64(i/2+1), event.AggregateID)
+	}
+
+	remaining, err := repo.GetOutboxEvents(context.Background(), 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, remaining)
+}
+
+func TestOutboxRelay_FailureHoldsBackLaterEventsOfTheTransaction(t *testing.T) {
+	repo := repositories.NewMemoryRepository(repositories.DemoStocks())
+	createOrders(t, repo, 2)
+
+	sink := &recordingSink{failing: map[int64]bool{1: true}}
+	relay := NewOutboxRelay(repo, []ports.EventSink{sink}, time.Hour, 100, logging.Discard())
+
+	assert.Error(t, relay.Relay(context.Background()))
+
+	// The other transaction's events are unaffected.
+	published := sink.Published()
+	require.Len(t, published, 2)
+	assert.Equal(t, int64(2), published[0].AggregateID)
+	assert.Equal(t, domain.OrderCreated, published[0].EventType)
+	assert.Equal(t, domain.OrderStatusChanged, published[1].EventType)
+
+	remaining, err := repo.GetOutboxEvents(context.Background(), 0, 10)
+	require.NoError(t, err)
+	require.Len(t, remaining, 2)
+
+	// Once the sink recovers, the held events follow in order.
+	sink.failing = nil
+	require.NoError(t, relay.Relay(context.Background()))
+
+	published = sink.Published()
+	require.Len(t, published, 4)
+	assert.Equal(t, int64(1), published[2].AggregateID)
+	assert.Equal(t, domain.OrderCreated, published[2].EventType)
+	assert.Equal(t, domain.OrderStatusChanged, published[3].EventType)
+}
+
+func TestOutboxRelay_BlockedTransactionDoesNotHoldUpOthers(t *testing.T) {
+	repo := repositories.NewMemoryRepository(repositories.DemoStocks())
+	createOrders(t, repo, 1)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, repo.UpdateTransactionStatus(context.Background(), 1, domain.Completed))
+	}
+	createOrders(t, repo, 1)
+
+	// Transaction 1 has more events than a batch, all stuck.
+	sink := &recordingSink{failing: map[int64]bool{1: true}}
+	relay := NewOutboxRelay(repo, []ports.EventSink{sink}, time.Hour, 2, logging.Discard())
+
+	assert.Error(t, relay.Relay(context.Background()))
+
+	published := sink.Published()
+	require.Len(t, published, 2)
+	for _, event := range published {
+		assert.Equal(t, int64(2), event.AggregateID)
+	}
+}
+
+func TestOutboxRelay_Start(t *testing.T) {
+	repo := repositories.NewMemoryRepository(repositories.DemoStocks())
+	createOrders(t, repo, 1)
+
+	sink := &recordingSink{}
+	relay := NewOutboxRelay(repo, []ports.EventSink{sink}, 10*time.Millisecond, 100, logging.Discard())
+
+	relay.Start(context.Background())
+	assert.Eventually(t, func() bool { return len(sink.Published()) == 2 }, time.Second, 10*time.Millisecond)
+	relay.Stop()
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/metrics"
+)
+
+// QuoteCache serves stock quotes from process memory. Misses are read from
+// the shared QuoteStore, or from the database while the store has no quotes
+// (before the first update, or when Redis is down). Entries are dropped when
+// the store reports that their quotes changed, which keeps every instance
+// coherent with the StockUpdater's writes.
+//
+// Quotes are only kept while subscribed, so nothing is cached until Start
+// has been called.
+type QuoteCache struct {
+	store  ports.QuoteStore
+	stocks ports.StockReader
+	logger *slog.Logger
+
+	mu     sync.RWMutex
+	quotes map[string]domain.Stock
+	// complete is set while quotes holds every stock.
+	complete   bool
+	subscribed bool
+	// generation counts invalidations, so that a load racing one doesn't
+	// cache the quotes it invalidated.
+	generation uint64
+
+	watchMu     sync.Mutex
+	watchers    map[int]func(symbols []string)
+	nextWatcher int
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewQuoteCache(store ports.QuoteStore, stocks ports.StockReader, logger *slog.Logger) *QuoteCache {
+	return &QuoteCache{
+		store:    store,
+		stocks:   stocks,
+		logger:   logger,
+		quotes:   make(map[string]domain.Stock),
+		watchers: make(map[int]func(symbols []string)),
+	}
+}
+
+// Start subscribes to quote changes until ctx is done or Stop is called.
+func (c *QuoteCache) Start(ctx context.Context) {
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+
+	go func() {
+		defer close(c.done)
+		err := c.store.Subscribe(ctx, func(symbols []string) {
+			c.invalidate(symbols, true)
+			c.notify(symbols)
+		})
+		if err != nil {
+			c.logger.ErrorContext(ctx, "quote subscription failed", "error", err)
+		}
+		c.invalidate(nil, false)
+		c.notify(nil)
+	}()
+}
+
+// Stop unsubscribes and empties the cache.
+func (c *QuoteCache) Stop() {
+	if c.cancel == nil {
+		return
+	}
+	c.cancel()
+	<-c.done
+}
+
+func (c *QuoteCache) GetAllStocks(ctx context.Context) ([]domain.Stock, error) {
+	c.mu.RLock()
+	if c.complete {
+		stocks := make([]domain.Stock, 0, len(c.quotes))
+		for _, stock := range c.quotes {
+			stocks = append(stocks, stock)
+		}
+		c.mu.RUnlock()
+		metrics.QuoteCacheRequests.WithLabelValues("memory").Inc()
+		sort.Slice(stocks, func(i, j int) bool { return stocks[i].StockID < stocks[j].StockID })
+		return stocks, nil
+	}
+	generation := c.generation
+	c.mu.RUnlock()
+
+	stocks, err := c.store.GetQuotes(ctx)
+	if err != nil {
+		c.logger.WarnContext(ctx, "failed to read quotes from the quote store", "error", err)
+	}
+	if err == nil && len(stocks) > 0 {
+		metrics.QuoteCacheRequests.WithLabelValues("store").Inc()
+	} else {
+		// A cancelled or expired request shouldn't fall through to the
+		// database.
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		metrics.QuoteCacheRequests.WithLabelValues("database").Inc()
+		if stocks, err = c.stocks.GetAllStocks(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	c.remember(generation, stocks, true)
+	return stocks, nil
+}
+
+func (c *QuoteCache) GetStockBySymbol(ctx context.Context, symbol string) (*domain.Stock, error) {
+	c.mu.RLock()
+	stock, ok := c.quotes[symbol]
+	generation := c.generation
+	c.mu.RUnlock()
+	if ok {
+		metrics.QuoteCacheRequests.WithLabelValues("memory").Inc()
+		return &stock, nil
+	}
+
+	quote, err := c.store.GetQuote(ctx, symbol)
+	if err != nil && !errors.Is(err, ports.ErrCacheMiss) {
+		c.logger.WarnContext(ctx, "failed to read quote from the quote store", "symbol", symbol, "error", err)
+	}
+	if err == nil {
+		metrics.QuoteCacheRequests.WithLabelValues("store").Inc()
+	} else {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		metrics.QuoteCacheRequests.WithLabelValues("database").Inc()
+		if quote, err = c.stocks.GetStockBySymbol(ctx, symbol); err != nil {
+			return nil, err
+		}
+	}
+
+	c.remember(generation, []domain.Stock{*quote}, false)
+	return quote, nil
+}
+
+// remember caches stocks loaded at generation, unless they have been
+// invalidated since. complete marks stocks as the full list.
+func (c *QuoteCache) remember(generation uint64, stocks []domain.Stock, complete bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.subscribed || c.generation != generation {
+		return
+	}
+	for _, stock := range stocks {
+		c.quotes[stock.Symbol] = stock
+	}
+	if complete {
+		c.complete = true
+	}
+}
+
+// WatchQuotes passes on the changes the cache is told of, once the changed
+// quotes have been dropped, so that watchers reading them get the new ones.
+func (c *QuoteCache) WatchQuotes(onChange func(symbols []string)) (stop func()) {
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+	id := c.nextWatcher
+	c.nextWatcher++
+	c.watchers[id] = onChange
+
+	return func() {
+		c.watchMu.Lock()
+		defer c.watchMu.Unlock()
+		delete(c.watchers, id)
+	}
+}
+
+func (c *QuoteCache) notify(symbols []string) {
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+	for _, onChange := range c.watchers {
+		onChange(symbols)
+	}
+}
+
+// invalidate drops the quotes of symbols, or every quote if symbols is
+// empty, and records whether changes are being received.
+func (c *QuoteCache) invalidate(symbols []string, subscribed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.subscribed = subscribed
+	c.complete = false
+	if len(symbols) == 0 {
+		c.quotes = make(map[string]domain.Stock)
+		return
+	}
+	for _, symbol := range symbols {
+		delete(c.quotes, symbol)
+	}
+}
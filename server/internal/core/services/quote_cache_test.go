@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/repositories"
+)
+
+func startQuoteCache(t *testing.T, store ports.QuoteStore, stocks ports.StockReader) *QuoteCache {
+	quoteCache := NewQuoteCache(store, stocks, logging.Discard())
+	quoteCache.Start(context.Background())
+	t.Cleanup(quoteCache.Stop)
+
+	// Wait for the subscription, after which quotes are cached.
+	require.Eventually(t, func() bool {
+		quoteCache.mu.RLock()
+		defer quoteCache.mu.RUnlock()
+		return quoteCache.subscribed
+	}, time.Second, 10*time.Millisecond)
+	return quoteCache
+}
+
+func TestQuoteCache_FallsBackToDatabase(t *testing.T) {
+	stock := &domain.Stock{StockID: 1, Symbol: "AAPL", BidPrice: 150.00, AskPrice: 150.50}
+	mockStockRepo := new(MockStockRepository)
+	mockStockRepo.On("GetStockBySymbol", mock.Anything, "AAPL").Return(stock, nil)
+	mockStockRepo.On("GetAllStocks", mock.Anything).Return([]domain.Stock{*stock}, nil)
+
+	quoteCache := startQuoteCache(t, repositories.NewMemoryQuoteStore(), mockStockRepo)
+
+	// The empty store is skipped, and the database read kept.
+	for i := 0; i < 2; i++ {
+		got, err := quoteCache.GetStockBySymbol(context.Background(), "AAPL")
+		require.NoError(t, err)
+		assert.Equal(t, stock, got)
+
+		stocks, err := quoteCache.GetAllStocks(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []domain.Stock{*stock}, stocks)
+	}
+	mockStockRepo.AssertNumberOfCalls(t, "GetStockBySymbol", 1)
+	mockStockRepo.AssertNumberOfCalls(t, "GetAllStocks", 1)
+}
+
+func TestQuoteCache_UnknownSymbol(t *testing.T) {
+	notFound := domain.NewNotFoundError("stock_not_found", "stock TSLA not found")
+	mockStockRepo := new(MockStockRepository)
+	mockStockRepo.On("GetStockBySymbol", mock.Anything, "TSLA").Return(nil, notFound)
+
+	quoteCache := startQuoteCache(t, repositories.NewMemoryQuoteStore(), mockStockRepo)
+
+	_, err := quoteCache.GetStockBySymbol(context.Background(), "TSLA")
+	assert.ErrorIs(t, err, notFound)
+
+	// A cancelled request must not reach the database.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = quoteCache.GetStockBySymbol(ctx, "TSLA")
+	assert.ErrorIs(t, err, context.Canceled)
+	mockStockRepo.AssertNumberOfCalls(t, "GetStockBySymbol", 1)
+}
+
+func TestQuoteCache_NotCachedBeforeStart(t *testing.T) {
+	store := repositories.NewMemoryQuoteStore()
+	quoteCache := NewQuoteCache(store, new(MockStockRepository), logging.Discard())
+
+	require.NoError(t, store.SaveQuotes(context.Background(), []domain.Stock{{StockID: 1, Symbol: "AAPL", AskPrice: 150.50}}))
+	_, err := quoteCache.GetStockBySymbol(context.Background(), "AAPL")
+	require.NoError(t, err)
+
+	// Without a subscription the change would go unnoticed, so the first
+	// read was not kept.
+	require.NoError(t, store.SaveQuotes(context.Background(), []domain.Stock{{StockID: 1, Symbol: "AAPL", AskPrice: 151.00}}))
+	got, err := quoteCache.GetStockBySymbol(context.Background(), "AAPL")
+	require.NoError(t, err)
+	assert.Equal(t, 151.00, got.AskPrice)
+}
+
+// TestQuoteCache_CoherentAcrossInstances runs two instances on one Redis:
+// a quote saved by one is seen by the other once the notification arrives.
+func TestQuoteCache_CoherentAcrossInstances(t *testing.T) {
+	server := miniredis.RunT(t)
+	newStore := func() ports.QuoteStore {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+		return repositories.NewRedisQuoteStore(client)
+	}
+
+	writer := newStore()
+	require.NoError(t, writer.SaveQuotes(context.Background(), []domain.Stock{
+		{StockID: 1, Symbol: "AAPL", AskPrice: 150.50},
+		{StockID: 2, Symbol: "MSFT", AskPrice: 379.45},
+	}))
+
+	mockStockRepo := new(MockStockRepository)
+	reader := startQuoteCache(t, newStore(), mockStockRepo)
+
+	stocks, err := reader.GetAllStocks(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, stocks, 2)
+	stock, err := reader.GetStockBySymbol(context.Background(), "AAPL")
+	require.NoError(t, err)
+	assert.Equal(t, 150.50, stock.AskPrice)
+
+	require.NoError(t, writer.SaveQuotes(context.Background(), []domain.Stock{{StockID: 1, Symbol: "AAPL", AskPrice: 151.00}}))
+
+	assert.Eventually(t, func() bool {
+		stock, err := reader.GetStockBySymbol(context.Background(), "AAPL")
+		return err == nil && stock.AskPrice == 151.00
+	}, time.Second, 10*time.Millisecond)
+
+	stocks, err = reader.GetAllStocks(context.Background())
+	require.NoError(t, err)
+	if assert.Len(t, stocks, 2) {
+		assert.Equal(t, "AAPL", stocks[0].Symbol)
+		assert.Equal(t, 151.00, stocks[0].AskPrice)
+	}
+	mockStockRepo.AssertNotCalled(t, "GetAllStocks", mock.Anything)
+	mockStockRepo.AssertNotCalled(t, "GetStockBySymbol", mock.Anything, mock.Anything)
+}
+
+func TestQuoteCache_WatchQuotes(t *testing.T) {
+	store := repositories.NewMemoryQuoteStore()
+	quoteCache := startQuoteCache(t, store, new(MockStockRepository))
+	require.NoError(t, store.SaveQuotes(context.Background(), []domain.Stock{{StockID: 1, Symbol: "AAPL", AskPrice: 150.50}}))
+
+	var seen [][]string
+	var price float64
+	stop := quoteCache.WatchQuotes(func(symbols []string) {
+		seen = append(seen, symbols)
+		// The changed quote has been dropped, so reading it gets the new one.
+		stock, err := quoteCache.GetStockBySymbol(context.Background(), "AAPL")
+		require.NoError(t, err)
+		price = stock.AskPrice
+	})
+	require.NoError(t, store.SaveQuotes(context.Background(), []domain.Stock{{StockID: 1, Symbol: "AAPL", AskPrice: 151.00}}))
+	stop()
+	require.NoError(t, store.SaveQuotes(context.Background(), []domain.Stock{{StockID: 1, Symbol: "AAPL", AskPrice: 152.00}}))
+
+	assert.Equal(t, [][]string{{"AAPL"}}, seen)
+	assert.Equal(t, 151.00, price)
+}
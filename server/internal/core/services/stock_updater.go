@@ -2,31 +2,70 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"math/rand"
+	"sync/atomic"
 	"time"
-	"github.com/touchsung/maxion-server/internal/core/ports"
+
 	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/metrics"
 )
 
+// StockUpdater simulates quote changes. Each tick writes the new quotes to
+// the database and then to the quote store, which notifies every instance,
+// and finally hands them to the listener.
 type StockUpdater struct {
 	stockRepo ports.StockRepository
-	done      chan bool
+	quotes    ports.QuoteStore
+	calendar  ports.MarketCalendar
+	listener  ports.QuoteListener
+	interval  time.Duration
+	logger    *slog.Logger
+	cancel    context.CancelFunc
+	done      chan struct{}
+	lastTick  atomic.Int64
 }
 
-func NewStockUpdater(stockRepo ports.StockRepository) *StockUpdater {
+func NewStockUpdater(
+	stockRepo ports.StockRepository,
+	quotes ports.QuoteStore,
+	calendar ports.MarketCalendar,
+	listener ports.QuoteListener,
+	interval time.Duration,
+	logger *slog.Logger,
+) *StockUpdater {
 	return &StockUpdater{
 		stockRepo: stockRepo,
-		done:      make(chan bool),
+		quotes:    quotes,
+		calendar:  calendar,
+		listener:  listener,
+		interval:  interval,
+		logger:    logger,
 	}
 }
 
+// Start updates quotes in the background until ctx is done or Stop is
+// called.
 func (su *StockUpdater) Start(ctx context.Context) {
-	ticker := time.NewTicker(2 * time.Second)
+	ctx, su.cancel = context.WithCancel(ctx)
+	su.done = make(chan struct{})
+
+	ticker := time.NewTicker(su.interval)
 	go func() {
 		for {
 			select {
 			case <-ticker.C:
-				su.updateStockPrices()
+				start := time.Now()
+				err := su.updateStockPrices(ctx)
+				metrics.StockUpdateDuration.Observe(time.Since(start).Seconds())
+				if err != nil {
+					metrics.StockUpdateErrors.Inc()
+				} else {
+					su.lastTick.Store(time.Now().UnixNano())
+				}
 			case <-ctx.Done():
 				ticker.Stop()
 				close(su.done)
@@ -36,27 +75,75 @@ func (su *StockUpdater) Start(ctx context.Context) {
 	}()
 }
 
+// Stop halts the updater and waits for an in-progress tick to finish.
 func (su *StockUpdater) Stop() {
+	if su.cancel == nil {
+		return
+	}
+	su.cancel()
 	<-su.done
 }
 
-func (su *StockUpdater) updateStockPrices() {
-	stocks, err := su.stockRepo.GetAllStocks()
+// LastTick returns when the updater last completed a tick without errors,
+// or the zero time if it has not yet done so.
+func (su *StockUpdater) LastTick() time.Time {
+	if nanos := su.lastTick.Load(); nanos != 0 {
+		return time.Unix(0, nanos)
+	}
+	return time.Time{}
+}
+
+func (su *StockUpdater) Interval() time.Duration {
+	return su.interval
+}
+
+func (su *StockUpdater) updateStockPrices(ctx context.Context) error {
+	// Quotes are frozen while the exchange is closed.
+	if !su.calendar.Status(time.Now()).IsOpen {
+		return nil
+	}
+
+	stocks, err := su.stockRepo.GetAllStocks(ctx)
 	if err != nil {
-		return
+		su.logger.ErrorContext(ctx, "failed to load stocks for price update", "error", err)
+		return err
 	}
 
+	var errs []error
+	quotes := make([]domain.Stock, 0, len(stocks))
+
 	for _, stock := range stocks {
 		updatedStock := domain.Stock{
 			StockID:     stock.StockID,
 			Symbol:      stock.Symbol,
-			BidPrice:    stock.BidPrice * (1 + (rand.Float64() - 0.5) * 0.01),
-			AskPrice:    stock.AskPrice * (1 + (rand.Float64() - 0.5) * 0.01),
-			BidVolume:   int(float64(stock.BidVolume) * (1 + (rand.Float64() - 0.5) * 0.2)),
-			AskVolume:   int(float64(stock.AskVolume) * (1 + (rand.Float64() - 0.5) * 0.2)),
+			BidPrice:    stock.BidPrice * (1 + (rand.Float64()-0.5)*0.01),
+			AskPrice:    stock.AskPrice * (1 + (rand.Float64()-0.5)*0.01),
+			BidVolume:   int(float64(stock.BidVolume) * (1 + (rand.Float64()-0.5)*0.2)),
+			AskVolume:   int(float64(stock.AskVolume) * (1 + (rand.Float64()-0.5)*0.2)),
 			LastUpdated: time.Now(),
 		}
-		
-		su.stockRepo.UpdateStock(&updatedStock)
+
+		if err := su.stockRepo.UpdateStock(ctx, &updatedStock); err != nil {
+			su.logger.ErrorContext(ctx, "failed to update stock price", "symbol", stock.Symbol, "error", err)
+			errs = append(errs, fmt.Errorf("failed to update %s: %w", stock.Symbol, err))
+			// The store keeps every stock, at the price the database has.
+			quotes = append(quotes, stock)
+			continue
+		}
+		quotes = append(quotes, updatedStock)
+	}
+
+	if err := su.quotes.SaveQuotes(ctx, quotes); err != nil {
+		su.logger.ErrorContext(ctx, "failed to save quotes", "error", err)
+		errs = append(errs, err)
+	}
+
+	// The quotes are saved by now; a listener failing doesn't fail the tick.
+	if su.listener != nil {
+		if err := su.listener.QuotesUpdated(ctx, quotes); err != nil {
+			su.logger.WarnContext(ctx, "quote listener failed", "error", err)
+		}
 	}
-} 
\ No newline at end of file
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/repositories"
+)
+
+func TestStockUpdater_SavesQuotes(t *testing.T) {
+	stocks := []domain.Stock{
+		{StockID: 1, Symbol: "AAPL", BidPrice: 150.00, BidVolume: 1000, AskPrice: 150.50, AskVolume: 800},
+		{StockID: 2, Symbol: "MSFT", BidPrice: 378.92, BidVolume: 250, AskPrice: 379.45, AskVolume: 400},
+	}
+	mockStockRepo := new(MockStockRepository)
+	mockStockRepo.On("GetAllStocks", mock.Anything).Return(stocks, nil)
+	mockStockRepo.On("UpdateStock", mock.Anything, mock.MatchedBy(func(stock *domain.Stock) bool {
+		return stock.Symbol == "AAPL"
+	})).Return(nil)
+	mockStockRepo.On("UpdateStock", mock.Anything, mock.MatchedBy(func(stock *domain.Stock) bool {
+		return stock.Symbol == "MSFT"
+	})).Return(errors.New("deadlock"))
+
+	store := repositories.NewMemoryQuoteStore()
+	updater := NewStockUpdater(mockStockRepo, store, openMarketCalendar(), nil, testSyncInterval, logging.Discard())
+
+	err := updater.updateStockPrices(context.Background())
+	assert.ErrorContains(t, err, "MSFT")
+
+	aapl, err := store.GetQuote(context.Background(), "AAPL")
+	require.NoError(t, err)
+	assert.InEpsilon(t, 150.50, aapl.AskPrice, 0.01)
+	assert.False(t, aapl.LastUpdated.IsZero())
+
+	// A stock the database didn't take keeps the database's price.
+	msft, err := store.GetQuote(context.Background(), "MSFT")
+	require.NoError(t, err)
+	assert.Equal(t, stocks[1], *msft)
+}
+
+type quoteListenerFunc func(ctx context.Context, quotes []domain.Stock) error
+
+func (f quoteListenerFunc) QuotesUpdated(ctx context.Context, quotes []domain.Stock) error {
+	return f(ctx, quotes)
+}
+
+func TestStockUpdater_NotifiesListener(t *testing.T) {
+	stocks := []domain.Stock{
+		{StockID: 1, Symbol: "AAPL", BidPrice: 150.00, BidVolume: 1000, AskPrice: 150.50, AskVolume: 800},
+	}
+	mockStockRepo := new(MockStockRepository)
+	mockStockRepo.On("GetAllStocks", mock.Anything).Return(stocks, nil)
+	mockStockRepo.On("UpdateStock", mock.Anything, mock.Anything).Return(nil)
+
+	var notified []domain.Stock
+	listener := quoteListenerFunc(func(ctx context.Context, quotes []domain.Stock) error {
+		notified = quotes
+		return errors.New("alert rules unavailable")
+	})
+	updater := NewStockUpdater(mockStockRepo, repositories.NewMemoryQuoteStore(), openMarketCalendar(), listener, testSyncInterval, logging.Discard())
+
+	// A listener failing doesn't fail the tick.
+	require.NoError(t, updater.updateStockPrices(context.Background()))
+	require.Len(t, notified, 1)
+	assert.Equal(t, "AAPL", notified[0].Symbol)
+	assert.False(t, notified[0].LastUpdated.IsZero())
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// SyncWorker runs CacheService.Sync every sync interval. Nothing is synced in
+// the background until Start is called.
+type SyncWorker struct {
+	cacheService *CacheService
+	interval     time.Duration
+	logger       *slog.Logger
+	cancel       context.CancelFunc
+	done         chan struct{}
+	lastSync     atomic.Int64
+}
+
+func NewSyncWorker(cacheService *CacheService, logger *slog.Logger) *SyncWorker {
+	return &SyncWorker{
+		cacheService: cacheService,
+		interval:     cacheService.SyncInterval(),
+		logger:       logger,
+	}
+}
+
+// Start syncs in the background until ctx is done or Stop is called.
+func (w *SyncWorker) Start(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+	w.done = make(chan struct{})
+
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				// A sync in progress runs to completion rather than being
+				// cut off partway through its writes, unless the lease
+				// expires first.
+				runCtx, cancel := runContext(ctx)
+				err := w.cacheService.Sync(runCtx)
+				cancel()
+				if err != nil {
+					w.logger.WarnContext(ctx, "cache sync left writes pending", "error", err)
+					continue
+				}
+				w.lastSync.Store(time.Now().UnixNano())
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop halts the worker and waits for an in-progress sync to finish. Pending
+// writes stay in the cache; call CacheService.Flush to drain them.
+func (w *SyncWorker) Stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	<-w.done
+}
+
+// LastSync returns when a sync last completed without errors, or the zero
+// time if none has.
+func (w *SyncWorker) LastSync() time.Time {
+	if nanos := w.lastSync.Load(); nanos != 0 {
+		return time.Unix(0, nanos)
+	}
+	return time.Time{}
+}
+
+func (w *SyncWorker) Interval() time.Duration {
+	return w.interval
+}
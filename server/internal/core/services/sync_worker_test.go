@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/repositories"
+)
+
+func TestSyncWorker_Start(t *testing.T) {
+	cache := repositories.NewMemoryCache()
+
+	mockDB := new(MockTransactionRepository)
+	cacheService := NewCacheService(cache, mockDB, new(MockFailedWriteRepository), testCacheTTL, testSyncInterval, logging.Discard())
+	worker := NewSyncWorker(cacheService, logging.Discard())
+
+	tx := &domain.Transaction{
+		TransactionID: 1,
+		Symbol:        "AAPL",
+		Type:          domain.Buy,
+		Status:        domain.Pending,
+		Quantity:      100,
+		Price:         150.50,
+		TotalAmount:   15050.00,
+		OrderTime:     time.Now(),
+	}
+
+	mockDB.On("CreateTransaction", mock.Anything, mock.AnythingOfType("*domain.Transaction")).Return(nil)
+	mockDB.On("UpdateTransactionStatus", mock.Anything, int64(1), domain.Completed).Return(nil)
+	mockDB.On("GetTransactionGroup", mock.Anything, int64(1)).Return([]domain.Transaction{{TransactionID: 1, Status: domain.Pending}}, nil)
+
+	err := cacheService.CacheTransaction(context.Background(), tx)
+	assert.NoError(t, err)
+
+	err = cacheService.CacheTransactionUpdate(context.Background(), 1, domain.Completed)
+	assert.NoError(t, err)
+
+	// Nothing is synced before the worker is started.
+	time.Sleep(testSyncInterval + 500*time.Millisecond)
+	mockDB.AssertNotCalled(t, "CreateTransaction", mock.Anything, mock.Anything)
+	assert.True(t, worker.LastSync().IsZero())
+
+	worker.Start(context.Background())
+	time.Sleep(testSyncInterval + 500*time.Millisecond)
+	worker.Stop()
+
+	pending, err := cacheService.PendingCount(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, pending)
+	assert.False(t, worker.LastSync().IsZero())
+
+	mockDB.AssertExpectations(t)
+}
+
+func TestSyncWorker_SyncErrorsKeepLastSync(t *testing.T) {
+	cache := repositories.NewMemoryCache()
+
+	mockDB := new(MockTransactionRepository)
+	mockDB.On("CreateTransaction", mock.Anything, mock.AnythingOfType("*domain.Transaction")).Return(errors.New("database unavailable"))
+	cacheService := NewCacheService(cache, mockDB, new(MockFailedWriteRepository), testCacheTTL, testSyncInterval, logging.Discard())
+	worker := NewSyncWorker(cacheService, logging.Discard())
+
+	assert.NoError(t, cacheService.CacheTransaction(context.Background(), &domain.Transaction{Symbol: "AAPL", Quantity: 1}))
+
+	worker.Start(context.Background())
+	time.Sleep(testSyncInterval + 500*time.Millisecond)
+	worker.Stop()
+
+	mockDB.AssertCalled(t, "CreateTransaction", mock.Anything, mock.Anything)
+	assert.True(t, worker.LastSync().IsZero())
+
+	pending, err := cacheService.PendingCount(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, pending)
+}
+
+func TestSyncWorker_StopWithoutStart(t *testing.T) {
+	cacheService := NewCacheService(repositories.NewMemoryCache(), new(MockTransactionRepository), new(MockFailedWriteRepository), testCacheTTL, time.Hour, logging.Discard())
+	worker := NewSyncWorker(cacheService, logging.Discard())
+
+	worker.Stop()
+	assert.Equal(t, time.Hour, worker.Interval())
+}
@@ -2,54 +2,240 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/touchsung/maxion-server/internal/core/domain"
 	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/metrics"
+	"github.com/touchsung/maxion-server/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type tradingService struct {
-	stockRepo       ports.StockRepository
+	stocks          ports.StockReader
 	transactionRepo ports.TransactionRepository
 	cacheService    *CacheService
+	calendar        ports.MarketCalendar
+	logger          *slog.Logger
 }
 
 func NewTradingService(
-	stockRepo ports.StockRepository, 
+	stocks ports.StockReader,
 	transactionRepo ports.TransactionRepository,
 	cacheService *CacheService,
+	calendar ports.MarketCalendar,
+	logger *slog.Logger,
 ) ports.TradingService {
 	return &tradingService{
-		stockRepo:       stockRepo,
+		stocks:          stocks,
 		transactionRepo: transactionRepo,
 		cacheService:    cacheService,
+		calendar:        calendar,
+		logger:          logger,
 	}
 }
 
-func (s *tradingService) GetAllStocks() ([]domain.Stock, error) {
-	return s.stockRepo.GetAllStocks()
+func (s *tradingService) GetAllStocks(ctx context.Context) ([]domain.Stock, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TradingService.GetAllStocks")
+	defer span.End()
+
+	stocks, err := s.stocks.GetAllStocks(ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+	}
+	return stocks, err
 }
 
-func (s *tradingService) GetAllTransactions() ([]domain.Transaction, error) {
-	return s.cacheService.GetAllTransactions(context.Background())
+func (s *tradingService) GetAllTransactions(ctx context.Context) ([]domain.Transaction, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TradingService.GetAllTransactions")
+	defer span.End()
+
+	transactions, err := s.cacheService.GetAllTransactions(ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+	}
+	return transactions, err
 }
 
-func (s *tradingService) CreateTransaction(tx *domain.Transaction) error {
-	stock, err := s.stockRepo.GetStockBySymbol(tx.Symbol)
+func (s *tradingService) CreateTransaction(ctx context.Context, tx *domain.Transaction) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TradingService.CreateTransaction",
+		trace.WithAttributes(
+			attribute.String("order.symbol", tx.Symbol),
+			attribute.String("order.type", tx.Type.String()),
+			attribute.Int("order.quantity", tx.Quantity),
+		),
+	)
+	defer func() {
+		if err != nil {
+			tracing.RecordError(span, err)
+		}
+		span.End()
+	}()
+
+	if status := s.calendar.Status(time.Now()); !status.AcceptsOrders {
+		return fmt.Errorf("%w: %s", domain.ErrMarketClosed, status.Reason)
+	}
+
+	stock, err := s.stocks.GetStockBySymbol(ctx, tx.Symbol)
 	if err != nil {
 		return err
 	}
 
-	if tx.Type == domain.Buy {
-		tx.Price = stock.AskPrice
-	} else {
-		tx.Price = stock.BidPrice
+	tx.Price = marketPrice(stock, tx.Type)
+	tx.TotalAmount = float64(tx.Quantity) * tx.Price
+
+	if err := s.cacheService.CacheTransaction(ctx, tx); err != nil {
+		s.logger.ErrorContext(ctx, "failed to cache transaction", "symbol", tx.Symbol, "error", err)
+		return err
+	}
+
+	metrics.OrdersCreated.WithLabelValues(tx.Type.String(), tx.Symbol).Inc()
+	s.logger.InfoContext(ctx, "order created",
+		"symbol", tx.Symbol,
+		"type", tx.Type.String(),
+		"quantity", tx.Quantity,
+		"price", tx.Price,
+	)
+	return nil
+}
+
+// CreateOrderGroup places the entry order of a bracket at the market price,
+// like CreateTransaction, and the exit legs at their own prices. The legs of
+// a bracket are held until the entry fills; those of an OCO group are
+// active straight away.
+func (s *tradingService) CreateOrderGroup(ctx context.Context, group *domain.OrderGroup) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TradingService.CreateOrderGroup",
+		trace.WithAttributes(
+			attribute.String("order_group.type", string(group.Type)),
+			attribute.String("order.symbol", group.Symbol),
+			attribute.String("order.type", group.Side.String()),
+			attribute.Int("order.quantity", group.Quantity),
+		),
+	)
+	defer func() {
+		if err != nil {
+			tracing.RecordError(span, err)
+		}
+		span.End()
+	}()
+
+	if status := s.calendar.Status(time.Now()); !status.AcceptsOrders {
+		return fmt.Errorf("%w: %s", domain.ErrMarketClosed, status.Reason)
 	}
 
-	tx.TotalAmount = float64(tx.Quantity) * tx.Price
+	switch {
+	case group.Type == domain.OCOGroup && (group.TakeProfitPrice == nil || group.StopLossPrice == nil):
+		return domain.NewValidationError("incomplete_order_group", "an OCO group needs a take-profit and a stop-loss price")
+	case group.TakeProfitPrice == nil && group.StopLossPrice == nil:
+		return domain.NewValidationError("incomplete_order_group", "a bracket needs a take-profit or a stop-loss price")
+	}
+
+	stock, err := s.stocks.GetStockBySymbol(ctx, group.Symbol)
+	if err != nil {
+		return err
+	}
+
+	// The exit legs must not be executable at the current price: the
+	// take-profit must be better than the price the position could be
+	// closed at now, and that price better than the stop-loss.
+	exitSide := group.Side.Opposite()
+	exitPrice := marketPrice(stock, exitSide)
+	long := group.Side == domain.Buy
+	if price := group.TakeProfitPrice; price != nil && !profitable(*price, exitPrice, long) {
+		return domain.NewRejectedError("invalid_take_profit_price",
+			fmt.Sprintf("take-profit price %.2f is on the wrong side of the market price %.2f", *price, exitPrice))
+	}
+	if price := group.StopLossPrice; price != nil && !profitable(exitPrice, *price, long) {
+		return domain.NewRejectedError("invalid_stop_loss_price",
+			fmt.Sprintf("stop-loss price %.2f is on the wrong side of the market price %.2f", *price, exitPrice))
+	}
+
+	group.ID = uuid.NewString()
+	group.Orders = nil
+	legStatus := domain.Pending
+	if group.Type == domain.BracketGroup {
+		group.Orders = append(group.Orders, newGroupOrder(group, domain.EntryLeg, group.Side, marketPrice(stock, group.Side), domain.Pending))
+		legStatus = domain.Held
+	}
+	if price := group.TakeProfitPrice; price != nil {
+		group.Orders = append(group.Orders, newGroupOrder(group, domain.TakeProfitLeg, exitSide, *price, legStatus))
+	}
+	if price := group.StopLossPrice; price != nil {
+		group.Orders = append(group.Orders, newGroupOrder(group, domain.StopLossLeg, exitSide, *price, legStatus))
+	}
+
+	if err := s.cacheService.CacheOrderGroup(ctx, group.Orders); err != nil {
+		s.logger.ErrorContext(ctx, "failed to cache order group", "symbol", group.Symbol, "error", err)
+		return err
+	}
+
+	for _, order := range group.Orders {
+		metrics.OrdersCreated.WithLabelValues(order.Type.String(), order.Symbol).Inc()
+	}
+	s.logger.InfoContext(ctx, "order group created",
+		"group_id", group.ID,
+		"group_type", group.Type,
+		"symbol", group.Symbol,
+		"type", group.Side.String(),
+		"quantity", group.Quantity,
+	)
+	return nil
+}
 
-	return s.cacheService.CacheTransaction(tx)
+func newGroupOrder(group *domain.OrderGroup, leg domain.OrderLeg, side domain.TransactionType, price float64, status domain.TransactionStatus) domain.Transaction {
+	return domain.Transaction{
+		Symbol:      group.Symbol,
+		Type:        side,
+		Status:      status,
+		Quantity:    group.Quantity,
+		Price:       price,
+		TotalAmount: float64(group.Quantity) * price,
+		Notes:       group.Notes,
+		GroupID:     &group.ID,
+		Leg:         &leg,
+	}
+}
+
+func (s *tradingService) UpdateTransactionStatus(ctx context.Context, id int64, status domain.TransactionStatus) error {
+	ctx, span := tracing.Tracer().Start(ctx, "TradingService.UpdateTransactionStatus",
+		trace.WithAttributes(
+			attribute.Int64("transaction.id", id),
+			attribute.String("transaction.status", status.String()),
+		),
+	)
+	defer span.End()
+
+	if err := s.cacheService.CacheTransactionUpdate(ctx, id, status); err != nil {
+		tracing.RecordError(span, err)
+		// A change the order's group refuses is the caller's mistake.
+		if kind, _ := domain.ErrorKindOf(err); kind != domain.KindConflict {
+			s.logger.ErrorContext(ctx, "failed to cache transaction update", "transaction_id", id, "error", err)
+		}
+		return err
+	}
+
+	metrics.OrderStatusTransitions.WithLabelValues(status.String()).Inc()
+	return nil
+}
+
+// marketPrice is the price an order of side trades at now: buyers pay the
+// ask and sellers get the bid.
+func marketPrice(stock *domain.Stock, side domain.TransactionType) float64 {
+	if side == domain.Buy {
+		return stock.AskPrice
+	}
+	return stock.BidPrice
 }
 
-func (s *tradingService) UpdateTransactionStatus(id int64, status domain.TransactionStatus) error {
-	return s.cacheService.CacheTransactionUpdate(id, status)
-} 
\ No newline at end of file
+// profitable reports whether price is strictly better than other for a
+// position: higher for a long position, lower for a short one.
+func profitable(price float64, other float64, long bool) bool {
+	if long {
+		return price > other
+	}
+	return price < other
+}
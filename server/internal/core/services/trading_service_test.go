@@ -5,9 +5,15 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/metrics"
+	"github.com/touchsung/maxion-server/internal/repositories"
 )
 
 // MockStockRepository mocks the StockRepository interface
@@ -15,32 +21,51 @@ type MockStockRepository struct {
 	mock.Mock
 }
 
-func (m *MockStockRepository) GetAllStocks() ([]domain.Stock, error) {
-	args := m.Called()
+func (m *MockStockRepository) GetAllStocks(ctx context.Context) ([]domain.Stock, error) {
+	args := m.Called(ctx)
 	return args.Get(0).([]domain.Stock), args.Error(1)
 }
 
-func (m *MockStockRepository) GetStockBySymbol(symbol string) (*domain.Stock, error) {
-	args := m.Called(symbol)
+func (m *MockStockRepository) GetStockBySymbol(ctx context.Context, symbol string) (*domain.Stock, error) {
+	args := m.Called(ctx, symbol)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Stock), args.Error(1)
 }
 
-func (m *MockStockRepository) UpdateStock(stock *domain.Stock) error {
-	args := m.Called(stock)
+func (m *MockStockRepository) UpdateStock(ctx context.Context, stock *domain.Stock) error {
+	args := m.Called(ctx, stock)
 	return args.Error(0)
 }
 
+// MockMarketCalendar mocks the MarketCalendar interface
+type MockMarketCalendar struct {
+	mock.Mock
+}
+
+func (m *MockMarketCalendar) Status(at time.Time) domain.MarketStatus {
+	args := m.Called(at)
+	return args.Get(0).(domain.MarketStatus)
+}
+
+func openMarketCalendar() *MockMarketCalendar {
+	calendar := new(MockMarketCalendar)
+	calendar.On("Status", mock.Anything).Return(domain.MarketStatus{
+		Session:       domain.Regular,
+		IsOpen:        true,
+		AcceptsOrders: true,
+	})
+	return calendar
+}
+
 func TestTradingService_GetAllStocks(t *testing.T) {
 	mockStockRepo := new(MockStockRepository)
 	mockTransactionRepo := new(MockTransactionRepository)
-	redisClient := setupRedis(t)
-	defer cleanupRedis(redisClient, t)
+	cache := repositories.NewMemoryCache()
 
-	cacheService := NewCacheService(redisClient, mockTransactionRepo)
-	tradingService := NewTradingService(mockStockRepo, mockTransactionRepo, cacheService)
+	cacheService := NewCacheService(cache, mockTransactionRepo, new(MockFailedWriteRepository), testCacheTTL, testSyncInterval, logging.Discard())
+	tradingService := NewTradingService(mockStockRepo, mockTransactionRepo, cacheService, openMarketCalendar(), logging.Discard())
 
 	fixedTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
 	expectedStocks := []domain.Stock{
@@ -64,9 +89,9 @@ func TestTradingService_GetAllStocks(t *testing.T) {
 		},
 	}
 
-	mockStockRepo.On("GetAllStocks").Return(expectedStocks, nil)
+	mockStockRepo.On("GetAllStocks", mock.Anything).Return(expectedStocks, nil)
 
-	stocks, err := tradingService.GetAllStocks()
+	stocks, err := tradingService.GetAllStocks(context.Background())
 
 	// Assert
 	assert.NoError(t, err)
@@ -81,14 +106,43 @@ func TestTradingService_GetAllStocks(t *testing.T) {
 	}
 }
 
+func TestTradingService_GetAllTransactions_PropagatesContext(t *testing.T) {
+	mockStockRepo := new(MockStockRepository)
+	mockTransactionRepo := new(MockTransactionRepository)
+	cache := repositories.NewMemoryCache()
+
+	cacheService := NewCacheService(cache, mockTransactionRepo, new(MockFailedWriteRepository), testCacheTTL, testSyncInterval, logging.Discard())
+	tradingService := NewTradingService(mockStockRepo, mockTransactionRepo, cacheService, openMarketCalendar(), logging.Discard())
+
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	mockTransactionRepo.On("GetAllTransactions", mock.MatchedBy(func(ctx context.Context) bool {
+		got, ok := ctx.Deadline()
+		return ok && got.Equal(deadline)
+	})).Return([]domain.Transaction{}, nil)
+
+	_, err := tradingService.GetAllTransactions(ctx)
+	assert.NoError(t, err)
+	mockTransactionRepo.AssertExpectations(t)
+
+	// A cancelled request must not reach the database.
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	assert.NoError(t, cache.Delete(context.Background(), ALL_TRANSACTIONS_KEY))
+
+	_, err = tradingService.GetAllTransactions(cancelled)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
 func TestTradingService_GetAllTransactions(t *testing.T) {
 	mockStockRepo := new(MockStockRepository)
 	mockTransactionRepo := new(MockTransactionRepository)
-	redisClient := setupRedis(t)
-	defer cleanupRedis(redisClient, t)
+	cache := repositories.NewMemoryCache()
 
-	cacheService := NewCacheService(redisClient, mockTransactionRepo)
-	tradingService := NewTradingService(mockStockRepo, mockTransactionRepo, cacheService)
+	cacheService := NewCacheService(cache, mockTransactionRepo, new(MockFailedWriteRepository), testCacheTTL, testSyncInterval, logging.Discard())
+	tradingService := NewTradingService(mockStockRepo, mockTransactionRepo, cacheService, openMarketCalendar(), logging.Discard())
 
 	fixedTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
 	expectedTransactions := []domain.Transaction{
@@ -104,9 +158,9 @@ func TestTradingService_GetAllTransactions(t *testing.T) {
 		},
 	}
 
-	mockTransactionRepo.On("GetAllTransactions").Return(expectedTransactions, nil)
+	mockTransactionRepo.On("GetAllTransactions", mock.Anything).Return(expectedTransactions, nil)
 
-	transactions, err := tradingService.GetAllTransactions()
+	transactions, err := tradingService.GetAllTransactions(context.Background())
 
 	assert.NoError(t, err)
 	assert.Equal(t, len(expectedTransactions), len(transactions))
@@ -124,11 +178,10 @@ func TestTradingService_GetAllTransactions(t *testing.T) {
 func TestTradingService_CreateTransaction(t *testing.T) {
 	mockStockRepo := new(MockStockRepository)
 	mockTransactionRepo := new(MockTransactionRepository)
-	redisClient := setupRedis(t)
-	defer cleanupRedis(redisClient, t)
+	cache := repositories.NewMemoryCache()
 
-	cacheService := NewCacheService(redisClient, mockTransactionRepo)
-	tradingService := NewTradingService(mockStockRepo, mockTransactionRepo, cacheService)
+	cacheService := NewCacheService(cache, mockTransactionRepo, new(MockFailedWriteRepository), testCacheTTL, testSyncInterval, logging.Discard())
+	tradingService := NewTradingService(mockStockRepo, mockTransactionRepo, cacheService, openMarketCalendar(), logging.Discard())
 
 	stock := &domain.Stock{
 		StockID:   1,
@@ -166,34 +219,211 @@ func TestTradingService_CreateTransaction(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			mockStockRepo.On("GetStockBySymbol", tc.transaction.Symbol).Return(stock, nil)
+			mockStockRepo.On("GetStockBySymbol", mock.Anything, tc.transaction.Symbol).Return(stock, nil)
+			created := metrics.OrdersCreated.WithLabelValues(tc.transaction.Type.String(), "AAPL")
+			before := testutil.ToFloat64(created)
 
-			err := tradingService.CreateTransaction(tc.transaction)
+			err := tradingService.CreateTransaction(context.Background(), tc.transaction)
 
 			assert.NoError(t, err)
+			assert.Equal(t, before+1, testutil.ToFloat64(created))
 			assert.Equal(t, tc.expectedPrice, tc.transaction.Price)
 			assert.Equal(t, tc.expectedPrice*float64(tc.transaction.Quantity), tc.transaction.TotalAmount)
 		})
 	}
 }
 
+func TestTradingService_CreateTransaction_MarketClosed(t *testing.T) {
+	mockStockRepo := new(MockStockRepository)
+	mockTransactionRepo := new(MockTransactionRepository)
+	cache := repositories.NewMemoryCache()
+
+	calendar := new(MockMarketCalendar)
+	calendar.On("Status", mock.Anything).Return(domain.MarketStatus{
+		Session: domain.Closed,
+		Reason:  "non-trading day",
+	})
+
+	cacheService := NewCacheService(cache, mockTransactionRepo, new(MockFailedWriteRepository), testCacheTTL, testSyncInterval, logging.Discard())
+	tradingService := NewTradingService(mockStockRepo, mockTransactionRepo, cacheService, calendar, logging.Discard())
+
+	err := tradingService.CreateTransaction(context.Background(), &domain.Transaction{
+		Symbol:   "AAPL",
+		Type:     domain.Buy,
+		Quantity: 100,
+	})
+
+	assert.ErrorIs(t, err, domain.ErrMarketClosed)
+	mockStockRepo.AssertNotCalled(t, "GetStockBySymbol", mock.Anything, mock.Anything)
+
+	keys, err := cache.Keys(context.Background(), PENDING_CREATE_PREFIX)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(keys))
+}
+
 func TestTradingService_UpdateTransactionStatus(t *testing.T) {
 	mockStockRepo := new(MockStockRepository)
 	mockTransactionRepo := new(MockTransactionRepository)
-	redisClient := setupRedis(t)
-	defer cleanupRedis(redisClient, t)
+	cache := repositories.NewMemoryCache()
+	mockTransactionRepo.On("GetTransactionGroup", mock.Anything, int64(1)).
+		Return([]domain.Transaction{{TransactionID: 1, Status: domain.Pending}}, nil)
 
-	cacheService := NewCacheService(redisClient, mockTransactionRepo)
-	tradingService := NewTradingService(mockStockRepo, mockTransactionRepo, cacheService)
+	cacheService := NewCacheService(cache, mockTransactionRepo, new(MockFailedWriteRepository), testCacheTTL, testSyncInterval, logging.Discard())
+	tradingService := NewTradingService(mockStockRepo, mockTransactionRepo, cacheService, openMarketCalendar(), logging.Discard())
 
 	transactionID := int64(1)
 	newStatus := domain.Completed
 
-	err := tradingService.UpdateTransactionStatus(transactionID, newStatus)
+	err := tradingService.UpdateTransactionStatus(context.Background(), transactionID, newStatus)
 
 	assert.NoError(t, err)
 
-	keys, err := redisClient.Keys(context.Background(), PENDING_UPDATE_PREFIX+"*").Result()
+	keys, err := cache.Keys(context.Background(), PENDING_UPDATE_PREFIX)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(keys))
 }
+
+// newOrderGroupTest runs the trading service on a memory repository, so that
+// order groups go through the cache and sync as in production.
+func newOrderGroupTest() (ports.TradingService, *CacheService, ports.TransactionRepository) {
+	repo := repositories.NewMemoryRepository([]domain.Stock{
+		{Symbol: "AAPL", BidPrice: 150.00, BidVolume: 1000, AskPrice: 150.50, AskVolume: 800},
+	})
+	cacheService := NewCacheService(repositories.NewMemoryCache(), repo, repo, testCacheTTL, testSyncInterval, logging.Discard())
+	return NewTradingService(repo, repo, cacheService, openMarketCalendar(), logging.Discard()), cacheService, repo
+}
+
+func TestTradingService_CreateOrderGroup(t *testing.T) {
+	price := func(p float64) *float64 { return &p }
+
+	testCases := []struct {
+		name         string
+		group        domain.OrderGroup
+		expectedKind domain.ErrorKind
+		expected     []domain.Transaction
+	}{
+		{
+			name:  "Bracket",
+			group: domain.OrderGroup{Type: domain.BracketGroup, Side: domain.Buy, TakeProfitPrice: price(160), StopLossPrice: price(140)},
+			expected: []domain.Transaction{
+				{Type: domain.Buy, Status: domain.Pending, Price: 150.50},
+				{Type: domain.Sell, Status: domain.Held, Price: 160},
+				{Type: domain.Sell, Status: domain.Held, Price: 140},
+			},
+		},
+		{
+			name:  "Bracket with a stop-loss only",
+			group: domain.OrderGroup{Type: domain.BracketGroup, Side: domain.Sell, StopLossPrice: price(155)},
+			expected: []domain.Transaction{
+				{Type: domain.Sell, Status: domain.Pending, Price: 150.00},
+				{Type: domain.Buy, Status: domain.Held, Price: 155},
+			},
+		},
+		{
+			name:  "OCO closing a short position",
+			group: domain.OrderGroup{Type: domain.OCOGroup, Side: domain.Sell, TakeProfitPrice: price(140), StopLossPrice: price(160)},
+			expected: []domain.Transaction{
+				{Type: domain.Buy, Status: domain.Pending, Price: 140},
+				{Type: domain.Buy, Status: domain.Pending, Price: 160},
+			},
+		},
+		{
+			name:         "OCO without a stop-loss",
+			group:        domain.OrderGroup{Type: domain.OCOGroup, Side: domain.Buy, TakeProfitPrice: price(160)},
+			expectedKind: domain.KindValidation,
+		},
+		{
+			name:         "Bracket without legs",
+			group:        domain.OrderGroup{Type: domain.BracketGroup, Side: domain.Buy},
+			expectedKind: domain.KindValidation,
+		},
+		{
+			name:         "Take-profit below the bid",
+			group:        domain.OrderGroup{Type: domain.BracketGroup, Side: domain.Buy, TakeProfitPrice: price(149)},
+			expectedKind: domain.KindRejected,
+		},
+		{
+			name:         "Stop-loss at the bid",
+			group:        domain.OrderGroup{Type: domain.BracketGroup, Side: domain.Buy, StopLossPrice: price(150)},
+			expectedKind: domain.KindRejected,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tradingService, _, _ := newOrderGroupTest()
+			group := tc.group
+			group.Symbol = "AAPL"
+			group.Quantity = 10
+
+			err := tradingService.CreateOrderGroup(context.Background(), &group)
+
+			if tc.expectedKind != "" {
+				kind, _ := domain.ErrorKindOf(err)
+				assert.Equal(t, tc.expectedKind, kind)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, group.ID)
+			require.Len(t, group.Orders, len(tc.expected))
+			for i, order := range group.Orders {
+				assert.Equal(t, tc.expected[i].Type, order.Type)
+				assert.Equal(t, tc.expected[i].Status, order.Status)
+				assert.Equal(t, tc.expected[i].Price, order.Price)
+				assert.Equal(t, 10*order.Price, order.TotalAmount)
+				assert.Equal(t, group.ID, *order.GroupID)
+			}
+		})
+	}
+}
+
+func TestTradingService_OrderGroupThroughCache(t *testing.T) {
+	tradingService, cacheService, repo := newOrderGroupTest()
+	ctx := context.Background()
+	price := func(p float64) *float64 { return &p }
+
+	group := &domain.OrderGroup{
+		Type:            domain.BracketGroup,
+		Symbol:          "AAPL",
+		Side:            domain.Buy,
+		Quantity:        10,
+		TakeProfitPrice: price(160),
+		StopLossPrice:   price(140),
+	}
+	require.NoError(t, tradingService.CreateOrderGroup(ctx, group))
+	require.NoError(t, cacheService.Sync(ctx))
+
+	transactions, err := repo.GetAllTransactions(ctx)
+	require.NoError(t, err)
+	require.Len(t, transactions, 3)
+	entry, takeProfit, stopLoss := transactions[0], transactions[1], transactions[2]
+
+	// A held leg can't fill before its entry.
+	err = tradingService.UpdateTransactionStatus(ctx, stopLoss.TransactionID, domain.Completed)
+	kind, _ := domain.ErrorKindOf(err)
+	assert.Equal(t, domain.KindConflict, kind)
+
+	// Updates cached between two syncs are checked and applied in the order
+	// they were made: the entry fills, activating the legs, then the
+	// take-profit fills, cancelling the stop-loss, which can then no longer
+	// fill.
+	require.NoError(t, tradingService.UpdateTransactionStatus(ctx, entry.TransactionID, domain.Completed))
+	require.NoError(t, tradingService.UpdateTransactionStatus(ctx, takeProfit.TransactionID, domain.Completed))
+	err = tradingService.UpdateTransactionStatus(ctx, stopLoss.TransactionID, domain.Completed)
+	kind, _ = domain.ErrorKindOf(err)
+	assert.Equal(t, domain.KindConflict, kind)
+	require.NoError(t, cacheService.Sync(ctx))
+
+	transactions, err = repo.GetAllTransactions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, domain.Completed, transactions[0].Status)
+	assert.Equal(t, domain.Completed, transactions[1].Status)
+	assert.Equal(t, domain.Cancelled, transactions[2].Status)
+
+	err = tradingService.UpdateTransactionStatus(ctx, stopLoss.TransactionID, domain.Completed)
+	kind, _ = domain.ErrorKindOf(err)
+	assert.Equal(t, domain.KindConflict, kind)
+	pending, err := cacheService.PendingCount(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, pending)
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/metrics"
+)
+
+// webhookRetrySchedule is how long to wait after each failed attempt before
+// the next. A delivery that fails once more after the last wait is given up.
+var webhookRetrySchedule = []time.Duration{
+	10 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+	30 * time.Minute,
+	2 * time.Hour,
+	6 * time.Hour,
+}
+
+// dispatchBatchSize is how many due deliveries are read at a time.
+const dispatchBatchSize = 100
+
+// dispatchParallelism is how many subscriptions are sent to at once. Each
+// subscription's deliveries are sent one at a time, in order.
+const dispatchParallelism = 8
+
+// WebhookDispatcher sends due webhook deliveries, retrying failed ones on
+// webhookRetrySchedule. It is run by the leader, so that a delivery is not
+// sent by two instances at once.
+type WebhookDispatcher struct {
+	repo     ports.WebhookRepository
+	sender   ports.WebhookSender
+	interval time.Duration
+	logger   *slog.Logger
+	now      func() time.Time
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func NewWebhookDispatcher(
+	repo ports.WebhookRepository,
+	sender ports.WebhookSender,
+	interval time.Duration,
+	logger *slog.Logger,
+) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		repo:     repo,
+		sender:   sender,
+		interval: interval,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Start sends deliveries in the background until ctx is done or Stop is
+// called.
+func (d *WebhookDispatcher) Start(ctx context.Context) {
+	ctx, d.cancel = context.WithCancel(ctx)
+	d.done = make(chan struct{})
+
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := d.Dispatch(ctx); err != nil && ctx.Err() == nil {
+					d.logger.WarnContext(ctx, "webhook dispatch failed", "error", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop halts the dispatcher and waits for in-progress sends to finish.
+func (d *WebhookDispatcher) Stop() {
+	if d.cancel == nil {
+		return
+	}
+	d.cancel()
+	<-d.done
+}
+
+// Dispatch sends every delivery that is due. Failed sends are scheduled for
+// a retry rather than reported as errors. Once a subscription cannot be
+// reached, its remaining due deliveries are put off until the failed one is
+// retried, rather than each waiting out the timeout.
+func (d *WebhookDispatcher) Dispatch(ctx context.Context) error {
+	for {
+		deliveries, err := d.repo.GetDueWebhookDeliveries(ctx, d.now(), dispatchBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to read due webhook deliveries: %w", err)
+		}
+
+		if err := d.dispatchBatch(ctx, deliveries); err != nil {
+			return err
+		}
+		if len(deliveries) < dispatchBatchSize {
+			return nil
+		}
+	}
+}
+
+// dispatchBatch sends deliveries to up to dispatchParallelism subscriptions
+// at once.
+func (d *WebhookDispatcher) dispatchBatch(ctx context.Context, deliveries []domain.WebhookDelivery) error {
+	var order []int64
+	bySubscription := make(map[int64][]domain.WebhookDelivery)
+	for _, delivery := range deliveries {
+		if _, ok := bySubscription[delivery.SubscriptionID]; !ok {
+			order = append(order, delivery.SubscriptionID)
+		}
+		bySubscription[delivery.SubscriptionID] = append(bySubscription[delivery.SubscriptionID], delivery)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	slots := make(chan struct{}, dispatchParallelism)
+	for _, id := range order {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(deliveries []domain.WebhookDelivery) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			if err := d.dispatchSubscription(ctx, deliveries); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(bySubscription[id])
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// dispatchSubscription sends the due deliveries of one subscription in
+// order.
+func (d *WebhookDispatcher) dispatchSubscription(ctx context.Context, deliveries []domain.WebhookDelivery) error {
+	sub, err := d.repo.GetWebhookSubscription(ctx, deliveries[0].SubscriptionID)
+	if kind, _ := domain.ErrorKindOf(err); kind == domain.KindNotFound {
+		// Deleted along with its deliveries since they were read.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read webhook subscription: %w", err)
+	}
+
+	var retryAt *time.Time
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		if retryAt != nil {
+			// Not an attempt, so it doesn't use up a retry.
+			delivery.NextAttemptAt = retryAt
+			if err := d.repo.UpdateWebhookDelivery(ctx, delivery); err != nil {
+				return fmt.Errorf("failed to postpone webhook delivery %d: %w", delivery.DeliveryID, err)
+			}
+			continue
+		}
+
+		reached, err := d.attempt(ctx, sub, delivery)
+		if err != nil {
+			return err
+		}
+		if !reached {
+			retryAt = delivery.NextAttemptAt
+			if retryAt == nil {
+				next := d.now().UTC().Add(webhookRetrySchedule[0])
+				retryAt = &next
+			}
+			if postponed := len(deliveries) - i - 1; postponed > 0 {
+				d.logger.WarnContext(ctx, "webhook unreachable, postponing its deliveries",
+					"webhook_id", sub.SubscriptionID,
+					"postponed", postponed,
+					"until", *retryAt,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// attempt sends delivery to sub and records the outcome. It reports whether
+// the subscriber was reached, that is whether it responded at all.
+func (d *WebhookDispatcher) attempt(ctx context.Context, sub *domain.WebhookSubscription, delivery *domain.WebhookDelivery) (bool, error) {
+	reached := true
+	var sendErr error
+	if sub.Active {
+		var status int
+		status, sendErr = d.sender.Send(ctx, *sub, *delivery)
+		delivery.Attempts++
+		delivery.ResponseStatus = nil
+		if status != 0 {
+			delivery.ResponseStatus = &status
+		}
+		reached = sendErr == nil || status != 0
+	} else {
+		sendErr = errors.New("webhook is inactive")
+	}
+
+	now := d.now().UTC()
+	result := d.record(delivery, sendErr, now)
+	metrics.WebhookDeliveries.WithLabelValues(result).Inc()
+	if sendErr != nil {
+		d.logger.WarnContext(ctx, "webhook delivery failed",
+			"webhook_id", sub.SubscriptionID,
+			"delivery_id", delivery.DeliveryID,
+			"event_id", delivery.EventID,
+			"attempts", delivery.Attempts,
+			"result", result,
+			"error", sendErr,
+		)
+	}
+
+	if err := d.repo.UpdateWebhookDelivery(ctx, delivery); err != nil {
+		// The delivery stays due and is sent again.
+		return reached, fmt.Errorf("failed to record webhook delivery %d: %w", delivery.DeliveryID, err)
+	}
+	return reached, nil
+}
+
+// record sets the outcome of an attempt on delivery and returns it as a
+// metric label.
+func (d *WebhookDispatcher) record(delivery *domain.WebhookDelivery, sendErr error, now time.Time) string {
+	delivery.NextAttemptAt = nil
+	if sendErr == nil {
+		delivery.Status = domain.DeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.LastError = nil
+		return "succeeded"
+	}
+
+	message := sendErr.Error()
+	delivery.LastError = &message
+	if delivery.Attempts > 0 && delivery.Attempts <= len(webhookRetrySchedule) {
+		next := now.Add(webhookRetrySchedule[delivery.Attempts-1])
+		delivery.NextAttemptAt = &next
+		return "retrying"
+	}
+	delivery.Status = domain.DeliveryFailed
+	return "failed"
+}
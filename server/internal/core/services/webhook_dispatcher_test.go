@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/events"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/repositories"
+)
+
+// webhookReceiver is a subscriber that verifies signatures and answers with
+// the statuses in responses, then 200 once they run out.
+type webhookReceiver struct {
+	mu        sync.Mutex
+	secret    string
+	responses []int
+	received  []string
+	invalid   int
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body, _ := io.ReadAll(req.Body)
+	var unix int64
+	var signature string
+	for _, part := range strings.Split(req.Header.Get(events.SignatureHeader), ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			unix, _ = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			signature = value
+		}
+	}
+	if signature != events.Sign(r.secret, time.Unix(unix, 0), body) {
+		r.invalid++
+		w.WriteHeader(401)
+		return
+	}
+
+	r.received = append(r.received, req.Header.Get("Event-Id"))
+	status := 200
+	if len(r.responses) > 0 {
+		status, r.responses = r.responses[0], r.responses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (r *webhookReceiver) Received() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.received...)
+}
+
+// setupWebhookDispatcher subscribes receiver, queues a completed order
+// event and returns a dispatcher whose clock is moved with advance.
+func setupWebhookDispatcher(t *testing.T, receiver *webhookReceiver) (*WebhookDispatcher, ports.WebhookRepository, *domain.WebhookSubscription, func(time.Duration)) {
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	repo := repositories.NewMemoryRepository(nil)
+	service := NewWebhookService(repo, logging.Discard())
+	sub := &domain.WebhookSubscription{URL: server.URL, Secret: receiver.secret}
+	require.NoError(t, service.CreateSubscription(context.Background(), sub))
+	require.NoError(t, service.Publish(context.Background(), statusChanged(t, 1, 42, domain.Completed)))
+
+	dispatcher := NewWebhookDispatcher(repo, events.NewWebhookSender(time.Second), time.Hour, logging.Discard())
+	now := time.Now()
+	dispatcher.now = func() time.Time { return now }
+	return dispatcher, repo, sub, func(d time.Duration) { now = now.Add(d) }
+}
+
+func onlyDelivery(t *testing.T, repo ports.WebhookRepository, subscriptionID int64) domain.WebhookDelivery {
+	deliveries, err := repo.GetWebhookDeliveries(context.Background(), subscriptionID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	return deliveries[0]
+}
+
+func TestWebhookDispatcher_Delivers(t *testing.T) {
+	receiver := &webhookReceiver{secret: "a-secret-of-sixteen"}
+	dispatcher, repo, sub, _ := setupWebhookDispatcher(t, receiver)
+
+	require.NoError(t, dispatcher.Dispatch(context.Background()))
+
+	delivery := onlyDelivery(t, repo, sub.SubscriptionID)
+	assert.Equal(t, []string{delivery.EventID}, receiver.Received())
+	assert.Zero(t, receiver.invalid)
+	assert.Equal(t, domain.DeliverySucceeded, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	require.NotNil(t, delivery.ResponseStatus)
+	assert.Equal(t, 200, *delivery.ResponseStatus)
+	assert.NotNil(t, delivery.DeliveredAt)
+	assert.Nil(t, delivery.NextAttemptAt)
+
+	// Nothing is left to send.
+	require.NoError(t, dispatcher.Dispatch(context.Background()))
+	assert.Len(t, receiver.Received(), 1)
+}
+
+func TestWebhookDispatcher_Retries(t *testing.T) {
+	receiver := &webhookReceiver{secret: "a-secret-of-sixteen", responses: []int{500, 503}}
+	dispatcher, repo, sub, advance := setupWebhookDispatcher(t, receiver)
+
+	require.NoError(t, dispatcher.Dispatch(context.Background()))
+	delivery := onlyDelivery(t, repo, sub.SubscriptionID)
+	assert.Equal(t, domain.DeliveryPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, 500, *delivery.ResponseStatus)
+	assert.Contains(t, *delivery.LastError, "500")
+
+	// Not due again until the first wait has passed.
+	advance(webhookRetrySchedule[0] - time.Second)
+	require.NoError(t, dispatcher.Dispatch(context.Background()))
+	assert.Len(t, receiver.Received(), 1)
+
+	advance(time.Second)
+	require.NoError(t, dispatcher.Dispatch(context.Background()))
+	advance(webhookRetrySchedule[1])
+	require.NoError(t, dispatcher.Dispatch(context.Background()))
+
+	delivery = onlyDelivery(t, repo, sub.SubscriptionID)
+	assert.Len(t, receiver.Received(), 3)
+	assert.Equal(t, domain.DeliverySucceeded, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Nil(t, delivery.LastError)
+}
+
+func TestWebhookDispatcher_GivesUp(t *testing.T) {
+	responses := make([]int, len(webhookRetrySchedule)+1)
+	for i := range responses {
+		responses[i] = 500
+	}
+	receiver := &webhookReceiver{secret: "a-secret-of-sixteen", responses: responses}
+	dispatcher, repo, sub, advance := setupWebhookDispatcher(t, receiver)
+
+	require.NoError(t, dispatcher.Dispatch(context.Background()))
+	for _, wait := range webhookRetrySchedule {
+		advance(wait)
+		require.NoError(t, dispatcher.Dispatch(context.Background()))
+	}
+
+	delivery := onlyDelivery(t, repo, sub.SubscriptionID)
+	assert.Equal(t, domain.DeliveryFailed, delivery.Status)
+	assert.Equal(t, len(webhookRetrySchedule)+1, delivery.Attempts)
+	assert.Nil(t, delivery.NextAttemptAt)
+
+	// A failed delivery can be sent again by hand.
+	service := NewWebhookService(repo, logging.Discard())
+	_, err := service.Redeliver(context.Background(), sub.SubscriptionID, delivery.DeliveryID)
+	require.NoError(t, err)
+	require.NoError(t, dispatcher.Dispatch(context.Background()))
+
+	received := receiver.Received()
+	assert.Len(t, received, len(webhookRetrySchedule)+2)
+	assert.Equal(t, delivery.EventID, received[len(received)-1])
+}
+
+func TestWebhookDispatcher_InactiveSubscription(t *testing.T) {
+	receiver := &webhookReceiver{secret: "a-secret-of-sixteen"}
+	dispatcher, repo, sub, _ := setupWebhookDispatcher(t, receiver)
+
+	sub.Active = false
+	require.NoError(t, repo.UpdateWebhookSubscription(context.Background(), sub))
+	require.NoError(t, dispatcher.Dispatch(context.Background()))
+
+	delivery := onlyDelivery(t, repo, sub.SubscriptionID)
+	assert.Empty(t, receiver.Received())
+	assert.Equal(t, domain.DeliveryFailed, delivery.Status)
+	assert.Zero(t, delivery.Attempts)
+}
+
+func TestWebhookDispatcher_WrongSecretIsRejected(t *testing.T) {
+	receiver := &webhookReceiver{secret: "a-secret-of-sixteen"}
+	dispatcher, repo, sub, _ := setupWebhookDispatcher(t, receiver)
+
+	receiver.mu.Lock()
+	receiver.secret = "another-secret-of-sixteen"
+	receiver.mu.Unlock()
+	require.NoError(t, dispatcher.Dispatch(context.Background()))
+
+	delivery := onlyDelivery(t, repo, sub.SubscriptionID)
+	assert.Empty(t, receiver.Received())
+	assert.Equal(t, 1, receiver.invalid)
+	assert.Equal(t, domain.DeliveryPending, delivery.Status)
+	assert.Equal(t, 401, *delivery.ResponseStatus)
+}
+
+func TestWebhookDispatcher_UnreachableSubscription(t *testing.T) {
+	release := make(chan struct{})
+	var hung sync.Mutex
+	hangs := 0
+	unreachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hung.Lock()
+		hangs++
+		hung.Unlock()
+		<-release
+	}))
+	t.Cleanup(unreachable.Close)
+	t.Cleanup(func() { close(release) })
+	receiver := &webhookReceiver{secret: "a-secret-of-sixteen"}
+	reachable := httptest.NewServer(receiver)
+	t.Cleanup(reachable.Close)
+
+	repo := repositories.NewMemoryRepository(nil)
+	service := NewWebhookService(repo, logging.Discard())
+	ctx := context.Background()
+	down := &domain.WebhookSubscription{URL: unreachable.URL, Secret: receiver.secret}
+	up := &domain.WebhookSubscription{URL: reachable.URL, Secret: receiver.secret}
+	require.NoError(t, service.CreateSubscription(ctx, down))
+	require.NoError(t, service.CreateSubscription(ctx, up))
+	for i := int64(1); i <= 3; i++ {
+		require.NoError(t, service.Publish(ctx, statusChanged(t, i, i, domain.Completed)))
+	}
+
+	dispatcher := NewWebhookDispatcher(repo, events.NewWebhookSender(50*time.Millisecond), time.Hour, logging.Discard())
+	now := time.Now()
+	dispatcher.now = func() time.Time { return now }
+	require.NoError(t, dispatcher.Dispatch(ctx))
+
+	// Only the first delivery waited out the timeout; the others wait for
+	// its retry without using up an attempt.
+	hung.Lock()
+	assert.Equal(t, 1, hangs)
+	hung.Unlock()
+	deliveries, err := repo.GetWebhookDeliveries(ctx, down.SubscriptionID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 3)
+	retryAt := now.UTC().Add(webhookRetrySchedule[0])
+	for _, delivery := range deliveries {
+		assert.Equal(t, domain.DeliveryPending, delivery.Status)
+		require.NotNil(t, delivery.NextAttemptAt)
+		assert.True(t, retryAt.Equal(*delivery.NextAttemptAt))
+	}
+	attempts := 0
+	for _, delivery := range deliveries {
+		attempts += delivery.Attempts
+	}
+	assert.Equal(t, 1, attempts)
+
+	assert.Len(t, receiver.Received(), 3)
+}
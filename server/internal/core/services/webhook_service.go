@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+)
+
+// deliveryLogSize is how many of a subscription's latest deliveries are
+// listed.
+const deliveryLogSize = 100
+
+// webhookEventNamespace derives webhook event IDs from outbox event IDs.
+var webhookEventNamespace = uuid.MustParse("6f0c2a4e-93d1-4b8e-a5c7-1d2e3f405162")
+
+// webhookPayload is the body POSTed to subscribers.
+type webhookPayload struct {
+	ID        string           `json:"id"`
+	Type      string           `json:"type"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Data      webhookOrderData `json:"data"`
+}
+
+type webhookOrderData struct {
+	TransactionID int64  `json:"transactionId"`
+	Status        string `json:"status"`
+}
+
+type webhookService struct {
+	repo   ports.WebhookRepository
+	logger *slog.Logger
+}
+
+// NewWebhookService manages webhook subscriptions. As a sink of the outbox
+// relay it queues a delivery to each subscriber when an order completes, is
+// cancelled or fails. Deliveries are sent by a WebhookDispatcher.
+func NewWebhookService(repo ports.WebhookRepository, logger *slog.Logger) *webhookService {
+	return &webhookService{repo: repo, logger: logger}
+}
+
+// CreateSubscription subscribes sub to every event if it names none, and
+// generates its secret if it has none.
+func (s *webhookService) CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	if len(sub.Events) == 0 {
+		sub.Events = append([]string(nil), domain.WebhookEvents...)
+	}
+	if sub.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return err
+		}
+		sub.Secret = secret
+	}
+	sub.Active = true
+	sub.CreatedAt = time.Now().UTC()
+	return s.repo.CreateWebhookSubscription(ctx, sub)
+}
+
+func (s *webhookService) GetSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	return s.repo.GetWebhookSubscriptions(ctx)
+}
+
+func (s *webhookService) GetSubscription(ctx context.Context, id int64) (*domain.WebhookSubscription, error) {
+	return s.repo.GetWebhookSubscription(ctx, id)
+}
+
+// UpdateSubscription changes the URL, events and whether sub is active. The
+// secret is kept unless sub has a new one.
+func (s *webhookService) UpdateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	current, err := s.repo.GetWebhookSubscription(ctx, sub.SubscriptionID)
+	if err != nil {
+		return err
+	}
+	if len(sub.Events) == 0 {
+		sub.Events = append([]string(nil), domain.WebhookEvents...)
+	}
+	if sub.Secret == "" {
+		sub.Secret = current.Secret
+	}
+	sub.CreatedAt = current.CreatedAt
+	return s.repo.UpdateWebhookSubscription(ctx, sub)
+}
+
+func (s *webhookService) DeleteSubscription(ctx context.Context, id int64) error {
+	return s.repo.DeleteWebhookSubscription(ctx, id)
+}
+
+func (s *webhookService) GetDeliveries(ctx context.Context, subscriptionID int64) ([]domain.WebhookDelivery, error) {
+	if _, err := s.repo.GetWebhookSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetWebhookDeliveries(ctx, subscriptionID, deliveryLogSize)
+}
+
+func (s *webhookService) Redeliver(ctx context.Context, subscriptionID int64, deliveryID int64) (*domain.WebhookDelivery, error) {
+	original, err := s.repo.GetWebhookDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if original.SubscriptionID != subscriptionID {
+		return nil, domain.NewNotFoundError("webhook_delivery_not_found",
+			fmt.Sprintf("webhook delivery %d not found", deliveryID))
+	}
+
+	now := time.Now().UTC()
+	delivery := &domain.WebhookDelivery{
+		SubscriptionID: subscriptionID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		Status:         domain.DeliveryPending,
+		NextAttemptAt:  &now,
+		CreatedAt:      now,
+	}
+	if err := s.repo.CreateWebhookDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (s *webhookService) Name() string {
+	return "webhooks"
+}
+
+// Publish queues a delivery of an order.status_changed outbox event to every
+// active subscriber of its webhook event, so that webhooks follow the status
+// changes the database committed, including those an order group made. The
+// deliveries of an event are queued all or none, and the webhook event ID is
+// derived from the outbox event, so an event published again keeps its ID.
+// Other events, and changes to statuses without a webhook event, are
+// ignored.
+func (s *webhookService) Publish(ctx context.Context, event domain.OutboxEvent) error {
+	if event.EventType != domain.OrderStatusChanged {
+		return nil
+	}
+	var order domain.OrderEvent
+	if err := json.Unmarshal([]byte(event.Payload), &order); err != nil {
+		// Retrying can't fix it, so don't hold up the order's later events.
+		s.logger.ErrorContext(ctx, "skipping undecodable order event", "event_id", event.OutboxID, "error", err)
+		return nil
+	}
+	status, _ := domain.ParseTransactionStatus(order.Status)
+	eventType, ok := domain.WebhookEventFor(status)
+	if !ok {
+		return nil
+	}
+
+	subs, err := s.repo.GetWebhookSubscriptions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+
+	eventID := uuid.NewSHA1(webhookEventNamespace, []byte(strconv.FormatInt(event.OutboxID, 10))).String()
+	payload, err := json.Marshal(webhookPayload{
+		ID:        eventID,
+		Type:      eventType,
+		CreatedAt: event.CreatedAt.UTC(),
+		Data:      webhookOrderData{TransactionID: order.TransactionID, Status: order.Status},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	now := time.Now().UTC()
+	var deliveries []domain.WebhookDelivery
+	for _, sub := range subs {
+		if !sub.Active || !sub.Subscribes(eventType) {
+			continue
+		}
+		deliveries = append(deliveries, domain.WebhookDelivery{
+			SubscriptionID: sub.SubscriptionID,
+			EventID:        eventID,
+			EventType:      eventType,
+			Payload:        string(payload),
+			Status:         domain.DeliveryPending,
+			NextAttemptAt:  &now,
+			CreatedAt:      now,
+		})
+	}
+	if err := s.repo.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+		return fmt.Errorf("failed to queue webhooks: %w", err)
+	}
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/repositories"
+)
+
+func TestWebhookService_CreateSubscription(t *testing.T) {
+	service := NewWebhookService(repositories.NewMemoryRepository(nil), logging.Discard())
+
+	sub := &domain.WebhookSubscription{URL: "https://example.com/hooks"}
+	require.NoError(t, service.CreateSubscription(context.Background(), sub))
+
+	assert.NotZero(t, sub.SubscriptionID)
+	assert.True(t, sub.Active)
+	assert.Equal(t, domain.WebhookEvents, sub.Events)
+	assert.Regexp(t, `^whsec_[0-9a-f]{48}$`, sub.Secret)
+
+	other := &domain.WebhookSubscription{URL: "https://example.com/hooks"}
+	require.NoError(t, service.CreateSubscription(context.Background(), other))
+	assert.NotEqual(t, sub.Secret, other.Secret)
+}
+
+func TestWebhookService_UpdateSubscription_KeepsSecret(t *testing.T) {
+	service := NewWebhookService(repositories.NewMemoryRepository(nil), logging.Discard())
+
+	sub := &domain.WebhookSubscription{URL: "https://example.com/hooks", Secret: "a-secret-of-sixteen"}
+	require.NoError(t, service.CreateSubscription(context.Background(), sub))
+
+	update := &domain.WebhookSubscription{
+		SubscriptionID: sub.SubscriptionID,
+		URL:            "https://example.com/other",
+		Events:         []string{domain.OrderFailed},
+	}
+	require.NoError(t, service.UpdateSubscription(context.Background(), update))
+
+	got, err := service.GetSubscription(context.Background(), sub.SubscriptionID)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/other", got.URL)
+	assert.Equal(t, "a-secret-of-sixteen", got.Secret)
+	assert.Equal(t, []string{domain.OrderFailed}, got.Events)
+	assert.False(t, got.Active)
+
+	err = service.UpdateSubscription(context.Background(), &domain.WebhookSubscription{SubscriptionID: 99})
+	kind, _ := domain.ErrorKindOf(err)
+	assert.Equal(t, domain.KindNotFound, kind)
+}
+
+// statusChanged returns the outbox event of transaction id moving to status.
+func statusChanged(t *testing.T, outboxID int64, id int64, status domain.TransactionStatus) domain.OutboxEvent {
+	event, err := domain.NewOrderEvent(domain.OrderStatusChanged, &domain.Transaction{TransactionID: id, Symbol: "AAPL", Status: status})
+	require.NoError(t, err)
+	event.OutboxID = outboxID
+	return *event
+}
+
+func TestWebhookService_Publish(t *testing.T) {
+	repo := repositories.NewMemoryRepository(nil)
+	service := NewWebhookService(repo, logging.Discard())
+	ctx := context.Background()
+
+	all := &domain.WebhookSubscription{URL: "https://example.com/all"}
+	failures := &domain.WebhookSubscription{URL: "https://example.com/failures", Events: []string{domain.OrderFailed}}
+	disabled := &domain.WebhookSubscription{URL: "https://example.com/disabled"}
+	for _, sub := range []*domain.WebhookSubscription{all, failures, disabled} {
+		require.NoError(t, service.CreateSubscription(ctx, sub))
+	}
+	disabled.Active = false
+	require.NoError(t, service.UpdateSubscription(ctx, disabled))
+
+	require.NoError(t, service.Publish(ctx, statusChanged(t, 1, 42, domain.Completed)))
+	// Pending has no webhook event, and new orders none at all.
+	require.NoError(t, service.Publish(ctx, statusChanged(t, 2, 42, domain.Pending)))
+	created, err := domain.NewOrderEvent(domain.OrderCreated, &domain.Transaction{TransactionID: 43, Status: domain.Completed})
+	require.NoError(t, err)
+	require.NoError(t, service.Publish(ctx, *created))
+
+	deliveries, err := service.GetDeliveries(ctx, all.SubscriptionID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, domain.OrderCompleted, deliveries[0].EventType)
+	assert.Equal(t, domain.DeliveryPending, deliveries[0].Status)
+	assert.NotNil(t, deliveries[0].NextAttemptAt)
+
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal([]byte(deliveries[0].Payload), &payload))
+	assert.Equal(t, deliveries[0].EventID, payload["id"])
+	assert.Equal(t, "order.completed", payload["type"])
+	assert.Equal(t, map[string]any{"transactionId": float64(42), "status": "COMPLETED"}, payload["data"])
+
+	for _, sub := range []*domain.WebhookSubscription{failures, disabled} {
+		deliveries, err := service.GetDeliveries(ctx, sub.SubscriptionID)
+		require.NoError(t, err)
+		assert.Empty(t, deliveries, sub.URL)
+	}
+
+	// An event the relay publishes again keeps its ID, so receivers can
+	// skip it.
+	require.NoError(t, service.Publish(ctx, statusChanged(t, 1, 42, domain.Completed)))
+	again, err := service.GetDeliveries(ctx, all.SubscriptionID)
+	require.NoError(t, err)
+	require.Len(t, again, 2)
+	assert.Equal(t, deliveries[0].EventID, again[0].EventID)
+}
+
+// TestWebhookService_OrderGroup relays the events of an OCO group, whose
+// take-profit fills and cancels the stop-loss.
+func TestWebhookService_OrderGroup(t *testing.T) {
+	repo := repositories.NewMemoryRepository([]domain.Stock{{Symbol: "AAPL", BidPrice: 150.00, AskPrice: 150.50}})
+	service := NewWebhookService(repo, logging.Discard())
+	relay := NewOutboxRelay(repo, []ports.EventSink{service}, time.Hour, 100, logging.Discard())
+	ctx := context.Background()
+
+	sub := &domain.WebhookSubscription{URL: "https://example.com/hooks"}
+	require.NoError(t, service.CreateSubscription(ctx, sub))
+
+	groupID := "3f1c9a52-7d1e-4c1a-9b7e-2a4d5c6e7f80"
+	takeProfit, stopLoss := domain.TakeProfitLeg, domain.StopLossLeg
+	orders := []domain.Transaction{
+		{Symbol: "AAPL", Type: domain.Sell, Status: domain.Pending, Quantity: 10, Price: 160, GroupID: &groupID, Leg: &takeProfit},
+		{Symbol: "AAPL", Type: domain.Sell, Status: domain.Pending, Quantity: 10, Price: 140, GroupID: &groupID, Leg: &stopLoss},
+	}
+	require.NoError(t, repo.CreateOrderGroup(ctx, orders))
+	require.NoError(t, repo.UpdateTransactionStatus(ctx, orders[0].TransactionID, domain.Completed))
+	// Refused, so nothing is sent for it.
+	require.Error(t, repo.UpdateTransactionStatus(ctx, orders[1].TransactionID, domain.Completed))
+	require.NoError(t, relay.Relay(ctx))
+
+	deliveries, err := service.GetDeliveries(ctx, sub.SubscriptionID)
+	require.NoError(t, err)
+	var sent []string
+	for _, delivery := range deliveries {
+		var payload webhookPayload
+		require.NoError(t, json.Unmarshal([]byte(delivery.Payload), &payload))
+		sent = append(sent, fmt.Sprintf("%s %d", payload.Type, payload.Data.TransactionID))
+	}
+	assert.ElementsMatch(t, []string{
+		fmt.Sprintf("order.completed %d", orders[0].TransactionID),
+		fmt.Sprintf("order.cancelled %d", orders[1].TransactionID),
+	}, sent)
+}
+
+func TestWebhookService_Redeliver(t *testing.T) {
+	repo := repositories.NewMemoryRepository(nil)
+	service := NewWebhookService(repo, logging.Discard())
+	ctx := context.Background()
+
+	sub := &domain.WebhookSubscription{URL: "https://example.com/hooks"}
+	require.NoError(t, service.CreateSubscription(ctx, sub))
+	require.NoError(t, service.Publish(ctx, statusChanged(t, 1, 42, domain.Cancelled)))
+	deliveries, err := service.GetDeliveries(ctx, sub.SubscriptionID)
+	require.NoError(t, err)
+	original := deliveries[0]
+
+	redelivery, err := service.Redeliver(ctx, sub.SubscriptionID, original.DeliveryID)
+	require.NoError(t, err)
+	assert.NotEqual(t, original.DeliveryID, redelivery.DeliveryID)
+	assert.Equal(t, original.EventID, redelivery.EventID)
+	assert.Equal(t, original.Payload, redelivery.Payload)
+	assert.Equal(t, domain.DeliveryPending, redelivery.Status)
+
+	// A delivery is only found under its own subscription.
+	_, err = service.Redeliver(ctx, sub.SubscriptionID+1, original.DeliveryID)
+	kind, _ := domain.ErrorKindOf(err)
+	assert.Equal(t, domain.KindNotFound, kind)
+}
@@ -0,0 +1,31 @@
+// Package events provides the sinks the outbox relay publishes order events
+// to.
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+// Envelope is how events are published to sinks that carry a single
+// document per event. ID is unique per event and is the same each time the
+// event is published.
+type Envelope struct {
+	ID            int64           `json:"id"`
+	Type          string          `json:"type"`
+	TransactionID int64           `json:"transactionId"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	Data          json.RawMessage `json:"data"`
+}
+
+func encode(event domain.OutboxEvent) ([]byte, error) {
+	return json.Marshal(Envelope{
+		ID:            event.OutboxID,
+		Type:          event.EventType,
+		TransactionID: event.AggregateID,
+		CreatedAt:     event.CreatedAt,
+		Data:          json.RawMessage(event.Payload),
+	})
+}
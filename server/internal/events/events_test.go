@@ -0,0 +1,210 @@
+package events
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/logging"
+)
+
+func testEvent() domain.OutboxEvent {
+	return domain.OutboxEvent{
+		OutboxID:    7,
+		AggregateID: 42,
+		EventType:   domain.OrderStatusChanged,
+		Payload:     `{"transactionId":42,"status":"COMPLETED"}`,
+		CreatedAt:   time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestRedisStreamSink(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	sink := NewRedisStreamSink(client, "order_events")
+
+	require.NoError(t, sink.Publish(context.Background(), testEvent()))
+
+	entries, err := client.XRange(context.Background(), "order_events", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, map[string]interface{}{
+		"id":             "7",
+		"type":           "order.status_changed",
+		"transaction_id": "42",
+		"created_at":     "2024-01-01T09:30:00Z",
+		"payload":        `{"transactionId":42,"status":"COMPLETED"}`,
+	}, entries[0].Values)
+}
+
+func TestRedisStreamSource(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	sink := NewRedisStreamSink(client, "order_events")
+	source := NewRedisStreamSource(client, "order_events", logging.Discard())
+	ctx, cancel := context.WithCancel(context.Background())
+
+	before := testEvent()
+	before.OutboxID = 1
+	require.NoError(t, sink.Publish(ctx, before))
+
+	var mu sync.Mutex
+	var received []domain.OutboxEvent
+	done := make(chan error)
+	go func() {
+		done <- source.Subscribe(ctx, func(event domain.OutboxEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, event)
+		})
+	}()
+
+	// Publish until the subscription is reading, as it only sees events
+	// added after its first read.
+	require.Eventually(t, func() bool {
+		require.NoError(t, sink.Publish(ctx, testEvent()))
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) > 0
+	}, 2*time.Second, 20*time.Millisecond)
+
+	require.NoError(t, client.XAdd(ctx, &redis.XAddArgs{Stream: "order_events", Values: []string{"id", "garbled"}}).Err())
+	last := testEvent()
+	last.OutboxID = 8
+	require.NoError(t, sink.Publish(ctx, last))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return received[len(received)-1].OutboxID == 8
+	}, 2*time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * streamBlock):
+		t.Fatal("Subscribe did not return after cancel")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, testEvent(), received[0], "events before subscribing are skipped")
+	for _, event := range received {
+		assert.NotEqual(t, int64(1), event.OutboxID)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var received Envelope
+	var eventID string
+	status := http.StatusNoContent
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &received)
+		eventID = r.Header.Get("Event-Id")
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(receiver.Close)
+
+	sink := NewWebhookSink(receiver.URL)
+	require.NoError(t, sink.Publish(context.Background(), testEvent()))
+
+	assert.Equal(t, "7", eventID)
+	assert.Equal(t, int64(7), received.ID)
+	assert.Equal(t, int64(42), received.TransactionID)
+	assert.Equal(t, "order.status_changed", received.Type)
+	assert.JSONEq(t, `{"transactionId":42,"status":"COMPLETED"}`, string(received.Data))
+
+	status = http.StatusServiceUnavailable
+	assert.Error(t, sink.Publish(context.Background(), testEvent()))
+}
+
+type publisherFunc func(ctx context.Context, subject string, data []byte) error
+
+func (f publisherFunc) Publish(ctx context.Context, subject string, data []byte) error {
+	return f(ctx, subject, data)
+}
+
+func TestNATSSink(t *testing.T) {
+	var subject string
+	var data []byte
+	sink := NewNATSSink(publisherFunc(func(ctx context.Context, s string, d []byte) error {
+		subject, data = s, d
+		return nil
+	}), "maxion")
+
+	require.NoError(t, sink.Publish(context.Background(), testEvent()))
+	assert.Equal(t, "maxion.order.status_changed", subject)
+	assert.JSONEq(t, `{
+		"id": 7,
+		"type": "order.status_changed",
+		"transactionId": 42,
+		"createdAt": "2024-01-01T09:30:00Z",
+		"data": {"transactionId": 42, "status": "COMPLETED"}
+	}`, string(data))
+
+	failing := NewNATSSink(publisherFunc(func(ctx context.Context, s string, d []byte) error {
+		return errors.New("no responders")
+	}), "maxion")
+	assert.Error(t, failing.Publish(context.Background(), testEvent()))
+}
+
+func TestWebhookSender_SignsPayload(t *testing.T) {
+	sub := domain.WebhookSubscription{Secret: "0123456789abcdef"}
+	delivery := domain.WebhookDelivery{
+		DeliveryID: 3,
+		EventID:    "evt-1",
+		EventType:  domain.OrderCompleted,
+		Payload:    `{"type":"order.completed"}`,
+	}
+
+	var signature, body string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		body = string(raw)
+		signature = r.Header.Get(SignatureHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(receiver.Close)
+	sub.URL = receiver.URL
+
+	sender := NewWebhookSender(time.Second)
+	sender.now = func() time.Time { return time.Unix(1704067200, 0) }
+
+	status, err := sender.Send(context.Background(), sub, delivery)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, delivery.Payload, body)
+
+	// Recomputed as a receiver would, from the timestamp and raw body.
+	mac := hmac.New(sha256.New, []byte(sub.Secret))
+	mac.Write([]byte("1704067200." + body))
+	assert.Equal(t, "t=1704067200,v1="+hex.EncodeToString(mac.Sum(nil)), signature)
+}
+
+func TestWebhookSender_RejectedDelivery(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	t.Cleanup(receiver.Close)
+
+	status, err := NewWebhookSender(time.Second).Send(context.Background(),
+		domain.WebhookSubscription{URL: receiver.URL, Secret: "0123456789abcdef"},
+		domain.WebhookDelivery{Payload: `{}`})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusGone, status)
+}
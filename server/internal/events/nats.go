@@ -0,0 +1,39 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+// Publisher publishes a message on a NATS subject, returning once the
+// server has acknowledged it. A JetStream context satisfies it with a small
+// wrapper; core NATS publishes are not acknowledged, so they would lose
+// events.
+type Publisher interface {
+	Publish(ctx context.Context, subject string, data []byte) error
+}
+
+type natsSink struct {
+	publisher Publisher
+	prefix    string
+}
+
+// NewNATSSink publishes each event's Envelope on the subject
+// <prefix>.<event type>, e.g. "maxion.order.created".
+func NewNATSSink(publisher Publisher, prefix string) *natsSink {
+	return &natsSink{publisher: publisher, prefix: prefix}
+}
+
+func (s *natsSink) Name() string {
+	return "nats"
+}
+
+func (s *natsSink) Publish(ctx context.Context, event domain.OutboxEvent) error {
+	data, err := encode(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	return s.publisher.Publish(ctx, s.prefix+"."+event.EventType, data)
+}
@@ -0,0 +1,133 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+// streamMaxLen caps the stream, approximately, so that it does not grow
+// without bound when nothing consumes it.
+const streamMaxLen = 100_000
+
+type redisStreamSink struct {
+	client redis.UniversalClient
+	stream string
+}
+
+// NewRedisStreamSink adds events to stream, one entry per event with the
+// fields id, type, transaction_id, created_at and payload.
+func NewRedisStreamSink(client redis.UniversalClient, stream string) *redisStreamSink {
+	return &redisStreamSink{client: client, stream: stream}
+}
+
+func (s *redisStreamSink) Name() string {
+	return "redis_stream"
+}
+
+func (s *redisStreamSink) Publish(ctx context.Context, event domain.OutboxEvent) error {
+	return s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.stream,
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: []string{
+			"id", strconv.FormatInt(event.OutboxID, 10),
+			"type", event.EventType,
+			"transaction_id", strconv.FormatInt(event.AggregateID, 10),
+			"created_at", event.CreatedAt.UTC().Format(time.RFC3339Nano),
+			"payload", event.Payload,
+		},
+	}).Err()
+}
+
+// Reads block for at most streamBlock, so that a cancelled subscription
+// returns promptly, and failed reads are retried after streamRetry.
+const (
+	streamBlock = 5 * time.Second
+	streamRetry = time.Second
+)
+
+type redisStreamSource struct {
+	client redis.UniversalClient
+	stream string
+	logger *slog.Logger
+}
+
+// NewRedisStreamSource reads the events NewRedisStreamSink adds to stream.
+func NewRedisStreamSource(client redis.UniversalClient, stream string, logger *slog.Logger) *redisStreamSource {
+	return &redisStreamSource{client: client, stream: stream, logger: logger}
+}
+
+// Subscribe reads the events added to the stream after it was called. Failed
+// reads are logged and retried from the last entry read, so no event is
+// missed unless the stream is trimmed in the meantime. Entries that cannot
+// be decoded are logged and skipped.
+func (s *redisStreamSource) Subscribe(ctx context.Context, onEvent func(domain.OutboxEvent)) error {
+	lastID := "$"
+	for {
+		streams, err := s.client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{s.stream, lastID},
+			Block:   streamBlock,
+		}).Result()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to read order events", "stream", s.stream, "error", err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(streamRetry):
+			}
+			continue
+		}
+
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				lastID = message.ID
+				event, err := decodeStreamEntry(message.Values)
+				if err != nil {
+					s.logger.ErrorContext(ctx, "skipping undecodable order event", "stream", s.stream, "entry", message.ID, "error", err)
+					continue
+				}
+				onEvent(event)
+			}
+		}
+	}
+}
+
+func decodeStreamEntry(values map[string]any) (domain.OutboxEvent, error) {
+	field := func(name string) string {
+		value, _ := values[name].(string)
+		return value
+	}
+
+	id, err := strconv.ParseInt(field("id"), 10, 64)
+	if err != nil {
+		return domain.OutboxEvent{}, fmt.Errorf("invalid id: %w", err)
+	}
+	aggregateID, err := strconv.ParseInt(field("transaction_id"), 10, 64)
+	if err != nil {
+		return domain.OutboxEvent{}, fmt.Errorf("invalid transaction_id: %w", err)
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, field("created_at"))
+	if err != nil {
+		return domain.OutboxEvent{}, fmt.Errorf("invalid created_at: %w", err)
+	}
+	return domain.OutboxEvent{
+		OutboxID:    id,
+		AggregateID: aggregateID,
+		EventType:   field("type"),
+		Payload:     field("payload"),
+		CreatedAt:   createdAt,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+const webhookTimeout = 10 * time.Second
+
+type webhookSink struct {
+	client *http.Client
+	url    string
+}
+
+// NewWebhookSink POSTs each event's Envelope to url. Any 2xx response
+// accepts the event; the event ID is also sent in the Event-Id header.
+func NewWebhookSink(url string) *webhookSink {
+	return &webhookSink{
+		client: &http.Client{Timeout: webhookTimeout},
+		url:    url,
+	}
+}
+
+func (s *webhookSink) Name() string {
+	return "webhook"
+}
+
+func (s *webhookSink) Publish(ctx context.Context, event domain.OutboxEvent) error {
+	body, err := encode(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Event-Id", strconv.FormatInt(event.OutboxID, 10))
+	req.Header.Set("Event-Type", event.EventType)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+// SignatureHeader carries the signature of a webhook subscription delivery,
+// as "t=<unix seconds>,v1=<hex HMAC-SHA256>".
+const SignatureHeader = "Webhook-Signature"
+
+// Sign returns the HMAC-SHA256 of "<unix seconds>.<body>" keyed with secret,
+// hex encoded. Receivers recompute it from the t value of the signature
+// header and the raw body, and should reject old timestamps to prevent
+// replays.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp.Unix())
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type webhookSender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewWebhookSender POSTs webhook subscription deliveries, signed with the
+// subscription's secret, waiting up to timeout for a response.
+func NewWebhookSender(timeout time.Duration) *webhookSender {
+	return &webhookSender{
+		client: &http.Client{Timeout: timeout},
+		now:    time.Now,
+	}
+}
+
+func (s *webhookSender) Send(ctx context.Context, sub domain.WebhookSubscription, delivery domain.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := s.now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Event-Id", delivery.EventID)
+	req.Header.Set("Event-Type", delivery.EventType)
+	req.Header.Set("Delivery-Id", strconv.FormatInt(delivery.DeliveryID, 10))
+	req.Header.Set(SignatureHeader, fmt.Sprintf("t=%d,v1=%s", timestamp.Unix(), Sign(sub.Secret, timestamp, body)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,73 @@
+// Package fanout hands values from one producer to many in-process
+// consumers.
+package fanout
+
+import "sync"
+
+// Buffer is how many values a subscriber may fall behind before it is
+// dropped.
+const Buffer = 256
+
+// Hub hands each published value to every subscriber. Publishing never
+// blocks: a subscriber that falls more than Buffer values behind has its
+// channel closed and is dropped, rather than holding up the others, and has
+// to resubscribe and catch up by other means.
+type Hub[T any] struct {
+	mu     sync.Mutex
+	subs   map[chan T]struct{}
+	closed bool
+}
+
+func NewHub[T any]() *Hub[T] {
+	return &Hub[T]{subs: make(map[chan T]struct{})}
+}
+
+// Subscribe returns a channel of the values published from now on, and a
+// function that ends the subscription. The channel is closed when the
+// subscriber is dropped, the subscription ends or the hub is closed.
+func (h *Hub[T]) Subscribe() (<-chan T, func()) {
+	ch := make(chan T, Buffer)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	h.subs[ch] = struct{}{}
+	return ch, func() { h.drop(ch) }
+}
+
+func (h *Hub[T]) Publish(value T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- value:
+		default:
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close ends every subscription. Later subscriptions are closed straight
+// away.
+func (h *Hub[T]) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch := range h.subs {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+func (h *Hub[T]) drop(ch chan T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
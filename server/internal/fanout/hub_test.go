@@ -0,0 +1,73 @@
+package fanout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// drain returns the values left in ch and whether it has been closed.
+func drain(ch <-chan int) ([]int, bool) {
+	var values []int
+	for {
+		select {
+		case value, ok := <-ch:
+			if !ok {
+				return values, true
+			}
+			values = append(values, value)
+		default:
+			return values, false
+		}
+	}
+}
+
+func TestHub_Publish(t *testing.T) {
+	hub := NewHub[int]()
+	first, _ := hub.Subscribe()
+	hub.Publish(1)
+	second, _ := hub.Subscribe()
+	hub.Publish(2)
+
+	values, closed := drain(first)
+	assert.Equal(t, []int{1, 2}, values)
+	assert.False(t, closed)
+	values, _ = drain(second)
+	assert.Equal(t, []int{2}, values, "only values published after subscribing")
+}
+
+func TestHub_DropsSlowSubscribers(t *testing.T) {
+	hub := NewHub[int]()
+	slow, _ := hub.Subscribe()
+	for i := 0; i <= Buffer; i++ {
+		hub.Publish(i)
+	}
+	fast, _ := hub.Subscribe()
+	hub.Publish(-1)
+
+	values, closed := drain(slow)
+	assert.Len(t, values, Buffer)
+	assert.True(t, closed)
+	values, closed = drain(fast)
+	assert.Equal(t, []int{-1}, values)
+	assert.False(t, closed)
+}
+
+func TestHub_UnsubscribeAndClose(t *testing.T) {
+	hub := NewHub[int]()
+	ch, unsubscribe := hub.Subscribe()
+	unsubscribe()
+	unsubscribe()
+	_, closed := drain(ch)
+	assert.True(t, closed)
+
+	ch, unsubscribe = hub.Subscribe()
+	hub.Close()
+	_, closed = drain(ch)
+	assert.True(t, closed)
+	unsubscribe()
+
+	ch, _ = hub.Subscribe()
+	_, closed = drain(ch)
+	assert.True(t, closed, "subscriptions after Close are closed")
+}
@@ -0,0 +1,173 @@
+package fix
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/ports"
+)
+
+type Config struct {
+	// SenderCompID identifies this gateway; counterparties send it as
+	// TargetCompID.
+	SenderCompID string
+	// TargetCompIDs lists the counterparties allowed to log on. Any
+	// counterparty is accepted when it is empty.
+	TargetCompIDs []string
+	// PollInterval is how long a session waits before catching up on its
+	// orders when the order feed stops delivering changes to it.
+	PollInterval time.Duration
+	// RequestTimeout bounds each call on the trading service.
+	RequestTimeout time.Duration
+	// RateLimit, if set, is called with the counterparty's CompID before
+	// each order is placed, including the new order of a cancel/replace.
+	// The order is rejected if it returns an error.
+	RateLimit func(ctx context.Context, compID string) error
+}
+
+// Acceptor is a FIX 4.4 order-entry gateway. It accepts initiator
+// connections, runs the session layer and turns orders into calls on the
+// trading service, reporting their progress with ExecutionReports as the
+// order feed delivers their changes.
+type Acceptor struct {
+	cfg       Config
+	trading   ports.TradingService
+	orderFeed ports.OrderFeed
+	sessions  ports.FIXSessionRepository
+	logger    *slog.Logger
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[*session]bool
+	// loggedOn maps session IDs to their connection, so that a session is
+	// only logged on once at a time.
+	loggedOn map[string]*session
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+func NewAcceptor(cfg Config, trading ports.TradingService, orderFeed ports.OrderFeed, sessions ports.FIXSessionRepository, logger *slog.Logger) *Acceptor {
+	return &Acceptor{
+		cfg:       cfg,
+		trading:   trading,
+		orderFeed: orderFeed,
+		sessions:  sessions,
+		logger:    logger,
+		conns:     make(map[*session]bool),
+		loggedOn:  make(map[string]*session),
+	}
+}
+
+// Serve accepts connections on lis until Close is called, when it returns
+// nil.
+func (a *Acceptor) Serve(lis net.Listener) error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return net.ErrClosed
+	}
+	a.listener = lis
+	a.mu.Unlock()
+
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			a.mu.Lock()
+			closed := a.closed
+			a.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		s := newSession(a, conn)
+		a.mu.Lock()
+		if a.closed {
+			a.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		a.conns[s] = true
+		a.wg.Add(1)
+		a.mu.Unlock()
+
+		go func() {
+			defer a.wg.Done()
+			s.run()
+		}()
+	}
+}
+
+// Close stops accepting connections and logs out every session, waiting
+// for the counterparties to confirm until ctx expires. Connections that
+// are still open then are dropped.
+func (a *Acceptor) Close(ctx context.Context) error {
+	a.mu.Lock()
+	a.closed = true
+	lis := a.listener
+	conns := make([]*session, 0, len(a.conns))
+	for s := range a.conns {
+		conns = append(conns, s)
+	}
+	a.mu.Unlock()
+
+	var errs []error
+	if lis != nil {
+		if err := lis.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			errs = append(errs, err)
+		}
+	}
+	for _, s := range conns {
+		s.shutdown("server shutting down")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		for _, s := range conns {
+			s.conn.Close()
+		}
+		<-done
+		errs = append(errs, ctx.Err())
+	}
+	return errors.Join(errs...)
+}
+
+func (a *Acceptor) allowed(targetCompID string) bool {
+	return len(a.cfg.TargetCompIDs) == 0 || slices.Contains(a.cfg.TargetCompIDs, targetCompID)
+}
+
+// register marks the session ID as logged on by s, failing if another
+// connection already holds it.
+func (a *Acceptor) register(id string, s *session) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, taken := a.loggedOn[id]; taken {
+		return false
+	}
+	a.loggedOn[id] = s
+	return true
+}
+
+func (a *Acceptor) remove(s *session) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.conns, s)
+	if s.id != "" && a.loggedOn[s.id] == s {
+		delete(a.loggedOn, s.id)
+	}
+}
@@ -0,0 +1,732 @@
+package fix
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/fanout"
+	"github.com/touchsung/maxion-server/internal/logging"
+)
+
+// fakeTradingService keeps orders in memory and is also their order feed.
+// Like the real service, created orders have no ID until they are inserted,
+// which tests trigger with insert, and only inserted orders have events.
+type fakeTradingService struct {
+	mu           sync.Mutex
+	transactions []domain.Transaction
+	nextID       int64
+	createErr    error
+	feed         *fanout.Hub[domain.OrderChange]
+}
+
+func newFakeTradingService() *fakeTradingService {
+	return &fakeTradingService{feed: fanout.NewHub[domain.OrderChange]()}
+}
+
+func (f *fakeTradingService) SubscribeOrders() (<-chan domain.OrderChange, func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.feed.Subscribe()
+}
+
+// publish adds the event of a change to tx to the feed. The caller holds mu.
+func (f *fakeTradingService) publish(eventType string, tx domain.Transaction) {
+	f.feed.Publish(domain.OrderChange{EventType: eventType, Order: domain.OrderEvent{
+		TransactionID: tx.TransactionID,
+		Symbol:        tx.Symbol,
+		Type:          tx.Type.String(),
+		Status:        tx.Status.String(),
+		Quantity:      tx.Quantity,
+		Price:         tx.Price,
+		TotalAmount:   tx.TotalAmount,
+		ClientOrderID: tx.ClientOrderID,
+	}})
+}
+
+// interrupt ends every feed subscription and runs change, whose effects
+// have no events, before later subscriptions are accepted again.
+func (f *fakeTradingService) interrupt(change func()) {
+	f.mu.Lock()
+	f.feed.Close()
+	f.mu.Unlock()
+
+	change()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.feed = fanout.NewHub[domain.OrderChange]()
+}
+
+func (f *fakeTradingService) GetAllStocks(ctx context.Context) ([]domain.Stock, error) {
+	return nil, nil
+}
+
+func (f *fakeTradingService) GetAllTransactions(ctx context.Context) ([]domain.Transaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]domain.Transaction(nil), f.transactions...), nil
+}
+
+func (f *fakeTradingService) CreateTransaction(ctx context.Context, tx *domain.Transaction) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.createErr != nil {
+		return f.createErr
+	}
+	if tx.Symbol != "AAPL" {
+		return domain.NewNotFoundError("stock_not_found", fmt.Sprintf("stock %s not found", tx.Symbol))
+	}
+	tx.Price = 150.50
+	tx.TotalAmount = float64(tx.Quantity) * tx.Price
+	f.transactions = append(f.transactions, *tx)
+	return nil
+}
+
+// The gateway doesn't place order groups.
+func (f *fakeTradingService) CreateOrderGroup(ctx context.Context, group *domain.OrderGroup) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (f *fakeTradingService) UpdateTransactionStatus(ctx context.Context, id int64, status domain.TransactionStatus) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.transactions {
+		if f.transactions[i].TransactionID == id {
+			f.transactions[i].Status = status
+			f.publish(domain.OrderStatusChanged, f.transactions[i])
+			return nil
+		}
+	}
+	return domain.NewNotFoundError("transaction_not_found", fmt.Sprintf("transaction %d not found", id))
+}
+
+// insert assigns IDs to the orders created so far.
+func (f *fakeTradingService) insert() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.transactions {
+		if f.transactions[i].TransactionID == 0 {
+			f.nextID++
+			f.transactions[i].TransactionID = f.nextID
+			f.publish(domain.OrderCreated, f.transactions[i])
+		}
+	}
+}
+
+func (f *fakeTradingService) setStatus(id int64, status domain.TransactionStatus) {
+	_ = f.UpdateTransactionStatus(context.Background(), id, status)
+}
+
+type memorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]domain.FIXSession
+}
+
+func (m *memorySessionStore) GetFIXSession(ctx context.Context, sessionID string) (*domain.FIXSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[sessionID]
+	if !ok {
+		return nil, domain.NewNotFoundError("fix_session_not_found", "FIX session not found")
+	}
+	return &session, nil
+}
+
+func (m *memorySessionStore) SaveFIXSession(ctx context.Context, session *domain.FIXSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions[session.SessionID] = *session
+	return nil
+}
+
+type testGateway struct {
+	acceptor *Acceptor
+	addr     string
+	trading  *fakeTradingService
+	store    *memorySessionStore
+}
+
+func setupTest(t *testing.T, options ...func(*Config)) *testGateway {
+	trading := newFakeTradingService()
+	store := &memorySessionStore{sessions: make(map[string]domain.FIXSession)}
+	cfg := Config{
+		SenderCompID:   "MAXION",
+		TargetCompIDs:  []string{"CLIENT"},
+		PollInterval:   10 * time.Millisecond,
+		RequestTimeout: time.Second,
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+	acceptor := NewAcceptor(cfg, trading, trading, store, logging.Discard())
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = acceptor.Serve(lis)
+	}()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = acceptor.Close(ctx)
+	})
+	return &testGateway{acceptor: acceptor, addr: lis.Addr().String(), trading: trading, store: store}
+}
+
+// initiator is the counterparty side of a session.
+type initiator struct {
+	t            *testing.T
+	conn         net.Conn
+	reader       *bufio.Reader
+	senderCompID string
+	seq          int
+}
+
+func (g *testGateway) dial(t *testing.T, senderCompID string) *initiator {
+	conn, err := net.Dial("tcp", g.addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return &initiator{t: t, conn: conn, reader: bufio.NewReader(conn), senderCompID: senderCompID, seq: 1}
+}
+
+// logon connects as CLIENT, starting at seq, and returns the Logon reply.
+func (g *testGateway) logon(t *testing.T, seq int, fields ...field) (*initiator, *Message) {
+	c := g.dial(t, "CLIENT")
+	c.seq = seq
+	msg := newMessage(msgLogon).Set(tagEncryptMethod, "0").SetInt(tagHeartBtInt, 30)
+	for _, f := range fields {
+		msg.Set(f.tag, f.value)
+	}
+	c.send(msg)
+	return c, c.expect(msgLogon)
+}
+
+func (c *initiator) send(msg *Message) {
+	c.sendSeq(msg, c.seq)
+	c.seq++
+}
+
+func (c *initiator) sendSeq(msg *Message, seq int) {
+	out := newMessage(msg.MsgType()).
+		Set(tagSenderCompID, c.senderCompID).
+		Set(tagTargetCompID, "MAXION").
+		SetInt(tagMsgSeqNum, seq).
+		Set(tagSendingTime, time.Now().UTC().Format(sendingTimeLayout))
+	for _, f := range msg.fields {
+		out.Set(f.tag, f.value)
+	}
+	_, err := c.conn.Write(out.Bytes())
+	require.NoError(c.t, err)
+}
+
+// expect reads the next message, skipping heartbeats that do not answer a
+// TestRequest, and checks its type.
+func (c *initiator) expect(msgType string) *Message {
+	c.t.Helper()
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	for {
+		msg, err := readMessage(c.reader)
+		require.NoError(c.t, err)
+		if msg.MsgType() == msgHeartbeat && msg.Get(tagTestReqID) == "" {
+			continue
+		}
+		require.Equal(c.t, msgType, msg.MsgType(), "unexpected message %s", msg)
+		return msg
+	}
+}
+
+// expectClosed checks that the acceptor drops the connection without
+// sending anything else.
+func (c *initiator) expectClosed() {
+	c.t.Helper()
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	msg, err := readMessage(c.reader)
+	require.Error(c.t, err, "unexpected message %s", msg)
+	assert.NotErrorIs(c.t, err, errGarbled)
+}
+
+func (c *initiator) newOrder(clOrdID string, symbol string, qty int) *Message {
+	return c.expectAfter(newMessage(msgNewOrderSingle).
+		Set(tagClOrdID, clOrdID).
+		Set(tagSymbol, symbol).
+		Set(tagSide, sideBuy).
+		SetInt(tagOrderQty, qty).
+		Set(tagOrdType, ordTypeMarket).
+		Set(tagTransactTime, time.Now().UTC().Format(sendingTimeLayout)), msgExecutionReport)
+}
+
+func (c *initiator) expectAfter(msg *Message, msgType string) *Message {
+	c.t.Helper()
+	c.send(msg)
+	return c.expect(msgType)
+}
+
+func TestLogon(t *testing.T) {
+	gateway := setupTest(t)
+
+	_, reply := gateway.logon(t, 1)
+
+	assert.Equal(t, "MAXION", reply.Get(tagSenderCompID))
+	assert.Equal(t, "CLIENT", reply.Get(tagTargetCompID))
+	assert.Equal(t, "1", reply.Get(tagMsgSeqNum))
+	assert.Equal(t, "30", reply.Get(tagHeartBtInt))
+}
+
+func TestLogon_Refused(t *testing.T) {
+	testCases := []struct {
+		name         string
+		senderCompID string
+		msgType      string
+		heartBtInt   int
+	}{
+		{name: "Unknown SenderCompID", senderCompID: "OTHER", msgType: msgLogon, heartBtInt: 30},
+		{name: "Not a Logon", senderCompID: "CLIENT", msgType: msgHeartbeat, heartBtInt: 30},
+		{name: "No heartbeat interval", senderCompID: "CLIENT", msgType: msgLogon, heartBtInt: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := setupTest(t)
+			c := gateway.dial(t, tc.senderCompID)
+
+			c.send(newMessage(tc.msgType).Set(tagEncryptMethod, "0").SetInt(tagHeartBtInt, tc.heartBtInt))
+
+			c.expectClosed()
+		})
+	}
+}
+
+func TestLogon_AlreadyLoggedOn(t *testing.T) {
+	gateway := setupTest(t)
+	gateway.logon(t, 1)
+
+	c := gateway.dial(t, "CLIENT")
+	c.send(newMessage(msgLogon).Set(tagEncryptMethod, "0").SetInt(tagHeartBtInt, 30))
+
+	c.expectClosed()
+}
+
+func TestTestRequestAndLogout(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+
+	heartbeat := c.expectAfter(newMessage(msgTestRequest).Set(tagTestReqID, "T1"), msgHeartbeat)
+	assert.Equal(t, "T1", heartbeat.Get(tagTestReqID))
+
+	c.expectAfter(newMessage(msgLogout), msgLogout)
+	c.expectClosed()
+}
+
+func TestSequenceNumbers_PersistAcrossLogons(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+	c.expectAfter(newMessage(msgTestRequest).Set(tagTestReqID, "T1"), msgHeartbeat)
+	c.expectAfter(newMessage(msgLogout), msgLogout)
+	c.expectClosed()
+
+	state, err := gateway.store.GetFIXSession(context.Background(), "FIX.4.4:MAXION->CLIENT")
+	require.NoError(t, err)
+	assert.Equal(t, 4, state.NextSenderSeqNum)
+	assert.Equal(t, 4, state.NextTargetSeqNum)
+
+	_, reply := gateway.logon(t, 4)
+	assert.Equal(t, "4", reply.Get(tagMsgSeqNum))
+}
+
+func TestSequenceNumbers_Reset(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+	c.expectAfter(newMessage(msgLogout), msgLogout)
+	c.expectClosed()
+
+	_, reply := gateway.logon(t, 1, field{tag: tagResetSeqNumFlag, value: "Y"})
+
+	assert.Equal(t, "1", reply.Get(tagMsgSeqNum))
+	assert.Equal(t, "Y", reply.Get(tagResetSeqNumFlag))
+}
+
+func TestSequenceNumbers_TooLowOnLogon(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+	c.expectAfter(newMessage(msgLogout), msgLogout)
+	c.expectClosed()
+
+	c = gateway.dial(t, "CLIENT")
+	c.send(newMessage(msgLogon).Set(tagEncryptMethod, "0").SetInt(tagHeartBtInt, 30))
+
+	logout := c.expect(msgLogout)
+	assert.Contains(t, logout.Get(tagText), "MsgSeqNum too low")
+	c.expectClosed()
+}
+
+func TestSequenceNumbers_GapTriggersResendRequest(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+
+	c.seq = 5
+	resend := c.expectAfter(newMessage(msgTestRequest).Set(tagTestReqID, "T1"), msgResendRequest)
+	assert.Equal(t, "2", resend.Get(tagBeginSeqNo))
+	assert.Equal(t, "0", resend.Get(tagEndSeqNo))
+
+	// Filling the gap lets the session continue from the new number.
+	c.sendSeq(newMessage(msgSequenceReset).Set(tagGapFillFlag, "Y").SetInt(tagNewSeqNo, 6), 2)
+	c.seq = 6
+	heartbeat := c.expectAfter(newMessage(msgTestRequest).Set(tagTestReqID, "T2"), msgHeartbeat)
+	assert.Equal(t, "T2", heartbeat.Get(tagTestReqID))
+}
+
+func TestResendRequest_GapFills(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+	c.expectAfter(newMessage(msgTestRequest).Set(tagTestReqID, "T1"), msgHeartbeat)
+
+	reset := c.expectAfter(newMessage(msgResendRequest).SetInt(tagBeginSeqNo, 1).SetInt(tagEndSeqNo, 0), msgSequenceReset)
+
+	assert.Equal(t, "1", reset.Get(tagMsgSeqNum))
+	assert.Equal(t, "Y", reset.Get(tagGapFillFlag))
+	assert.Equal(t, "Y", reset.Get(tagPossDupFlag))
+	assert.Equal(t, "3", reset.Get(tagNewSeqNo))
+}
+
+func TestUnsupportedMsgType(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+
+	reject := c.expectAfter(newMessage("AE"), msgReject)
+
+	assert.Equal(t, "2", reject.Get(tagRefSeqNum))
+	assert.Equal(t, sessionRejectInvalidMsgType, reject.Get(tagSessionRejectReason))
+}
+
+func TestNewOrderSingle_Lifecycle(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+
+	report := c.newOrder("ord-1", "AAPL", 10)
+	assert.Equal(t, execTypePendingNew, report.Get(tagExecType))
+	assert.Equal(t, ordStatusPendingNew, report.Get(tagOrdStatus))
+	assert.Equal(t, "NONE", report.Get(tagOrderID))
+	assert.Equal(t, "ord-1", report.Get(tagClOrdID))
+
+	gateway.trading.insert()
+	report = c.expect(msgExecutionReport)
+	assert.Equal(t, execTypeNew, report.Get(tagExecType))
+	assert.Equal(t, "1", report.Get(tagOrderID))
+	assert.Equal(t, "10", report.Get(tagLeavesQty))
+
+	gateway.trading.setStatus(1, domain.Completed)
+	report = c.expect(msgExecutionReport)
+	assert.Equal(t, execTypeTrade, report.Get(tagExecType))
+	assert.Equal(t, ordStatusFilled, report.Get(tagOrdStatus))
+	assert.Equal(t, "150.5", report.Get(tagLastPx))
+	assert.Equal(t, "10", report.Get(tagLastQty))
+	assert.Equal(t, "10", report.Get(tagCumQty))
+	assert.Equal(t, "0", report.Get(tagLeavesQty))
+
+	tx := gateway.trading.transactions[0]
+	require.NotNil(t, tx.ClientOrderID)
+	assert.Equal(t, "CLIENT/ord-1", *tx.ClientOrderID)
+}
+
+func TestNewOrderSingle_Rejected(t *testing.T) {
+	testCases := []struct {
+		name      string
+		symbol    string
+		ordType   string
+		createErr error
+		reason    string
+	}{
+		{name: "Unknown symbol", symbol: "ZZZZ", ordType: ordTypeMarket, reason: ordRejReasonUnknownSymbol},
+		{name: "Invalid symbol", symbol: "aapl", ordType: ordTypeMarket, reason: ordRejReasonOther},
+		{name: "Limit order", symbol: "AAPL", ordType: "2", reason: ordRejReasonUnsupportedOrder},
+		{name: "Market closed", symbol: "AAPL", ordType: ordTypeMarket, createErr: domain.ErrMarketClosed, reason: ordRejReasonExchangeClosed},
+		{name: "Internal error", symbol: "AAPL", ordType: ordTypeMarket, createErr: fmt.Errorf("connection refused"), reason: ordRejReasonOther},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := setupTest(t)
+			gateway.trading.createErr = tc.createErr
+			c, _ := gateway.logon(t, 1)
+
+			report := c.expectAfter(newMessage(msgNewOrderSingle).
+				Set(tagClOrdID, "ord-1").
+				Set(tagSymbol, tc.symbol).
+				Set(tagSide, sideBuy).
+				SetInt(tagOrderQty, 10).
+				Set(tagOrdType, tc.ordType), msgExecutionReport)
+
+			assert.Equal(t, execTypeRejected, report.Get(tagExecType))
+			assert.Equal(t, ordStatusRejected, report.Get(tagOrdStatus))
+			assert.Equal(t, tc.reason, report.Get(tagOrdRejReason))
+			assert.NotContains(t, report.Get(tagText), "connection refused")
+		})
+	}
+}
+
+func TestNewOrderSingle_DuplicateClOrdID(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+	c.newOrder("ord-1", "AAPL", 10)
+
+	report := c.newOrder("ord-1", "AAPL", 10)
+
+	assert.Equal(t, execTypeRejected, report.Get(tagExecType))
+	assert.Equal(t, ordRejReasonDuplicateOrder, report.Get(tagOrdRejReason))
+	assert.Len(t, gateway.trading.transactions, 1)
+}
+
+func TestNewOrderSingle_MissingTag(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+
+	reject := c.expectAfter(newMessage(msgNewOrderSingle).
+		Set(tagClOrdID, "ord-1").
+		Set(tagSide, sideBuy).
+		SetInt(tagOrderQty, 10).
+		Set(tagOrdType, ordTypeMarket), msgReject)
+
+	assert.Equal(t, sessionRejectRequiredTagMissing, reject.Get(tagSessionRejectReason))
+	assert.Equal(t, "55", reject.Get(tagRefTagID))
+}
+
+func TestOrderCancelRequest(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+	c.newOrder("ord-1", "AAPL", 10)
+	gateway.trading.insert()
+	c.expect(msgExecutionReport)
+
+	report := c.expectAfter(newMessage(msgOrderCancelRequest).
+		Set(tagClOrdID, "cxl-1").
+		Set(tagOrigClOrdID, "ord-1").
+		Set(tagSymbol, "AAPL").
+		Set(tagSide, sideBuy), msgExecutionReport)
+	assert.Equal(t, execTypePendingCancel, report.Get(tagExecType))
+	assert.Equal(t, "cxl-1", report.Get(tagClOrdID))
+	assert.Equal(t, "ord-1", report.Get(tagOrigClOrdID))
+
+	report = c.expect(msgExecutionReport)
+	assert.Equal(t, execTypeCanceled, report.Get(tagExecType))
+	assert.Equal(t, ordStatusCanceled, report.Get(tagOrdStatus))
+	assert.Equal(t, "cxl-1", report.Get(tagClOrdID))
+	assert.Equal(t, "ord-1", report.Get(tagOrigClOrdID))
+
+	reject := c.expectAfter(newMessage(msgOrderCancelRequest).
+		Set(tagClOrdID, "cxl-2").
+		Set(tagOrigClOrdID, "ord-1"), msgOrderCancelReject)
+	assert.Equal(t, cxlRejReasonTooLate, reject.Get(tagCxlRejReason))
+	assert.Equal(t, ordStatusCanceled, reject.Get(tagOrdStatus))
+}
+
+func TestOrderCancelRequest_Rejected(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+
+	reject := c.expectAfter(newMessage(msgOrderCancelRequest).
+		Set(tagClOrdID, "cxl-1").
+		Set(tagOrigClOrdID, "missing"), msgOrderCancelReject)
+	assert.Equal(t, cxlRejReasonUnknownOrder, reject.Get(tagCxlRejReason))
+	assert.Equal(t, cxlRejResponseToCancel, reject.Get(tagCxlRejResponseTo))
+
+	// Orders can only be cancelled once they have an ID.
+	c.newOrder("ord-1", "AAPL", 10)
+	reject = c.expectAfter(newMessage(msgOrderCancelRequest).
+		Set(tagClOrdID, "cxl-2").
+		Set(tagOrigClOrdID, "ord-1"), msgOrderCancelReject)
+	assert.Equal(t, cxlRejReasonOther, reject.Get(tagCxlRejReason))
+	assert.Equal(t, ordStatusPendingNew, reject.Get(tagOrdStatus))
+}
+
+func TestOrderCancelReplaceRequest(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+	c.newOrder("ord-1", "AAPL", 10)
+	gateway.trading.insert()
+	c.expect(msgExecutionReport)
+
+	report := c.expectAfter(newMessage(msgOrderCancelReplaceRequest).
+		Set(tagClOrdID, "ord-2").
+		Set(tagOrigClOrdID, "ord-1").
+		Set(tagSymbol, "AAPL").
+		Set(tagSide, sideBuy).
+		SetInt(tagOrderQty, 20).
+		Set(tagOrdType, ordTypeMarket), msgExecutionReport)
+	assert.Equal(t, execTypeReplaced, report.Get(tagExecType))
+	assert.Equal(t, ordStatusPendingNew, report.Get(tagOrdStatus))
+	assert.Equal(t, "ord-2", report.Get(tagClOrdID))
+	assert.Equal(t, "ord-1", report.Get(tagOrigClOrdID))
+	assert.Equal(t, "20", report.Get(tagOrderQty))
+
+	// The original's cancellation is not reported separately.
+	gateway.trading.insert()
+	report = c.expect(msgExecutionReport)
+	assert.Equal(t, execTypeNew, report.Get(tagExecType))
+	assert.Equal(t, "ord-2", report.Get(tagClOrdID))
+	assert.Equal(t, "2", report.Get(tagOrderID))
+
+	gateway.trading.mu.Lock()
+	defer gateway.trading.mu.Unlock()
+	assert.Equal(t, domain.Cancelled, gateway.trading.transactions[0].Status)
+}
+
+func TestOrderCancelReplaceRequest_ChangedSide(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+	c.newOrder("ord-1", "AAPL", 10)
+	gateway.trading.insert()
+	c.expect(msgExecutionReport)
+
+	reject := c.expectAfter(newMessage(msgOrderCancelReplaceRequest).
+		Set(tagClOrdID, "ord-2").
+		Set(tagOrigClOrdID, "ord-1").
+		Set(tagSymbol, "AAPL").
+		Set(tagSide, sideSell).
+		SetInt(tagOrderQty, 20).
+		Set(tagOrdType, ordTypeMarket), msgOrderCancelReject)
+
+	assert.Equal(t, cxlRejResponseToReplace, reject.Get(tagCxlRejResponseTo))
+	assert.Equal(t, ordStatusNew, reject.Get(tagOrdStatus))
+}
+
+// allowOrders limits the session to n orders.
+func allowOrders(n int) func(*Config) {
+	return func(cfg *Config) {
+		var mu sync.Mutex
+		cfg.RateLimit = func(ctx context.Context, compID string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if n == 0 {
+				return domain.NewRateLimitedError("rate_limited", "rate limit of 1 requests per 1m0s exceeded")
+			}
+			n--
+			return nil
+		}
+	}
+}
+
+func TestNewOrderSingle_RateLimited(t *testing.T) {
+	gateway := setupTest(t, allowOrders(1))
+	c, _ := gateway.logon(t, 1)
+	c.newOrder("ord-1", "AAPL", 10)
+
+	report := c.newOrder("ord-2", "AAPL", 10)
+
+	assert.Equal(t, execTypeRejected, report.Get(tagExecType))
+	assert.Equal(t, ordRejReasonOther, report.Get(tagOrdRejReason))
+	assert.Contains(t, report.Get(tagText), "rate limit")
+	assert.Len(t, gateway.trading.transactions, 1)
+}
+
+func TestOrderCancelReplaceRequest_RateLimited(t *testing.T) {
+	gateway := setupTest(t, allowOrders(1))
+	c, _ := gateway.logon(t, 1)
+	c.newOrder("ord-1", "AAPL", 10)
+	gateway.trading.insert()
+	c.expect(msgExecutionReport)
+
+	reject := c.expectAfter(newMessage(msgOrderCancelReplaceRequest).
+		Set(tagClOrdID, "ord-2").
+		Set(tagOrigClOrdID, "ord-1").
+		Set(tagSymbol, "AAPL").
+		Set(tagSide, sideBuy).
+		SetInt(tagOrderQty, 20).
+		Set(tagOrdType, ordTypeMarket), msgOrderCancelReject)
+
+	// The original is left working rather than cancelled.
+	assert.Equal(t, ordStatusNew, reject.Get(tagOrdStatus))
+	gateway.trading.mu.Lock()
+	defer gateway.trading.mu.Unlock()
+	assert.Equal(t, domain.Pending, gateway.trading.transactions[0].Status)
+}
+
+func TestOrders_RecoveredAfterReconnect(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+	c.newOrder("ord-1", "AAPL", 10)
+	c.expectAfter(newMessage(msgLogout), msgLogout)
+	c.expectClosed()
+
+	gateway.trading.insert()
+	c, _ = gateway.logon(t, c.seq)
+
+	// The pending order is picked up again, so it can still be cancelled.
+	report := c.expectAfter(newMessage(msgOrderCancelRequest).
+		Set(tagClOrdID, "cxl-1").
+		Set(tagOrigClOrdID, "ord-1"), msgExecutionReport)
+	assert.Equal(t, execTypePendingCancel, report.Get(tagExecType))
+	assert.Equal(t, "1", report.Get(tagOrderID))
+}
+
+func TestOrders_CaughtUpAfterFeedInterrupted(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+	c.newOrder("ord-1", "AAPL", 10)
+	gateway.trading.insert()
+	c.expect(msgExecutionReport)
+
+	// The fill has no event, so it is only found by reading the orders
+	// again once the feed is back.
+	gateway.trading.interrupt(func() {
+		gateway.trading.mu.Lock()
+		defer gateway.trading.mu.Unlock()
+		gateway.trading.transactions[0].Status = domain.Completed
+	})
+
+	report := c.expect(msgExecutionReport)
+	assert.Equal(t, execTypeTrade, report.Get(tagExecType))
+	assert.Equal(t, ordStatusFilled, report.Get(tagOrdStatus))
+}
+
+func TestOrders_RepeatedEventsIgnored(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+	c.newOrder("ord-1", "AAPL", 10)
+	gateway.trading.insert()
+	c.expect(msgExecutionReport)
+	gateway.trading.setStatus(1, domain.Completed)
+	c.expect(msgExecutionReport)
+
+	// A redelivered order.created event doesn't take the order back to
+	// new, so the next message is the reply to the TestRequest.
+	gateway.trading.mu.Lock()
+	created := gateway.trading.transactions[0]
+	created.Status = domain.Pending
+	gateway.trading.publish(domain.OrderCreated, created)
+	gateway.trading.mu.Unlock()
+	gateway.trading.setStatus(1, domain.Completed)
+
+	c.expectAfter(newMessage(msgTestRequest).Set(tagTestReqID, "check"), msgHeartbeat)
+}
+
+func TestClose_LogsOutSessions(t *testing.T) {
+	gateway := setupTest(t)
+	c, _ := gateway.logon(t, 1)
+
+	closed := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		closed <- gateway.acceptor.Close(ctx)
+	}()
+
+	logout := c.expect(msgLogout)
+	assert.Equal(t, "server shutting down", logout.Get(tagText))
+	c.send(newMessage(msgLogout))
+
+	assert.NoError(t, <-closed)
+}
@@ -0,0 +1,200 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	beginString = "FIX.4.4"
+	soh         = '\x01'
+	// maxBodyLength bounds the buffer allocated for an incoming message.
+	maxBodyLength = 64 << 10
+)
+
+// errGarbled marks a message whose framing was intact but whose checksum or
+// body did not parse. Such messages are ignored rather than ending the
+// session, as the FIX session protocol requires.
+var errGarbled = errors.New("garbled message")
+
+type field struct {
+	tag   int
+	value string
+}
+
+// Message is a FIX message as an ordered list of fields, excluding the
+// BeginString, BodyLength and CheckSum fields that frame it on the wire.
+type Message struct {
+	fields []field
+}
+
+func newMessage(msgType string) *Message {
+	return (&Message{}).Set(tagMsgType, msgType)
+}
+
+func (m *Message) MsgType() string {
+	return m.Get(tagMsgType)
+}
+
+// Get returns the value of the first field with tag, or "" if absent.
+func (m *Message) Get(tag int) string {
+	value, _ := m.Lookup(tag)
+	return value
+}
+
+func (m *Message) Lookup(tag int) (string, bool) {
+	for _, f := range m.fields {
+		if f.tag == tag {
+			return f.value, true
+		}
+	}
+	return "", false
+}
+
+func (m *Message) Int(tag int) (int, error) {
+	value, ok := m.Lookup(tag)
+	if !ok {
+		return 0, fmt.Errorf("tag %d missing", tag)
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("tag %d: invalid integer %q", tag, value)
+	}
+	return n, nil
+}
+
+// Set replaces the first field with tag or appends a new one.
+func (m *Message) Set(tag int, value string) *Message {
+	for i := range m.fields {
+		if m.fields[i].tag == tag {
+			m.fields[i].value = value
+			return m
+		}
+	}
+	m.fields = append(m.fields, field{tag: tag, value: value})
+	return m
+}
+
+func (m *Message) SetInt(tag int, value int) *Message {
+	return m.Set(tag, strconv.Itoa(value))
+}
+
+// Bytes frames the message for the wire. MsgType is written first, as the
+// protocol requires, followed by the other fields in the order they were set.
+func (m *Message) Bytes() []byte {
+	var body bytes.Buffer
+	writeField(&body, tagMsgType, m.MsgType())
+	for _, f := range m.fields {
+		if f.tag != tagMsgType {
+			writeField(&body, f.tag, f.value)
+		}
+	}
+
+	var out bytes.Buffer
+	writeField(&out, tagBeginString, beginString)
+	writeField(&out, tagBodyLength, strconv.Itoa(body.Len()))
+	out.Write(body.Bytes())
+	writeField(&out, tagCheckSum, fmt.Sprintf("%03d", checksum(out.Bytes())))
+	return out.Bytes()
+}
+
+// String renders the message with "|" in place of SOH, for logs.
+func (m *Message) String() string {
+	return strings.ReplaceAll(string(m.Bytes()), string(soh), "|")
+}
+
+// readMessage reads one message, checking its BeginString, BodyLength and
+// CheckSum. Framing errors are returned as is; a message that was read in
+// full but fails validation yields errGarbled.
+func readMessage(r *bufio.Reader) (*Message, error) {
+	var raw bytes.Buffer
+
+	tag, value, err := readField(r, &raw)
+	if err != nil {
+		return nil, err
+	}
+	if tag != tagBeginString || value != beginString {
+		return nil, fmt.Errorf("expected BeginString %s, got %d=%s", beginString, tag, value)
+	}
+
+	tag, value, err = readField(r, &raw)
+	if err != nil {
+		return nil, err
+	}
+	length, convErr := strconv.Atoi(value)
+	if tag != tagBodyLength || convErr != nil || length <= 0 || length > maxBodyLength {
+		return nil, fmt.Errorf("invalid BodyLength %d=%s", tag, value)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	raw.Write(body)
+	sum := checksum(raw.Bytes())
+
+	tag, value, err = readField(r, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	if tag != tagCheckSum || value != fmt.Sprintf("%03d", sum) {
+		return nil, fmt.Errorf("%w: checksum %s, expected %03d", errGarbled, value, sum)
+	}
+
+	return parseBody(body)
+}
+
+func parseBody(body []byte) (*Message, error) {
+	if len(body) == 0 || body[len(body)-1] != soh {
+		return nil, fmt.Errorf("%w: body not terminated by SOH", errGarbled)
+	}
+
+	m := &Message{}
+	for _, part := range bytes.Split(body[:len(body)-1], []byte{soh}) {
+		tagText, value, ok := bytes.Cut(part, []byte{'='})
+		tag, err := strconv.Atoi(string(tagText))
+		if !ok || err != nil || tag <= 0 {
+			return nil, fmt.Errorf("%w: malformed field %q", errGarbled, part)
+		}
+		m.fields = append(m.fields, field{tag: tag, value: string(value)})
+	}
+	if len(m.fields) == 0 || m.fields[0].tag != tagMsgType {
+		return nil, fmt.Errorf("%w: MsgType is not the first body field", errGarbled)
+	}
+	return m, nil
+}
+
+func readField(r *bufio.Reader, raw io.Writer) (int, string, error) {
+	text, err := r.ReadString(soh)
+	if err != nil {
+		return 0, "", err
+	}
+	_, _ = io.WriteString(raw, text)
+
+	tagText, value, ok := strings.Cut(strings.TrimSuffix(text, string(soh)), "=")
+	tag, convErr := strconv.Atoi(tagText)
+	if !ok || convErr != nil {
+		return 0, "", fmt.Errorf("malformed field %q", text)
+	}
+	return tag, value, nil
+}
+
+func writeField(buf *bytes.Buffer, tag int, value string) {
+	buf.WriteString(strconv.Itoa(tag))
+	buf.WriteByte('=')
+	buf.WriteString(value)
+	buf.WriteByte(soh)
+}
+
+func checksum(data []byte) int {
+	sum := 0
+	for _, b := range data {
+		sum += int(b)
+	}
+	return sum % 256
+}
@@ -0,0 +1,83 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessage_Bytes(t *testing.T) {
+	msg := newMessage(msgHeartbeat).
+		Set(tagSenderCompID, "MAXION").
+		Set(tagTargetCompID, "CLIENT").
+		SetInt(tagMsgSeqNum, 2)
+
+	assert.Equal(t, "8=FIX.4.4|9=30|35=0|49=MAXION|56=CLIENT|34=2|10=135|", msg.String())
+}
+
+func TestReadMessage_RoundTrip(t *testing.T) {
+	msg := newMessage(msgNewOrderSingle).
+		Set(tagClOrdID, "ord-1").
+		Set(tagSymbol, "AAPL").
+		Set(tagText, "a=b")
+
+	got, err := readMessage(bufio.NewReader(bytes.NewReader(msg.Bytes())))
+
+	require.NoError(t, err)
+	assert.Equal(t, msgNewOrderSingle, got.MsgType())
+	assert.Equal(t, "ord-1", got.Get(tagClOrdID))
+	assert.Equal(t, "a=b", got.Get(tagText))
+	assert.Equal(t, msg.String(), got.String())
+}
+
+func TestReadMessage_Invalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		raw     string
+		garbled bool
+	}{
+		{
+			name:    "Bad checksum",
+			raw:     strings.Replace(frame("35=0|34=1|"), "10=", "10=9", 1),
+			garbled: true,
+		},
+		{
+			name:    "MsgType not first",
+			raw:     frame("34=1|35=0|"),
+			garbled: true,
+		},
+		{
+			name: "Wrong BeginString",
+			raw:  strings.Replace(frame("35=0|34=1|"), "FIX.4.4", "FIX.4.2", 1),
+		},
+		{
+			name: "Oversized body",
+			raw:  "8=FIX.4.4|9=99999999|35=0|",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			raw := strings.ReplaceAll(tc.raw, "|", string(soh))
+
+			_, err := readMessage(bufio.NewReader(strings.NewReader(raw)))
+
+			require.Error(t, err)
+			assert.Equal(t, tc.garbled, errors.Is(err, errGarbled))
+		})
+	}
+}
+
+// frame wraps a "|"-separated body in a valid BeginString, BodyLength and
+// CheckSum.
+func frame(body string) string {
+	head := fmt.Sprintf("8=%s|9=%d|%s", beginString, len(body), body)
+	sum := checksum([]byte(strings.ReplaceAll(head, "|", string(soh))))
+	return fmt.Sprintf("%s10=%03d|", head, sum)
+}
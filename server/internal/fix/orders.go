@@ -0,0 +1,563 @@
+package fix
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/validation"
+)
+
+// newOrderRequest holds the NewOrderSingle fields checked by the same rules
+// as the REST and gRPC order entry.
+type newOrderRequest struct {
+	Symbol   string `json:"Symbol" validate:"required,max=10,printascii,uppercase"`
+	Quantity int    `json:"OrderQty" validate:"gt=0,lte=2147483647"`
+}
+
+// trackedOrder is an order entered through the session. Orders are created
+// asynchronously, so id stays zero until the transaction has been inserted
+// and its order.created event arrives.
+type trackedOrder struct {
+	clOrdID  string
+	id       int64
+	symbol   string
+	side     string
+	quantity int
+	price    float64
+	status   domain.TransactionStatus
+	// acked is set once the PendingNew has been sent; changes to the order
+	// are not reported before then.
+	acked bool
+	// early is the latest change that arrived before acked was set,
+	// reported once it is.
+	early *domain.Transaction
+	// cancelClOrdID is the ClOrdID of a pending cancel or replace.
+	cancelClOrdID string
+	// replacedBy is the ClOrdID of the order replacing this one, whose
+	// ExecutionReport stands in for this order's cancellation.
+	replacedBy string
+}
+
+func (o *trackedOrder) done() bool {
+	return o.status != domain.Pending
+}
+
+func (o *trackedOrder) ordStatus() string {
+	switch {
+	case o.status == domain.Completed:
+		return ordStatusFilled
+	case o.status == domain.Cancelled:
+		return ordStatusCanceled
+	case o.status == domain.Failed:
+		return ordStatusRejected
+	case o.cancelClOrdID != "":
+		return ordStatusPendingCancel
+	case o.id == 0:
+		return ordStatusPendingNew
+	default:
+		return ordStatusNew
+	}
+}
+
+func (o *trackedOrder) orderID() string {
+	if o.id == 0 {
+		return "NONE"
+	}
+	return strconv.FormatInt(o.id, 10)
+}
+
+func (s *session) clientOrderID(clOrdID string) string {
+	return s.targetCompID + "/" + clOrdID
+}
+
+// requireTags rejects msg unless it has every tag, returning false if so.
+func (s *session) requireTags(msg *Message, tags ...int) bool {
+	for _, tag := range tags {
+		if msg.Get(tag) == "" {
+			s.reject(msg, sessionRejectRequiredTagMissing, tag, "required tag missing")
+			return false
+		}
+	}
+	return true
+}
+
+func (s *session) onNewOrderSingle(ctx context.Context, msg *Message) {
+	if !s.requireTags(msg, tagClOrdID, tagSymbol, tagSide, tagOrderQty, tagOrdType) {
+		return
+	}
+	side := msg.Get(tagSide)
+	if side != sideBuy && side != sideSell {
+		s.reject(msg, sessionRejectValueIncorrect, tagSide, "unsupported Side "+side)
+		return
+	}
+	quantity, err := msg.Int(tagOrderQty)
+	if err != nil {
+		s.reject(msg, sessionRejectValueIncorrect, tagOrderQty, "OrderQty must be a whole number")
+		return
+	}
+
+	o := &trackedOrder{
+		clOrdID:  msg.Get(tagClOrdID),
+		symbol:   msg.Get(tagSymbol),
+		side:     side,
+		quantity: quantity,
+		status:   domain.Pending,
+	}
+
+	s.ordersMu.Lock()
+	_, duplicate := s.orders[o.clOrdID]
+	if !duplicate {
+		s.orders[o.clOrdID] = o
+	}
+	s.ordersMu.Unlock()
+
+	if duplicate {
+		s.rejectOrder(o, ordRejReasonDuplicateOrder, "duplicate ClOrdID")
+		return
+	}
+	if ordType := msg.Get(tagOrdType); ordType != ordTypeMarket {
+		s.failOrder(o, ordRejReasonUnsupportedOrder, "unsupported OrdType "+ordType)
+		return
+	}
+
+	if err := s.allowOrder(ctx); err != nil {
+		reason, text := rejectReason(err)
+		s.failOrder(o, reason, text)
+		return
+	}
+	if err := s.createOrder(ctx, o); err != nil {
+		reason, text := rejectReason(err)
+		s.failOrder(o, reason, text)
+		return
+	}
+
+	s.ordersMu.Lock()
+	defer s.ordersMu.Unlock()
+	s.acknowledge(o, s.executionReport(o, execTypePendingNew))
+}
+
+// acknowledge sends the first report of o and then any change that arrived
+// before it. The caller holds ordersMu.
+func (s *session) acknowledge(o *trackedOrder, report *Message) {
+	s.sendOrLog(report)
+	o.acked = true
+	if tx := o.early; tx != nil {
+		o.early = nil
+		s.applyOrder(*tx)
+	}
+}
+
+// allowOrder counts an order against the counterparty's rate limit.
+func (s *session) allowOrder(ctx context.Context) error {
+	if s.acceptor.cfg.RateLimit == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.acceptor.cfg.RequestTimeout)
+	defer cancel()
+	return s.acceptor.cfg.RateLimit(ctx, s.targetCompID)
+}
+
+// createOrder validates o and places it with the trading service.
+func (s *session) createOrder(ctx context.Context, o *trackedOrder) error {
+	if err := validation.Struct(newOrderRequest{Symbol: o.symbol, Quantity: o.quantity}); err != nil {
+		return err
+	}
+
+	txType := domain.Buy
+	if o.side == sideSell {
+		txType = domain.Sell
+	}
+	clientOrderID := s.clientOrderID(o.clOrdID)
+	tx := &domain.Transaction{
+		Symbol:        o.symbol,
+		Type:          txType,
+		Quantity:      o.quantity,
+		Status:        domain.Pending,
+		ClientOrderID: &clientOrderID,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.acceptor.cfg.RequestTimeout)
+	defer cancel()
+	if err := s.acceptor.trading.CreateTransaction(ctx, tx); err != nil {
+		return err
+	}
+
+	s.ordersMu.Lock()
+	o.id = tx.TransactionID
+	o.price = tx.Price
+	s.ordersMu.Unlock()
+	return nil
+}
+
+// failOrder marks a tracked order as rejected and reports it.
+func (s *session) failOrder(o *trackedOrder, reason string, text string) {
+	s.ordersMu.Lock()
+	o.status = domain.Failed
+	o.acked = true
+	s.ordersMu.Unlock()
+
+	s.rejectOrder(o, reason, text)
+}
+
+func (s *session) rejectOrder(o *trackedOrder, reason string, text string) {
+	report := s.executionReport(&trackedOrder{
+		clOrdID:  o.clOrdID,
+		symbol:   o.symbol,
+		side:     o.side,
+		quantity: o.quantity,
+		status:   domain.Failed,
+	}, execTypeRejected)
+	report.Set(tagOrdRejReason, reason).Set(tagText, text)
+	s.sendOrLog(report)
+}
+
+// rejectReason maps an order entry error to an OrdRejReason and a text that
+// is safe to send to the counterparty.
+func rejectReason(err error) (string, string) {
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) {
+		return ordRejReasonOther, "internal error"
+	}
+
+	text := domainErr.Message
+	if len(domainErr.Fields) > 0 {
+		fields := make([]string, 0, len(domainErr.Fields))
+		for _, field := range domainErr.Fields {
+			fields = append(fields, field.Field+" "+field.Message)
+		}
+		text = strings.Join(fields, "; ")
+	}
+
+	switch {
+	case errors.Is(err, domain.ErrMarketClosed):
+		return ordRejReasonExchangeClosed, text
+	case domainErr.Code == "stock_not_found":
+		return ordRejReasonUnknownSymbol, text
+	default:
+		return ordRejReasonOther, text
+	}
+}
+
+func (s *session) onOrderCancelRequest(ctx context.Context, msg *Message) {
+	if !s.requireTags(msg, tagClOrdID, tagOrigClOrdID) {
+		return
+	}
+	clOrdID := msg.Get(tagClOrdID)
+
+	o, ok := s.startCancel(msg, clOrdID, cxlRejResponseToCancel)
+	if !ok {
+		return
+	}
+	if err := s.cancelOrder(ctx, o); err != nil {
+		_, text := rejectReason(err)
+		s.cancelReject(o, clOrdID, cxlRejResponseToCancel, cxlRejReasonOther, text)
+		return
+	}
+
+	s.ordersMu.Lock()
+	defer s.ordersMu.Unlock()
+	report := s.executionReport(o, execTypePendingCancel)
+	report.Set(tagClOrdID, clOrdID).Set(tagOrigClOrdID, o.clOrdID)
+	s.sendOrLog(report)
+}
+
+func (s *session) onOrderCancelReplaceRequest(ctx context.Context, msg *Message) {
+	if !s.requireTags(msg, tagClOrdID, tagOrigClOrdID, tagSymbol, tagSide, tagOrderQty, tagOrdType) {
+		return
+	}
+	clOrdID := msg.Get(tagClOrdID)
+	quantity, err := msg.Int(tagOrderQty)
+	if err != nil {
+		s.reject(msg, sessionRejectValueIncorrect, tagOrderQty, "OrderQty must be a whole number")
+		return
+	}
+
+	s.ordersMu.Lock()
+	_, duplicate := s.orders[clOrdID]
+	s.ordersMu.Unlock()
+	if duplicate {
+		s.rejectCancel(msg, clOrdID, cxlRejResponseToReplace, cxlRejReasonOther, "duplicate ClOrdID")
+		return
+	}
+
+	o, ok := s.startCancel(msg, clOrdID, cxlRejResponseToReplace)
+	if !ok {
+		return
+	}
+	if o.symbol != msg.Get(tagSymbol) || o.side != msg.Get(tagSide) {
+		s.abortCancel(o)
+		s.cancelReject(o, clOrdID, cxlRejResponseToReplace, cxlRejReasonOther, "Symbol and Side cannot be replaced")
+		return
+	}
+	if ordType := msg.Get(tagOrdType); ordType != ordTypeMarket {
+		s.abortCancel(o)
+		s.cancelReject(o, clOrdID, cxlRejResponseToReplace, cxlRejReasonOther, "unsupported OrdType "+ordType)
+		return
+	}
+
+	// Orders cannot be amended in place, so the original is cancelled and a
+	// new order placed. Cancelling first means a failure can never leave
+	// both orders working. The rate limit is checked up front so that it
+	// cannot refuse the replacement once the original is gone.
+	if err := s.allowOrder(ctx); err != nil {
+		s.abortCancel(o)
+		_, text := rejectReason(err)
+		s.cancelReject(o, clOrdID, cxlRejResponseToReplace, cxlRejReasonOther, text)
+		return
+	}
+	s.ordersMu.Lock()
+	o.replacedBy = clOrdID
+	s.ordersMu.Unlock()
+	if err := s.cancelOrder(ctx, o); err != nil {
+		_, text := rejectReason(err)
+		s.cancelReject(o, clOrdID, cxlRejResponseToReplace, cxlRejReasonOther, text)
+		return
+	}
+
+	replacement := &trackedOrder{
+		clOrdID:  clOrdID,
+		symbol:   o.symbol,
+		side:     o.side,
+		quantity: quantity,
+		status:   domain.Pending,
+	}
+	s.ordersMu.Lock()
+	s.orders[clOrdID] = replacement
+	s.ordersMu.Unlock()
+
+	if err := s.createOrder(ctx, replacement); err != nil {
+		s.ordersMu.Lock()
+		replacement.status = domain.Failed
+		replacement.acked = true
+		o.replacedBy = ""
+		s.ordersMu.Unlock()
+
+		// The original stays cancelled and is reported as such when its
+		// change arrives, under the replace request's ClOrdID.
+		_, text := rejectReason(err)
+		s.cancelReject(o, clOrdID, cxlRejResponseToReplace, cxlRejReasonOther,
+			"original order cancelled but replacement rejected: "+text)
+		return
+	}
+
+	s.ordersMu.Lock()
+	defer s.ordersMu.Unlock()
+	report := s.executionReport(replacement, execTypeReplaced)
+	report.Set(tagOrigClOrdID, o.clOrdID)
+	s.acknowledge(replacement, report)
+}
+
+// startCancel finds the order named by OrigClOrdID and marks it as having a
+// cancel pending, or sends an OrderCancelReject and returns false.
+func (s *session) startCancel(msg *Message, clOrdID string, responseTo string) (*trackedOrder, bool) {
+	s.ordersMu.Lock()
+	o, ok := s.orders[msg.Get(tagOrigClOrdID)]
+	var reason, text string
+	switch {
+	case !ok:
+		reason, text = cxlRejReasonUnknownOrder, "unknown order"
+	case o.done():
+		reason, text = cxlRejReasonTooLate, "order is already "+strings.ToLower(o.status.String())
+	case o.id == 0:
+		reason, text = cxlRejReasonOther, "order not yet acknowledged"
+	case o.cancelClOrdID != "":
+		reason, text = cxlRejReasonAlreadyPending, "cancel already pending"
+	default:
+		o.cancelClOrdID = clOrdID
+	}
+	s.ordersMu.Unlock()
+
+	if reason == "" {
+		return o, true
+	}
+	if o == nil {
+		s.rejectCancel(msg, clOrdID, responseTo, reason, text)
+	} else {
+		s.cancelReject(o, clOrdID, responseTo, reason, text)
+	}
+	return nil, false
+}
+
+func (s *session) abortCancel(o *trackedOrder) {
+	s.ordersMu.Lock()
+	o.cancelClOrdID = ""
+	o.replacedBy = ""
+	s.ordersMu.Unlock()
+}
+
+func (s *session) cancelOrder(ctx context.Context, o *trackedOrder) error {
+	ctx, cancel := context.WithTimeout(ctx, s.acceptor.cfg.RequestTimeout)
+	defer cancel()
+
+	if err := s.acceptor.trading.UpdateTransactionStatus(ctx, o.id, domain.Cancelled); err != nil {
+		s.abortCancel(o)
+		return err
+	}
+	return nil
+}
+
+func (s *session) cancelReject(o *trackedOrder, clOrdID string, responseTo string, reason string, text string) {
+	s.ordersMu.Lock()
+	orderID, ordStatus := o.orderID(), o.ordStatus()
+	s.ordersMu.Unlock()
+
+	s.sendOrLog(newMessage(msgOrderCancelReject).
+		Set(tagOrderID, orderID).
+		Set(tagClOrdID, clOrdID).
+		Set(tagOrigClOrdID, o.clOrdID).
+		Set(tagOrdStatus, ordStatus).
+		Set(tagCxlRejResponseTo, responseTo).
+		Set(tagCxlRejReason, reason).
+		Set(tagText, text))
+}
+
+// rejectCancel rejects a cancel or replace of an order the session does not
+// know.
+func (s *session) rejectCancel(msg *Message, clOrdID string, responseTo string, reason string, text string) {
+	s.sendOrLog(newMessage(msgOrderCancelReject).
+		Set(tagOrderID, "NONE").
+		Set(tagClOrdID, clOrdID).
+		Set(tagOrigClOrdID, msg.Get(tagOrigClOrdID)).
+		Set(tagOrdStatus, ordStatusRejected).
+		Set(tagCxlRejResponseTo, responseTo).
+		Set(tagCxlRejReason, reason).
+		Set(tagText, text))
+}
+
+// followOrders subscribes to the order feed and then reads the orders once
+// to report the changes made before the subscription, so that none is
+// missed in between. Pending orders entered before a reconnect are picked
+// up again by their ClientOrderID.
+func (s *session) followOrders(ctx context.Context) (<-chan domain.OrderChange, func()) {
+	changes, unsubscribe := s.acceptor.orderFeed.SubscribeOrders()
+
+	ctx, cancel := context.WithTimeout(ctx, s.acceptor.cfg.RequestTimeout)
+	defer cancel()
+	transactions, err := s.acceptor.trading.GetAllTransactions(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Warn("failed to read fix orders", "error", err)
+		}
+		return changes, unsubscribe
+	}
+
+	s.ordersMu.Lock()
+	defer s.ordersMu.Unlock()
+	for _, tx := range transactions {
+		s.applyOrder(tx)
+	}
+	return changes, unsubscribe
+}
+
+func (s *session) onOrderChange(change domain.OrderChange) {
+	s.ordersMu.Lock()
+	defer s.ordersMu.Unlock()
+	s.applyOrder(change.Order.Transaction())
+}
+
+// applyOrder reports the change to tx if it is an order of this session.
+// The caller holds ordersMu.
+func (s *session) applyOrder(tx domain.Transaction) {
+	prefix := s.clientOrderID("")
+	if tx.ClientOrderID == nil || !strings.HasPrefix(*tx.ClientOrderID, prefix) || tx.TransactionID == 0 {
+		return
+	}
+	clOrdID := strings.TrimPrefix(*tx.ClientOrderID, prefix)
+
+	o, ok := s.orders[clOrdID]
+	if !ok {
+		s.orders[clOrdID] = &trackedOrder{
+			clOrdID:  clOrdID,
+			id:       tx.TransactionID,
+			symbol:   tx.Symbol,
+			side:     sideOf(tx.Type),
+			quantity: tx.Quantity,
+			price:    tx.Price,
+			status:   tx.Status,
+			acked:    true,
+		}
+		return
+	}
+	if !o.acked {
+		o.early = &tx
+		return
+	}
+	// Orders never leave a final status, so a change arriving after one is
+	// a repeated or stale event.
+	if o.done() {
+		return
+	}
+
+	if o.id == 0 {
+		o.id = tx.TransactionID
+		if tx.Status == domain.Pending {
+			s.sendOrLog(s.executionReport(o, execTypeNew))
+		}
+	}
+	if tx.Status == o.status {
+		return
+	}
+	o.status = tx.Status
+	o.price = tx.Price
+
+	switch tx.Status {
+	case domain.Completed:
+		s.sendOrLog(s.executionReport(o, execTypeTrade).
+			Set(tagLastPx, formatPrice(o.price)).
+			SetInt(tagLastQty, o.quantity))
+	case domain.Cancelled:
+		if o.replacedBy != "" {
+			return
+		}
+		report := s.executionReport(o, execTypeCanceled)
+		if o.cancelClOrdID != "" {
+			report.Set(tagClOrdID, o.cancelClOrdID).Set(tagOrigClOrdID, o.clOrdID)
+		}
+		s.sendOrLog(report)
+	case domain.Failed:
+		s.sendOrLog(s.executionReport(o, execTypeRejected).
+			Set(tagOrdRejReason, ordRejReasonOther))
+	}
+}
+
+// executionReport builds a report of o's current state. The caller holds
+// ordersMu.
+func (s *session) executionReport(o *trackedOrder, execType string) *Message {
+	leavesQty, cumQty, avgPx := o.quantity, 0, 0.0
+	if o.done() {
+		leavesQty = 0
+	}
+	if o.status == domain.Completed {
+		cumQty, avgPx = o.quantity, o.price
+	}
+
+	return newMessage(msgExecutionReport).
+		Set(tagOrderID, o.orderID()).
+		Set(tagClOrdID, o.clOrdID).
+		Set(tagExecID, uuid.NewString()).
+		Set(tagExecType, execType).
+		Set(tagOrdStatus, o.ordStatus()).
+		Set(tagSymbol, o.symbol).
+		Set(tagSide, o.side).
+		SetInt(tagOrderQty, o.quantity).
+		SetInt(tagLeavesQty, leavesQty).
+		SetInt(tagCumQty, cumQty).
+		Set(tagAvgPx, formatPrice(avgPx)).
+		Set(tagTransactTime, time.Now().UTC().Format(sendingTimeLayout))
+}
+
+func sideOf(txType domain.TransactionType) string {
+	if txType == domain.Sell {
+		return sideSell
+	}
+	return sideBuy
+}
+
+func formatPrice(price float64) string {
+	return strconv.FormatFloat(price, 'f', -1, 64)
+}
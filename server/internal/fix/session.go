@@ -0,0 +1,529 @@
+package fix
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/metrics"
+)
+
+const (
+	sendingTimeLayout = "20060102-15:04:05.000"
+
+	logonTimeout = 10 * time.Second
+	// logoutTimeout is how long to wait for the counterparty to confirm a
+	// Logout we sent before dropping the connection.
+	logoutTimeout = 2 * time.Second
+	writeTimeout  = 10 * time.Second
+)
+
+// session is one counterparty connection. The read loop handles incoming
+// messages while maintain sends heartbeats and order updates; sends from
+// both are serialised by mu.
+type session struct {
+	acceptor *Acceptor
+	conn     net.Conn
+	reader   *bufio.Reader
+	logger   *slog.Logger
+
+	// Set by logon and read-only afterwards.
+	id           string
+	targetCompID string
+	heartbeat    time.Duration
+
+	mu           sync.Mutex
+	state        *domain.FIXSession
+	lastSent     time.Time
+	lastReceived time.Time
+	testReqID    string
+	testReqSent  time.Time
+	// resendUpTo is the highest sequence number covered by our outstanding
+	// ResendRequest, or zero if there is none.
+	resendUpTo int
+	loggingOut bool
+
+	ordersMu sync.Mutex
+	orders   map[string]*trackedOrder
+}
+
+func newSession(acceptor *Acceptor, conn net.Conn) *session {
+	return &session{
+		acceptor: acceptor,
+		conn:     conn,
+		reader:   bufio.NewReader(conn),
+		logger:   acceptor.logger.With("remote_addr", conn.RemoteAddr().String()),
+		orders:   make(map[string]*trackedOrder),
+	}
+}
+
+func (s *session) run() {
+	defer s.acceptor.remove(s)
+	defer s.conn.Close()
+
+	if err := s.logon(); err != nil {
+		s.logger.Warn("fix logon failed", "error", err)
+		return
+	}
+	s.logger.Info("fix session logged on", "heartbeat", s.heartbeat)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Pick up orders entered before a reconnect before handling any
+	// cancels for them.
+	changes, unsubscribe := s.followOrders(ctx)
+	go s.maintain(ctx, changes, unsubscribe)
+
+	if err := s.readLoop(ctx); err != nil {
+		s.logger.Warn("fix session ended", "error", err)
+		return
+	}
+	s.logger.Info("fix session logged out")
+}
+
+// logon handles the first message, which must be a valid Logon. Invalid
+// logons are dropped without a reply, as the protocol requires.
+func (s *session) logon() error {
+	if err := s.conn.SetReadDeadline(time.Now().Add(logonTimeout)); err != nil {
+		return err
+	}
+	msg, err := s.read()
+	if err != nil {
+		return err
+	}
+	if msg.MsgType() != msgLogon {
+		return fmt.Errorf("first message was MsgType %s, not Logon", msg.MsgType())
+	}
+
+	target := msg.Get(tagSenderCompID)
+	if msg.Get(tagTargetCompID) != s.acceptor.cfg.SenderCompID || !s.acceptor.allowed(target) {
+		return fmt.Errorf("unknown session %s->%s", target, msg.Get(tagTargetCompID))
+	}
+	heartbeat, err := msg.Int(tagHeartBtInt)
+	if err != nil || heartbeat <= 0 {
+		return fmt.Errorf("invalid HeartBtInt %q", msg.Get(tagHeartBtInt))
+	}
+	seq, err := msg.Int(tagMsgSeqNum)
+	if err != nil {
+		return err
+	}
+
+	s.id = fmt.Sprintf("%s:%s->%s", beginString, s.acceptor.cfg.SenderCompID, target)
+	s.targetCompID = target
+	s.heartbeat = time.Duration(heartbeat) * time.Second
+	s.logger = s.logger.With("fix_session", s.id)
+
+	if !s.acceptor.register(s.id, s) {
+		s.id = ""
+		return fmt.Errorf("session %s->%s is already logged on", target, s.acceptor.cfg.SenderCompID)
+	}
+
+	state, err := s.loadState()
+	if err != nil {
+		return err
+	}
+	reset := msg.Get(tagResetSeqNumFlag) == "Y"
+	if reset {
+		state.NextSenderSeqNum = 1
+		state.NextTargetSeqNum = 1
+	}
+
+	s.mu.Lock()
+	s.state = state
+	s.lastReceived = time.Now()
+	s.mu.Unlock()
+
+	if seq < state.NextTargetSeqNum {
+		s.sendLogout(fmt.Sprintf("MsgSeqNum too low, expecting %d but received %d", state.NextTargetSeqNum, seq))
+		return fmt.Errorf("logon MsgSeqNum %d below expected %d", seq, state.NextTargetSeqNum)
+	}
+
+	reply := newMessage(msgLogon).
+		Set(tagEncryptMethod, "0").
+		SetInt(tagHeartBtInt, heartbeat)
+	if reset {
+		reply.Set(tagResetSeqNumFlag, "Y")
+	}
+	if err := s.send(reply); err != nil {
+		return err
+	}
+
+	if seq > state.NextTargetSeqNum {
+		return s.requestResend(seq)
+	}
+	s.advanceTarget(seq + 1)
+	return nil
+}
+
+func (s *session) loadState() (*domain.FIXSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.acceptor.cfg.RequestTimeout)
+	defer cancel()
+
+	state, err := s.acceptor.sessions.GetFIXSession(ctx, s.id)
+	if kind, _ := domain.ErrorKindOf(err); kind == domain.KindNotFound {
+		return &domain.FIXSession{SessionID: s.id, NextSenderSeqNum: 1, NextTargetSeqNum: 1}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session state: %w", err)
+	}
+	return state, nil
+}
+
+// saveLocked persists the sequence numbers. A failure is logged rather than
+// ending the session; the numbers are saved again with the next message.
+func (s *session) saveLocked() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.acceptor.cfg.RequestTimeout)
+	defer cancel()
+
+	s.state.UpdatedAt = time.Now().UTC()
+	saved := *s.state
+	if err := s.acceptor.sessions.SaveFIXSession(ctx, &saved); err != nil {
+		s.logger.Error("failed to persist fix sequence numbers", "error", err)
+	}
+}
+
+func (s *session) readLoop(ctx context.Context) error {
+	if err := s.conn.SetReadDeadline(time.Time{}); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := s.read()
+		if errors.Is(err, errGarbled) {
+			s.logger.Warn("ignoring garbled fix message", "error", err)
+			continue
+		}
+		if err != nil {
+			s.mu.Lock()
+			loggingOut := s.loggingOut
+			s.mu.Unlock()
+			if loggingOut && (errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed)) {
+				return nil
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		s.lastReceived = time.Now()
+		s.mu.Unlock()
+
+		if done := s.handle(ctx, msg); done {
+			return nil
+		}
+	}
+}
+
+// handle checks the sequence number of msg and dispatches it. It returns
+// true once the session is over.
+func (s *session) handle(ctx context.Context, msg *Message) bool {
+	seq, err := msg.Int(tagMsgSeqNum)
+	if err != nil {
+		s.sendLogout("MsgSeqNum missing")
+		return true
+	}
+
+	// A SequenceReset in reset mode applies regardless of its own MsgSeqNum.
+	if msg.MsgType() == msgSequenceReset && msg.Get(tagGapFillFlag) != "Y" {
+		s.applySequenceReset(msg)
+		return false
+	}
+
+	s.mu.Lock()
+	expected := s.state.NextTargetSeqNum
+	s.mu.Unlock()
+
+	switch {
+	case seq < expected:
+		if msg.Get(tagPossDupFlag) == "Y" {
+			return false
+		}
+		s.sendLogout(fmt.Sprintf("MsgSeqNum too low, expecting %d but received %d", expected, seq))
+		return true
+	case seq > expected:
+		if msg.MsgType() == msgLogout {
+			return s.onLogout()
+		}
+		// The message is dropped; the counterparty resends it along with
+		// the rest of the gap.
+		if err := s.requestResend(seq); err != nil {
+			s.logger.Warn("failed to send ResendRequest", "error", err)
+		}
+		return false
+	}
+	s.advanceTarget(seq + 1)
+
+	switch msg.MsgType() {
+	case msgHeartbeat:
+		s.onHeartbeat(msg)
+	case msgTestRequest:
+		s.sendOrLog(newMessage(msgHeartbeat).Set(tagTestReqID, msg.Get(tagTestReqID)))
+	case msgResendRequest:
+		s.onResendRequest(msg)
+	case msgSequenceReset:
+		s.applySequenceReset(msg)
+	case msgReject:
+		s.logger.Warn("fix message rejected by counterparty",
+			"ref_seq_num", msg.Get(tagRefSeqNum), "text", msg.Get(tagText))
+	case msgLogout:
+		return s.onLogout()
+	case msgNewOrderSingle:
+		s.onNewOrderSingle(ctx, msg)
+	case msgOrderCancelRequest:
+		s.onOrderCancelRequest(ctx, msg)
+	case msgOrderCancelReplaceRequest:
+		s.onOrderCancelReplaceRequest(ctx, msg)
+	default:
+		s.reject(msg, sessionRejectInvalidMsgType, 0, "unsupported MsgType "+msg.MsgType())
+	}
+	return false
+}
+
+func (s *session) advanceTarget(next int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if next <= s.state.NextTargetSeqNum {
+		return
+	}
+	s.state.NextTargetSeqNum = next
+	if s.resendUpTo != 0 && next > s.resendUpTo {
+		s.resendUpTo = 0
+	}
+	s.saveLocked()
+}
+
+// requestResend asks for everything from the expected sequence number on,
+// unless an earlier request already covers seq.
+func (s *session) requestResend(seq int) error {
+	s.mu.Lock()
+	if s.resendUpTo >= seq {
+		s.mu.Unlock()
+		return nil
+	}
+	s.resendUpTo = seq
+	expected := s.state.NextTargetSeqNum
+	s.mu.Unlock()
+
+	return s.send(newMessage(msgResendRequest).
+		SetInt(tagBeginSeqNo, expected).
+		SetInt(tagEndSeqNo, 0))
+}
+
+func (s *session) applySequenceReset(msg *Message) {
+	newSeqNo, err := msg.Int(tagNewSeqNo)
+	if err != nil {
+		s.reject(msg, sessionRejectRequiredTagMissing, tagNewSeqNo, "NewSeqNo missing")
+		return
+	}
+	s.advanceTarget(newSeqNo)
+}
+
+func (s *session) onHeartbeat(msg *Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.testReqID != "" && msg.Get(tagTestReqID) == s.testReqID {
+		s.testReqID = ""
+	}
+}
+
+// onResendRequest gap-fills the requested range. Sent messages are not
+// stored, so admin and application messages alike are skipped; order state
+// is recovered from ExecutionReports sent after the gap.
+func (s *session) onResendRequest(msg *Message) {
+	begin, err := msg.Int(tagBeginSeqNo)
+	if err != nil {
+		s.reject(msg, sessionRejectRequiredTagMissing, tagBeginSeqNo, "BeginSeqNo missing")
+		return
+	}
+
+	s.mu.Lock()
+	next := s.state.NextSenderSeqNum
+	s.mu.Unlock()
+	if begin >= next {
+		return
+	}
+
+	gapFill := newMessage(msgSequenceReset).
+		Set(tagPossDupFlag, "Y").
+		Set(tagGapFillFlag, "Y").
+		SetInt(tagNewSeqNo, next)
+	if err := s.write(gapFill, begin); err != nil {
+		s.logger.Warn("failed to send SequenceReset", "error", err)
+	}
+}
+
+// onLogout confirms a counterparty's Logout, or completes one we started.
+func (s *session) onLogout() bool {
+	s.mu.Lock()
+	loggingOut := s.loggingOut
+	s.loggingOut = true
+	s.mu.Unlock()
+
+	if !loggingOut {
+		s.sendOrLog(newMessage(msgLogout))
+	}
+	return true
+}
+
+// shutdown starts a Logout from our side. The read loop ends when the
+// counterparty confirms or the logout timeout passes.
+func (s *session) shutdown(reason string) {
+	s.mu.Lock()
+	loggedOn := s.state != nil && !s.loggingOut
+	s.mu.Unlock()
+
+	if !loggedOn {
+		s.conn.Close()
+		return
+	}
+	s.sendLogout(reason)
+}
+
+func (s *session) sendLogout(text string) {
+	s.mu.Lock()
+	s.loggingOut = true
+	s.mu.Unlock()
+
+	s.sendOrLog(newMessage(msgLogout).Set(tagText, text))
+	_ = s.conn.SetReadDeadline(time.Now().Add(logoutTimeout))
+}
+
+// reject sends a session-level Reject for msg. refTag is omitted when zero.
+func (s *session) reject(msg *Message, reason string, refTag int, text string) {
+	reject := newMessage(msgReject).
+		Set(tagRefSeqNum, msg.Get(tagMsgSeqNum)).
+		Set(tagRefMsgType, msg.MsgType()).
+		Set(tagSessionRejectReason, reason).
+		Set(tagText, text)
+	if refTag != 0 {
+		reject.SetInt(tagRefTagID, refTag)
+	}
+	s.sendOrLog(reject)
+}
+
+// maintain sends heartbeats and test requests and reports the order changes
+// from the feed until ctx is cancelled. If the feed stops delivering changes,
+// it follows the orders again after PollInterval.
+func (s *session) maintain(ctx context.Context, changes <-chan domain.OrderChange, unsubscribe func()) {
+	defer func() { unsubscribe() }()
+	heartbeats := time.NewTicker(s.heartbeat / 4)
+	defer heartbeats.Stop()
+
+	var resubscribe <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeats.C:
+			s.checkHeartbeat()
+		case change, ok := <-changes:
+			if !ok {
+				s.logger.Warn("fix order updates interrupted", "retry_in", s.acceptor.cfg.PollInterval)
+				unsubscribe()
+				changes = nil
+				resubscribe = time.After(s.acceptor.cfg.PollInterval)
+				continue
+			}
+			s.onOrderChange(change)
+		case <-resubscribe:
+			resubscribe = nil
+			changes, unsubscribe = s.followOrders(ctx)
+		}
+	}
+}
+
+// checkHeartbeat sends a Heartbeat when nothing else was sent within the
+// interval and a TestRequest when nothing was received. A TestRequest left
+// unanswered for another interval ends the session.
+func (s *session) checkHeartbeat() {
+	now := time.Now()
+
+	s.mu.Lock()
+	idleOut := now.Sub(s.lastSent) >= s.heartbeat
+	idleIn := now.Sub(s.lastReceived)
+	pending := s.testReqID != ""
+	testReqAge := now.Sub(s.testReqSent)
+	var testReqID string
+	if !pending && idleIn >= s.heartbeat+s.heartbeat/5 {
+		testReqID = now.UTC().Format(sendingTimeLayout)
+		s.testReqID = testReqID
+		s.testReqSent = now
+	}
+	s.mu.Unlock()
+
+	switch {
+	case pending && testReqAge >= s.heartbeat:
+		s.logger.Warn("fix counterparty stopped responding")
+		s.conn.Close()
+	case testReqID != "":
+		s.sendOrLog(newMessage(msgTestRequest).Set(tagTestReqID, testReqID))
+	case idleOut:
+		s.sendOrLog(newMessage(msgHeartbeat))
+	}
+}
+
+func (s *session) read() (*Message, error) {
+	msg, err := readMessage(s.reader)
+	if err != nil {
+		return nil, err
+	}
+	metrics.FIXMessages.WithLabelValues("in", msg.MsgType()).Inc()
+	return msg, nil
+}
+
+func (s *session) send(msg *Message) error {
+	return s.write(msg, 0)
+}
+
+func (s *session) sendOrLog(msg *Message) {
+	if err := s.send(msg); err != nil {
+		s.logger.Warn("failed to send fix message", "msg_type", msg.MsgType(), "error", err)
+	}
+}
+
+// write adds the standard header and sends msg with the next outgoing
+// sequence number, or with seq when it is non-zero, as gap fills are.
+func (s *session) write(msg *Message, seq int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	next := seq == 0
+	if next {
+		seq = s.state.NextSenderSeqNum
+	}
+
+	out := newMessage(msg.MsgType()).
+		Set(tagSenderCompID, s.acceptor.cfg.SenderCompID).
+		Set(tagTargetCompID, s.targetCompID).
+		SetInt(tagMsgSeqNum, seq).
+		Set(tagSendingTime, now.UTC().Format(sendingTimeLayout))
+	for _, f := range msg.fields {
+		if f.tag != tagMsgType {
+			out.Set(f.tag, f.value)
+		}
+	}
+
+	if err := s.conn.SetWriteDeadline(now.Add(writeTimeout)); err != nil {
+		return err
+	}
+	if _, err := s.conn.Write(out.Bytes()); err != nil {
+		return err
+	}
+	metrics.FIXMessages.WithLabelValues("out", msg.MsgType()).Inc()
+
+	s.lastSent = now
+	if next {
+		s.state.NextSenderSeqNum++
+		s.saveLocked()
+	}
+	return nil
+}
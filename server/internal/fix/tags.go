@@ -0,0 +1,103 @@
+package fix
+
+// Field tags used by the gateway.
+const (
+	tagAvgPx               = 6
+	tagBeginSeqNo          = 7
+	tagBeginString         = 8
+	tagBodyLength          = 9
+	tagCheckSum            = 10
+	tagClOrdID             = 11
+	tagCumQty              = 14
+	tagEndSeqNo            = 16
+	tagExecID              = 17
+	tagLastPx              = 31
+	tagLastQty             = 32
+	tagMsgSeqNum           = 34
+	tagMsgType             = 35
+	tagNewSeqNo            = 36
+	tagOrderID             = 37
+	tagOrderQty            = 38
+	tagOrdStatus           = 39
+	tagOrdType             = 40
+	tagOrigClOrdID         = 41
+	tagPossDupFlag         = 43
+	tagRefSeqNum           = 45
+	tagSenderCompID        = 49
+	tagSendingTime         = 52
+	tagSide                = 54
+	tagSymbol              = 55
+	tagTargetCompID        = 56
+	tagText                = 58
+	tagTransactTime        = 60
+	tagEncryptMethod       = 98
+	tagCxlRejReason        = 102
+	tagOrdRejReason        = 103
+	tagHeartBtInt          = 108
+	tagTestReqID           = 112
+	tagGapFillFlag         = 123
+	tagResetSeqNumFlag     = 141
+	tagExecType            = 150
+	tagLeavesQty           = 151
+	tagRefTagID            = 371
+	tagRefMsgType          = 372
+	tagSessionRejectReason = 373
+	tagCxlRejResponseTo    = 434
+)
+
+// Message types.
+const (
+	msgHeartbeat                 = "0"
+	msgTestRequest               = "1"
+	msgResendRequest             = "2"
+	msgReject                    = "3"
+	msgSequenceReset             = "4"
+	msgLogout                    = "5"
+	msgExecutionReport           = "8"
+	msgOrderCancelReject         = "9"
+	msgLogon                     = "A"
+	msgNewOrderSingle            = "D"
+	msgOrderCancelRequest        = "F"
+	msgOrderCancelReplaceRequest = "G"
+)
+
+// Enumerated field values.
+const (
+	sideBuy  = "1"
+	sideSell = "2"
+
+	ordTypeMarket = "1"
+
+	execTypeNew           = "0"
+	execTypeCanceled      = "4"
+	execTypeReplaced      = "5"
+	execTypePendingCancel = "6"
+	execTypeRejected      = "8"
+	execTypePendingNew    = "A"
+	execTypeTrade         = "F"
+
+	ordStatusNew           = "0"
+	ordStatusFilled        = "2"
+	ordStatusCanceled      = "4"
+	ordStatusPendingCancel = "6"
+	ordStatusRejected      = "8"
+	ordStatusPendingNew    = "A"
+
+	ordRejReasonUnknownSymbol    = "1"
+	ordRejReasonExchangeClosed   = "2"
+	ordRejReasonDuplicateOrder   = "6"
+	ordRejReasonUnsupportedOrder = "11"
+	ordRejReasonOther            = "99"
+
+	cxlRejReasonTooLate        = "0"
+	cxlRejReasonUnknownOrder   = "1"
+	cxlRejReasonAlreadyPending = "3"
+	cxlRejReasonOther          = "99"
+
+	cxlRejResponseToCancel  = "1"
+	cxlRejResponseToReplace = "2"
+
+	sessionRejectRequiredTagMissing = "1"
+	sessionRejectValueIncorrect     = "5"
+	sessionRejectInvalidMsgType     = "11"
+)
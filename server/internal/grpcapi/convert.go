@@ -0,0 +1,77 @@
+package grpcapi
+
+import (
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/grpcapi/maxionv1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The enum numbers in trading.proto match the domain constants, so values
+// convert directly; UNSPECIFIED (0) has no domain equivalent.
+
+func toProtoStock(stock domain.Stock) *maxionv1.Stock {
+	return &maxionv1.Stock{
+		Id:          stock.StockID,
+		Symbol:      stock.Symbol,
+		BidPrice:    stock.BidPrice,
+		BidVolume:   int32(stock.BidVolume),
+		AskPrice:    stock.AskPrice,
+		AskVolume:   int32(stock.AskVolume),
+		LastUpdated: timestamppb.New(stock.LastUpdated),
+	}
+}
+
+func toProtoOrder(tx domain.Transaction) *maxionv1.Order {
+	order := &maxionv1.Order{
+		Id:          tx.TransactionID,
+		Symbol:      tx.Symbol,
+		Side:        maxionv1.OrderSide(tx.Type),
+		Status:      maxionv1.OrderStatus(tx.Status),
+		Quantity:    int32(tx.Quantity),
+		Price:       tx.Price,
+		TotalAmount: tx.TotalAmount,
+		OrderTime:   timestamppb.New(tx.OrderTime),
+		Notes:       tx.Notes,
+	}
+	if tx.Status == domain.Completed {
+		order.Fill = &maxionv1.Fill{
+			Quantity:    int32(tx.Quantity),
+			Price:       tx.Price,
+			TotalAmount: tx.TotalAmount,
+		}
+		if tx.ExecutionTime != nil {
+			order.Fill.ExecutedAt = timestamppb.New(*tx.ExecutionTime)
+		}
+	}
+	return order
+}
+
+func toProtoAlert(alert domain.Alert) *maxionv1.Alert {
+	return &maxionv1.Alert{
+		Id:          alert.AlertID,
+		RuleId:      alert.RuleID,
+		Symbol:      alert.Symbol,
+		Type:        toProtoAlertRuleType(alert.Type),
+		Observed:    alert.Observed,
+		Message:     alert.Message,
+		TriggeredAt: timestamppb.New(alert.TriggeredAt),
+	}
+}
+
+// Alert rule types are strings in the domain, so they are mapped by name.
+func toProtoAlertRuleType(ruleType domain.AlertRuleType) maxionv1.AlertRuleType {
+	switch ruleType {
+	case domain.ThresholdRule:
+		return maxionv1.AlertRuleType_ALERT_RULE_TYPE_THRESHOLD
+	case domain.PercentMoveRule:
+		return maxionv1.AlertRuleType_ALERT_RULE_TYPE_PERCENT_MOVE
+	case domain.VolumeRule:
+		return maxionv1.AlertRuleType_ALERT_RULE_TYPE_VOLUME
+	default:
+		return maxionv1.AlertRuleType_ALERT_RULE_TYPE_UNSPECIFIED
+	}
+}
+
+func toDomainStatus(status maxionv1.OrderStatus) domain.TransactionStatus {
+	return domain.TransactionStatus(status)
+}
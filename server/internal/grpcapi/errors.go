@@ -0,0 +1,92 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// errorDomain identifies this service in ErrorInfo details.
+const errorDomain = "maxion"
+
+var kindCode = map[domain.ErrorKind]codes.Code{
+	domain.KindNotFound:    codes.NotFound,
+	domain.KindValidation:  codes.InvalidArgument,
+	domain.KindConflict:    codes.Aborted,
+	domain.KindRejected:    codes.FailedPrecondition,
+	domain.KindUnavailable: codes.Unavailable,
+	domain.KindRateLimited: codes.ResourceExhausted,
+}
+
+// toStatus is the gRPC equivalent of handlers.NewProblem. Domain errors keep
+// their code as ErrorInfo.Reason and their field errors as BadRequest
+// details; anything unrecognised becomes an opaque Internal error.
+func toStatus(err error) *status.Status {
+	if s, ok := status.FromError(err); ok {
+		return s
+	}
+
+	var domainErr *domain.Error
+	switch {
+	case errors.As(err, &domainErr):
+		code, ok := kindCode[domainErr.Kind]
+		if !ok {
+			code = codes.Internal
+		}
+		message := err.Error()
+		if domainErr.Kind == domain.KindUnavailable {
+			message = domainErr.Message
+		}
+		return withDetails(status.New(code, message), domainErr)
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.New(codes.DeadlineExceeded, "the request did not complete in time")
+	case errors.Is(err, context.Canceled):
+		return status.New(codes.Canceled, "the request was cancelled")
+	default:
+		return status.New(codes.Internal, "an unexpected error occurred")
+	}
+}
+
+func withDetails(s *status.Status, domainErr *domain.Error) *status.Status {
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: domainErr.Code, Domain: errorDomain}}
+	if len(domainErr.Fields) > 0 {
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(domainErr.Fields))
+		for _, field := range domainErr.Fields {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{
+				Field:       field.Field,
+				Description: field.Message,
+			})
+		}
+		details = append(details, &errdetails.BadRequest{FieldViolations: violations})
+	}
+
+	withDetails, err := s.WithDetails(details...)
+	if err != nil {
+		return s
+	}
+	return withDetails
+}
+
+// unaryErrors converts errors returned by handlers with toStatus. It runs
+// innermost so the logging, metrics and tracing interceptors see the final
+// code.
+func unaryErrors(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, toStatus(err).Err()
+	}
+	return resp, nil
+}
+
+func streamErrors(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := handler(srv, stream); err != nil {
+		return toStatus(err).Err()
+	}
+	return nil
+}
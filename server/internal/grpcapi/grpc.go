@@ -0,0 +1,59 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/grpcapi/maxionv1"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/metrics"
+	"github.com/touchsung/maxion-server/internal/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// Interceptors are run after the ones NewServer sets up, e.g. for rate
+// limiting.
+type Interceptors struct {
+	Unary  []grpc.UnaryServerInterceptor
+	Stream []grpc.StreamServerInterceptor
+}
+
+// NewServer returns a gRPC server for trading with the interceptors that
+// mirror the REST middleware, in the same order: tracing, metrics, request
+// IDs and access logging, error mapping and, for unary calls, the request
+// timeout, followed by extra. Server reflection, for tools such as grpcurl,
+// is registered if reflect is set.
+func NewServer(trading *TradingServer, requestTimeout time.Duration, reflect bool, extra Interceptors, logger *slog.Logger) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(append([]grpc.UnaryServerInterceptor{
+			tracing.UnaryServerInterceptor(),
+			metrics.UnaryServerInterceptor(),
+			logging.UnaryServerInterceptor(logger),
+			unaryErrors,
+			unaryTimeout(requestTimeout),
+		}, extra.Unary...)...),
+		grpc.ChainStreamInterceptor(append([]grpc.StreamServerInterceptor{
+			tracing.StreamServerInterceptor(),
+			metrics.StreamServerInterceptor(),
+			logging.StreamServerInterceptor(logger),
+			streamErrors,
+		}, extra.Stream...)...),
+	)
+	maxionv1.RegisterTradingServiceServer(server, trading)
+	if reflect {
+		reflection.Register(server)
+	}
+	return server
+}
+
+// unaryTimeout is the counterpart of the REST requestTimeout middleware.
+// Streams are long-lived and are not bounded.
+func unaryTimeout(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
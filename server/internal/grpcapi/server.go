@@ -0,0 +1,385 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/fanout"
+	"github.com/touchsung/maxion-server/internal/grpcapi/maxionv1"
+	"github.com/touchsung/maxion-server/internal/validation"
+)
+
+// placeOrderRequest carries the rules of the REST order entry DTOs, named
+// after the proto fields so that violations point at them.
+type placeOrderRequest struct {
+	Symbol   string  `json:"symbol" validate:"required,max=10,printascii,uppercase"`
+	Side     string  `json:"side" validate:"oneof=BUY SELL"`
+	Quantity int32   `json:"quantity" validate:"gt=0"`
+	Notes    *string `json:"notes,omitempty" validate:"omitempty,max=500"`
+}
+
+type updateOrderStatusRequest struct {
+	OrderID int64  `json:"order_id" validate:"gt=0"`
+	Status  string `json:"status" validate:"oneof=PENDING COMPLETED CANCELLED FAILED"`
+}
+
+// TradingServer implements maxionv1.TradingServiceServer on top of the
+// same TradingService and AlertService as the REST handlers. Streams share
+// one reader per kind of update rather than each reading the tables: quotes
+// are re-read once per change the quote watcher reports, order updates come
+// from the order feed and alerts are polled every streamInterval. Streams
+// send their response headers once subscribed, so a client that waits for
+// them misses no later update.
+type TradingServer struct {
+	maxionv1.UnimplementedTradingServiceServer
+
+	trading        ports.TradingService
+	alerts         ports.AlertService
+	quoteWatcher   ports.QuoteWatcher
+	orderFeed      ports.OrderFeed
+	streamInterval time.Duration
+	logger         *slog.Logger
+
+	quotes    *fanout.Hub[[]domain.Stock]
+	newAlerts *fanout.Hub[domain.Alert]
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewTradingServer(trading ports.TradingService, alerts ports.AlertService, quoteWatcher ports.QuoteWatcher, orderFeed ports.OrderFeed, streamInterval time.Duration, logger *slog.Logger) *TradingServer {
+	return &TradingServer{
+		trading:        trading,
+		alerts:         alerts,
+		quoteWatcher:   quoteWatcher,
+		orderFeed:      orderFeed,
+		streamInterval: streamInterval,
+		logger:         logger,
+		quotes:         fanout.NewHub[[]domain.Stock](),
+		newAlerts:      fanout.NewHub[domain.Alert](),
+		done:           make(chan struct{}),
+	}
+}
+
+// Start runs the shared quote and alert readers until ctx is done or Close
+// is called.
+func (s *TradingServer) Start(ctx context.Context) {
+	go s.readQuotes(ctx)
+	go s.pollAlerts(ctx)
+}
+
+// Close ends all open streams, which would otherwise hold a graceful
+// shutdown open indefinitely.
+func (s *TradingServer) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+func (s *TradingServer) ListStocks(ctx context.Context, _ *maxionv1.ListStocksRequest) (*maxionv1.ListStocksResponse, error) {
+	stocks, err := s.trading.GetAllStocks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &maxionv1.ListStocksResponse{Stocks: make([]*maxionv1.Stock, 0, len(stocks))}
+	for _, stock := range stocks {
+		resp.Stocks = append(resp.Stocks, toProtoStock(stock))
+	}
+	return resp, nil
+}
+
+func (s *TradingServer) ListOrders(ctx context.Context, _ *maxionv1.ListOrdersRequest) (*maxionv1.ListOrdersResponse, error) {
+	transactions, err := s.trading.GetAllTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &maxionv1.ListOrdersResponse{Orders: make([]*maxionv1.Order, 0, len(transactions))}
+	for _, tx := range transactions {
+		resp.Orders = append(resp.Orders, toProtoOrder(tx))
+	}
+	return resp, nil
+}
+
+func (s *TradingServer) PlaceOrder(ctx context.Context, req *maxionv1.PlaceOrderRequest) (*maxionv1.Order, error) {
+	side := domain.TransactionType(req.GetSide())
+	if err := validation.Struct(placeOrderRequest{
+		Symbol:   req.GetSymbol(),
+		Side:     side.String(),
+		Quantity: req.GetQuantity(),
+		Notes:    req.Notes,
+	}); err != nil {
+		return nil, err
+	}
+
+	tx := &domain.Transaction{
+		Symbol:   req.GetSymbol(),
+		Type:     side,
+		Quantity: int(req.GetQuantity()),
+		Notes:    req.Notes,
+		Status:   domain.Pending,
+	}
+	if err := s.trading.CreateTransaction(ctx, tx); err != nil {
+		return nil, err
+	}
+	return toProtoOrder(*tx), nil
+}
+
+func (s *TradingServer) UpdateOrderStatus(ctx context.Context, req *maxionv1.UpdateOrderStatusRequest) (*maxionv1.UpdateOrderStatusResponse, error) {
+	status := toDomainStatus(req.GetStatus())
+	if err := validation.Struct(updateOrderStatusRequest{
+		OrderID: req.GetOrderId(),
+		Status:  status.String(),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := s.trading.UpdateTransactionStatus(ctx, req.GetOrderId(), status); err != nil {
+		return nil, err
+	}
+	return &maxionv1.UpdateOrderStatusResponse{}, nil
+}
+
+func (s *TradingServer) StreamQuotes(req *maxionv1.StreamQuotesRequest, stream maxionv1.TradingService_StreamQuotesServer) error {
+	symbols := make(map[string]bool, len(req.GetSymbols()))
+	for _, symbol := range req.GetSymbols() {
+		symbols[symbol] = true
+	}
+	sent := make(map[string]domain.Stock)
+	send := func(stocks []domain.Stock) error {
+		for _, stock := range stocks {
+			if len(symbols) > 0 && !symbols[stock.Symbol] {
+				continue
+			}
+			if last, ok := sent[stock.Symbol]; ok && sameQuote(last, stock) {
+				continue
+			}
+			if err := stream.Send(toProtoStock(stock)); err != nil {
+				return err
+			}
+			sent[stock.Symbol] = stock
+		}
+		return nil
+	}
+
+	// Subscribe before reading the current quotes, so that no change in
+	// between is missed.
+	quotes, unsubscribe := s.quotes.Subscribe()
+	defer unsubscribe()
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+	ctx := stream.Context()
+	stocks, err := s.trading.GetAllStocks(ctx)
+	if err != nil {
+		return err
+	}
+	if err := send(stocks); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.done:
+			return nil
+		case stocks, ok := <-quotes:
+			if !ok {
+				return s.streamEnded()
+			}
+			if err := send(stocks); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *TradingServer) StreamOrderUpdates(req *maxionv1.StreamOrderUpdatesRequest, stream maxionv1.TradingService_StreamOrderUpdatesServer) error {
+	ids := make(map[int64]bool, len(req.GetOrderIds()))
+	for _, id := range req.GetOrderIds() {
+		ids[id] = true
+	}
+
+	changes, unsubscribe := s.orderFeed.SubscribeOrders()
+	defer unsubscribe()
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.done:
+			return nil
+		case change, ok := <-changes:
+			if !ok {
+				return s.streamEnded()
+			}
+			if len(ids) > 0 && !ids[change.Order.TransactionID] {
+				continue
+			}
+			// Newly placed orders have no previous status.
+			var previous maxionv1.OrderStatus
+			if status, ok := domain.ParseTransactionStatus(change.Order.PreviousStatus); ok {
+				previous = maxionv1.OrderStatus(status)
+			}
+			if err := stream.Send(&maxionv1.OrderUpdate{
+				Order:          toProtoOrder(change.Order.Transaction()),
+				PreviousStatus: previous,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *TradingServer) StreamAlerts(req *maxionv1.StreamAlertsRequest, stream maxionv1.TradingService_StreamAlertsServer) error {
+	ruleIDs := make(map[int64]bool, len(req.GetRuleIds()))
+	for _, id := range req.GetRuleIds() {
+		ruleIDs[id] = true
+	}
+	symbols := make(map[string]bool, len(req.GetSymbols()))
+	for _, symbol := range req.GetSymbols() {
+		symbols[symbol] = true
+	}
+
+	alerts, unsubscribe := s.newAlerts.Subscribe()
+	defer unsubscribe()
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.done:
+			return nil
+		case alert, ok := <-alerts:
+			if !ok {
+				return s.streamEnded()
+			}
+			if len(ruleIDs) > 0 && !ruleIDs[alert.RuleID] {
+				continue
+			}
+			if len(symbols) > 0 && !symbols[alert.Symbol] {
+				continue
+			}
+			if err := stream.Send(toProtoAlert(alert)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// streamEnded is the result of a stream whose updates stopped, either
+// because the server is closing or because the stream fell too far behind
+// and was dropped; the client then has to reconnect.
+func (s *TradingServer) streamEnded() error {
+	select {
+	case <-s.done:
+		return nil
+	default:
+		return domain.NewUnavailableError("stream_interrupted", "updates were interrupted, reconnect to resume", nil)
+	}
+}
+
+// readQuotes reads the quotes once for every change the quote watcher
+// reports and hands them to the quote streams. Failed reads are retried
+// every streamInterval.
+func (s *TradingServer) readQuotes(ctx context.Context) {
+	defer s.quotes.Close()
+
+	changed := make(chan struct{}, 1)
+	markChanged := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	stop := s.quoteWatcher.WatchQuotes(func([]string) { markChanged() })
+	defer stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.done:
+			return
+		case <-changed:
+		}
+
+		stocks, err := s.trading.GetAllStocks(ctx)
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to read quotes", "error", err)
+			time.AfterFunc(s.streamInterval, markChanged)
+			continue
+		}
+		s.quotes.Publish(stocks)
+	}
+}
+
+// pollAlerts reads the alerts fired since the last poll every
+// streamInterval and hands them to the alert streams. The first poll only
+// finds the latest alert, so that earlier ones are not replayed.
+func (s *TradingServer) pollAlerts(ctx context.Context) {
+	defer s.newAlerts.Close()
+
+	var lastID *int64
+	s.poll(ctx, func(ctx context.Context) {
+		if lastID == nil {
+			latest, err := s.alerts.GetAlerts(ctx, 0)
+			if err != nil {
+				s.logger.WarnContext(ctx, "failed to poll alerts", "error", err)
+				return
+			}
+			lastID = new(int64)
+			if len(latest) > 0 {
+				*lastID = latest[0].AlertID
+			}
+			return
+		}
+
+		alerts, err := s.alerts.GetAlertsAfter(ctx, *lastID)
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to poll alerts", "error", err)
+			return
+		}
+		for _, alert := range alerts {
+			*lastID = alert.AlertID
+			s.newAlerts.Publish(alert)
+		}
+	})
+}
+
+// poll calls fn straight away and then every streamInterval, until ctx is
+// done or the server is closed.
+func (s *TradingServer) poll(ctx context.Context, fn func(context.Context)) {
+	ticker := time.NewTicker(s.streamInterval)
+	defer ticker.Stop()
+
+	for {
+		fn(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func sameQuote(a domain.Stock, b domain.Stock) bool {
+	return a.BidPrice == b.BidPrice &&
+		a.BidVolume == b.BidVolume &&
+		a.AskPrice == b.AskPrice &&
+		a.AskVolume == b.AskVolume &&
+		a.LastUpdated.Equal(b.LastUpdated)
+}
@@ -0,0 +1,465 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/fanout"
+	"github.com/touchsung/maxion-server/internal/grpcapi/maxionv1"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// MockTradingService implements ports.TradingService for testing
+type MockTradingService struct {
+	mock.Mock
+}
+
+func (m *MockTradingService) GetAllStocks(ctx context.Context) ([]domain.Stock, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.Stock), args.Error(1)
+}
+
+func (m *MockTradingService) GetAllTransactions(ctx context.Context) ([]domain.Transaction, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.Transaction), args.Error(1)
+}
+
+func (m *MockTradingService) CreateTransaction(ctx context.Context, tx *domain.Transaction) error {
+	args := m.Called(ctx, tx)
+	return args.Error(0)
+}
+
+func (m *MockTradingService) CreateOrderGroup(ctx context.Context, group *domain.OrderGroup) error {
+	args := m.Called(ctx, group)
+	return args.Error(0)
+}
+
+func (m *MockTradingService) UpdateTransactionStatus(ctx context.Context, id int64, status domain.TransactionStatus) error {
+	args := m.Called(ctx, id, status)
+	return args.Error(0)
+}
+
+// MockAlertService implements ports.AlertService for testing
+type MockAlertService struct {
+	mock.Mock
+}
+
+func (m *MockAlertService) CreateRule(ctx context.Context, rule *domain.AlertRule) error {
+	args := m.Called(ctx, rule)
+	return args.Error(0)
+}
+
+func (m *MockAlertService) GetRules(ctx context.Context) ([]domain.AlertRule, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.AlertRule), args.Error(1)
+}
+
+func (m *MockAlertService) GetRule(ctx context.Context, id int64) (*domain.AlertRule, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AlertRule), args.Error(1)
+}
+
+func (m *MockAlertService) DeleteRule(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockAlertService) GetAlerts(ctx context.Context, ruleID int64) ([]domain.Alert, error) {
+	args := m.Called(ctx, ruleID)
+	return args.Get(0).([]domain.Alert), args.Error(1)
+}
+
+func (m *MockAlertService) GetAlertsAfter(ctx context.Context, afterID int64) ([]domain.Alert, error) {
+	args := m.Called(ctx, afterID)
+	return args.Get(0).([]domain.Alert), args.Error(1)
+}
+
+// fakeQuoteWatcher reports the changes passed to change.
+type fakeQuoteWatcher struct {
+	mu       sync.Mutex
+	onChange func(symbols []string)
+}
+
+func (w *fakeQuoteWatcher) WatchQuotes(onChange func(symbols []string)) func() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onChange = onChange
+	return func() {}
+}
+
+func (w *fakeQuoteWatcher) change(symbols ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onChange(symbols)
+}
+
+// fakeOrderFeed hands out the changes published to it.
+type fakeOrderFeed struct {
+	*fanout.Hub[domain.OrderChange]
+}
+
+func (f fakeOrderFeed) SubscribeOrders() (<-chan domain.OrderChange, func()) {
+	return f.Subscribe()
+}
+
+const testStreamInterval = 10 * time.Millisecond
+
+type testServer struct {
+	client  maxionv1.TradingServiceClient
+	trading *TradingServer
+	quotes  *fakeQuoteWatcher
+	orders  fakeOrderFeed
+}
+
+func setupTest(t *testing.T) (maxionv1.TradingServiceClient, *MockTradingService, *TradingServer) {
+	mockService := new(MockTradingService)
+	server := serve(t, mockService, new(MockAlertService))
+	return server.client, mockService, server.trading
+}
+
+func setupAlertTest(t *testing.T) (maxionv1.TradingServiceClient, *MockAlertService) {
+	mockService := new(MockAlertService)
+	server := serve(t, new(MockTradingService), mockService)
+	return server.client, mockService
+}
+
+func serve(t *testing.T, tradingService *MockTradingService, alertService *MockAlertService) *testServer {
+	quotes := &fakeQuoteWatcher{}
+	orders := fakeOrderFeed{fanout.NewHub[domain.OrderChange]()}
+	trading := NewTradingServer(tradingService, alertService, quotes, orders, testStreamInterval, logging.Discard())
+	server := NewServer(trading, time.Second, false, Interceptors{}, logging.Discard())
+
+	lis := bufconn.Listen(1 << 20)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		trading.Close()
+		server.Stop()
+	})
+	return &testServer{client: maxionv1.NewTradingServiceClient(conn), trading: trading, quotes: quotes, orders: orders}
+}
+
+func TestNewServer_Reflection(t *testing.T) {
+	trading := NewTradingServer(new(MockTradingService), new(MockAlertService), &fakeQuoteWatcher{}, fakeOrderFeed{fanout.NewHub[domain.OrderChange]()}, testStreamInterval, logging.Discard())
+
+	for _, reflect := range []bool{false, true} {
+		server := NewServer(trading, time.Second, reflect, Interceptors{}, logging.Discard())
+		_, registered := server.GetServiceInfo()["grpc.reflection.v1.ServerReflection"]
+		assert.Equal(t, reflect, registered)
+	}
+}
+
+func TestListStocks(t *testing.T) {
+	client, mockService, _ := setupTest(t)
+	fixedTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mockService.On("GetAllStocks", mock.Anything).Return([]domain.Stock{
+		{StockID: 1, Symbol: "AAPL", BidPrice: 150.00, BidVolume: 1000, AskPrice: 150.50, AskVolume: 800, LastUpdated: fixedTime},
+	}, nil)
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-1")
+	resp, err := client.ListStocks(ctx, &maxionv1.ListStocksRequest{}, grpc.Header(&header))
+
+	require.NoError(t, err)
+	require.Len(t, resp.Stocks, 1)
+	assert.Equal(t, "AAPL", resp.Stocks[0].Symbol)
+	assert.Equal(t, 150.50, resp.Stocks[0].AskPrice)
+	assert.True(t, resp.Stocks[0].LastUpdated.AsTime().Equal(fixedTime))
+	assert.Equal(t, []string{"req-1"}, header.Get("x-request-id"))
+}
+
+func TestListOrders_Fill(t *testing.T) {
+	client, mockService, _ := setupTest(t)
+	executed := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	mockService.On("GetAllTransactions", mock.Anything).Return([]domain.Transaction{
+		{TransactionID: 1, Symbol: "AAPL", Type: domain.Buy, Status: domain.Pending, Quantity: 10, Price: 150.50},
+		{TransactionID: 2, Symbol: "AAPL", Type: domain.Sell, Status: domain.Completed, Quantity: 10, Price: 150.00, TotalAmount: 1500.00, ExecutionTime: &executed},
+	}, nil)
+
+	resp, err := client.ListOrders(context.Background(), &maxionv1.ListOrdersRequest{})
+
+	require.NoError(t, err)
+	require.Len(t, resp.Orders, 2)
+	assert.Equal(t, maxionv1.OrderSide_ORDER_SIDE_BUY, resp.Orders[0].Side)
+	assert.Equal(t, maxionv1.OrderStatus_ORDER_STATUS_PENDING, resp.Orders[0].Status)
+	assert.Nil(t, resp.Orders[0].Fill)
+
+	fill := resp.Orders[1].Fill
+	require.NotNil(t, fill)
+	assert.Equal(t, 1500.00, fill.TotalAmount)
+	assert.True(t, fill.ExecutedAt.AsTime().Equal(executed))
+}
+
+func TestPlaceOrder(t *testing.T) {
+	client, mockService, _ := setupTest(t)
+
+	testCases := []struct {
+		name           string
+		request        *maxionv1.PlaceOrderRequest
+		serviceErr     error
+		expectedCode   codes.Code
+		expectedReason string
+		expectedFields []string
+	}{
+		{
+			name:         "Valid Order",
+			request:      &maxionv1.PlaceOrderRequest{Symbol: "AAPL", Side: maxionv1.OrderSide_ORDER_SIDE_SELL, Quantity: 100},
+			expectedCode: codes.OK,
+		},
+		{
+			name:           "Invalid Order",
+			request:        &maxionv1.PlaceOrderRequest{Symbol: "aapl"},
+			expectedCode:   codes.InvalidArgument,
+			expectedReason: "validation_failed",
+			expectedFields: []string{"quantity", "side", "symbol"},
+		},
+		{
+			name:           "Market Closed",
+			request:        &maxionv1.PlaceOrderRequest{Symbol: "AAPL", Side: maxionv1.OrderSide_ORDER_SIDE_BUY, Quantity: 100},
+			serviceErr:     fmt.Errorf("%w: %s", domain.ErrMarketClosed, "non-trading day"),
+			expectedCode:   codes.FailedPrecondition,
+			expectedReason: "market_closed",
+		},
+		{
+			name:         "Unexpected Error",
+			request:      &maxionv1.PlaceOrderRequest{Symbol: "AAPL", Side: maxionv1.OrderSide_ORDER_SIDE_BUY, Quantity: 100},
+			serviceErr:   errors.New("connection reset by peer"),
+			expectedCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.expectedFields) == 0 {
+				mockService.On("CreateTransaction", mock.Anything, mock.MatchedBy(func(tx *domain.Transaction) bool {
+					return tx.Type == domain.TransactionType(tc.request.Side) && tx.Status == domain.Pending
+				})).Return(tc.serviceErr).Once()
+			}
+
+			order, err := client.PlaceOrder(context.Background(), tc.request)
+
+			st := status.Convert(err)
+			assert.Equal(t, tc.expectedCode, st.Code())
+			if tc.expectedCode == codes.OK {
+				assert.Equal(t, tc.request.Side, order.Side)
+				assert.Equal(t, maxionv1.OrderStatus_ORDER_STATUS_PENDING, order.Status)
+				return
+			}
+			assert.NotContains(t, st.Message(), "connection reset")
+
+			var reason string
+			var fields []string
+			for _, detail := range st.Details() {
+				switch d := detail.(type) {
+				case *errdetails.ErrorInfo:
+					reason = d.Reason
+				case *errdetails.BadRequest:
+					for _, violation := range d.FieldViolations {
+						fields = append(fields, violation.Field)
+					}
+				}
+			}
+			assert.Equal(t, tc.expectedReason, reason)
+			assert.ElementsMatch(t, tc.expectedFields, fields)
+		})
+	}
+}
+
+func TestUpdateOrderStatus(t *testing.T) {
+	client, mockService, _ := setupTest(t)
+
+	mockService.On("UpdateTransactionStatus", mock.Anything, int64(7), domain.Cancelled).Return(nil).Once()
+
+	_, err := client.UpdateOrderStatus(context.Background(), &maxionv1.UpdateOrderStatusRequest{
+		OrderId: 7,
+		Status:  maxionv1.OrderStatus_ORDER_STATUS_CANCELLED,
+	})
+	assert.NoError(t, err)
+
+	_, err = client.UpdateOrderStatus(context.Background(), &maxionv1.UpdateOrderStatusRequest{OrderId: 7})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	mockService.AssertExpectations(t)
+}
+
+func TestStreamQuotes(t *testing.T) {
+	mockService := new(MockTradingService)
+	alertService := new(MockAlertService)
+	alertService.On("GetAlerts", mock.Anything, int64(0)).Return([]domain.Alert(nil), nil)
+	alertService.On("GetAlertsAfter", mock.Anything, int64(0)).Return([]domain.Alert(nil), nil)
+	server := serve(t, mockService, alertService)
+	server.trading.Start(context.Background())
+	fixedTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	aapl := domain.Stock{StockID: 1, Symbol: "AAPL", BidPrice: 150.00, AskPrice: 150.50, LastUpdated: fixedTime}
+	msft := domain.Stock{StockID: 2, Symbol: "MSFT", BidPrice: 400.00, AskPrice: 400.50, LastUpdated: fixedTime}
+	moved := aapl
+	moved.BidPrice = 151.00
+	moved.LastUpdated = fixedTime.Add(time.Second)
+	movedMSFT := msft
+	movedMSFT.BidPrice = 401.00
+
+	mockService.On("GetAllStocks", mock.Anything).Return([]domain.Stock{aapl, msft}, nil).Once()
+	read := make(chan struct{})
+	mockService.On("GetAllStocks", mock.Anything).Return([]domain.Stock{aapl, movedMSFT}, nil).Run(func(mock.Arguments) {
+		close(read)
+	}).Once()
+	mockService.On("GetAllStocks", mock.Anything).Return([]domain.Stock{moved, movedMSFT}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := server.client.StreamQuotes(ctx, &maxionv1.StreamQuotesRequest{Symbols: []string{"AAPL"}})
+	require.NoError(t, err)
+
+	first, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, 150.00, first.BidPrice)
+
+	// The MSFT move is filtered out and AAPL is unchanged, so the next
+	// quote is the AAPL move.
+	server.quotes.change("MSFT")
+	<-read
+	server.quotes.change("AAPL")
+	second, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "AAPL", second.Symbol)
+	assert.Equal(t, 151.00, second.BidPrice)
+}
+
+func TestStreamOrderUpdates(t *testing.T) {
+	server := serve(t, new(MockTradingService), new(MockAlertService))
+
+	placed := &domain.Transaction{TransactionID: 2, Symbol: "MSFT", Type: domain.Sell, Status: domain.Pending}
+	completed := &domain.Transaction{TransactionID: 1, Symbol: "AAPL", Type: domain.Buy, Status: domain.Completed, Quantity: 10, Price: 150.50}
+	publish := func(event *domain.OutboxEvent, err error) {
+		require.NoError(t, err)
+		var order domain.OrderEvent
+		require.NoError(t, json.Unmarshal([]byte(event.Payload), &order))
+		server.orders.Publish(domain.OrderChange{EventType: event.EventType, Order: order})
+	}
+
+	stream, err := server.client.StreamOrderUpdates(context.Background(), &maxionv1.StreamOrderUpdatesRequest{})
+	require.NoError(t, err)
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	publish(domain.NewOrderEvent(domain.OrderCreated, placed))
+	publish(domain.NewStatusChangedEvent(completed, domain.Pending))
+
+	update, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), update.Order.Id)
+	assert.Equal(t, maxionv1.OrderStatus_ORDER_STATUS_UNSPECIFIED, update.PreviousStatus)
+
+	update, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), update.Order.Id)
+	assert.Equal(t, maxionv1.OrderStatus_ORDER_STATUS_PENDING, update.PreviousStatus)
+	assert.Equal(t, maxionv1.OrderStatus_ORDER_STATUS_COMPLETED, update.Order.Status)
+	assert.NotNil(t, update.Order.Fill)
+
+	// Closing the server ends open streams cleanly.
+	server.trading.Close()
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestStreamOrderUpdates_FeedInterrupted(t *testing.T) {
+	server := serve(t, new(MockTradingService), new(MockAlertService))
+
+	stream, err := server.client.StreamOrderUpdates(context.Background(), &maxionv1.StreamOrderUpdatesRequest{})
+	require.NoError(t, err)
+	_, err = stream.Header()
+	require.NoError(t, err)
+	server.orders.Close()
+
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestStreamAlerts(t *testing.T) {
+	mockService := new(MockAlertService)
+	server := serve(t, new(MockTradingService), mockService)
+	fixedTime := time.Date(2024, 1, 1, 15, 0, 0, 0, time.UTC)
+
+	old := domain.Alert{AlertID: 4, RuleID: 1, Symbol: "AAPL", Type: domain.ThresholdRule}
+	other := domain.Alert{AlertID: 5, RuleID: 2, Symbol: "MSFT", Type: domain.VolumeRule}
+	fired := domain.Alert{
+		AlertID:     6,
+		RuleID:      1,
+		Symbol:      "AAPL",
+		Type:        domain.PercentMoveRule,
+		Observed:    2.03,
+		Message:     "AAPL ask rose 2.03%",
+		TriggeredAt: fixedTime,
+	}
+
+	// The poller waits for the stream before finding the new alerts.
+	opened := make(chan struct{})
+	mockService.On("GetAlerts", mock.Anything, int64(0)).Return([]domain.Alert{old}, nil).Once()
+	mockService.On("GetAlertsAfter", mock.Anything, int64(4)).Return([]domain.Alert(nil), nil).Run(func(mock.Arguments) {
+		<-opened
+	}).Once()
+	mockService.On("GetAlertsAfter", mock.Anything, int64(4)).Return([]domain.Alert{other, fired}, nil).Once()
+	mockService.On("GetAlertsAfter", mock.Anything, int64(6)).Return([]domain.Alert(nil), nil)
+	server.trading.Start(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	streams := make([]maxionv1.TradingService_StreamAlertsClient, 2)
+	for i := range streams {
+		stream, err := server.client.StreamAlerts(ctx, &maxionv1.StreamAlertsRequest{Symbols: []string{"AAPL"}})
+		require.NoError(t, err)
+		_, err = stream.Header()
+		require.NoError(t, err)
+		streams[i] = stream
+	}
+	close(opened)
+
+	// The alert that fired before the stream opened is not replayed, and
+	// the MSFT one is filtered out. Both streams get the alert from the one
+	// poll.
+	for _, stream := range streams {
+		alert, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, int64(6), alert.Id)
+		assert.Equal(t, maxionv1.AlertRuleType_ALERT_RULE_TYPE_PERCENT_MOVE, alert.Type)
+		assert.Equal(t, "AAPL ask rose 2.03%", alert.Message)
+		assert.True(t, alert.TriggeredAt.AsTime().Equal(fixedTime))
+	}
+	mockService.AssertNumberOfCalls(t, "GetAlerts", 1)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+)
+
+var errInvalidAlertRuleID = domain.NewValidationError("invalid_alert_rule_id", "Invalid alert rule ID")
+
+type AlertHandlers struct {
+	alertService ports.AlertService
+}
+
+func NewAlertHandlers(alertService ports.AlertService) *AlertHandlers {
+	return &AlertHandlers{
+		alertService: alertService,
+	}
+}
+
+func (h *AlertHandlers) CreateAlertRule(c *fiber.Ctx) error {
+	var req CreateAlertRuleRequestV1
+	if err := bindBody(c, &req); err != nil {
+		return err
+	}
+
+	// Validation has already restricted the enums to the known names.
+	rule := &domain.AlertRule{
+		Symbol:    req.Symbol,
+		Type:      domain.AlertRuleType(req.Type),
+		Side:      domain.QuoteSide(req.Side),
+		Direction: domain.AlertDirection(req.Direction),
+		Value:     req.Value,
+	}
+	if req.CooldownSeconds != nil {
+		rule.CooldownSeconds = *req.CooldownSeconds
+	}
+
+	if err := h.alertService.CreateRule(c.UserContext(), rule); err != nil {
+		return err
+	}
+	return c.Status(201).JSON(NewAlertRuleV1(*rule))
+}
+
+func (h *AlertHandlers) GetAlertRules(c *fiber.Ctx) error {
+	rules, err := h.alertService.GetRules(c.UserContext())
+	if err != nil {
+		return err
+	}
+	return c.JSON(NewAlertRulesV1(rules))
+}
+
+func (h *AlertHandlers) GetAlertRule(c *fiber.Ctx) error {
+	id, err := alertRuleID(c)
+	if err != nil {
+		return err
+	}
+
+	rule, err := h.alertService.GetRule(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(NewAlertRuleV1(*rule))
+}
+
+func (h *AlertHandlers) DeleteAlertRule(c *fiber.Ctx) error {
+	id, err := alertRuleID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.alertService.DeleteRule(c.UserContext(), id); err != nil {
+		return err
+	}
+	return c.SendStatus(204)
+}
+
+func (h *AlertHandlers) GetAlerts(c *fiber.Ctx) error {
+	alerts, err := h.alertService.GetAlerts(c.UserContext(), 0)
+	if err != nil {
+		return err
+	}
+	return c.JSON(NewAlertsV1(alerts))
+}
+
+func (h *AlertHandlers) GetRuleAlerts(c *fiber.Ctx) error {
+	id, err := alertRuleID(c)
+	if err != nil {
+		return err
+	}
+
+	alerts, err := h.alertService.GetAlerts(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(NewAlertsV1(alerts))
+}
+
+func alertRuleID(c *fiber.Ctx) (int64, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return 0, errInvalidAlertRuleID
+	}
+	return int64(id), nil
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+// MockAlertService implements ports.AlertService for testing
+type MockAlertService struct {
+	mock.Mock
+}
+
+func (m *MockAlertService) CreateRule(ctx context.Context, rule *domain.AlertRule) error {
+	args := m.Called(ctx, rule)
+	return args.Error(0)
+}
+
+func (m *MockAlertService) GetRules(ctx context.Context) ([]domain.AlertRule, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.AlertRule), args.Error(1)
+}
+
+func (m *MockAlertService) GetRule(ctx context.Context, id int64) (*domain.AlertRule, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AlertRule), args.Error(1)
+}
+
+func (m *MockAlertService) DeleteRule(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockAlertService) GetAlerts(ctx context.Context, ruleID int64) ([]domain.Alert, error) {
+	args := m.Called(ctx, ruleID)
+	return args.Get(0).([]domain.Alert), args.Error(1)
+}
+
+func (m *MockAlertService) GetAlertsAfter(ctx context.Context, afterID int64) ([]domain.Alert, error) {
+	args := m.Called(ctx, afterID)
+	return args.Get(0).([]domain.Alert), args.Error(1)
+}
+
+func setupAlertTest() (*fiber.App, *MockAlertService) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	mockService := new(MockAlertService)
+	handlers := NewAlertHandlers(mockService)
+
+	app.Post("/v1/alert-rules", handlers.CreateAlertRule)
+	app.Get("/v1/alert-rules", handlers.GetAlertRules)
+	app.Get("/v1/alert-rules/:id", handlers.GetAlertRule)
+	app.Delete("/v1/alert-rules/:id", handlers.DeleteAlertRule)
+	app.Get("/v1/alert-rules/:id/alerts", handlers.GetRuleAlerts)
+	app.Get("/v1/alerts", handlers.GetAlerts)
+
+	return app, mockService
+}
+
+func TestCreateAlertRule(t *testing.T) {
+	testCases := []struct {
+		name           string
+		requestBody    map[string]any
+		expectedStatus int
+	}{
+		{
+			name: "Threshold",
+			requestBody: map[string]any{
+				"symbol":    "AAPL",
+				"type":      "THRESHOLD",
+				"side":      "ASK",
+				"direction": "BELOW",
+				"value":     165,
+			},
+			expectedStatus: 201,
+		},
+		{
+			name: "Percent move with cooldown",
+			requestBody: map[string]any{
+				"symbol":          "AAPL",
+				"type":            "PERCENT_MOVE",
+				"side":            "BID",
+				"direction":       "ABOVE",
+				"value":           2.5,
+				"cooldownSeconds": 60,
+			},
+			expectedStatus: 201,
+		},
+		{
+			name: "Unknown type",
+			requestBody: map[string]any{
+				"symbol":    "AAPL",
+				"type":      "SPREAD",
+				"side":      "ASK",
+				"direction": "BELOW",
+				"value":     165,
+			},
+			expectedStatus: 400,
+		},
+		{
+			name: "Missing value",
+			requestBody: map[string]any{
+				"symbol":    "AAPL",
+				"type":      "VOLUME",
+				"side":      "ASK",
+				"direction": "ABOVE",
+			},
+			expectedStatus: 400,
+		},
+		{
+			name: "No cooldown",
+			requestBody: map[string]any{
+				"symbol":          "AAPL",
+				"type":            "THRESHOLD",
+				"side":            "ASK",
+				"direction":       "BELOW",
+				"value":           165,
+				"cooldownSeconds": 0,
+			},
+			expectedStatus: 400,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app, mockService := setupAlertTest()
+			mockService.On("CreateRule", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				rule := args.Get(1).(*domain.AlertRule)
+				rule.RuleID = 1
+				if rule.CooldownSeconds == 0 {
+					rule.CooldownSeconds = 300
+				}
+			}).Return(nil)
+
+			jsonBody, _ := json.Marshal(tc.requestBody)
+			req := httptest.NewRequest("POST", "/v1/alert-rules", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := app.Test(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+			if tc.expectedStatus != 201 {
+				mockService.AssertNotCalled(t, "CreateRule", mock.Anything, mock.Anything)
+				return
+			}
+
+			var result AlertRuleV1
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+			assert.Equal(t, int64(1), result.ID)
+			assert.Equal(t, AlertRuleTypeV1(tc.requestBody["type"].(string)), result.Type)
+			assert.NotZero(t, result.CooldownSeconds)
+		})
+	}
+}
+
+func TestCreateAlertRule_UnknownStock(t *testing.T) {
+	app, mockService := setupAlertTest()
+	mockService.On("CreateRule", mock.Anything, mock.Anything).
+		Return(domain.NewNotFoundError("stock_not_found", "stock ZZZZ not found"))
+
+	jsonBody, _ := json.Marshal(map[string]any{"symbol": "ZZZZ", "type": "VOLUME", "side": "BID", "direction": "ABOVE", "value": 1000})
+	req := httptest.NewRequest("POST", "/v1/alert-rules", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 404, resp.StatusCode)
+}
+
+func TestDeleteAlertRule(t *testing.T) {
+	app, mockService := setupAlertTest()
+	mockService.On("DeleteRule", mock.Anything, int64(1)).Return(nil)
+
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/v1/alert-rules/1", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 204, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/v1/alert-rules/abc", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode)
+}
+
+func TestGetAlerts(t *testing.T) {
+	app, mockService := setupAlertTest()
+	fixedTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	alerts := []domain.Alert{
+		{
+			AlertID:     3,
+			RuleID:      1,
+			Symbol:      "AAPL",
+			Type:        domain.ThresholdRule,
+			Observed:    164.5,
+			Message:     "AAPL ask fell below 165 (164.50)",
+			TriggeredAt: fixedTime,
+		},
+	}
+	mockService.On("GetAlerts", mock.Anything, int64(0)).Return(alerts, nil)
+	mockService.On("GetAlerts", mock.Anything, int64(1)).Return(alerts, nil)
+	mockService.On("GetAlerts", mock.Anything, int64(2)).
+		Return([]domain.Alert(nil), domain.NewNotFoundError("alert_rule_not_found", "alert rule 2 not found"))
+
+	for _, path := range []string{"/v1/alerts", "/v1/alert-rules/1/alerts"} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var result []map[string]any
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		assert.Equal(t, []map[string]any{
+			{
+				"id":          float64(3),
+				"ruleId":      float64(1),
+				"symbol":      "AAPL",
+				"type":        "THRESHOLD",
+				"observed":    164.5,
+				"message":     "AAPL ask fell below 165 (164.50)",
+				"triggeredAt": "2024-01-01T00:00:00Z",
+			},
+		}, result, path)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/v1/alert-rules/2/alerts", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 404, resp.StatusCode)
+}
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+// The v1 DTOs are the public JSON contract of the /v1 routes. They are
+// mapped from the domain structs so that schema or GORM changes don't leak
+// into responses; change them only alongside a new API version.
+
+// TransactionTypeV1 is a transaction type by name, e.g. "BUY".
+type TransactionTypeV1 string
+
+// TransactionStatusV1 is a transaction status by name, e.g. "COMPLETED".
+type TransactionStatusV1 string
+
+// OrderGroupTypeV1 is an order group type by name, e.g. "BRACKET".
+type OrderGroupTypeV1 string
+
+// OrderLegV1 is the part an order plays in its group, e.g. "TAKE_PROFIT".
+type OrderLegV1 string
+
+type StockV1 struct {
+	ID          int64     `json:"id"`
+	Symbol      string    `json:"symbol"`
+	BidPrice    float64   `json:"bidPrice"`
+	BidVolume   int       `json:"bidVolume"`
+	AskPrice    float64   `json:"askPrice"`
+	AskVolume   int       `json:"askVolume"`
+	LastUpdated time.Time `json:"lastUpdated"`
+}
+
+type TransactionV1 struct {
+	ID            int64               `json:"id"`
+	Symbol        string              `json:"symbol"`
+	Type          TransactionTypeV1   `json:"type"`
+	Status        TransactionStatusV1 `json:"status"`
+	Quantity      int                 `json:"quantity"`
+	Price         float64             `json:"price"`
+	TotalAmount   float64             `json:"totalAmount"`
+	OrderTime     time.Time           `json:"orderTime"`
+	ExecutionTime *time.Time          `json:"executionTime"`
+	Notes         *string             `json:"notes"`
+	// GroupID and Leg are only set on the orders of a group.
+	GroupID *string     `json:"groupId,omitempty"`
+	Leg     *OrderLegV1 `json:"leg,omitempty"`
+}
+
+type CreateTransactionRequestV1 struct {
+	Symbol   string  `json:"symbol" validate:"required,max=10,printascii,uppercase"`
+	Type     string  `json:"type" validate:"oneof=BUY SELL"`
+	Quantity int     `json:"quantity" validate:"gt=0,lte=2147483647"`
+	Notes    *string `json:"notes,omitempty" validate:"omitempty,max=500"`
+}
+
+// CreateOrderGroupRequestV1 places a bracket or OCO group. Side is the side
+// of the position the bracket enters or the OCO legs close; the legs take
+// the other side.
+type CreateOrderGroupRequestV1 struct {
+	Type            string   `json:"type" validate:"oneof=BRACKET OCO"`
+	Symbol          string   `json:"symbol" validate:"required,max=10,printascii,uppercase"`
+	Side            string   `json:"side" validate:"oneof=BUY SELL"`
+	Quantity        int      `json:"quantity" validate:"gt=0,lte=2147483647"`
+	TakeProfitPrice *float64 `json:"takeProfitPrice,omitempty" validate:"omitempty,gt=0"`
+	StopLossPrice   *float64 `json:"stopLossPrice,omitempty" validate:"omitempty,gt=0"`
+	Notes           *string  `json:"notes,omitempty" validate:"omitempty,max=500"`
+}
+
+// OrderGroupV1 is a placed order group. Its orders have no IDs yet, as
+// they are inserted asynchronously; they are listed with their groupId.
+type OrderGroupV1 struct {
+	ID     string           `json:"id"`
+	Type   OrderGroupTypeV1 `json:"type"`
+	Orders []TransactionV1  `json:"orders"`
+}
+
+type UpdateTransactionStatusRequestV1 struct {
+	Status string `json:"status" validate:"oneof=PENDING COMPLETED CANCELLED FAILED"`
+}
+
+func NewStockV1(stock domain.Stock) StockV1 {
+	return StockV1{
+		ID:          stock.StockID,
+		Symbol:      stock.Symbol,
+		BidPrice:    stock.BidPrice,
+		BidVolume:   stock.BidVolume,
+		AskPrice:    stock.AskPrice,
+		AskVolume:   stock.AskVolume,
+		LastUpdated: stock.LastUpdated,
+	}
+}
+
+// NewStocksV1 maps stocks, returning an empty slice rather than nil so that
+// the response is always a JSON array.
+func NewStocksV1(stocks []domain.Stock) []StockV1 {
+	result := make([]StockV1, 0, len(stocks))
+	for _, stock := range stocks {
+		result = append(result, NewStockV1(stock))
+	}
+	return result
+}
+
+func NewTransactionV1(tx domain.Transaction) TransactionV1 {
+	return TransactionV1{
+		ID:            tx.TransactionID,
+		Symbol:        tx.Symbol,
+		Type:          TransactionTypeV1(tx.Type.String()),
+		Status:        TransactionStatusV1(tx.Status.String()),
+		Quantity:      tx.Quantity,
+		Price:         tx.Price,
+		TotalAmount:   tx.TotalAmount,
+		OrderTime:     tx.OrderTime,
+		ExecutionTime: tx.ExecutionTime,
+		Notes:         tx.Notes,
+		GroupID:       tx.GroupID,
+		Leg:           (*OrderLegV1)(tx.Leg),
+	}
+}
+
+func NewTransactionsV1(transactions []domain.Transaction) []TransactionV1 {
+	result := make([]TransactionV1, 0, len(transactions))
+	for _, tx := range transactions {
+		result = append(result, NewTransactionV1(tx))
+	}
+	return result
+}
+
+func NewOrderGroupV1(group domain.OrderGroup) OrderGroupV1 {
+	return OrderGroupV1{
+		ID:     group.ID,
+		Type:   OrderGroupTypeV1(group.Type),
+		Orders: NewTransactionsV1(group.Orders),
+	}
+}
+
+// WebhookDeliveryStatusV1 is a webhook delivery status by name, e.g.
+// "SUCCEEDED".
+type WebhookDeliveryStatusV1 string
+
+// WebhookV1 is a webhook subscription. Its secret is only returned when the
+// subscription is created.
+type WebhookV1 struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type WebhookDeliveryV1 struct {
+	ID             int64                   `json:"id"`
+	EventID        string                  `json:"eventId"`
+	EventType      string                  `json:"eventType"`
+	Status         WebhookDeliveryStatusV1 `json:"status"`
+	Attempts       int                     `json:"attempts"`
+	ResponseStatus *int                    `json:"responseStatus"`
+	LastError      *string                 `json:"lastError"`
+	NextAttemptAt  *time.Time              `json:"nextAttemptAt"`
+	CreatedAt      time.Time               `json:"createdAt"`
+	DeliveredAt    *time.Time              `json:"deliveredAt"`
+}
+
+// CreateWebhookRequestV1 mirrors the WebhookSubscriptions table: Url is
+// VARCHAR(2000) and Secret VARCHAR(200). Events defaults to every event and
+// Secret is generated when omitted.
+type CreateWebhookRequestV1 struct {
+	URL    string   `json:"url" validate:"required,max=2000,http_url"`
+	Events []string `json:"events,omitempty" validate:"omitempty,dive,oneof=order.completed order.cancelled order.failed"`
+	Secret *string  `json:"secret,omitempty" validate:"omitempty,min=16,max=200"`
+}
+
+// UpdateWebhookRequestV1 replaces a subscription. The secret is kept when
+// omitted.
+type UpdateWebhookRequestV1 struct {
+	URL    string   `json:"url" validate:"required,max=2000,http_url"`
+	Events []string `json:"events,omitempty" validate:"omitempty,dive,oneof=order.completed order.cancelled order.failed"`
+	Active *bool    `json:"active" validate:"required"`
+	Secret *string  `json:"secret,omitempty" validate:"omitempty,min=16,max=200"`
+}
+
+func NewWebhookV1(sub domain.WebhookSubscription) WebhookV1 {
+	return WebhookV1{
+		ID:        sub.SubscriptionID,
+		URL:       sub.URL,
+		Events:    sub.Events,
+		Active:    sub.Active,
+		CreatedAt: sub.CreatedAt,
+	}
+}
+
+func NewWebhooksV1(subs []domain.WebhookSubscription) []WebhookV1 {
+	result := make([]WebhookV1, 0, len(subs))
+	for _, sub := range subs {
+		result = append(result, NewWebhookV1(sub))
+	}
+	return result
+}
+
+func NewWebhookDeliveryV1(delivery domain.WebhookDelivery) WebhookDeliveryV1 {
+	return WebhookDeliveryV1{
+		ID:             delivery.DeliveryID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         WebhookDeliveryStatusV1(delivery.Status),
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		LastError:      delivery.LastError,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+}
+
+func NewWebhookDeliveriesV1(deliveries []domain.WebhookDelivery) []WebhookDeliveryV1 {
+	result := make([]WebhookDeliveryV1, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		result = append(result, NewWebhookDeliveryV1(delivery))
+	}
+	return result
+}
+
+// AlertRuleTypeV1 is an alert rule type by name, e.g. "THRESHOLD".
+type AlertRuleTypeV1 string
+
+// QuoteSideV1 is the side of a quote by name, "BID" or "ASK".
+type QuoteSideV1 string
+
+// AlertDirectionV1 is an alert direction by name, "ABOVE" or "BELOW".
+type AlertDirectionV1 string
+
+type AlertRuleV1 struct {
+	ID              int64            `json:"id"`
+	Symbol          string           `json:"symbol"`
+	Type            AlertRuleTypeV1  `json:"type"`
+	Side            QuoteSideV1      `json:"side"`
+	Direction       AlertDirectionV1 `json:"direction"`
+	Value           float64          `json:"value"`
+	ReferencePrice  *float64         `json:"referencePrice"`
+	CooldownSeconds int              `json:"cooldownSeconds"`
+	LastFiredAt     *time.Time       `json:"lastFiredAt"`
+	CreatedAt       time.Time        `json:"createdAt"`
+}
+
+type AlertV1 struct {
+	ID          int64           `json:"id"`
+	RuleID      int64           `json:"ruleId"`
+	Symbol      string          `json:"symbol"`
+	Type        AlertRuleTypeV1 `json:"type"`
+	Observed    float64         `json:"observed"`
+	Message     string          `json:"message"`
+	TriggeredAt time.Time       `json:"triggeredAt"`
+}
+
+// CreateAlertRuleRequestV1 mirrors the AlertRules table: Symbol is
+// VARCHAR(10). Value is a price, a percent or a volume depending on Type.
+// CooldownSeconds defaults to 300.
+type CreateAlertRuleRequestV1 struct {
+	Symbol          string  `json:"symbol" validate:"required,max=10,printascii,uppercase"`
+	Type            string  `json:"type" validate:"oneof=THRESHOLD PERCENT_MOVE VOLUME"`
+	Side            string  `json:"side" validate:"oneof=BID ASK"`
+	Direction       string  `json:"direction" validate:"oneof=ABOVE BELOW"`
+	Value           float64 `json:"value" validate:"gt=0"`
+	CooldownSeconds *int    `json:"cooldownSeconds,omitempty" validate:"omitempty,gte=1,lte=86400"`
+}
+
+func NewAlertRuleV1(rule domain.AlertRule) AlertRuleV1 {
+	return AlertRuleV1{
+		ID:              rule.RuleID,
+		Symbol:          rule.Symbol,
+		Type:            AlertRuleTypeV1(rule.Type),
+		Side:            QuoteSideV1(rule.Side),
+		Direction:       AlertDirectionV1(rule.Direction),
+		Value:           rule.Value,
+		ReferencePrice:  rule.ReferencePrice,
+		CooldownSeconds: rule.CooldownSeconds,
+		LastFiredAt:     rule.LastFiredAt,
+		CreatedAt:       rule.CreatedAt,
+	}
+}
+
+func NewAlertRulesV1(rules []domain.AlertRule) []AlertRuleV1 {
+	result := make([]AlertRuleV1, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, NewAlertRuleV1(rule))
+	}
+	return result
+}
+
+func NewAlertV1(alert domain.Alert) AlertV1 {
+	return AlertV1{
+		ID:          alert.AlertID,
+		RuleID:      alert.RuleID,
+		Symbol:      alert.Symbol,
+		Type:        AlertRuleTypeV1(alert.Type),
+		Observed:    alert.Observed,
+		Message:     alert.Message,
+		TriggeredAt: alert.TriggeredAt,
+	}
+}
+
+func NewAlertsV1(alerts []domain.Alert) []AlertV1 {
+	result := make([]AlertV1, 0, len(alerts))
+	for _, alert := range alerts {
+		result = append(result, NewAlertV1(alert))
+	}
+	return result
+}
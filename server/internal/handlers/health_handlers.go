@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+)
+
+type HealthHandlers struct {
+	healthService ports.HealthService
+}
+
+func NewHealthHandlers(healthService ports.HealthService) *HealthHandlers {
+	return &HealthHandlers{
+		healthService: healthService,
+	}
+}
+
+// Liveness reports that the process is up and serving requests. It does not
+// touch any dependency so a database outage never restarts the container.
+func (h *HealthHandlers) Liveness(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": domain.Healthy})
+}
+
+// Readiness probes the database and Redis and reports background worker
+// freshness. A degraded server still receives traffic; an unavailable one
+// answers 503.
+func (h *HealthHandlers) Readiness(c *fiber.Ctx) error {
+	readiness := h.healthService.Readiness(c.UserContext())
+	if readiness.Status == domain.Unavailable {
+		return c.Status(503).JSON(readiness)
+	}
+	return c.JSON(readiness)
+}
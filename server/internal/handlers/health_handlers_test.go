@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+// MockHealthService implements ports.HealthService for testing
+type MockHealthService struct {
+	mock.Mock
+}
+
+func (m *MockHealthService) Readiness(ctx context.Context) domain.Readiness {
+	args := m.Called(ctx)
+	return args.Get(0).(domain.Readiness)
+}
+
+func setupHealthTest() (*fiber.App, *MockHealthService) {
+	app := fiber.New()
+	mockService := new(MockHealthService)
+	handlers := NewHealthHandlers(mockService)
+
+	app.Get("/healthz", handlers.Liveness)
+	app.Get("/readyz", handlers.Readiness)
+
+	return app, mockService
+}
+
+func TestLiveness(t *testing.T) {
+	app, mockService := setupHealthTest()
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/healthz", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	mockService.AssertNotCalled(t, "Readiness", mock.Anything)
+}
+
+func TestReadiness(t *testing.T) {
+	testCases := []struct {
+		name           string
+		status         domain.HealthState
+		expectedStatus int
+	}{
+		{name: "Healthy", status: domain.Healthy, expectedStatus: 200},
+		{name: "Degraded", status: domain.Degraded, expectedStatus: 200},
+		{name: "Unavailable", status: domain.Unavailable, expectedStatus: 503},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app, mockService := setupHealthTest()
+			mockService.On("Readiness", mock.Anything).Return(domain.Readiness{
+				Status: tc.status,
+				Dependencies: map[string]domain.DependencyHealth{
+					"database": {Status: tc.status},
+				},
+			})
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/readyz", nil))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+
+			var result domain.Readiness
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+			assert.Equal(t, tc.status, result.Status)
+		})
+	}
+}
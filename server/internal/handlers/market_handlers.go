@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+)
+
+type MarketHandlers struct {
+	calendar ports.MarketCalendar
+}
+
+func NewMarketHandlers(calendar ports.MarketCalendar) *MarketHandlers {
+	return &MarketHandlers{
+		calendar: calendar,
+	}
+}
+
+func (h *MarketHandlers) GetMarketStatus(c *fiber.Ctx) error {
+	return c.JSON(h.calendar.Status(time.Now()))
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+// MockMarketCalendar implements ports.MarketCalendar for testing
+type MockMarketCalendar struct {
+	mock.Mock
+}
+
+func (m *MockMarketCalendar) Status(at time.Time) domain.MarketStatus {
+	args := m.Called(at)
+	return args.Get(0).(domain.MarketStatus)
+}
+
+func TestGetMarketStatus(t *testing.T) {
+	app := fiber.New()
+	calendar := new(MockMarketCalendar)
+	handlers := NewMarketHandlers(calendar)
+	app.Get("/market/status", handlers.GetMarketStatus)
+
+	calendar.On("Status", mock.Anything).Return(domain.MarketStatus{
+		Exchange:      "NASDAQ",
+		Session:       domain.Regular,
+		IsOpen:        true,
+		AcceptsOrders: true,
+	})
+
+	req := httptest.NewRequest("GET", "/market/status", nil)
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var result domain.MarketStatus
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	assert.NoError(t, err)
+	assert.Equal(t, "NASDAQ", result.Exchange)
+	assert.Equal(t, domain.Regular, result.Session)
+	assert.True(t, result.AcceptsOrders)
+}
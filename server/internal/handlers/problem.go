@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body. Code is an extension member
+// carrying a stable, machine-readable error code, and Errors lists the
+// fields that failed validation.
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Code     string              `json:"code"`
+	Errors   []domain.FieldError `json:"errors,omitempty"`
+}
+
+var kindStatus = map[domain.ErrorKind]int{
+	domain.KindNotFound:    404,
+	domain.KindValidation:  400,
+	domain.KindConflict:    409,
+	domain.KindRejected:    422,
+	domain.KindUnavailable: 503,
+	domain.KindRateLimited: 429,
+}
+
+// ErrorHandler is the Fiber error handler for the API. Handlers return
+// errors instead of writing error bodies; domain errors are mapped to their
+// status code and code, and anything unrecognised becomes an opaque 500.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	problem := NewProblem(err)
+	problem.Instance = c.OriginalURL()
+	return WriteProblem(c, problem)
+}
+
+// NewProblem describes err as problem details. Only domain errors and Fiber
+// errors reveal their message; other errors may carry internals.
+func NewProblem(err error) Problem {
+	var domainErr *domain.Error
+	var fiberErr *fiber.Error
+
+	switch {
+	case errors.As(err, &domainErr):
+		status, ok := kindStatus[domainErr.Kind]
+		if !ok {
+			status = 500
+		}
+		detail := err.Error()
+		if domainErr.Kind == domain.KindUnavailable {
+			detail = domainErr.Message
+		}
+		problem := newProblem(status, domainErr.Code, detail)
+		problem.Errors = domainErr.Fields
+		return problem
+	case errors.Is(err, context.DeadlineExceeded):
+		return newProblem(504, "timeout", "The request did not complete in time")
+	case errors.As(err, &fiberErr):
+		return newProblem(fiberErr.Code, fiberCode(fiberErr.Code), fiberErr.Message)
+	default:
+		return newProblem(500, "internal_error", "An unexpected error occurred")
+	}
+}
+
+func WriteProblem(c *fiber.Ctx, problem Problem) error {
+	return c.Status(problem.Status).JSON(problem, problemContentType)
+}
+
+func newProblem(status int, code string, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+func fiberCode(status int) string {
+	switch status {
+	case 404:
+		return "route_not_found"
+	case 405:
+		return "method_not_allowed"
+	case 413:
+		return "request_too_large"
+	default:
+		if status >= 500 {
+			return "internal_error"
+		}
+		return "bad_request"
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+func TestNewProblem(t *testing.T) {
+	testCases := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   string
+		expectedDetail string
+	}{
+		{
+			name:           "Not found",
+			err:            domain.NewNotFoundError("stock_not_found", "stock ZZZZ not found"),
+			expectedStatus: 404,
+			expectedCode:   "stock_not_found",
+			expectedDetail: "stock ZZZZ not found",
+		},
+		{
+			name:           "Validation",
+			err:            errInvalidBody,
+			expectedStatus: 400,
+			expectedCode:   "invalid_body",
+			expectedDetail: "Invalid request body",
+		},
+		{
+			name:           "Conflict",
+			err:            domain.NewConflictError("duplicate_order", "order already exists"),
+			expectedStatus: 409,
+			expectedCode:   "duplicate_order",
+			expectedDetail: "order already exists",
+		},
+		{
+			name:           "Wrapped rejection keeps context",
+			err:            fmt.Errorf("%w: %s", domain.ErrMarketClosed, "non-trading day"),
+			expectedStatus: 422,
+			expectedCode:   "market_closed",
+			expectedDetail: "market is closed for order entry: non-trading day",
+		},
+		{
+			name:           "Unavailable hides cause",
+			err:            domain.NewUnavailableError("order_cache_unavailable", "orders cannot be accepted right now", errors.New("dial tcp 10.0.0.3:6379: refused")),
+			expectedStatus: 503,
+			expectedCode:   "order_cache_unavailable",
+			expectedDetail: "orders cannot be accepted right now",
+		},
+		{
+			name:           "Rate limited",
+			err:            domain.NewRateLimitedError("rate_limited", "rate limit of 60 requests per 1m0s exceeded"),
+			expectedStatus: 429,
+			expectedCode:   "rate_limited",
+			expectedDetail: "rate limit of 60 requests per 1m0s exceeded",
+		},
+		{
+			name:           "Deadline exceeded",
+			err:            fmt.Errorf("query: %w", context.DeadlineExceeded),
+			expectedStatus: 504,
+			expectedCode:   "timeout",
+		},
+		{
+			name:           "Fiber error",
+			err:            fiber.ErrMethodNotAllowed,
+			expectedStatus: 405,
+			expectedCode:   "method_not_allowed",
+		},
+		{
+			name:           "Unknown error is opaque",
+			err:            errors.New("mssql: login failed for user 'sa'"),
+			expectedStatus: 500,
+			expectedCode:   "internal_error",
+			expectedDetail: "An unexpected error occurred",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problem := NewProblem(tc.err)
+
+			assert.Equal(t, tc.expectedStatus, problem.Status)
+			assert.Equal(t, tc.expectedCode, problem.Code)
+			assert.Equal(t, "about:blank", problem.Type)
+			assert.NotEmpty(t, problem.Title)
+			if tc.expectedDetail != "" {
+				assert.Equal(t, tc.expectedDetail, problem.Detail)
+			}
+		})
+	}
+}
+
+func TestErrorHandler_WritesProblemDetails(t *testing.T) {
+	app, mockService := setupTest()
+
+	mockService.On("CreateTransaction", mock.Anything, mock.AnythingOfType("*domain.Transaction")).
+		Return(domain.NewNotFoundError("stock_not_found", "stock ZZZZ not found")).Once()
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"symbol":   "ZZZZ",
+		"type":     1,
+		"quantity": 100,
+	})
+	req := httptest.NewRequest("POST", "/transactions", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, 404, resp.StatusCode)
+	assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+
+	var problem Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	assert.Equal(t, "stock_not_found", problem.Code)
+	assert.Equal(t, "Not Found", problem.Title)
+	assert.Equal(t, "/transactions", problem.Instance)
+}
@@ -4,8 +4,27 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/touchsung/maxion-server/internal/core/domain"
 	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/validation"
 )
 
+var (
+	errInvalidBody          = domain.NewValidationError("invalid_body", "Invalid request body")
+	errInvalidTransactionID = domain.NewValidationError("invalid_transaction_id", "Invalid transaction ID")
+)
+
+// CreateTransactionRequest mirrors the Transactions table constraints: Symbol
+// is VARCHAR(10), Quantity is a positive INT and Notes is NVARCHAR(500).
+type CreateTransactionRequest struct {
+	Symbol   string  `json:"symbol" validate:"required,max=10,printascii,uppercase"`
+	Type     int     `json:"type" validate:"oneof=1 2"`
+	Quantity int     `json:"quantity" validate:"gt=0,lte=2147483647"`
+	Notes    *string `json:"notes,omitempty" validate:"omitempty,max=500"`
+}
+
+type UpdateTransactionStatusRequest struct {
+	Status int `json:"status" validate:"oneof=1 2 3 4"`
+}
+
 type TradingHandlers struct {
 	tradingService ports.TradingService
 }
@@ -17,31 +36,25 @@ func NewTradingHandlers(tradingService ports.TradingService) *TradingHandlers {
 }
 
 func (h *TradingHandlers) GetAllStocks(c *fiber.Ctx) error {
-	stocks, err := h.tradingService.GetAllStocks()
+	stocks, err := h.tradingService.GetAllStocks(c.UserContext())
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch stocks"})
+		return err
 	}
 	return c.JSON(stocks)
 }
 
 func (h *TradingHandlers) GetAllTransactions(c *fiber.Ctx) error {
-	transactions, err := h.tradingService.GetAllTransactions()
+	transactions, err := h.tradingService.GetAllTransactions(c.UserContext())
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch transactions"})
+		return err
 	}
 	return c.JSON(transactions)
 }
 
 func (h *TradingHandlers) CreateTransaction(c *fiber.Ctx) error {
-	var req struct {
-		Symbol   string  `json:"symbol"`
-		Type     int     `json:"type"`
-		Quantity int     `json:"quantity"`
-		Notes    *string `json:"notes,omitempty"`
-	}
-
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	var req CreateTransactionRequest
+	if err := bindBody(c, &req); err != nil {
+		return err
 	}
 
 	tx := &domain.Transaction{
@@ -52,8 +65,8 @@ func (h *TradingHandlers) CreateTransaction(c *fiber.Ctx) error {
 		Status:   domain.Pending,
 	}
 
-	if err := h.tradingService.CreateTransaction(tx); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to create transaction"})
+	if err := h.tradingService.CreateTransaction(c.UserContext(), tx); err != nil {
+		return err
 	}
 
 	return c.Status(201).JSON(tx)
@@ -61,22 +74,28 @@ func (h *TradingHandlers) CreateTransaction(c *fiber.Ctx) error {
 
 func (h *TradingHandlers) UpdateTransactionStatus(c *fiber.Ctx) error {
 	id, err := c.ParamsInt("id")
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid transaction ID"})
-	}
-
-	var req struct {
-		Status int `json:"status"`
+	if err != nil || id <= 0 {
+		return errInvalidTransactionID
 	}
 
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	var req UpdateTransactionStatusRequest
+	if err := bindBody(c, &req); err != nil {
+		return err
 	}
 
-	err = h.tradingService.UpdateTransactionStatus(int64(id), domain.TransactionStatus(req.Status))
+	err = h.tradingService.UpdateTransactionStatus(c.UserContext(), int64(id), domain.TransactionStatus(req.Status))
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Failed to update transaction"})
+		return err
 	}
 
 	return c.SendStatus(200)
-} 
\ No newline at end of file
+}
+
+// bindBody parses the request body into req and validates it, so that
+// malformed orders are rejected before they reach the service layer.
+func bindBody(c *fiber.Ctx, req any) error {
+	if err := c.BodyParser(req); err != nil {
+		return errInvalidBody
+	}
+	return validation.Struct(req)
+}
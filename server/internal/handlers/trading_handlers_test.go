@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,28 +21,33 @@ type MockTradingService struct {
 	mock.Mock
 }
 
-func (m *MockTradingService) GetAllStocks() ([]domain.Stock, error) {
-	args := m.Called()
+func (m *MockTradingService) GetAllStocks(ctx context.Context) ([]domain.Stock, error) {
+	args := m.Called(ctx)
 	return args.Get(0).([]domain.Stock), args.Error(1)
 }
 
-func (m *MockTradingService) GetAllTransactions() ([]domain.Transaction, error) {
-	args := m.Called()
+func (m *MockTradingService) GetAllTransactions(ctx context.Context) ([]domain.Transaction, error) {
+	args := m.Called(ctx)
 	return args.Get(0).([]domain.Transaction), args.Error(1)
 }
 
-func (m *MockTradingService) CreateTransaction(tx *domain.Transaction) error {
-	args := m.Called(tx)
+func (m *MockTradingService) CreateTransaction(ctx context.Context, tx *domain.Transaction) error {
+	args := m.Called(ctx, tx)
 	return args.Error(0)
 }
 
-func (m *MockTradingService) UpdateTransactionStatus(id int64, status domain.TransactionStatus) error {
-	args := m.Called(id, status)
+func (m *MockTradingService) CreateOrderGroup(ctx context.Context, group *domain.OrderGroup) error {
+	args := m.Called(ctx, group)
+	return args.Error(0)
+}
+
+func (m *MockTradingService) UpdateTransactionStatus(ctx context.Context, id int64, status domain.TransactionStatus) error {
+	args := m.Called(ctx, id, status)
 	return args.Error(0)
 }
 
 func setupTest() (*fiber.App, *MockTradingService) {
-	app := fiber.New()
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
 	mockService := new(MockTradingService)
 	handlers := NewTradingHandlers(mockService)
 
@@ -78,7 +86,7 @@ func TestGetAllStocks(t *testing.T) {
 		},
 	}
 
-	mockService.On("GetAllStocks").Return(expectedStocks, nil)
+	mockService.On("GetAllStocks", mock.Anything).Return(expectedStocks, nil)
 
 	// Execute
 	req := httptest.NewRequest("GET", "/stocks", nil)
@@ -115,7 +123,7 @@ func TestGetAllTransactions(t *testing.T) {
 		},
 	}
 
-	mockService.On("GetAllTransactions").Return(expectedTxs, nil)
+	mockService.On("GetAllTransactions", mock.Anything).Return(expectedTxs, nil)
 
 	// Execute
 	req := httptest.NewRequest("GET", "/transactions", nil)
@@ -153,13 +161,50 @@ func TestCreateTransaction(t *testing.T) {
 			},
 			expectedStatus: 201,
 		},
+		{
+			name: "Empty Symbol",
+			requestBody: map[string]interface{}{
+				"symbol":   "",
+				"type":     1,
+				"quantity": 100,
+			},
+			expectedStatus: 400,
+		},
+		{
+			name: "Unknown Type",
+			requestBody: map[string]interface{}{
+				"symbol":   "AAPL",
+				"type":     7,
+				"quantity": 100,
+			},
+			expectedStatus: 400,
+		},
+		{
+			name: "Negative Quantity",
+			requestBody: map[string]interface{}{
+				"symbol":   "AAPL",
+				"type":     2,
+				"quantity": -5,
+			},
+			expectedStatus: 400,
+		},
+		{
+			name: "Notes Too Long",
+			requestBody: map[string]interface{}{
+				"symbol":   "AAPL",
+				"type":     1,
+				"quantity": 100,
+				"notes":    strings.Repeat("n", 501),
+			},
+			expectedStatus: 400,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// Setup mock expectation if needed
 			if tc.expectedStatus == 201 {
-				mockService.On("CreateTransaction", mock.AnythingOfType("*domain.Transaction")).Return(nil).Once()
+				mockService.On("CreateTransaction", mock.Anything, mock.AnythingOfType("*domain.Transaction")).Return(nil).Once()
 			}
 
 			// Create request body
@@ -177,6 +222,70 @@ func TestCreateTransaction(t *testing.T) {
 	}
 }
 
+func TestCreateTransaction_MarketClosed(t *testing.T) {
+	app, mockService := setupTest()
+
+	mockService.On("CreateTransaction", mock.Anything, mock.AnythingOfType("*domain.Transaction")).
+		Return(fmt.Errorf("%w: %s", domain.ErrMarketClosed, "non-trading day")).Once()
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"symbol":   "AAPL",
+		"type":     1,
+		"quantity": 100,
+	})
+	req := httptest.NewRequest("POST", "/transactions", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 422, resp.StatusCode)
+}
+
+func TestCreateTransaction_FieldErrors(t *testing.T) {
+	app, mockService := setupTest()
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"symbol":   "",
+		"type":     0,
+		"quantity": 0,
+	})
+	req := httptest.NewRequest("POST", "/transactions", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode)
+
+	var problem Problem
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	assert.Equal(t, "validation_failed", problem.Code)
+
+	fields := make(map[string]string)
+	for _, fieldErr := range problem.Errors {
+		fields[fieldErr.Field] = fieldErr.Code
+	}
+	assert.Equal(t, map[string]string{
+		"symbol":   "required",
+		"type":     "oneof",
+		"quantity": "gt",
+	}, fields)
+
+	mockService.AssertNotCalled(t, "CreateTransaction", mock.Anything, mock.Anything)
+}
+
+func TestGetAllTransactions_Timeout(t *testing.T) {
+	app, mockService := setupTest()
+
+	mockService.On("GetAllTransactions", mock.Anything).
+		Return([]domain.Transaction(nil), fmt.Errorf("failed to query: %w", context.DeadlineExceeded)).Once()
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/transactions", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 504, resp.StatusCode)
+}
+
 func TestUpdateTransactionStatus(t *testing.T) {
 	// Setup
 	app, mockService := setupTest()
@@ -212,13 +321,29 @@ func TestUpdateTransactionStatus(t *testing.T) {
 			},
 			expectedStatus: 400,
 		},
+		{
+			name:          "Unknown Status",
+			transactionID: "1",
+			requestBody: map[string]interface{}{
+				"status": 9,
+			},
+			expectedStatus: 400,
+		},
+		{
+			name:          "Non-positive Transaction ID",
+			transactionID: "0",
+			requestBody: map[string]interface{}{
+				"status": 2,
+			},
+			expectedStatus: 400,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// Setup mock expectation if needed
 			if tc.expectedStatus == 200 {
-				mockService.On("UpdateTransactionStatus", int64(1), domain.TransactionStatus(2)).Return(nil).Once()
+				mockService.On("UpdateTransactionStatus", mock.Anything, int64(1), domain.TransactionStatus(2)).Return(nil).Once()
 			}
 
 			// Create request body
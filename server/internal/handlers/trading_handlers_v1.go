@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+func (h *TradingHandlers) GetAllStocksV1(c *fiber.Ctx) error {
+	stocks, err := h.tradingService.GetAllStocks(c.UserContext())
+	if err != nil {
+		return err
+	}
+	return c.JSON(NewStocksV1(stocks))
+}
+
+func (h *TradingHandlers) GetAllTransactionsV1(c *fiber.Ctx) error {
+	transactions, err := h.tradingService.GetAllTransactions(c.UserContext())
+	if err != nil {
+		return err
+	}
+	return c.JSON(NewTransactionsV1(transactions))
+}
+
+func (h *TradingHandlers) CreateTransactionV1(c *fiber.Ctx) error {
+	var req CreateTransactionRequestV1
+	if err := bindBody(c, &req); err != nil {
+		return err
+	}
+
+	// Validation has already restricted Type to the known names.
+	txType, _ := domain.ParseTransactionType(req.Type)
+	tx := &domain.Transaction{
+		Symbol:   req.Symbol,
+		Type:     txType,
+		Quantity: req.Quantity,
+		Notes:    req.Notes,
+		Status:   domain.Pending,
+	}
+
+	if err := h.tradingService.CreateTransaction(c.UserContext(), tx); err != nil {
+		return err
+	}
+
+	return c.Status(201).JSON(NewTransactionV1(*tx))
+}
+
+func (h *TradingHandlers) CreateOrderGroupV1(c *fiber.Ctx) error {
+	var req CreateOrderGroupRequestV1
+	if err := bindBody(c, &req); err != nil {
+		return err
+	}
+
+	side, _ := domain.ParseTransactionType(req.Side)
+	group := &domain.OrderGroup{
+		Type:            domain.OrderGroupType(req.Type),
+		Symbol:          req.Symbol,
+		Side:            side,
+		Quantity:        req.Quantity,
+		TakeProfitPrice: req.TakeProfitPrice,
+		StopLossPrice:   req.StopLossPrice,
+		Notes:           req.Notes,
+	}
+
+	if err := h.tradingService.CreateOrderGroup(c.UserContext(), group); err != nil {
+		return err
+	}
+
+	return c.Status(201).JSON(NewOrderGroupV1(*group))
+}
+
+func (h *TradingHandlers) UpdateTransactionStatusV1(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return errInvalidTransactionID
+	}
+
+	var req UpdateTransactionStatusRequestV1
+	if err := bindBody(c, &req); err != nil {
+		return err
+	}
+
+	status, _ := domain.ParseTransactionStatus(req.Status)
+	if err := h.tradingService.UpdateTransactionStatus(c.UserContext(), int64(id), status); err != nil {
+		return err
+	}
+
+	return c.SendStatus(204)
+}
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+func setupV1Test() (*fiber.App, *MockTradingService) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	mockService := new(MockTradingService)
+	handlers := NewTradingHandlers(mockService)
+
+	app.Get("/v1/stocks", handlers.GetAllStocksV1)
+	app.Get("/v1/transactions", handlers.GetAllTransactionsV1)
+	app.Post("/v1/transactions", handlers.CreateTransactionV1)
+	app.Post("/v1/order-groups", handlers.CreateOrderGroupV1)
+	app.Put("/v1/transactions/:id/status", handlers.UpdateTransactionStatusV1)
+
+	return app, mockService
+}
+
+func TestGetAllStocksV1(t *testing.T) {
+	app, mockService := setupV1Test()
+	fixedTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mockService.On("GetAllStocks", mock.Anything).Return([]domain.Stock{
+		{
+			StockID:     1,
+			Symbol:      "AAPL",
+			BidPrice:    150.00,
+			BidVolume:   1000,
+			AskPrice:    150.50,
+			AskVolume:   800,
+			LastUpdated: fixedTime,
+		},
+	}, nil)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/v1/stocks", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var result []map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, []map[string]any{
+		{
+			"id":          float64(1),
+			"symbol":      "AAPL",
+			"bidPrice":    150.00,
+			"bidVolume":   float64(1000),
+			"askPrice":    150.50,
+			"askVolume":   float64(800),
+			"lastUpdated": "2024-01-01T00:00:00Z",
+		},
+	}, result)
+}
+
+func TestGetAllTransactionsV1(t *testing.T) {
+	app, mockService := setupV1Test()
+	fixedTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mockService.On("GetAllTransactions", mock.Anything).Return([]domain.Transaction{
+		{
+			TransactionID: 1,
+			Symbol:        "AAPL",
+			Type:          domain.Sell,
+			Status:        domain.Completed,
+			Quantity:      100,
+			Price:         150.50,
+			TotalAmount:   15050.00,
+			OrderTime:     fixedTime,
+			Stock:         domain.Stock{Symbol: "AAPL"},
+		},
+	}, nil)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/v1/transactions", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var result []map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, []map[string]any{
+		{
+			"id":            float64(1),
+			"symbol":        "AAPL",
+			"type":          "SELL",
+			"status":        "COMPLETED",
+			"quantity":      float64(100),
+			"price":         150.50,
+			"totalAmount":   15050.00,
+			"orderTime":     "2024-01-01T00:00:00Z",
+			"executionTime": nil,
+			"notes":         nil,
+		},
+	}, result)
+}
+
+func TestGetAllTransactionsV1_Empty(t *testing.T) {
+	app, mockService := setupV1Test()
+
+	mockService.On("GetAllTransactions", mock.Anything).Return([]domain.Transaction(nil), nil)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/v1/transactions", nil))
+	assert.NoError(t, err)
+
+	var body bytes.Buffer
+	_, err = body.ReadFrom(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", body.String())
+}
+
+func TestCreateTransactionV1(t *testing.T) {
+	app, mockService := setupV1Test()
+
+	testCases := []struct {
+		name           string
+		requestBody    map[string]interface{}
+		expectedType   domain.TransactionType
+		expectedStatus int
+	}{
+		{
+			name: "Buy",
+			requestBody: map[string]interface{}{
+				"symbol":   "AAPL",
+				"type":     "BUY",
+				"quantity": 100,
+			},
+			expectedType:   domain.Buy,
+			expectedStatus: 201,
+		},
+		{
+			name: "Sell",
+			requestBody: map[string]interface{}{
+				"symbol":   "AAPL",
+				"type":     "SELL",
+				"quantity": 100,
+			},
+			expectedType:   domain.Sell,
+			expectedStatus: 201,
+		},
+		{
+			name: "Numeric Type",
+			requestBody: map[string]interface{}{
+				"symbol":   "AAPL",
+				"type":     1,
+				"quantity": 100,
+			},
+			expectedStatus: 400,
+		},
+		{
+			name: "Lower-case Type",
+			requestBody: map[string]interface{}{
+				"symbol":   "AAPL",
+				"type":     "buy",
+				"quantity": 100,
+			},
+			expectedStatus: 400,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.expectedStatus == 201 {
+				mockService.On("CreateTransaction", mock.Anything, mock.MatchedBy(func(tx *domain.Transaction) bool {
+					return tx.Type == tc.expectedType
+				})).Run(func(args mock.Arguments) {
+					tx := args.Get(1).(*domain.Transaction)
+					tx.Price = 150.50
+					tx.TotalAmount = 15050.00
+				}).Return(nil).Once()
+			}
+
+			jsonBody, _ := json.Marshal(tc.requestBody)
+			req := httptest.NewRequest("POST", "/v1/transactions", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := app.Test(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+			if tc.expectedStatus != 201 {
+				return
+			}
+
+			var result TransactionV1
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+			assert.Equal(t, TransactionTypeV1(tc.expectedType.String()), result.Type)
+			assert.Equal(t, TransactionStatusV1("PENDING"), result.Status)
+			assert.Equal(t, 15050.00, result.TotalAmount)
+		})
+	}
+}
+
+func TestCreateOrderGroupV1(t *testing.T) {
+	testCases := []struct {
+		name           string
+		requestBody    map[string]interface{}
+		expectedStatus int
+	}{
+		{
+			name: "Bracket",
+			requestBody: map[string]interface{}{
+				"type":            "BRACKET",
+				"symbol":          "AAPL",
+				"side":            "BUY",
+				"quantity":        100,
+				"takeProfitPrice": 160,
+				"stopLossPrice":   140,
+			},
+			expectedStatus: 201,
+		},
+		{
+			name: "Unknown Type",
+			requestBody: map[string]interface{}{
+				"type":     "TRAILING",
+				"symbol":   "AAPL",
+				"side":     "BUY",
+				"quantity": 100,
+			},
+			expectedStatus: 400,
+		},
+		{
+			name: "Negative Price",
+			requestBody: map[string]interface{}{
+				"type":          "OCO",
+				"symbol":        "AAPL",
+				"side":          "BUY",
+				"quantity":      100,
+				"stopLossPrice": -1,
+			},
+			expectedStatus: 400,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app, mockService := setupV1Test()
+			mockService.On("CreateOrderGroup", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				group := args.Get(1).(*domain.OrderGroup)
+				group.ID = "group-1"
+				entry, tp := domain.EntryLeg, domain.TakeProfitLeg
+				group.Orders = []domain.Transaction{
+					{Symbol: group.Symbol, Type: group.Side, Status: domain.Pending, Quantity: group.Quantity, GroupID: &group.ID, Leg: &entry},
+					{Symbol: group.Symbol, Type: group.Side.Opposite(), Status: domain.Held, Quantity: group.Quantity, GroupID: &group.ID, Leg: &tp},
+				}
+			}).Return(nil)
+
+			jsonBody, _ := json.Marshal(tc.requestBody)
+			req := httptest.NewRequest("POST", "/v1/order-groups", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := app.Test(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+			if tc.expectedStatus != 201 {
+				mockService.AssertNotCalled(t, "CreateOrderGroup", mock.Anything, mock.Anything)
+				return
+			}
+
+			var result OrderGroupV1
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+			assert.Equal(t, "group-1", result.ID)
+			assert.Equal(t, OrderGroupTypeV1("BRACKET"), result.Type)
+			if assert.Len(t, result.Orders, 2) {
+				assert.Equal(t, OrderLegV1("ENTRY"), *result.Orders[0].Leg)
+				assert.Equal(t, TransactionStatusV1("HELD"), result.Orders[1].Status)
+				assert.Equal(t, TransactionTypeV1("SELL"), result.Orders[1].Type)
+			}
+		})
+	}
+}
+
+func TestUpdateTransactionStatusV1(t *testing.T) {
+	app, mockService := setupV1Test()
+
+	testCases := []struct {
+		name           string
+		requestBody    map[string]interface{}
+		expectedStatus int
+	}{
+		{
+			name:           "Valid Update",
+			requestBody:    map[string]interface{}{"status": "CANCELLED"},
+			expectedStatus: 204,
+		},
+		{
+			name:           "Numeric Status",
+			requestBody:    map[string]interface{}{"status": 3},
+			expectedStatus: 400,
+		},
+		{
+			name:           "Unknown Status",
+			requestBody:    map[string]interface{}{"status": "SETTLED"},
+			expectedStatus: 400,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.expectedStatus == 204 {
+				mockService.On("UpdateTransactionStatus", mock.Anything, int64(1), domain.Cancelled).Return(nil).Once()
+			}
+
+			jsonBody, _ := json.Marshal(tc.requestBody)
+			req := httptest.NewRequest("PUT", "/v1/transactions/1/status", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := app.Test(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+		})
+	}
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+)
+
+var (
+	errInvalidWebhookID  = domain.NewValidationError("invalid_webhook_id", "Invalid webhook ID")
+	errInvalidDeliveryID = domain.NewValidationError("invalid_delivery_id", "Invalid webhook delivery ID")
+)
+
+type WebhookHandlers struct {
+	webhookService ports.WebhookService
+}
+
+func NewWebhookHandlers(webhookService ports.WebhookService) *WebhookHandlers {
+	return &WebhookHandlers{
+		webhookService: webhookService,
+	}
+}
+
+func (h *WebhookHandlers) CreateWebhook(c *fiber.Ctx) error {
+	var req CreateWebhookRequestV1
+	if err := bindBody(c, &req); err != nil {
+		return err
+	}
+
+	sub := &domain.WebhookSubscription{
+		URL:    req.URL,
+		Events: req.Events,
+	}
+	if req.Secret != nil {
+		sub.Secret = *req.Secret
+	}
+
+	if err := h.webhookService.CreateSubscription(c.UserContext(), sub); err != nil {
+		return err
+	}
+
+	// The secret is shown once, so that the subscriber can verify
+	// signatures.
+	webhook := NewWebhookV1(*sub)
+	webhook.Secret = sub.Secret
+	return c.Status(201).JSON(webhook)
+}
+
+func (h *WebhookHandlers) GetWebhooks(c *fiber.Ctx) error {
+	subs, err := h.webhookService.GetSubscriptions(c.UserContext())
+	if err != nil {
+		return err
+	}
+	return c.JSON(NewWebhooksV1(subs))
+}
+
+func (h *WebhookHandlers) GetWebhook(c *fiber.Ctx) error {
+	id, err := webhookID(c)
+	if err != nil {
+		return err
+	}
+
+	sub, err := h.webhookService.GetSubscription(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(NewWebhookV1(*sub))
+}
+
+func (h *WebhookHandlers) UpdateWebhook(c *fiber.Ctx) error {
+	id, err := webhookID(c)
+	if err != nil {
+		return err
+	}
+
+	var req UpdateWebhookRequestV1
+	if err := bindBody(c, &req); err != nil {
+		return err
+	}
+
+	sub := &domain.WebhookSubscription{
+		SubscriptionID: id,
+		URL:            req.URL,
+		Events:         req.Events,
+		Active:         *req.Active,
+	}
+	if req.Secret != nil {
+		sub.Secret = *req.Secret
+	}
+
+	if err := h.webhookService.UpdateSubscription(c.UserContext(), sub); err != nil {
+		return err
+	}
+	return c.JSON(NewWebhookV1(*sub))
+}
+
+func (h *WebhookHandlers) DeleteWebhook(c *fiber.Ctx) error {
+	id, err := webhookID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.webhookService.DeleteSubscription(c.UserContext(), id); err != nil {
+		return err
+	}
+	return c.SendStatus(204)
+}
+
+func (h *WebhookHandlers) GetDeliveries(c *fiber.Ctx) error {
+	id, err := webhookID(c)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := h.webhookService.GetDeliveries(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(NewWebhookDeliveriesV1(deliveries))
+}
+
+func (h *WebhookHandlers) Redeliver(c *fiber.Ctx) error {
+	id, err := webhookID(c)
+	if err != nil {
+		return err
+	}
+	deliveryID, err := c.ParamsInt("deliveryId")
+	if err != nil || deliveryID <= 0 {
+		return errInvalidDeliveryID
+	}
+
+	delivery, err := h.webhookService.Redeliver(c.UserContext(), id, int64(deliveryID))
+	if err != nil {
+		return err
+	}
+	return c.Status(202).JSON(NewWebhookDeliveryV1(*delivery))
+}
+
+func webhookID(c *fiber.Ctx) (int64, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return 0, errInvalidWebhookID
+	}
+	return int64(id), nil
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+// MockWebhookService implements ports.WebhookService for testing
+type MockWebhookService struct {
+	mock.Mock
+}
+
+func (m *MockWebhookService) CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	args := m.Called(ctx, sub)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) GetSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookService) GetSubscription(ctx context.Context, id int64) (*domain.WebhookSubscription, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockWebhookService) UpdateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	args := m.Called(ctx, sub)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) DeleteSubscription(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) GetDeliveries(ctx context.Context, subscriptionID int64) ([]domain.WebhookDelivery, error) {
+	args := m.Called(ctx, subscriptionID)
+	return args.Get(0).([]domain.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookService) Redeliver(ctx context.Context, subscriptionID int64, deliveryID int64) (*domain.WebhookDelivery, error) {
+	args := m.Called(ctx, subscriptionID, deliveryID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.WebhookDelivery), args.Error(1)
+}
+
+func setupWebhookTest() (*fiber.App, *MockWebhookService) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	mockService := new(MockWebhookService)
+	handlers := NewWebhookHandlers(mockService)
+
+	app.Post("/v1/webhooks", handlers.CreateWebhook)
+	app.Get("/v1/webhooks", handlers.GetWebhooks)
+	app.Get("/v1/webhooks/:id", handlers.GetWebhook)
+	app.Put("/v1/webhooks/:id", handlers.UpdateWebhook)
+	app.Delete("/v1/webhooks/:id", handlers.DeleteWebhook)
+	app.Get("/v1/webhooks/:id/deliveries", handlers.GetDeliveries)
+	app.Post("/v1/webhooks/:id/deliveries/:deliveryId/redeliver", handlers.Redeliver)
+
+	return app, mockService
+}
+
+func TestCreateWebhook(t *testing.T) {
+	testCases := []struct {
+		name           string
+		requestBody    map[string]any
+		expectedStatus int
+	}{
+		{
+			name:           "Defaults",
+			requestBody:    map[string]any{"url": "https://example.com/hooks"},
+			expectedStatus: 201,
+		},
+		{
+			name: "Chosen events",
+			requestBody: map[string]any{
+				"url":    "https://example.com/hooks",
+				"events": []string{"order.completed"},
+			},
+			expectedStatus: 201,
+		},
+		{
+			name:           "Missing URL",
+			requestBody:    map[string]any{},
+			expectedStatus: 400,
+		},
+		{
+			name:           "Not a URL",
+			requestBody:    map[string]any{"url": "example.com/hooks"},
+			expectedStatus: 400,
+		},
+		{
+			name: "Unknown event",
+			requestBody: map[string]any{
+				"url":    "https://example.com/hooks",
+				"events": []string{"order.created"},
+			},
+			expectedStatus: 400,
+		},
+		{
+			name: "Short secret",
+			requestBody: map[string]any{
+				"url":    "https://example.com/hooks",
+				"secret": "short",
+			},
+			expectedStatus: 400,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app, mockService := setupWebhookTest()
+			mockService.On("CreateSubscription", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				sub := args.Get(1).(*domain.WebhookSubscription)
+				sub.SubscriptionID = 1
+				sub.Secret = "whsec_generated"
+				sub.Active = true
+			}).Return(nil)
+
+			jsonBody, _ := json.Marshal(tc.requestBody)
+			req := httptest.NewRequest("POST", "/v1/webhooks", bytes.NewBuffer(jsonBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := app.Test(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+			if tc.expectedStatus != 201 {
+				mockService.AssertNotCalled(t, "CreateSubscription", mock.Anything, mock.Anything)
+				return
+			}
+
+			var result WebhookV1
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+			assert.Equal(t, int64(1), result.ID)
+			assert.Equal(t, "whsec_generated", result.Secret)
+			assert.True(t, result.Active)
+		})
+	}
+}
+
+func TestGetWebhook_HidesSecret(t *testing.T) {
+	app, mockService := setupWebhookTest()
+	mockService.On("GetSubscription", mock.Anything, int64(1)).Return(&domain.WebhookSubscription{
+		SubscriptionID: 1,
+		URL:            "https://example.com/hooks",
+		Secret:         "whsec_generated",
+		Events:         []string{domain.OrderCompleted},
+		Active:         true,
+	}, nil)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/v1/webhooks/1", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var result map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.NotContains(t, result, "secret")
+	assert.Equal(t, "https://example.com/hooks", result["url"])
+}
+
+func TestGetWebhook_NotFound(t *testing.T) {
+	app, mockService := setupWebhookTest()
+	mockService.On("GetSubscription", mock.Anything, int64(9)).
+		Return(nil, domain.NewNotFoundError("webhook_not_found", "webhook 9 not found"))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/v1/webhooks/9", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 404, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/v1/webhooks/abc", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode)
+}
+
+func TestUpdateWebhook(t *testing.T) {
+	app, mockService := setupWebhookTest()
+	mockService.On("UpdateSubscription", mock.Anything, mock.MatchedBy(func(sub *domain.WebhookSubscription) bool {
+		return sub.SubscriptionID == 1 && !sub.Active && sub.Secret == ""
+	})).Return(nil)
+
+	jsonBody, _ := json.Marshal(map[string]any{"url": "https://example.com/hooks", "active": false})
+	req := httptest.NewRequest("PUT", "/v1/webhooks/1", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	mockService.AssertExpectations(t)
+
+	// Active must be given, so that omitting it doesn't disable the webhook.
+	jsonBody, _ = json.Marshal(map[string]any{"url": "https://example.com/hooks"})
+	req = httptest.NewRequest("PUT", "/v1/webhooks/1", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err = app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode)
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	app, mockService := setupWebhookTest()
+	mockService.On("DeleteSubscription", mock.Anything, int64(1)).Return(nil)
+
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/v1/webhooks/1", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 204, resp.StatusCode)
+	mockService.AssertExpectations(t)
+}
+
+func TestGetWebhookDeliveries(t *testing.T) {
+	app, mockService := setupWebhookTest()
+	fixedTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	responseStatus := 500
+	lastError := "webhook answered 500"
+
+	mockService.On("GetDeliveries", mock.Anything, int64(1)).Return([]domain.WebhookDelivery{
+		{
+			DeliveryID:     7,
+			SubscriptionID: 1,
+			EventID:        "evt-1",
+			EventType:      domain.OrderCompleted,
+			Status:         domain.DeliveryPending,
+			Attempts:       1,
+			ResponseStatus: &responseStatus,
+			LastError:      &lastError,
+			NextAttemptAt:  &fixedTime,
+			CreatedAt:      fixedTime,
+		},
+	}, nil)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/v1/webhooks/1/deliveries", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var result []map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, []map[string]any{
+		{
+			"id":             float64(7),
+			"eventId":        "evt-1",
+			"eventType":      "order.completed",
+			"status":         "PENDING",
+			"attempts":       float64(1),
+			"responseStatus": float64(500),
+			"lastError":      "webhook answered 500",
+			"nextAttemptAt":  "2024-01-01T00:00:00Z",
+			"createdAt":      "2024-01-01T00:00:00Z",
+			"deliveredAt":    nil,
+		},
+	}, result)
+}
+
+func TestRedeliverWebhook(t *testing.T) {
+	app, mockService := setupWebhookTest()
+	mockService.On("Redeliver", mock.Anything, int64(1), int64(7)).Return(&domain.WebhookDelivery{
+		DeliveryID:     8,
+		SubscriptionID: 1,
+		EventID:        "evt-1",
+		EventType:      domain.OrderCompleted,
+		Status:         domain.DeliveryPending,
+	}, nil)
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/v1/webhooks/1/deliveries/7/redeliver", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 202, resp.StatusCode)
+
+	var result WebhookDeliveryV1
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, int64(8), result.ID)
+	assert.Equal(t, "evt-1", result.EventID)
+
+	resp, err = app.Test(httptest.NewRequest("POST", "/v1/webhooks/1/deliveries/0/redeliver", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode)
+}
@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware_PropagatesRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+		status    int
+		err       error
+		level     string
+	}{
+		{name: "Reuses caller ID", requestID: "req-123", status: 200, level: "INFO"},
+		{name: "Generates ID", status: 200, level: "INFO"},
+		{name: "Client error", requestID: "req-456", status: 404, level: "WARN"},
+		{name: "Server error", requestID: "req-789", status: 500, level: "ERROR"},
+		{name: "Returned error", requestID: "req-012", status: 409, err: fiber.ErrConflict, level: "WARN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := New("info", "json", &buf)
+			require.NoError(t, err)
+
+			var handlerID string
+			app := fiber.New()
+			app.Use(Middleware(logger))
+			app.Get("/stocks", func(c *fiber.Ctx) error {
+				handlerID = RequestID(c.UserContext())
+				logger.InfoContext(c.UserContext(), "handled")
+				if tt.err != nil {
+					return tt.err
+				}
+				return c.SendStatus(tt.status)
+			})
+
+			req := httptest.NewRequest("GET", "/stocks", nil)
+			if tt.requestID != "" {
+				req.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+
+			id := resp.Header.Get(RequestIDHeader)
+			assert.NotEmpty(t, id)
+			if tt.requestID != "" {
+				assert.Equal(t, tt.requestID, id)
+			}
+			assert.Equal(t, id, handlerID)
+
+			lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+			require.Len(t, lines, 2)
+
+			var handled, access map[string]any
+			require.NoError(t, json.Unmarshal(lines[0], &handled))
+			require.NoError(t, json.Unmarshal(lines[1], &access))
+
+			assert.Equal(t, id, handled["request_id"])
+			assert.Equal(t, id, access["request_id"])
+			assert.Equal(t, tt.level, access["level"])
+			assert.Equal(t, "/stocks", access["route"])
+			assert.EqualValues(t, tt.status, access["status"])
+		})
+	}
+}
+
+func TestNew_RejectsInvalidOptions(t *testing.T) {
+	_, err := New("verbose", "json", &bytes.Buffer{})
+	assert.Error(t, err)
+
+	_, err = New("info", "xml", &bytes.Buffer{})
+	assert.Error(t, err)
+}
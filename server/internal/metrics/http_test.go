@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware_RecordsRoutePattern(t *testing.T) {
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Put("/transactions/:id/status", func(c *fiber.Ctx) error {
+		return c.SendStatus(200)
+	})
+
+	before := testutil.CollectAndCount(HTTPRequestDuration)
+
+	resp, err := app.Test(httptest.NewRequest("PUT", "/transactions/42/status", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("PUT", "/transactions/43/status", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	// Both requests share one series keyed by the route pattern.
+	assert.Equal(t, before+1, testutil.CollectAndCount(HTTPRequestDuration))
+}
+
+func TestHandler_ExposesMetrics(t *testing.T) {
+	app := fiber.New()
+	app.Get("/metrics", Handler())
+
+	OrdersCreated.WithLabelValues("BUY", "AAPL").Inc()
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `maxion_orders_created_total{symbol="AAPL",type="BUY"}`)
+	assert.Contains(t, string(body), "go_goroutines")
+}
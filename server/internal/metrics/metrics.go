@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "maxion"
+
+// Registry holds every collector exposed on /metrics. A dedicated registry
+// keeps tests and the exported series independent of global state in
+// third-party packages.
+var Registry = prometheus.NewRegistry()
+
+var (
+	OrdersCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_created_total",
+		Help:      "Orders accepted for processing, by transaction type and symbol.",
+	}, []string{"type", "symbol"})
+
+	OrderStatusTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "order_status_transitions_total",
+		Help:      "Requested order status changes, by target status.",
+	}, []string{"status"})
+
+	CacheSyncs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_sync_writes_total",
+		Help:      "Pending writes synced from Redis to the database, by operation and result.",
+	}, []string{"operation", "result"})
+
+	CacheSyncDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cache_sync_write_duration_seconds",
+		Help:      "Time taken to write a single pending write to the database.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	CachePendingWrites = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cache_pending_writes",
+		Help:      "Pending writes left in Redis after the last sync, by operation.",
+	}, []string{"operation"})
+
+	TransactionsCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transactions_cache_requests_total",
+		Help:      "Lookups of the cached transaction list, by result (hit or miss).",
+	}, []string{"result"})
+
+	QuoteCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quote_cache_requests_total",
+		Help:      "Stock quote lookups, by where they were served from (memory, store or database).",
+	}, []string{"source"})
+
+	StockUpdateDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "stock_update_duration_seconds",
+		Help:      "Time taken by a stock updater tick.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	StockUpdateErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stock_update_errors_total",
+		Help:      "Stock updater ticks that failed.",
+	})
+
+	Leader = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "leader",
+		Help:      "1 while this instance holds the leader lease and runs the background jobs.",
+	})
+
+	OutboxPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_events_published_total",
+		Help:      "Outbox events published, by sink and result.",
+	}, []string{"sink", "result"})
+
+	OutboxPending = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "outbox_pending_events",
+		Help:      "Outbox events that could not be published by the last relay run.",
+	})
+
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_delivery_attempts_total",
+		Help:      "Webhook delivery attempts, by result: succeeded, retrying or failed.",
+	}, []string{"result"})
+
+	AlertsFired = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "alerts_fired_total",
+		Help:      "Price alerts fired, by rule type.",
+	}, []string{"type"})
+
+	AlertsSuppressed = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "alerts_suppressed_total",
+		Help:      "Price alerts not fired because their rule fired too recently.",
+	})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by method, route pattern and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	GRPCRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "gRPC call latency, by full method name and status code. Streaming calls are observed when the stream ends.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	FIXMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fix_messages_total",
+		Help:      "FIX messages by direction (in or out) and MsgType.",
+	}, []string{"direction", "msg_type"})
+
+	RateLimitedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests refused by the rate limiter, by route and whether the client was identified by API key, IP or FIX CompID.",
+	}, []string{"route", "client"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		OrdersCreated,
+		OrderStatusTransitions,
+		CacheSyncs,
+		CacheSyncDuration,
+		CachePendingWrites,
+		TransactionsCacheRequests,
+		QuoteCacheRequests,
+		StockUpdateDuration,
+		StockUpdateErrors,
+		Leader,
+		OutboxPublished,
+		OutboxPending,
+		WebhookDeliveries,
+		AlertsFired,
+		AlertsSuppressed,
+		HTTPRequestDuration,
+		GRPCRequestDuration,
+		FIXMessages,
+		RateLimitedRequests,
+	)
+}
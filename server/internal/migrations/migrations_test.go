@@ -0,0 +1,158 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/config"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"gorm.io/gorm"
+)
+
+func setupMigrator(t *testing.T) (*Migrator, *gorm.DB) {
+	db, err := config.GetDatabaseConnection(config.DatabaseConfig{
+		Driver:            config.DriverSQLite,
+		Path:              ":memory:",
+		ConnectionTimeout: 5 * time.Second,
+	})
+	require.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := NewMigrator(db, config.DriverSQLite, logging.Discard())
+	require.NoError(t, err)
+	return migrator, db
+}
+
+func TestLoad_DriversShareVersions(t *testing.T) {
+	var expected []int64
+	for _, m := range mustLoad(t, config.DriverSQLServer) {
+		expected = append(expected, m.Version)
+	}
+	require.NotEmpty(t, expected)
+
+	for _, driver := range []string{config.DriverPostgres, config.DriverSQLite} {
+		t.Run(driver, func(t *testing.T) {
+			var versions []int64
+			for _, m := range mustLoad(t, driver) {
+				versions = append(versions, m.Version)
+			}
+			assert.Equal(t, expected, versions)
+		})
+	}
+}
+
+func TestLoad_UnknownDriver(t *testing.T) {
+	_, err := Load("mysql")
+	assert.Error(t, err)
+}
+
+func TestSplitBatches(t *testing.T) {
+	script := "CREATE TABLE A (Id INT);\nGO\n\nCREATE VIEW V AS SELECT * FROM A;\n  go  \nGO\nSELECT 'GO';\n"
+
+	assert.Equal(t, []string{
+		"CREATE TABLE A (Id INT);\n",
+		"\n\nCREATE VIEW V AS SELECT * FROM A;\n",
+		"\nSELECT 'GO';\n",
+	}, splitBatches(script))
+}
+
+func TestMigrator_UpAndDown(t *testing.T) {
+	migrator, db := setupMigrator(t)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, migrator.Check(ctx), ErrSchemaOutdated)
+
+	count, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(migrator.migrations), count)
+	assert.NoError(t, migrator.Check(ctx))
+	assert.True(t, db.Migrator().HasTable("Stocks"))
+
+	count, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, len(migrator.migrations))
+	assert.Equal(t, migrator.Latest(), statuses[len(statuses)-1].Version)
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt)
+	}
+
+	count, err = migrator.Down(ctx, len(migrator.migrations))
+	require.NoError(t, err)
+	assert.Equal(t, len(migrator.migrations), count)
+	assert.False(t, db.Migrator().HasTable("Stocks"))
+	assert.ErrorIs(t, migrator.Check(ctx), ErrSchemaOutdated)
+
+	// The down scripts must leave nothing behind that the up scripts create.
+	_, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+}
+
+func TestMigrator_FailedMigrationIsRolledBack(t *testing.T) {
+	migrator, db := setupMigrator(t)
+	ctx := context.Background()
+
+	migrator.migrations = append(migrator.migrations, Migration{
+		Version: migrator.Latest() + 1,
+		Name:    "broken",
+		up:      "CREATE TABLE Broken (Id INT);\nINSERT INTO Missing VALUES (1);",
+		down:    "DROP TABLE Broken;",
+	})
+
+	count, err := migrator.Up(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, len(migrator.migrations)-1, count)
+	assert.False(t, db.Migrator().HasTable("Broken"))
+	assert.ErrorIs(t, migrator.Check(ctx), ErrSchemaOutdated)
+}
+
+func TestMigrator_Baseline(t *testing.T) {
+	migrator, _ := setupMigrator(t)
+	ctx := context.Background()
+
+	assert.Error(t, migrator.Baseline(ctx, migrator.Latest()+1))
+
+	require.NoError(t, migrator.Baseline(ctx, migrator.Latest()))
+	assert.NoError(t, migrator.Check(ctx))
+
+	count, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestMigrator_AppliedByNewerServer(t *testing.T) {
+	migrator, db := setupMigrator(t)
+	ctx := context.Background()
+
+	_, err := migrator.Up(ctx)
+	require.NoError(t, err)
+
+	newer := migrator.Latest() + 1
+	require.NoError(t, db.Create(&schemaMigration{Version: newer, Name: "from_the_future", AppliedAt: time.Now()}).Error)
+
+	assert.NoError(t, migrator.Check(ctx))
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	last := statuses[len(statuses)-1]
+	assert.Equal(t, newer, last.Version)
+	assert.True(t, last.Unknown)
+
+	_, err = migrator.Down(ctx, 1)
+	assert.Error(t, err)
+}
+
+func mustLoad(t *testing.T, driver string) []Migration {
+	migrations, err := Load(driver)
+	require.NoError(t, err)
+	return migrations
+}
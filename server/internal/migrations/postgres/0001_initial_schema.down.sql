@@ -0,0 +1,15 @@
+DROP TABLE "FixSessions";
+DROP TABLE "FailedWrites";
+
+DROP VIEW "vw_TransactionDetails";
+DROP TABLE "Transactions";
+DROP FUNCTION transactions_completion();
+DROP TABLE "TransactionStatus";
+DROP TABLE "TransactionTypes";
+
+DROP VIEW "vw_StockChanges";
+DROP TABLE "Stocks";
+DROP FUNCTION stocks_log_changes();
+DROP FUNCTION stocks_versioning();
+DROP TABLE "StockChangeLog";
+DROP TABLE "StocksHistory";
@@ -0,0 +1,185 @@
+-- PostgreSQL schema, equivalent to the SQL Server one. Identifiers are
+-- quoted to keep the PascalCase names the repositories use.
+--
+-- PostgreSQL has no temporal tables, so Stocks history is kept by a trigger
+-- that copies the previous version of each updated row to StocksHistory.
+
+-- Create the Stocks table
+CREATE TABLE "Stocks" (
+    "StockId" SERIAL PRIMARY KEY,
+    "Symbol" VARCHAR(10) NOT NULL,
+    "BidPrice" NUMERIC(18,4) NOT NULL,
+    "BidVolume" INT NOT NULL,
+    "AskPrice" NUMERIC(18,4) NOT NULL,
+    "AskVolume" INT NOT NULL,
+    "LastUpdated" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX "IX_Stocks_Symbol" ON "Stocks"("Symbol");
+
+-- Previous versions of Stocks rows, valid from LastUpdated until ValidTo
+CREATE TABLE "StocksHistory" (
+    "StockId" INT NOT NULL,
+    "Symbol" VARCHAR(10) NOT NULL,
+    "BidPrice" NUMERIC(18,4) NOT NULL,
+    "BidVolume" INT NOT NULL,
+    "AskPrice" NUMERIC(18,4) NOT NULL,
+    "AskVolume" INT NOT NULL,
+    "LastUpdated" TIMESTAMPTZ NOT NULL,
+    "ValidTo" TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX "IX_StocksHistory_Period" ON "StocksHistory"("ValidTo", "LastUpdated");
+
+CREATE FUNCTION stocks_versioning() RETURNS trigger AS $$
+BEGIN
+    NEW."LastUpdated" := now();
+    INSERT INTO "StocksHistory" (
+        "StockId", "Symbol", "BidPrice", "BidVolume", "AskPrice", "AskVolume", "LastUpdated", "ValidTo"
+    )
+    VALUES (
+        OLD."StockId", OLD."Symbol", OLD."BidPrice", OLD."BidVolume", OLD."AskPrice", OLD."AskVolume",
+        OLD."LastUpdated", NEW."LastUpdated"
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tr_Stocks_Versioning
+BEFORE UPDATE ON "Stocks"
+FOR EACH ROW EXECUTE FUNCTION stocks_versioning();
+
+-- Create a view for the latest stock changes
+CREATE VIEW "vw_StockChanges" AS
+SELECT
+    s."Symbol",
+    s."BidPrice",
+    s."BidVolume",
+    s."AskPrice",
+    s."AskVolume",
+    s."LastUpdated"
+FROM "Stocks" s;
+
+-- Create log table for changes
+CREATE TABLE "StockChangeLog" (
+    "LogId" SERIAL PRIMARY KEY,
+    "Symbol" VARCHAR(10) NOT NULL,
+    "BidPrice" NUMERIC(18,4) NOT NULL,
+    "BidVolume" INT NOT NULL,
+    "AskPrice" NUMERIC(18,4) NOT NULL,
+    "AskVolume" INT NOT NULL,
+    "ChangeType" VARCHAR(10) NOT NULL,
+    "LoggedAt" TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE FUNCTION stocks_log_changes() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO "StockChangeLog" ("Symbol", "BidPrice", "BidVolume", "AskPrice", "AskVolume", "ChangeType")
+    VALUES (NEW."Symbol", NEW."BidPrice", NEW."BidVolume", NEW."AskPrice", NEW."AskVolume", TG_OP);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tr_Stocks_Changes
+AFTER INSERT OR UPDATE ON "Stocks"
+FOR EACH ROW EXECUTE FUNCTION stocks_log_changes();
+
+-- Create enum table for transaction types
+CREATE TABLE "TransactionTypes" (
+    "TypeId" INT PRIMARY KEY,
+    "TypeName" VARCHAR(20) NOT NULL UNIQUE
+);
+
+INSERT INTO "TransactionTypes" ("TypeId", "TypeName")
+VALUES
+    (1, 'BUY'),
+    (2, 'SELL');
+
+-- Create enum table for transaction status
+CREATE TABLE "TransactionStatus" (
+    "StatusId" INT PRIMARY KEY,
+    "StatusName" VARCHAR(20) NOT NULL UNIQUE
+);
+
+INSERT INTO "TransactionStatus" ("StatusId", "StatusName")
+VALUES
+    (1, 'PENDING'),
+    (2, 'COMPLETED'),
+    (3, 'CANCELLED'),
+    (4, 'FAILED');
+
+-- Create the main transactions table
+CREATE TABLE "Transactions" (
+    "TransactionId" BIGSERIAL PRIMARY KEY,
+    "Symbol" VARCHAR(10) NOT NULL,
+    "TypeId" INT NOT NULL,
+    "StatusId" INT NOT NULL,
+    "Quantity" INT NOT NULL,
+    "Price" NUMERIC(18,4) NOT NULL,
+    "TotalAmount" NUMERIC(18,4) NOT NULL,
+    "OrderTime" TIMESTAMPTZ DEFAULT now(),
+    "ExecutionTime" TIMESTAMPTZ,
+    "Notes" VARCHAR(500),
+    "ClientOrderId" VARCHAR(100),
+    CONSTRAINT "FK_Transactions_Stock" FOREIGN KEY ("Symbol") REFERENCES "Stocks"("Symbol"),
+    CONSTRAINT "FK_Transactions_Type" FOREIGN KEY ("TypeId") REFERENCES "TransactionTypes"("TypeId"),
+    CONSTRAINT "FK_Transactions_Status" FOREIGN KEY ("StatusId") REFERENCES "TransactionStatus"("StatusId"),
+    CONSTRAINT "CHK_Transactions_Quantity" CHECK ("Quantity" > 0),
+    CONSTRAINT "CHK_Transactions_Price" CHECK ("Price" > 0)
+);
+
+CREATE INDEX "IX_Transactions_Symbol" ON "Transactions"("Symbol");
+CREATE INDEX "IX_Transactions_OrderTime" ON "Transactions"("OrderTime");
+CREATE INDEX "IX_Transactions_Status" ON "Transactions"("StatusId");
+CREATE UNIQUE INDEX "IX_Transactions_ClientOrderId" ON "Transactions"("ClientOrderId") WHERE "ClientOrderId" IS NOT NULL;
+
+-- Create view for transaction details
+CREATE VIEW "vw_TransactionDetails" AS
+SELECT
+    t."TransactionId",
+    t."Symbol",
+    tt."TypeName" AS "TransactionType",
+    ts."StatusName" AS "Status",
+    t."Quantity",
+    t."Price",
+    t."TotalAmount",
+    t."OrderTime",
+    t."ExecutionTime",
+    t."Notes"
+FROM "Transactions" t
+JOIN "TransactionTypes" tt ON t."TypeId" = tt."TypeId"
+JOIN "TransactionStatus" ts ON t."StatusId" = ts."StatusId";
+
+-- Set ExecutionTime when status changes to COMPLETED
+CREATE FUNCTION transactions_completion() RETURNS trigger AS $$
+BEGIN
+    IF NEW."StatusId" = 2 AND OLD."StatusId" <> 2 THEN
+        NEW."ExecutionTime" := now();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tr_Transactions_Completion
+BEFORE UPDATE ON "Transactions"
+FOR EACH ROW EXECUTE FUNCTION transactions_completion();
+
+-- Create table for cached writes that could not be synced to the database
+CREATE TABLE "FailedWrites" (
+    "FailedWriteId" BIGSERIAL PRIMARY KEY,
+    "CacheKey" VARCHAR(200) NOT NULL,
+    "Operation" VARCHAR(20) NOT NULL,
+    "Payload" TEXT NOT NULL,
+    "Error" VARCHAR(1000) NOT NULL,
+    "FailedAt" TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX "IX_FailedWrites_FailedAt" ON "FailedWrites"("FailedAt");
+
+-- Create table for FIX session sequence numbers
+CREATE TABLE "FixSessions" (
+    "SessionId" VARCHAR(100) PRIMARY KEY,
+    "NextSenderSeqNum" INT NOT NULL,
+    "NextTargetSeqNum" INT NOT NULL,
+    "UpdatedAt" TIMESTAMPTZ NOT NULL
+);
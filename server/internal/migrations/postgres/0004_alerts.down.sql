@@ -0,0 +1,2 @@
+DROP TABLE "Alerts";
+DROP TABLE "AlertRules";
@@ -0,0 +1,27 @@
+-- Create tables for price alert rules and the alerts they fire
+CREATE TABLE "AlertRules" (
+    "RuleId" BIGSERIAL PRIMARY KEY,
+    "Symbol" VARCHAR(10) NOT NULL,
+    "Type" VARCHAR(20) NOT NULL,
+    "Side" VARCHAR(10) NOT NULL,
+    "Direction" VARCHAR(10) NOT NULL,
+    "Value" NUMERIC(18,4) NOT NULL,
+    "ReferencePrice" NUMERIC(18,4),
+    "CooldownSeconds" INT NOT NULL,
+    "LastFiredAt" TIMESTAMPTZ,
+    "CreatedAt" TIMESTAMPTZ NOT NULL,
+    CONSTRAINT "FK_AlertRules_Stock" FOREIGN KEY ("Symbol") REFERENCES "Stocks"("Symbol")
+);
+
+CREATE TABLE "Alerts" (
+    "AlertId" BIGSERIAL PRIMARY KEY,
+    "RuleId" BIGINT NOT NULL,
+    "Symbol" VARCHAR(10) NOT NULL,
+    "Type" VARCHAR(20) NOT NULL,
+    "Observed" NUMERIC(18,4) NOT NULL,
+    "Message" VARCHAR(200) NOT NULL,
+    "TriggeredAt" TIMESTAMPTZ NOT NULL,
+    CONSTRAINT "FK_Alerts_Rule" FOREIGN KEY ("RuleId") REFERENCES "AlertRules"("RuleId")
+);
+
+CREATE INDEX "IX_Alerts_Rule" ON "Alerts"("RuleId", "AlertId");
@@ -0,0 +1,12 @@
+DROP TABLE FixSessions;
+DROP TABLE FailedWrites;
+
+DROP VIEW vw_TransactionDetails;
+DROP TABLE Transactions;
+DROP TABLE TransactionStatus;
+DROP TABLE TransactionTypes;
+
+DROP VIEW vw_StockChanges;
+DROP TABLE Stocks;
+DROP TABLE StockChangeLog;
+DROP TABLE StocksHistory;
@@ -0,0 +1,183 @@
+-- SQLite schema, equivalent to the SQL Server one.
+--
+-- SQLite has no temporal tables, so Stocks history is kept by a trigger
+-- that copies the previous version of each updated row to StocksHistory.
+-- Times are stored as UTC text, which the driver reads back as time.Time.
+
+-- Create the Stocks table
+CREATE TABLE Stocks (
+    StockId INTEGER PRIMARY KEY AUTOINCREMENT,
+    Symbol VARCHAR(10) NOT NULL,
+    BidPrice NUMERIC(18,4) NOT NULL,
+    BidVolume INT NOT NULL,
+    AskPrice NUMERIC(18,4) NOT NULL,
+    AskVolume INT NOT NULL,
+    LastUpdated DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE UNIQUE INDEX IX_Stocks_Symbol ON Stocks(Symbol);
+
+-- Previous versions of Stocks rows, valid from LastUpdated until ValidTo
+CREATE TABLE StocksHistory (
+    StockId INTEGER NOT NULL,
+    Symbol VARCHAR(10) NOT NULL,
+    BidPrice NUMERIC(18,4) NOT NULL,
+    BidVolume INT NOT NULL,
+    AskPrice NUMERIC(18,4) NOT NULL,
+    AskVolume INT NOT NULL,
+    LastUpdated DATETIME NOT NULL,
+    ValidTo DATETIME NOT NULL
+);
+
+CREATE INDEX IX_StocksHistory_Period ON StocksHistory(ValidTo, LastUpdated);
+
+-- 'now' is fixed for the statement, so ValidTo and the new LastUpdated match.
+-- The inner UPDATE does not fire the trigger again, as recursive triggers are
+-- off by default.
+CREATE TRIGGER tr_Stocks_Versioning
+AFTER UPDATE ON Stocks
+FOR EACH ROW
+BEGIN
+    INSERT INTO StocksHistory (StockId, Symbol, BidPrice, BidVolume, AskPrice, AskVolume, LastUpdated, ValidTo)
+    VALUES (
+        OLD.StockId, OLD.Symbol, OLD.BidPrice, OLD.BidVolume, OLD.AskPrice, OLD.AskVolume,
+        OLD.LastUpdated, strftime('%Y-%m-%d %H:%M:%f', 'now')
+    );
+    UPDATE Stocks SET LastUpdated = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE StockId = NEW.StockId;
+END;
+
+-- Create a view for the latest stock changes
+CREATE VIEW vw_StockChanges AS
+SELECT
+    s.Symbol,
+    s.BidPrice,
+    s.BidVolume,
+    s.AskPrice,
+    s.AskVolume,
+    s.LastUpdated
+FROM Stocks s;
+
+-- Create log table for changes
+CREATE TABLE StockChangeLog (
+    LogId INTEGER PRIMARY KEY AUTOINCREMENT,
+    Symbol VARCHAR(10) NOT NULL,
+    BidPrice NUMERIC(18,4) NOT NULL,
+    BidVolume INT NOT NULL,
+    AskPrice NUMERIC(18,4) NOT NULL,
+    AskVolume INT NOT NULL,
+    ChangeType VARCHAR(10) NOT NULL,
+    LoggedAt DATETIME DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE TRIGGER tr_Stocks_Insert
+AFTER INSERT ON Stocks
+FOR EACH ROW
+BEGIN
+    INSERT INTO StockChangeLog (Symbol, BidPrice, BidVolume, AskPrice, AskVolume, ChangeType)
+    VALUES (NEW.Symbol, NEW.BidPrice, NEW.BidVolume, NEW.AskPrice, NEW.AskVolume, 'INSERT');
+END;
+
+CREATE TRIGGER tr_Stocks_Update
+AFTER UPDATE OF Symbol, BidPrice, BidVolume, AskPrice, AskVolume ON Stocks
+FOR EACH ROW
+BEGIN
+    INSERT INTO StockChangeLog (Symbol, BidPrice, BidVolume, AskPrice, AskVolume, ChangeType)
+    VALUES (NEW.Symbol, NEW.BidPrice, NEW.BidVolume, NEW.AskPrice, NEW.AskVolume, 'UPDATE');
+END;
+
+-- Create enum table for transaction types
+CREATE TABLE TransactionTypes (
+    TypeId INT PRIMARY KEY,
+    TypeName VARCHAR(20) NOT NULL UNIQUE
+);
+
+INSERT INTO TransactionTypes (TypeId, TypeName)
+VALUES
+    (1, 'BUY'),
+    (2, 'SELL');
+
+-- Create enum table for transaction status
+CREATE TABLE TransactionStatus (
+    StatusId INT PRIMARY KEY,
+    StatusName VARCHAR(20) NOT NULL UNIQUE
+);
+
+INSERT INTO TransactionStatus (StatusId, StatusName)
+VALUES
+    (1, 'PENDING'),
+    (2, 'COMPLETED'),
+    (3, 'CANCELLED'),
+    (4, 'FAILED');
+
+-- Create the main transactions table
+CREATE TABLE Transactions (
+    TransactionId INTEGER PRIMARY KEY AUTOINCREMENT,
+    Symbol VARCHAR(10) NOT NULL,
+    TypeId INT NOT NULL,
+    StatusId INT NOT NULL,
+    Quantity INT NOT NULL,
+    Price NUMERIC(18,4) NOT NULL,
+    TotalAmount NUMERIC(18,4) NOT NULL,
+    OrderTime DATETIME DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+    ExecutionTime DATETIME,
+    Notes VARCHAR(500),
+    ClientOrderId VARCHAR(100),
+    CONSTRAINT FK_Transactions_Stock FOREIGN KEY (Symbol) REFERENCES Stocks(Symbol),
+    CONSTRAINT FK_Transactions_Type FOREIGN KEY (TypeId) REFERENCES TransactionTypes(TypeId),
+    CONSTRAINT FK_Transactions_Status FOREIGN KEY (StatusId) REFERENCES TransactionStatus(StatusId),
+    CONSTRAINT CHK_Transactions_Quantity CHECK (Quantity > 0),
+    CONSTRAINT CHK_Transactions_Price CHECK (Price > 0)
+);
+
+CREATE INDEX IX_Transactions_Symbol ON Transactions(Symbol);
+CREATE INDEX IX_Transactions_OrderTime ON Transactions(OrderTime);
+CREATE INDEX IX_Transactions_Status ON Transactions(StatusId);
+CREATE UNIQUE INDEX IX_Transactions_ClientOrderId ON Transactions(ClientOrderId) WHERE ClientOrderId IS NOT NULL;
+
+-- Create view for transaction details
+CREATE VIEW vw_TransactionDetails AS
+SELECT
+    t.TransactionId,
+    t.Symbol,
+    tt.TypeName AS TransactionType,
+    ts.StatusName AS Status,
+    t.Quantity,
+    t.Price,
+    t.TotalAmount,
+    t.OrderTime,
+    t.ExecutionTime,
+    t.Notes
+FROM Transactions t
+JOIN TransactionTypes tt ON t.TypeId = tt.TypeId
+JOIN TransactionStatus ts ON t.StatusId = ts.StatusId;
+
+-- Set ExecutionTime when status changes to COMPLETED
+CREATE TRIGGER tr_Transactions_Completion
+AFTER UPDATE OF StatusId ON Transactions
+FOR EACH ROW
+WHEN NEW.StatusId = 2 AND OLD.StatusId <> 2
+BEGIN
+    UPDATE Transactions
+    SET ExecutionTime = strftime('%Y-%m-%d %H:%M:%f', 'now')
+    WHERE TransactionId = NEW.TransactionId;
+END;
+
+-- Create table for cached writes that could not be synced to the database
+CREATE TABLE FailedWrites (
+    FailedWriteId INTEGER PRIMARY KEY AUTOINCREMENT,
+    CacheKey VARCHAR(200) NOT NULL,
+    Operation VARCHAR(20) NOT NULL,
+    Payload TEXT NOT NULL,
+    Error VARCHAR(1000) NOT NULL,
+    FailedAt DATETIME DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE INDEX IX_FailedWrites_FailedAt ON FailedWrites(FailedAt);
+
+-- Create table for FIX session sequence numbers
+CREATE TABLE FixSessions (
+    SessionId VARCHAR(100) PRIMARY KEY,
+    NextSenderSeqNum INT NOT NULL,
+    NextTargetSeqNum INT NOT NULL,
+    UpdatedAt DATETIME NOT NULL
+);
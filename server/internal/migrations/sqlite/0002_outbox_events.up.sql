@@ -0,0 +1,10 @@
+-- Create table for order events awaiting publication by the outbox relay.
+-- Rows are written in the same transaction as the change they describe and
+-- deleted once published.
+CREATE TABLE OutboxEvents (
+    OutboxId INTEGER PRIMARY KEY AUTOINCREMENT,
+    AggregateId BIGINT NOT NULL,
+    EventType VARCHAR(50) NOT NULL,
+    Payload TEXT NOT NULL,
+    CreatedAt DATETIME NOT NULL
+);
@@ -0,0 +1,28 @@
+-- Create tables for webhook subscriptions and the deliveries of their events
+CREATE TABLE WebhookSubscriptions (
+    SubscriptionId INTEGER PRIMARY KEY AUTOINCREMENT,
+    Url VARCHAR(2000) NOT NULL,
+    Secret VARCHAR(200) NOT NULL,
+    Events VARCHAR(500) NOT NULL,
+    Active BOOLEAN NOT NULL,
+    CreatedAt DATETIME NOT NULL
+);
+
+CREATE TABLE WebhookDeliveries (
+    DeliveryId INTEGER PRIMARY KEY AUTOINCREMENT,
+    SubscriptionId BIGINT NOT NULL,
+    EventId VARCHAR(50) NOT NULL,
+    EventType VARCHAR(50) NOT NULL,
+    Payload TEXT NOT NULL,
+    Status VARCHAR(20) NOT NULL,
+    Attempts INT NOT NULL,
+    NextAttemptAt DATETIME,
+    ResponseStatus INT,
+    LastError VARCHAR(1000),
+    CreatedAt DATETIME NOT NULL,
+    DeliveredAt DATETIME,
+    CONSTRAINT FK_WebhookDeliveries_Subscription FOREIGN KEY (SubscriptionId) REFERENCES WebhookSubscriptions(SubscriptionId)
+);
+
+CREATE INDEX IX_WebhookDeliveries_Due ON WebhookDeliveries(Status, NextAttemptAt);
+CREATE INDEX IX_WebhookDeliveries_Subscription ON WebhookDeliveries(SubscriptionId, DeliveryId);
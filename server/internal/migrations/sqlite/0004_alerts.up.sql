@@ -0,0 +1,27 @@
+-- Create tables for price alert rules and the alerts they fire
+CREATE TABLE AlertRules (
+    RuleId INTEGER PRIMARY KEY AUTOINCREMENT,
+    Symbol VARCHAR(10) NOT NULL,
+    Type VARCHAR(20) NOT NULL,
+    Side VARCHAR(10) NOT NULL,
+    Direction VARCHAR(10) NOT NULL,
+    Value DECIMAL(18,4) NOT NULL,
+    ReferencePrice DECIMAL(18,4),
+    CooldownSeconds INT NOT NULL,
+    LastFiredAt DATETIME,
+    CreatedAt DATETIME NOT NULL,
+    CONSTRAINT FK_AlertRules_Stock FOREIGN KEY (Symbol) REFERENCES Stocks(Symbol)
+);
+
+CREATE TABLE Alerts (
+    AlertId INTEGER PRIMARY KEY AUTOINCREMENT,
+    RuleId BIGINT NOT NULL,
+    Symbol VARCHAR(10) NOT NULL,
+    Type VARCHAR(20) NOT NULL,
+    Observed DECIMAL(18,4) NOT NULL,
+    Message VARCHAR(200) NOT NULL,
+    TriggeredAt DATETIME NOT NULL,
+    CONSTRAINT FK_Alerts_Rule FOREIGN KEY (RuleId) REFERENCES AlertRules(RuleId)
+);
+
+CREATE INDEX IX_Alerts_Rule ON Alerts(RuleId, AlertId);
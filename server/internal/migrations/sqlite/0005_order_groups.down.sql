@@ -0,0 +1,8 @@
+DROP INDEX IX_Transactions_Group;
+
+ALTER TABLE Transactions DROP COLUMN Leg;
+ALTER TABLE Transactions DROP COLUMN GroupId;
+
+-- Held orders have no status to go back to.
+UPDATE Transactions SET StatusId = 3 WHERE StatusId = 5;
+DELETE FROM TransactionStatus WHERE StatusId = 5;
@@ -0,0 +1,19 @@
+DROP TABLE FixSessions;
+DROP TABLE FailedWrites;
+GO
+
+DROP VIEW vw_TransactionDetails;
+DROP TABLE Transactions;
+DROP TABLE TransactionStatus;
+DROP TABLE TransactionTypes;
+GO
+
+DROP VIEW vw_StockChanges;
+DROP TABLE StockChangeLog;
+GO
+
+-- A temporal table can only be dropped once versioning is off
+ALTER TABLE Stocks SET (SYSTEM_VERSIONING = OFF);
+DROP TABLE Stocks;
+DROP TABLE StocksHistory;
+GO
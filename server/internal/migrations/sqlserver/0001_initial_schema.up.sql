@@ -1,10 +1,3 @@
--- Create the database
-CREATE DATABASE TradingBot;
-GO
-
-USE TradingBot;
-GO
-
 -- Create the Stocks table with temporal support
 CREATE TABLE Stocks (
     StockId INT IDENTITY(1,1) PRIMARY KEY,
@@ -124,6 +117,7 @@ CREATE TABLE Transactions (
     OrderTime DATETIME2(7) DEFAULT GETUTCDATE(),
     ExecutionTime DATETIME2(7),
     Notes NVARCHAR(500),
+    ClientOrderId VARCHAR(100),
     CONSTRAINT FK_Transactions_Stock FOREIGN KEY (Symbol) REFERENCES Stocks(Symbol),
     CONSTRAINT FK_Transactions_Type FOREIGN KEY (TypeId) REFERENCES TransactionTypes(TypeId),
     CONSTRAINT FK_Transactions_Status FOREIGN KEY (StatusId) REFERENCES TransactionStatus(StatusId),
@@ -136,6 +130,7 @@ GO
 CREATE INDEX IX_Transactions_Symbol ON Transactions(Symbol);
 CREATE INDEX IX_Transactions_OrderTime ON Transactions(OrderTime);
 CREATE INDEX IX_Transactions_Status ON Transactions(StatusId);
+CREATE UNIQUE INDEX IX_Transactions_ClientOrderId ON Transactions(ClientOrderId) WHERE ClientOrderId IS NOT NULL;
 GO
 
 -- Create view for transaction details
@@ -180,4 +175,26 @@ BEGIN
         WHERE i.StatusId = 2;
     END
 END;
-GO
\ No newline at end of file
+GO
+-- Create table for cached writes that could not be synced to the database
+CREATE TABLE FailedWrites (
+    FailedWriteId BIGINT IDENTITY(1,1) PRIMARY KEY,
+    CacheKey VARCHAR(200) NOT NULL,
+    Operation VARCHAR(20) NOT NULL,
+    Payload NVARCHAR(MAX) NOT NULL,
+    Error NVARCHAR(1000) NOT NULL,
+    FailedAt DATETIME2(7) DEFAULT GETUTCDATE()
+);
+GO
+
+CREATE INDEX IX_FailedWrites_FailedAt ON FailedWrites(FailedAt);
+GO
+
+-- Create table for FIX session sequence numbers
+CREATE TABLE FixSessions (
+    SessionId VARCHAR(100) PRIMARY KEY,
+    NextSenderSeqNum INT NOT NULL,
+    NextTargetSeqNum INT NOT NULL,
+    UpdatedAt DATETIME2(7) NOT NULL
+);
+GO
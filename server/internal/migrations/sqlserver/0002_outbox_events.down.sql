@@ -0,0 +1,2 @@
+DROP TABLE OutboxEvents;
+GO
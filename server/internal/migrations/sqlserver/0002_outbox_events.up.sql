@@ -0,0 +1,11 @@
+-- Create table for order events awaiting publication by the outbox relay.
+-- Rows are written in the same transaction as the change they describe and
+-- deleted once published.
+CREATE TABLE OutboxEvents (
+    OutboxId BIGINT IDENTITY(1,1) PRIMARY KEY,
+    AggregateId BIGINT NOT NULL,
+    EventType VARCHAR(50) NOT NULL,
+    Payload NVARCHAR(MAX) NOT NULL,
+    CreatedAt DATETIME2(7) NOT NULL
+);
+GO
@@ -0,0 +1,3 @@
+DROP TABLE WebhookDeliveries;
+DROP TABLE WebhookSubscriptions;
+GO
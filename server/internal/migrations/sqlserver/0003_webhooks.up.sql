@@ -0,0 +1,31 @@
+-- Create tables for webhook subscriptions and the deliveries of their events
+CREATE TABLE WebhookSubscriptions (
+    SubscriptionId BIGINT IDENTITY(1,1) PRIMARY KEY,
+    Url NVARCHAR(2000) NOT NULL,
+    Secret VARCHAR(200) NOT NULL,
+    Events VARCHAR(500) NOT NULL,
+    Active BIT NOT NULL,
+    CreatedAt DATETIME2(7) NOT NULL
+);
+GO
+
+CREATE TABLE WebhookDeliveries (
+    DeliveryId BIGINT IDENTITY(1,1) PRIMARY KEY,
+    SubscriptionId BIGINT NOT NULL,
+    EventId VARCHAR(50) NOT NULL,
+    EventType VARCHAR(50) NOT NULL,
+    Payload NVARCHAR(MAX) NOT NULL,
+    Status VARCHAR(20) NOT NULL,
+    Attempts INT NOT NULL,
+    NextAttemptAt DATETIME2(7),
+    ResponseStatus INT,
+    LastError NVARCHAR(1000),
+    CreatedAt DATETIME2(7) NOT NULL,
+    DeliveredAt DATETIME2(7),
+    CONSTRAINT FK_WebhookDeliveries_Subscription FOREIGN KEY (SubscriptionId) REFERENCES WebhookSubscriptions(SubscriptionId)
+);
+GO
+
+CREATE INDEX IX_WebhookDeliveries_Due ON WebhookDeliveries(Status, NextAttemptAt);
+CREATE INDEX IX_WebhookDeliveries_Subscription ON WebhookDeliveries(SubscriptionId, DeliveryId);
+GO
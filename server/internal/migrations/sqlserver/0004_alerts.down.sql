@@ -0,0 +1,3 @@
+DROP TABLE Alerts;
+DROP TABLE AlertRules;
+GO
@@ -0,0 +1,30 @@
+-- Create tables for price alert rules and the alerts they fire
+CREATE TABLE AlertRules (
+    RuleId BIGINT IDENTITY(1,1) PRIMARY KEY,
+    Symbol VARCHAR(10) NOT NULL,
+    Type VARCHAR(20) NOT NULL,
+    Side VARCHAR(10) NOT NULL,
+    Direction VARCHAR(10) NOT NULL,
+    Value DECIMAL(18,4) NOT NULL,
+    ReferencePrice DECIMAL(18,4),
+    CooldownSeconds INT NOT NULL,
+    LastFiredAt DATETIME2(7),
+    CreatedAt DATETIME2(7) NOT NULL,
+    CONSTRAINT FK_AlertRules_Stock FOREIGN KEY (Symbol) REFERENCES Stocks(Symbol)
+);
+GO
+
+CREATE TABLE Alerts (
+    AlertId BIGINT IDENTITY(1,1) PRIMARY KEY,
+    RuleId BIGINT NOT NULL,
+    Symbol VARCHAR(10) NOT NULL,
+    Type VARCHAR(20) NOT NULL,
+    Observed DECIMAL(18,4) NOT NULL,
+    Message NVARCHAR(200) NOT NULL,
+    TriggeredAt DATETIME2(7) NOT NULL,
+    CONSTRAINT FK_Alerts_Rule FOREIGN KEY (RuleId) REFERENCES AlertRules(RuleId)
+);
+GO
+
+CREATE INDEX IX_Alerts_Rule ON Alerts(RuleId, AlertId);
+GO
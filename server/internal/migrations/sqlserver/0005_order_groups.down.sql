@@ -0,0 +1,10 @@
+DROP INDEX IX_Transactions_Group ON Transactions;
+GO
+
+ALTER TABLE Transactions DROP COLUMN Leg, GroupId;
+GO
+
+-- Held orders have no status to go back to.
+UPDATE Transactions SET StatusId = 3 WHERE StatusId = 5;
+DELETE FROM TransactionStatus WHERE StatusId = 5;
+GO
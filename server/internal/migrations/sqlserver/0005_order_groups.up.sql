@@ -0,0 +1,13 @@
+-- Add bracket and OCO order groups. The orders of a group share a GroupId
+-- and each has a Leg; exit legs wait as HELD until their entry order fills.
+INSERT INTO TransactionStatus (StatusId, StatusName)
+VALUES (5, 'HELD');
+GO
+
+ALTER TABLE Transactions ADD
+    GroupId VARCHAR(36) NULL,
+    Leg VARCHAR(20) NULL;
+GO
+
+CREATE INDEX IX_Transactions_Group ON Transactions(GroupId) WHERE GroupId IS NOT NULL;
+GO
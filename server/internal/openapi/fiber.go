@@ -0,0 +1,85 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+
+	"github.com/gofiber/fiber/v2"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// Handler serves the document as JSON. It is marshalled once up front.
+func Handler(doc *Document) fiber.Handler {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		panic(fmt.Sprintf("openapi: failed to marshal document: %v", err))
+	}
+
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(body)
+	}
+}
+
+// UIHandler serves a Swagger UI page for the document at specURL. The UI
+// assets are loaded from assetsURL, where UIAssetHandler serves them, so
+// that no third-party script runs on the API's origin.
+func UIHandler(title string, specURL string, assetsURL string) fiber.Handler {
+	page := fmt.Sprintf(swaggerUIPage, title, assetsURL, assetsURL, specURL)
+
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.SendString(page)
+	}
+}
+
+// uiAssets are the Swagger UI files the page loads, by name, with their
+// content types.
+var uiAssets = map[string]string{
+	"swagger-ui.css":       "text/css; charset=utf-8",
+	"swagger-ui-bundle.js": "text/javascript; charset=utf-8",
+}
+
+// UIAssetHandler serves the Swagger UI assets embedded in the binary, named
+// by the :asset route parameter. They are read once up front.
+func UIAssetHandler() fiber.Handler {
+	bodies := make(map[string][]byte, len(uiAssets))
+	for name := range uiAssets {
+		body, err := fs.ReadFile(swaggerFiles.FS, name)
+		if err != nil {
+			panic(fmt.Sprintf("openapi: failed to read %s: %v", name, err))
+		}
+		bodies[name] = body
+	}
+
+	return func(c *fiber.Ctx) error {
+		name := c.Params("asset")
+		body, ok := bodies[name]
+		if !ok {
+			return fiber.ErrNotFound
+		}
+		c.Set(fiber.HeaderContentType, uiAssets[name])
+		c.Set(fiber.HeaderCacheControl, "public, max-age=86400")
+		return c.Send(body)
+	}
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>%s</title>
+  <link rel="stylesheet" href="%s/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="%s/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: %q, dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
@@ -0,0 +1,362 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	jsonContentType    = "application/json"
+	problemContentType = "application/problem+json"
+)
+
+// Route describes one HTTP endpoint. Request and response bodies are given
+// as sample values whose Go types the schemas are derived from.
+type Route struct {
+	Method string
+	// Path uses Fiber syntax, e.g. "/transactions/:id/status".
+	Path       string
+	Summary    string
+	Tag        string
+	Deprecated bool
+	// Request is a value of the request body type, or nil for none.
+	Request any
+	// Responses maps status codes to a value of the response body type, or
+	// nil for an empty body.
+	Responses map[int]any
+	// ContentType overrides the content type of successful responses.
+	ContentType string
+}
+
+// Generator builds a Document from routes, deriving JSON schemas from Go
+// types by reflection in the same way encoding/json serialises them.
+type Generator struct {
+	doc     *Document
+	enums   map[reflect.Type][]any
+	names   map[reflect.Type]string
+	problem reflect.Type
+}
+
+func NewGenerator(info Info) *Generator {
+	return &Generator{
+		doc: &Document{
+			OpenAPI:    "3.0.3",
+			Info:       info,
+			Paths:      make(map[string]PathItem),
+			Components: Components{Schemas: make(map[string]*Schema)},
+		},
+		enums: make(map[reflect.Type][]any),
+		names: make(map[reflect.Type]string),
+	}
+}
+
+// Enum records the allowed values of sample's type, which has no other way
+// of expressing them through reflection.
+func (g *Generator) Enum(sample any, values ...any) {
+	g.enums[reflect.TypeOf(sample)] = values
+}
+
+// Problem marks sample's type as the error body, which is documented as
+// application/problem+json.
+func (g *Generator) Problem(sample any) {
+	g.problem = reflect.TypeOf(sample)
+}
+
+func (g *Generator) Add(route Route) {
+	path, params := convertPath(route.Path)
+
+	op := &Operation{
+		OperationID: operationID(route.Method, route.Path),
+		Summary:     route.Summary,
+		Deprecated:  route.Deprecated,
+		Responses:   make(map[string]Response),
+	}
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+	}
+	for _, name := range params {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+
+	if route.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				jsonContentType: {Schema: g.schemaFor(reflect.TypeOf(route.Request))},
+			},
+		}
+	}
+
+	for status, body := range route.Responses {
+		response := Response{Description: http.StatusText(status)}
+		if body != nil {
+			contentType := jsonContentType
+			if reflect.TypeOf(body) == g.problem {
+				contentType = problemContentType
+			} else if route.ContentType != "" {
+				contentType = route.ContentType
+			}
+			response.Content = map[string]MediaType{
+				contentType: {Schema: g.schemaFor(reflect.TypeOf(body))},
+			}
+		}
+		op.Responses[strconv.Itoa(status)] = response
+	}
+
+	item, ok := g.doc.Paths[path]
+	if !ok {
+		item = make(PathItem)
+		g.doc.Paths[path] = item
+	}
+	item[strings.ToLower(route.Method)] = op
+}
+
+func (g *Generator) Document() *Document {
+	return g.doc
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *Generator) schemaFor(t reflect.Type) *Schema {
+	if values, ok := g.enums[t]; ok {
+		schema := g.primitive(t)
+		schema.Enum = values
+		return schema
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := g.schemaFor(t.Elem())
+		if schema.Ref != "" {
+			// $ref siblings are ignored in OpenAPI 3.0, so nullable refs are
+			// left as plain refs.
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return g.objectSchema(t)
+		}
+		return g.ref(t)
+	case reflect.Interface:
+		return &Schema{}
+	default:
+		return g.primitive(t)
+	}
+}
+
+func (g *Generator) primitive(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	default:
+		panic(fmt.Sprintf("openapi: unsupported type %s", t))
+	}
+}
+
+// ref registers t as a component schema and returns a reference to it.
+func (g *Generator) ref(t reflect.Type) *Schema {
+	name, ok := g.names[t]
+	if !ok {
+		name = g.componentName(t)
+		g.names[t] = name
+		// Register before recursing so self-referencing types terminate.
+		g.doc.Components.Schemas[name] = &Schema{}
+		*g.doc.Components.Schemas[name] = *g.objectSchema(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (g *Generator) componentName(t reflect.Type) string {
+	name := t.Name()
+	if _, taken := g.doc.Components.Schemas[name]; !taken {
+		return name
+	}
+	// Disambiguate same-named types from different packages.
+	pkg := t.PkgPath()
+	prefix := pkg[strings.LastIndex(pkg, "/")+1:]
+	return strings.ToUpper(prefix[:1]) + prefix[1:] + name
+}
+
+func (g *Generator) objectSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(schema, t)
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func (g *Generator) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		omitEmpty := strings.Contains(opts, "omitempty")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(schema, embedded)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := g.schemaFor(field.Type)
+		applyValidation(property, field.Tag.Get("validate"))
+		schema.Properties[name] = property
+
+		if !omitEmpty && field.Type.Kind() != reflect.Pointer {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// applyValidation mirrors the validator rules that have a JSON schema
+// equivalent, so the documented constraints match the enforced ones.
+func applyValidation(schema *Schema, tag string) {
+	if tag == "" || schema.Ref != "" {
+		return
+	}
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "oneof":
+			schema.Enum = nil
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, parseValue(schema, value))
+			}
+		case "max", "lte":
+			if schema.Type == "string" {
+				schema.MaxLength = intPtr(param)
+			} else {
+				schema.Maximum = floatPtr(param)
+			}
+		case "min", "gte":
+			if schema.Type == "string" {
+				schema.MinLength = intPtr(param)
+			} else {
+				schema.Minimum = floatPtr(param)
+			}
+		case "gt":
+			schema.Minimum = floatPtr(param)
+			schema.ExclusiveMinimum = true
+		case "required":
+			if schema.Type == "string" && schema.MinLength == nil {
+				one := 1
+				schema.MinLength = &one
+			}
+		}
+	}
+}
+
+func parseValue(schema *Schema, value string) any {
+	switch schema.Type {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
+
+func intPtr(value string) *int {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+func floatPtr(value string) *float64 {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+// convertPath turns Fiber's ":name" parameters into OpenAPI "{name}" ones.
+func convertPath(path string) (string, []string) {
+	var params []string
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			name := strings.TrimSuffix(segment[1:], "?")
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationID derives a stable camelCase ID such as "putTransactionsIdStatus".
+func operationID(method string, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	upperNext := true
+	for _, r := range path {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upperNext = true
+			continue
+		}
+		if upperNext {
+			r = unicode.ToUpper(r)
+			upperNext = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// PathFor converts a Fiber route path to its OpenAPI form.
+func PathFor(fiberPath string) string {
+	path, _ := convertPath(fiberPath)
+	return path
+}
@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// apiKeyMetadataKey is APIKeyHeader as gRPC metadata, whose keys are lower
+// case.
+var apiKeyMetadataKey = strings.ToLower(APIKeyHeader)
+
+// Method is the quota of a gRPC method. Route names the bucket, so a method
+// given the route of its REST counterpart shares that route's quota.
+type Method struct {
+	Route string
+	Rule  Rule
+}
+
+// Proxies describes the reverse proxies in front of the server. Requests
+// arriving from one of Trusted are counted by the client address the proxy
+// reports in Header rather than by the proxy's own.
+type Proxies struct {
+	Header string
+	// Trusted lists proxy IP addresses or CIDR ranges.
+	Trusted []string
+}
+
+// UnaryServerInterceptor is the gRPC counterpart of Middleware for the calls
+// to methods, keyed by full method name; other calls are not limited. The
+// API key is read from the x-api-key metadata and the IP address from the
+// peer, or from proxies' header. The RateLimit headers are sent as response
+// metadata, and refused calls fail with a rate limited error.
+func UnaryServerInterceptor(limiter *Limiter, methods map[string]Method, apiKeys []string, proxies Proxies, logger *slog.Logger) grpc.UnaryServerInterceptor {
+	check := newMethodCheck(limiter, methods, apiKeys, proxies, logger)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := check(ctx, info.FullMethod, func(md metadata.MD) error { return grpc.SetHeader(ctx, md) }); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streams, counting
+// each stream opened to methods as one request.
+func StreamServerInterceptor(limiter *Limiter, methods map[string]Method, apiKeys []string, proxies Proxies, logger *slog.Logger) grpc.StreamServerInterceptor {
+	check := newMethodCheck(limiter, methods, apiKeys, proxies, logger)
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := check(ss.Context(), info.FullMethod, ss.SetHeader); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// newMethodCheck returns the check shared by the interceptors, which counts
+// a call to fullMethod and sets the RateLimit headers with setHeader.
+func newMethodCheck(limiter *Limiter, methods map[string]Method, apiKeys []string, proxies Proxies, logger *slog.Logger) func(ctx context.Context, fullMethod string, setHeader func(metadata.MD) error) error {
+	known := knownKeys(apiKeys)
+	header := strings.ToLower(proxies.Header)
+	trusted := parsePrefixes(proxies.Trusted)
+
+	return func(ctx context.Context, fullMethod string, setHeader func(metadata.MD) error) error {
+		method, ok := methods[fullMethod]
+		if !ok {
+			return nil
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		q := newQuota(method.Route, method.Rule, known, peerIP(ctx, md, header, trusted), first(md, apiKeyMetadataKey))
+		if q.limit <= 0 {
+			return nil
+		}
+
+		result, counted := q.count(ctx, limiter, logger)
+		if !counted {
+			return nil
+		}
+
+		reply := metadata.Pairs(
+			"ratelimit-limit", strconv.Itoa(result.Limit),
+			"ratelimit-remaining", strconv.Itoa(result.Remaining),
+			"ratelimit-reset", resetSeconds(result),
+			"ratelimit-policy", q.policy(),
+		)
+		if !result.Allowed {
+			reply.Set("retry-after", resetSeconds(result))
+		}
+		if err := setHeader(reply); err != nil {
+			logger.WarnContext(ctx, "failed to set rate limit metadata", "error", err)
+		}
+		if !result.Allowed {
+			return q.refused()
+		}
+		return nil
+	}
+}
+
+// peerIP returns the address of the client, taken from header when the call
+// comes from a trusted proxy.
+func peerIP(ctx context.Context, md metadata.MD, header string, trusted []netip.Prefix) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	ip := p.Addr.String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	if header == "" {
+		return ip
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !contains(trusted, addr.Unmap()) {
+		return ip
+	}
+	// X-Forwarded-For style headers list the client first.
+	forwarded, _, _ := strings.Cut(first(md, header), ",")
+	if client, err := netip.ParseAddr(strings.TrimSpace(forwarded)); err == nil {
+		return client.String()
+	}
+	return ip
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// parsePrefixes parses IP addresses and CIDR ranges, skipping invalid ones,
+// which the config rejects.
+func parsePrefixes(values []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(value); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		}
+	}
+	return prefixes
+}
+
+func contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
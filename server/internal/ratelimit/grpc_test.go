@@ -0,0 +1,162 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const placeOrder = "/maxion.v1.TradingService/PlaceOrder"
+
+// headerStream records the response metadata set by an interceptor.
+type headerStream struct {
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return placeOrder }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *headerStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *headerStream) SetTrailer(metadata.MD) error { return nil }
+
+func setupInterceptor(t *testing.T, rule Rule, proxies Proxies) grpc.UnaryServerInterceptor {
+	return UnaryServerInterceptor(NewLimiter(setupRedis(t)), map[string]Method{
+		placeOrder: {Route: "POST /orders", Rule: rule},
+	}, []string{"desk-key"}, proxies, logging.Discard())
+}
+
+// call invokes interceptor for method as if from addr, with md as the
+// request metadata.
+func call(interceptor grpc.UnaryServerInterceptor, method string, addr string, md metadata.MD) (metadata.MD, error) {
+	stream := &headerStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(addr), Port: 50000}})
+	ctx = metadata.NewIncomingContext(ctx, md)
+
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	})
+	return stream.header, err
+}
+
+func TestUnaryServerInterceptor_Limits(t *testing.T) {
+	interceptor := setupInterceptor(t, Rule{PerIP: 1, PerAPIKey: 5, Window: time.Minute}, Proxies{})
+
+	header, err := call(interceptor, placeOrder, "203.0.113.7", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, header.Get("ratelimit-limit"))
+	assert.Equal(t, []string{"0"}, header.Get("ratelimit-remaining"))
+	assert.Equal(t, []string{"1;w=60"}, header.Get("ratelimit-policy"))
+
+	header, err = call(interceptor, placeOrder, "203.0.113.7", nil)
+	kind, _ := domain.ErrorKindOf(err)
+	assert.Equal(t, domain.KindRateLimited, kind)
+	assert.Equal(t, []string{"60"}, header.Get("retry-after"))
+
+	_, err = call(interceptor, placeOrder, "203.0.113.7", metadata.Pairs("x-api-key", "desk-key"))
+	assert.NoError(t, err, "a known key has its own quota")
+
+	header, err = call(interceptor, "/maxion.v1.TradingService/ListOrders", "203.0.113.7", nil)
+	assert.NoError(t, err, "unlisted methods are not limited")
+	assert.Empty(t, header)
+}
+
+func TestUnaryServerInterceptor_Proxies(t *testing.T) {
+	testCases := []struct {
+		name string
+		peer string
+		// expectedLimited says whether the second client behind peer is
+		// refused because both are counted by the peer's address.
+		expectedLimited bool
+	}{
+		{name: "Trusted proxy reports the client", peer: "10.1.2.3", expectedLimited: false},
+		{name: "Untrusted peer is counted itself", peer: "198.51.100.9", expectedLimited: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			interceptor := setupInterceptor(t, Rule{PerIP: 1, Window: time.Minute}, Proxies{
+				Header:  "X-Forwarded-For",
+				Trusted: []string{"10.0.0.0/8"},
+			})
+
+			_, err := call(interceptor, placeOrder, tc.peer, metadata.Pairs("x-forwarded-for", "203.0.113.7, 10.1.2.3"))
+			require.NoError(t, err)
+			_, err = call(interceptor, placeOrder, tc.peer, metadata.Pairs("x-forwarded-for", "203.0.113.8, 10.1.2.3"))
+
+			assert.Equal(t, tc.expectedLimited, err != nil)
+		})
+	}
+}
+
+// serverStream is a stream opened from 203.0.113.7.
+type serverStream struct {
+	grpc.ServerStream
+	headerStream
+}
+
+func (s *serverStream) Context() context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 50000}})
+}
+
+func (s *serverStream) SetHeader(md metadata.MD) error { return s.headerStream.SetHeader(md) }
+
+func (s *serverStream) SendHeader(md metadata.MD) error { return s.headerStream.SendHeader(md) }
+
+func (s *serverStream) SetTrailer(md metadata.MD) { _ = s.headerStream.SetTrailer(md) }
+
+func TestStreamServerInterceptor_Limits(t *testing.T) {
+	const streamQuotes = "/maxion.v1.TradingService/StreamQuotes"
+	interceptor := StreamServerInterceptor(NewLimiter(setupRedis(t)), map[string]Method{
+		streamQuotes: {Route: "GET /stocks", Rule: Rule{PerIP: 1, Window: time.Minute}},
+	}, nil, Proxies{}, logging.Discard())
+	open := func(method string) (*serverStream, bool, error) {
+		stream := &serverStream{}
+		opened := false
+		err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: method, IsServerStream: true}, func(any, grpc.ServerStream) error {
+			opened = true
+			return nil
+		})
+		return stream, opened, err
+	}
+
+	stream, opened, err := open(streamQuotes)
+	require.NoError(t, err)
+	assert.True(t, opened)
+	assert.Equal(t, []string{"0"}, stream.header.Get("ratelimit-remaining"))
+
+	stream, opened, err = open(streamQuotes)
+	kind, _ := domain.ErrorKindOf(err)
+	assert.Equal(t, domain.KindRateLimited, kind)
+	assert.False(t, opened)
+	assert.Equal(t, []string{"60"}, stream.header.Get("retry-after"))
+
+	_, opened, err = open("/maxion.v1.TradingService/StreamAlerts")
+	assert.NoError(t, err, "unlisted methods are not limited")
+	assert.True(t, opened)
+}
+
+func TestFunc(t *testing.T) {
+	check := Func(NewLimiter(setupRedis(t)), "POST /orders", "fix", Rule{PerIP: 1, PerAPIKey: 2, Window: time.Minute}, logging.Discard())
+	ctx := context.Background()
+
+	assert.NoError(t, check(ctx, "DESK1"))
+	assert.NoError(t, check(ctx, "DESK1"))
+	kind, _ := domain.ErrorKindOf(check(ctx, "DESK1"))
+	assert.Equal(t, domain.KindRateLimited, kind)
+	assert.NoError(t, check(ctx, "DESK2"), "each counterparty has its own quota")
+}
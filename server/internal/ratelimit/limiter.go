@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const keyPrefix = "ratelimit:"
+
+// windowScript counts a request in the current fixed window, starting the
+// window with the first request. It returns the count so far and the
+// milliseconds left in the window.
+var windowScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// Limiter counts requests in fixed windows kept in Redis, so that every
+// server instance draws on the same quota.
+type Limiter struct {
+	client redis.UniversalClient
+}
+
+func NewLimiter(client redis.UniversalClient) *Limiter {
+	return &Limiter{client: client}
+}
+
+// Result is the state of a quota after counting a request.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time left until the window ends and the quota refills.
+	Reset time.Duration
+}
+
+// Allow counts a request against key, which may make limit requests per
+// window.
+func (l *Limiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	values, err := windowScript.Run(ctx, l.client, []string{keyPrefix + key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to count request: %w", err)
+	}
+	if len(values) != 2 {
+		return Result{}, fmt.Errorf("failed to count request: unexpected reply %v", values)
+	}
+
+	count := int(values[0])
+	return Result{
+		Allowed:   count <= limit,
+		Limit:     limit,
+		Remaining: max(limit-count, 0),
+		Reset:     time.Duration(values[1]) * time.Millisecond,
+	}, nil
+}
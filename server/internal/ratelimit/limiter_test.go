@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRedis(t *testing.T) *redis.Client {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{
+		Addr: server.Addr(),
+	})
+
+	_, err := client.Ping(context.Background()).Result()
+	if err != nil {
+		t.Fatalf("Failed to connect to Redis: %v", err)
+	}
+
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	limiter := NewLimiter(setupRedis(t))
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		result, err := limiter.Allow(ctx, "client", 3, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, 3-i, result.Remaining)
+		assert.InDelta(t, time.Minute, result.Reset, float64(time.Second))
+	}
+
+	result, err := limiter.Allow(ctx, "client", 3, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	// Other keys have their own quota.
+	result, err = limiter.Allow(ctx, "other", 3, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestLimiter_WindowExpires(t *testing.T) {
+	client := setupRedis(t)
+	limiter := NewLimiter(client)
+	ctx := context.Background()
+
+	_, err := limiter.Allow(ctx, "client", 1, time.Minute)
+	require.NoError(t, err)
+	result, err := limiter.Allow(ctx, "client", 1, time.Minute)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+
+	// Expire the window as if it had run out.
+	require.NoError(t, client.Del(ctx, keyPrefix+"client").Err())
+
+	result, err = limiter.Allow(ctx, "client", 1, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestLimiter_RedisDown(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "localhost:1"})
+	defer client.Close()
+
+	_, err := NewLimiter(client).Allow(context.Background(), "client", 1, time.Minute)
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/metrics"
+)
+
+// APIKeyHeader carries the key that identifies a client for rate limiting.
+const APIKeyHeader = "X-API-Key"
+
+// Rule is the quota of one route. Zero limits are unlimited.
+type Rule struct {
+	PerIP     int
+	PerAPIKey int
+	Window    time.Duration
+}
+
+// Middleware limits the requests to route, which names the bucket, e.g.
+// "POST /v1/transactions". Clients sending one of apiKeys in the X-API-Key
+// header are counted by key, everyone else by IP address; unknown keys are
+// ignored so that they cannot be used to dodge the per-IP limit.
+//
+// Responses carry RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers, and refused requests a Retry-After. If Redis is
+// unavailable requests are let through rather than failing the API.
+func Middleware(limiter *Limiter, route string, rule Rule, apiKeys []string, logger *slog.Logger) fiber.Handler {
+	known := knownKeys(apiKeys)
+
+	return func(c *fiber.Ctx) error {
+		q := newQuota(route, rule, known, c.IP(), c.Get(APIKeyHeader))
+		if q.limit <= 0 {
+			return c.Next()
+		}
+
+		result, counted := q.count(c.UserContext(), limiter, logger)
+		if !counted {
+			return c.Next()
+		}
+
+		c.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Set("RateLimit-Reset", resetSeconds(result))
+		c.Set("RateLimit-Policy", q.policy())
+		if result.Allowed {
+			return c.Next()
+		}
+
+		c.Set(fiber.HeaderRetryAfter, resetSeconds(result))
+		return q.refused()
+	}
+}
+
+// Func returns a check that counts requests to route by an opaque client ID
+// of the given kind, e.g. a FIX counterparty's CompID, against the per-key
+// limit of rule. Such clients have authenticated by other means, so they
+// get the quota of a known API key. The check returns a rate limited error
+// once the quota is used up, and nil if Redis is unavailable.
+func Func(limiter *Limiter, route string, kind string, rule Rule, logger *slog.Logger) func(ctx context.Context, id string) error {
+	return func(ctx context.Context, id string) error {
+		q := quota{route: route, rule: rule, client: kind, id: id, limit: rule.PerAPIKey}
+		if q.limit <= 0 {
+			return nil
+		}
+		if result, counted := q.count(ctx, limiter, logger); counted && !result.Allowed {
+			return q.refused()
+		}
+		return nil
+	}
+}
+
+func knownKeys(apiKeys []string) map[string]bool {
+	known := make(map[string]bool, len(apiKeys))
+	for _, key := range apiKeys {
+		known[key] = true
+	}
+	return known
+}
+
+// quota is the bucket a request is counted in and its limit.
+type quota struct {
+	route  string
+	rule   Rule
+	client string
+	id     string
+	limit  int
+}
+
+// newQuota picks the bucket for a request from ip, counting it by apiKey
+// instead if that is one of the known keys.
+func newQuota(route string, rule Rule, known map[string]bool, ip string, apiKey string) quota {
+	q := quota{route: route, rule: rule, client: "ip", id: ip, limit: rule.PerIP}
+	if apiKey != "" && known[apiKey] {
+		// Keys are hashed so that they don't appear in Redis.
+		sum := sha256.Sum256([]byte(apiKey))
+		q.client, q.id, q.limit = "api_key", hex.EncodeToString(sum[:8]), rule.PerAPIKey
+	}
+	return q
+}
+
+// count counts the request, returning false if Redis is unavailable and
+// the request should be let through uncounted.
+func (q quota) count(ctx context.Context, limiter *Limiter, logger *slog.Logger) (Result, bool) {
+	result, err := limiter.Allow(ctx, q.route+":"+q.client+":"+q.id, q.limit, q.rule.Window)
+	if err != nil {
+		logger.WarnContext(ctx, "rate limiter unavailable, allowing request", "route", q.route, "error", err)
+		return Result{}, false
+	}
+	return result, true
+}
+
+func (q quota) policy() string {
+	return fmt.Sprintf("%d;w=%d", q.limit, int(q.rule.Window.Seconds()))
+}
+
+// refused records a refused request and returns the error it fails with.
+func (q quota) refused() error {
+	metrics.RateLimitedRequests.WithLabelValues(q.route, q.client).Inc()
+	return domain.NewRateLimitedError("rate_limited",
+		fmt.Sprintf("rate limit of %d requests per %s exceeded", q.limit, q.rule.Window))
+}
+
+func resetSeconds(result Result) string {
+	return strconv.Itoa(int(math.Ceil(result.Reset.Seconds())))
+}
@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/handlers"
+	"github.com/touchsung/maxion-server/internal/logging"
+)
+
+func setupApp(client *redis.Client, rule Rule) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: handlers.ErrorHandler})
+	limit := Middleware(NewLimiter(client), "POST /orders", rule, []string{"desk-key"}, logging.Discard())
+	app.Post("/orders", limit, func(c *fiber.Ctx) error {
+		return c.SendStatus(201)
+	})
+	return app
+}
+
+func post(t *testing.T, app *fiber.App, apiKey string) (int, map[string]string) {
+	req := httptest.NewRequest("POST", "/orders", nil)
+	if apiKey != "" {
+		req.Header.Set(APIKeyHeader, apiKey)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+
+	headers := make(map[string]string)
+	for _, name := range []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"} {
+		headers[name] = resp.Header.Get(name)
+	}
+	return resp.StatusCode, headers
+}
+
+func TestMiddleware_Headers(t *testing.T) {
+	app := setupApp(setupRedis(t), Rule{PerIP: 2, PerAPIKey: 5, Window: time.Minute})
+
+	status, headers := post(t, app, "")
+
+	assert.Equal(t, 201, status)
+	assert.Equal(t, map[string]string{
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "1",
+		"RateLimit-Reset":     "60",
+		"RateLimit-Policy":    "2;w=60",
+		"Retry-After":         "",
+	}, headers)
+}
+
+func TestMiddleware_TooManyRequests(t *testing.T) {
+	app := setupApp(setupRedis(t), Rule{PerIP: 1, PerAPIKey: 5, Window: time.Minute})
+	post(t, app, "")
+
+	req := httptest.NewRequest("POST", "/orders", nil)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+
+	assert.Equal(t, 429, resp.StatusCode)
+	assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", resp.Header.Get("Retry-After"))
+	assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+
+	var problem handlers.Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	assert.Equal(t, "rate_limited", problem.Code)
+}
+
+func TestMiddleware_APIKey(t *testing.T) {
+	app := setupApp(setupRedis(t), Rule{PerIP: 1, PerAPIKey: 2, Window: time.Minute})
+
+	testCases := []struct {
+		name           string
+		apiKey         string
+		expectedStatus int
+		expectedLimit  string
+	}{
+		{name: "IP quota", apiKey: "", expectedStatus: 201, expectedLimit: "1"},
+		{name: "IP quota used up", apiKey: "", expectedStatus: 429, expectedLimit: "1"},
+		{name: "Unknown key counts against IP", apiKey: "made-up", expectedStatus: 429, expectedLimit: "1"},
+		{name: "Known key has its own quota", apiKey: "desk-key", expectedStatus: 201, expectedLimit: "2"},
+		{name: "Known key again", apiKey: "desk-key", expectedStatus: 201, expectedLimit: "2"},
+		{name: "Key quota used up", apiKey: "desk-key", expectedStatus: 429, expectedLimit: "2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, headers := post(t, app, tc.apiKey)
+
+			assert.Equal(t, tc.expectedStatus, status)
+			assert.Equal(t, tc.expectedLimit, headers["RateLimit-Limit"])
+		})
+	}
+}
+
+func TestMiddleware_Unlimited(t *testing.T) {
+	app := setupApp(setupRedis(t), Rule{PerIP: 0, PerAPIKey: 1, Window: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		status, headers := post(t, app, "")
+		assert.Equal(t, 201, status)
+		assert.Empty(t, headers["RateLimit-Limit"])
+	}
+}
+
+func TestMiddleware_FailsOpen(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "localhost:1"})
+	defer client.Close()
+	app := setupApp(client, Rule{PerIP: 1, Window: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		status, _ := post(t, app, "")
+		assert.Equal(t, 201, status)
+	}
+}
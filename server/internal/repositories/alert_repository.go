@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func alertRuleNotFound(id int64) error {
+	return domain.NewNotFoundError("alert_rule_not_found", fmt.Sprintf("alert rule %d not found", id))
+}
+
+func (r *tradingRepository) CreateAlertRule(ctx context.Context, rule *domain.AlertRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+func (r *tradingRepository) GetAlertRules(ctx context.Context) ([]domain.AlertRule, error) {
+	var rules []domain.AlertRule
+	err := r.db.WithContext(ctx).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "RuleId"}}).
+		Find(&rules).Error
+	return rules, err
+}
+
+func (r *tradingRepository) GetAlertRule(ctx context.Context, id int64) (*domain.AlertRule, error) {
+	var rule domain.AlertRule
+	err := r.db.WithContext(ctx).Where(map[string]any{"RuleId": id}).First(&rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, alertRuleNotFound(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *tradingRepository) DeleteAlertRule(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		if err := db.Where(map[string]any{"RuleId": id}).Delete(&domain.Alert{}).Error; err != nil {
+			return err
+		}
+		result := db.Where(map[string]any{"RuleId": id}).Delete(&domain.AlertRule{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return alertRuleNotFound(id)
+		}
+		return nil
+	})
+}
+
+func (r *tradingRepository) RecordAlert(ctx context.Context, rule *domain.AlertRule, alert *domain.Alert) error {
+	return r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		result := db.Model(&domain.AlertRule{}).
+			Where(map[string]any{"RuleId": rule.RuleID}).
+			Select("LastFiredAt", "ReferencePrice").
+			Updates(rule)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return alertRuleNotFound(rule.RuleID)
+		}
+		return db.Create(alert).Error
+	})
+}
+
+func (r *tradingRepository) GetAlerts(ctx context.Context, ruleID int64, limit int) ([]domain.Alert, error) {
+	query := r.db.WithContext(ctx)
+	if ruleID != 0 {
+		query = query.Where(map[string]any{"RuleId": ruleID})
+	}
+
+	var alerts []domain.Alert
+	err := query.
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "AlertId"}, Desc: true}).
+		Limit(limit).
+		Find(&alerts).Error
+	return alerts, err
+}
+
+func (r *tradingRepository) GetAlertsAfter(ctx context.Context, afterID int64, limit int) ([]domain.Alert, error) {
+	var alerts []domain.Alert
+	err := r.db.WithContext(ctx).
+		Where(clause.Gt{Column: clause.Column{Name: "AlertId"}, Value: afterID}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "AlertId"}}).
+		Limit(limit).
+		Find(&alerts).Error
+	return alerts, err
+}
@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+func TestRepository_AlertRules(t *testing.T) {
+	for name, repo := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			reference := 170.5
+
+			rule := &domain.AlertRule{
+				Symbol:          "AAPL",
+				Type:            domain.PercentMoveRule,
+				Side:            domain.AskSide,
+				Direction:       domain.Below,
+				Value:           2.5,
+				ReferencePrice:  &reference,
+				CooldownSeconds: 300,
+				CreatedAt:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			}
+			require.NoError(t, repo.CreateAlertRule(ctx, rule))
+			assert.NotZero(t, rule.RuleID)
+
+			got, err := repo.GetAlertRule(ctx, rule.RuleID)
+			require.NoError(t, err)
+			assert.Equal(t, rule, got)
+
+			rules, err := repo.GetAlertRules(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, []domain.AlertRule{*rule}, rules)
+
+			require.NoError(t, repo.DeleteAlertRule(ctx, rule.RuleID))
+			err = repo.DeleteAlertRule(ctx, rule.RuleID)
+			kind, _ := domain.ErrorKindOf(err)
+			assert.Equal(t, domain.KindNotFound, kind)
+			_, err = repo.GetAlertRule(ctx, rule.RuleID)
+			kind, _ = domain.ErrorKindOf(err)
+			assert.Equal(t, domain.KindNotFound, kind)
+		})
+	}
+}
+
+func TestRepository_Alerts(t *testing.T) {
+	for name, repo := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+			newRule := func() *domain.AlertRule {
+				rule := &domain.AlertRule{Symbol: "AAPL", Type: domain.ThresholdRule, Side: domain.AskSide, Direction: domain.Below, Value: 165, CooldownSeconds: 60, CreatedAt: now}
+				require.NoError(t, repo.CreateAlertRule(ctx, rule))
+				return rule
+			}
+			first, second := newRule(), newRule()
+
+			fire := func(rule *domain.AlertRule, at time.Time) *domain.Alert {
+				price := 164.5
+				rule.LastFiredAt = &at
+				rule.ReferencePrice = &price
+				alert := &domain.Alert{RuleID: rule.RuleID, Symbol: rule.Symbol, Type: rule.Type, Observed: price, Message: rule.Describe(price), TriggeredAt: at}
+				require.NoError(t, repo.RecordAlert(ctx, rule, alert))
+				return alert
+			}
+			a1 := fire(first, now)
+			a2 := fire(second, now.Add(time.Second))
+			a3 := fire(first, now.Add(2*time.Second))
+
+			got, err := repo.GetAlertRule(ctx, first.RuleID)
+			require.NoError(t, err)
+			assert.Equal(t, now.Add(2*time.Second), got.LastFiredAt.UTC())
+			assert.Equal(t, 164.5, *got.ReferencePrice)
+
+			alerts, err := repo.GetAlerts(ctx, 0, 10)
+			require.NoError(t, err)
+			assert.Equal(t, []int64{a3.AlertID, a2.AlertID, a1.AlertID}, alertIDs(alerts))
+
+			alerts, err = repo.GetAlerts(ctx, first.RuleID, 1)
+			require.NoError(t, err)
+			assert.Equal(t, []int64{a3.AlertID}, alertIDs(alerts))
+			assert.Equal(t, "AAPL ask fell below 165 (164.50)", alerts[0].Message)
+
+			alerts, err = repo.GetAlertsAfter(ctx, a1.AlertID, 10)
+			require.NoError(t, err)
+			assert.Equal(t, []int64{a2.AlertID, a3.AlertID}, alertIDs(alerts))
+
+			// Deleting a rule deletes its history.
+			require.NoError(t, repo.DeleteAlertRule(ctx, first.RuleID))
+			alerts, err = repo.GetAlerts(ctx, 0, 10)
+			require.NoError(t, err)
+			assert.Equal(t, []int64{a2.AlertID}, alertIDs(alerts))
+
+			err = repo.RecordAlert(ctx, first, &domain.Alert{RuleID: first.RuleID})
+			kind, _ := domain.ErrorKindOf(err)
+			assert.Equal(t, domain.KindNotFound, kind)
+		})
+	}
+}
+
+func alertIDs(alerts []domain.Alert) []int64 {
+	ids := make([]int64, 0, len(alerts))
+	for _, alert := range alerts {
+		ids = append(ids, alert.AlertID)
+	}
+	return ids
+}
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+)
+
+// testCache is a cache implementation and a way to move its clock forward.
+type testCache struct {
+	cache   ports.Cache
+	advance func(d time.Duration)
+}
+
+// cacheImplementations returns each cache, empty, for the tests that both
+// must pass.
+func cacheImplementations(t *testing.T) map[string]testCache {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	memory := NewMemoryCache()
+	now := time.Now()
+	memory.now = func() time.Time { return now }
+
+	return map[string]testCache{
+		"Redis":  {cache: NewRedisCache(client), advance: server.FastForward},
+		"Memory": {cache: memory, advance: func(d time.Duration) { now = now.Add(d) }},
+	}
+}
+
+func TestCache_GetSetDelete(t *testing.T) {
+	for name, impl := range cacheImplementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			cache := impl.cache
+
+			_, err := cache.Get(ctx, "key")
+			assert.ErrorIs(t, err, ports.ErrCacheMiss)
+
+			require.NoError(t, cache.Set(ctx, "key", "value", time.Minute))
+			value, err := cache.Get(ctx, "key")
+			require.NoError(t, err)
+			assert.Equal(t, "value", value)
+
+			require.NoError(t, cache.Delete(ctx, "key"))
+			_, err = cache.Get(ctx, "key")
+			assert.ErrorIs(t, err, ports.ErrCacheMiss)
+
+			// Deleting a missing key is not an error.
+			assert.NoError(t, cache.Delete(ctx, "key"))
+			assert.NoError(t, cache.Ping(ctx))
+		})
+	}
+}
+
+func TestCache_Expiry(t *testing.T) {
+	for name, impl := range cacheImplementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			cache := impl.cache
+
+			require.NoError(t, cache.Set(ctx, "expiring", "value", 10*time.Second))
+			require.NoError(t, cache.Set(ctx, "lasting", "value", 0))
+
+			ttl, err := cache.TTL(ctx, "expiring")
+			require.NoError(t, err)
+			assert.InDelta(t, 10*time.Second, ttl, float64(time.Second))
+
+			ttl, err = cache.TTL(ctx, "lasting")
+			require.NoError(t, err)
+			assert.Negative(t, ttl)
+
+			impl.advance(11 * time.Second)
+
+			_, err = cache.Get(ctx, "expiring")
+			assert.ErrorIs(t, err, ports.ErrCacheMiss)
+			ttl, err = cache.TTL(ctx, "expiring")
+			require.NoError(t, err)
+			assert.Negative(t, ttl)
+
+			value, err := cache.Get(ctx, "lasting")
+			require.NoError(t, err)
+			assert.Equal(t, "value", value)
+		})
+	}
+}
+
+func TestCache_Keys(t *testing.T) {
+	for name, impl := range cacheImplementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			cache := impl.cache
+
+			for _, key := range []string{"pending:1", "pending:2", "pending*:3", "other:1"} {
+				require.NoError(t, cache.Set(ctx, key, "value", time.Minute))
+			}
+			require.NoError(t, cache.Set(ctx, "pending:expired", "value", time.Second))
+			impl.advance(2 * time.Second)
+
+			keys, err := cache.Keys(ctx, "pending:")
+			require.NoError(t, err)
+			sort.Strings(keys)
+			assert.Equal(t, []string{"pending:1", "pending:2"}, keys)
+
+			// Wildcards in the prefix are matched literally.
+			keys, err = cache.Keys(ctx, "pending*")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"pending*:3"}, keys)
+
+			keys, err = cache.Keys(ctx, "missing:")
+			require.NoError(t, err)
+			assert.Empty(t, keys)
+		})
+	}
+}
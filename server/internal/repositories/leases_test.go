@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+)
+
+// testLeases is a lease implementation and a way to move its clock forward.
+type testLeases struct {
+	leases  ports.Leases
+	advance func(d time.Duration)
+}
+
+// leaseImplementations returns each lease implementation, with no leases
+// held, for the tests that both must pass.
+func leaseImplementations(t *testing.T) map[string]testLeases {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	memory := NewMemoryLeases()
+	now := time.Now()
+	memory.now = func() time.Time { return now }
+
+	return map[string]testLeases{
+		"Redis":  {leases: NewRedisLeases(client), advance: server.FastForward},
+		"Memory": {leases: memory, advance: func(d time.Duration) { now = now.Add(d) }},
+	}
+}
+
+func TestLeases_OneHolderAtATime(t *testing.T) {
+	for name, impl := range leaseImplementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			leases := impl.leases
+
+			held, err := leases.AcquireLease(ctx, "jobs", "a", 10*time.Second)
+			require.NoError(t, err)
+			assert.True(t, held)
+
+			held, err = leases.AcquireLease(ctx, "jobs", "b", 10*time.Second)
+			require.NoError(t, err)
+			assert.False(t, held)
+
+			// Other leases are independent.
+			held, err = leases.AcquireLease(ctx, "other", "b", 10*time.Second)
+			require.NoError(t, err)
+			assert.True(t, held)
+
+			// Renewing pushes the expiry back.
+			impl.advance(6 * time.Second)
+			held, err = leases.AcquireLease(ctx, "jobs", "a", 10*time.Second)
+			require.NoError(t, err)
+			assert.True(t, held)
+
+			impl.advance(6 * time.Second)
+			held, err = leases.AcquireLease(ctx, "jobs", "b", 10*time.Second)
+			require.NoError(t, err)
+			assert.False(t, held)
+
+			// An expired lease passes to the next holder.
+			impl.advance(5 * time.Second)
+			held, err = leases.AcquireLease(ctx, "jobs", "b", 10*time.Second)
+			require.NoError(t, err)
+			assert.True(t, held)
+		})
+	}
+}
+
+func TestLeases_Release(t *testing.T) {
+	for name, impl := range leaseImplementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			leases := impl.leases
+
+			held, err := leases.AcquireLease(ctx, "jobs", "a", 10*time.Second)
+			require.NoError(t, err)
+			require.True(t, held)
+
+			// Only the holder can release a lease.
+			require.NoError(t, leases.ReleaseLease(ctx, "jobs", "b"))
+			held, err = leases.AcquireLease(ctx, "jobs", "b", 10*time.Second)
+			require.NoError(t, err)
+			assert.False(t, held)
+
+			require.NoError(t, leases.ReleaseLease(ctx, "jobs", "a"))
+			held, err = leases.AcquireLease(ctx, "jobs", "b", 10*time.Second)
+			require.NoError(t, err)
+			assert.True(t, held)
+		})
+	}
+}
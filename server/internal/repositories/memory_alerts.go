@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+func (r *memoryRepository) CreateAlertRule(ctx context.Context, rule *domain.AlertRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextRuleID++
+	rule.RuleID = r.nextRuleID
+	r.alertRules = append(r.alertRules, cloneAlertRule(*rule))
+	return nil
+}
+
+func (r *memoryRepository) GetAlertRules(ctx context.Context) ([]domain.AlertRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rules := make([]domain.AlertRule, len(r.alertRules))
+	for i, rule := range r.alertRules {
+		rules[i] = cloneAlertRule(rule)
+	}
+	return rules, nil
+}
+
+func (r *memoryRepository) GetAlertRule(ctx context.Context, id int64) (*domain.AlertRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, rule := range r.alertRules {
+		if rule.RuleID == id {
+			rule = cloneAlertRule(rule)
+			return &rule, nil
+		}
+	}
+	return nil, alertRuleNotFound(id)
+}
+
+func (r *memoryRepository) DeleteAlertRule(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, rule := range r.alertRules {
+		if rule.RuleID != id {
+			continue
+		}
+		r.alertRules = append(r.alertRules[:i], r.alertRules[i+1:]...)
+		kept := r.alerts[:0]
+		for _, alert := range r.alerts {
+			if alert.RuleID != id {
+				kept = append(kept, alert)
+			}
+		}
+		r.alerts = kept
+		return nil
+	}
+	return alertRuleNotFound(id)
+}
+
+func (r *memoryRepository) RecordAlert(ctx context.Context, rule *domain.AlertRule, alert *domain.Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.alertRules {
+		stored := &r.alertRules[i]
+		if stored.RuleID != rule.RuleID {
+			continue
+		}
+		updated := cloneAlertRule(*rule)
+		stored.LastFiredAt = updated.LastFiredAt
+		stored.ReferencePrice = updated.ReferencePrice
+
+		r.nextAlertID++
+		alert.AlertID = r.nextAlertID
+		r.alerts = append(r.alerts, *alert)
+		return nil
+	}
+	return alertRuleNotFound(rule.RuleID)
+}
+
+func (r *memoryRepository) GetAlerts(ctx context.Context, ruleID int64, limit int) ([]domain.Alert, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var alerts []domain.Alert
+	for i := len(r.alerts) - 1; i >= 0 && len(alerts) < limit; i-- {
+		if ruleID == 0 || r.alerts[i].RuleID == ruleID {
+			alerts = append(alerts, r.alerts[i])
+		}
+	}
+	return alerts, nil
+}
+
+func (r *memoryRepository) GetAlertsAfter(ctx context.Context, afterID int64, limit int) ([]domain.Alert, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var alerts []domain.Alert
+	for _, alert := range r.alerts {
+		if len(alerts) == limit {
+			break
+		}
+		if alert.AlertID > afterID {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts, nil
+}
+
+// cloneAlertRule copies the pointer fields of rule, so that callers can't
+// change what is stored.
+func cloneAlertRule(rule domain.AlertRule) domain.AlertRule {
+	if rule.ReferencePrice != nil {
+		price := *rule.ReferencePrice
+		rule.ReferencePrice = &price
+	}
+	if rule.LastFiredAt != nil {
+		firedAt := *rule.LastFiredAt
+		rule.LastFiredAt = &firedAt
+	}
+	return rule
+}
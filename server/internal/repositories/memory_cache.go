@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/ports"
+)
+
+// memoryCache implements ports.Cache in process memory, for tests and demo
+// mode. Expired entries are dropped when they are next read.
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+	now     func() time.Time
+}
+
+type memoryCacheEntry struct {
+	value string
+	// expiresAt is zero for entries without expiry.
+	expiresAt time.Time
+}
+
+func NewMemoryCache() *memoryCache {
+	return &memoryCache{
+		entries: make(map[string]memoryCacheEntry),
+		now:     time.Now,
+	}
+}
+
+func (c *memoryCache) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entry(key)
+	if !ok {
+		return "", ports.ErrCacheMiss
+	}
+	return entry.value, nil
+}
+
+func (c *memoryCache) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := memoryCacheEntry{value: value}
+	if ttl > 0 {
+		entry.expiresAt = c.now().Add(ttl)
+	}
+	c.entries[key] = entry
+	return nil
+}
+
+func (c *memoryCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+	return nil
+}
+
+func (c *memoryCache) Keys(ctx context.Context, prefix string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var keys []string
+	for key := range c.entries {
+		if _, ok := c.entry(key); ok && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (c *memoryCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entry(key)
+	if !ok || entry.expiresAt.IsZero() {
+		return -1, nil
+	}
+	return entry.expiresAt.Sub(c.now()), nil
+}
+
+func (c *memoryCache) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// entry returns the live entry of key, dropping it if it has expired. The
+// caller must hold mu.
+func (c *memoryCache) entry(key string) (memoryCacheEntry, bool) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return memoryCacheEntry{}, false
+	}
+	if !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return memoryCacheEntry{}, false
+	}
+	return entry, true
+}
@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryLeases implements ports.Leases in process memory, for tests and demo
+// mode.
+type memoryLeases struct {
+	mu     sync.Mutex
+	leases map[string]memoryLease
+	now    func() time.Time
+}
+
+type memoryLease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+func NewMemoryLeases() *memoryLeases {
+	return &memoryLeases{
+		leases: make(map[string]memoryLease),
+		now:    time.Now,
+	}
+}
+
+func (l *memoryLeases) AcquireLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	lease, ok := l.leases[name]
+	if ok && lease.holder != holder && now.Before(lease.expiresAt) {
+		return false, nil
+	}
+	l.leases[name] = memoryLease{holder: holder, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (l *memoryLeases) ReleaseLease(ctx context.Context, name string, holder string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if lease, ok := l.leases[name]; ok && lease.holder == holder {
+		delete(l.leases, name)
+	}
+	return nil
+}
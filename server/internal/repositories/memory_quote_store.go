@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+)
+
+// memoryQuoteStore implements ports.QuoteStore in process memory, for tests
+// and demo mode. Subscribers are called synchronously by SaveQuotes.
+type memoryQuoteStore struct {
+	mu          sync.Mutex
+	quotes      map[string]domain.Stock
+	subscribers map[int]func(symbols []string)
+	nextID      int
+}
+
+func NewMemoryQuoteStore() *memoryQuoteStore {
+	return &memoryQuoteStore{
+		quotes:      make(map[string]domain.Stock),
+		subscribers: make(map[int]func(symbols []string)),
+	}
+}
+
+func (s *memoryQuoteStore) SaveQuotes(ctx context.Context, stocks []domain.Stock) error {
+	if len(stocks) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	symbols := make([]string, len(stocks))
+	for i, stock := range stocks {
+		s.quotes[stock.Symbol] = stock
+		symbols[i] = stock.Symbol
+	}
+	subscribers := make([]func(symbols []string), 0, len(s.subscribers))
+	for _, onChange := range s.subscribers {
+		subscribers = append(subscribers, onChange)
+	}
+	s.mu.Unlock()
+
+	for _, onChange := range subscribers {
+		onChange(symbols)
+	}
+	return nil
+}
+
+func (s *memoryQuoteStore) GetQuote(ctx context.Context, symbol string) (*domain.Stock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stock, ok := s.quotes[symbol]
+	if !ok {
+		return nil, ports.ErrCacheMiss
+	}
+	return &stock, nil
+}
+
+func (s *memoryQuoteStore) GetQuotes(ctx context.Context) ([]domain.Stock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stocks := make([]domain.Stock, 0, len(s.quotes))
+	for _, stock := range s.quotes {
+		stocks = append(stocks, stock)
+	}
+	sort.Slice(stocks, func(i, j int) bool { return stocks[i].StockID < stocks[j].StockID })
+	return stocks, nil
+}
+
+func (s *memoryQuoteStore) Subscribe(ctx context.Context, onChange func(symbols []string)) error {
+	s.mu.Lock()
+	id := s.nextID
+	s.nextID++
+	s.subscribers[id] = onChange
+	s.mu.Unlock()
+
+	onChange(nil)
+	<-ctx.Done()
+
+	s.mu.Lock()
+	delete(s.subscribers, id)
+	s.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,363 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+// memoryRepository keeps everything in process memory. It implements the
+// same ports as tradingRepository and mirrors the schema's constraints and
+// triggers, so it can stand in for the database in tests and demo mode.
+type memoryRepository struct {
+	mu           sync.RWMutex
+	stocks       map[string]domain.Stock
+	transactions []domain.Transaction
+	failedWrites []domain.FailedWrite
+	fixSessions  map[string]domain.FIXSession
+	outbox       []domain.OutboxEvent
+	webhooks     []domain.WebhookSubscription
+	deliveries   []domain.WebhookDelivery
+	alertRules   []domain.AlertRule
+	alerts       []domain.Alert
+	nextStockID  int64
+	nextTxID     int64
+	nextWriteID  int64
+	nextEventID  int64
+	nextHookID   int64
+	nextDelivery int64
+	nextRuleID   int64
+	nextAlertID  int64
+}
+
+func NewMemoryRepository(stocks []domain.Stock) *memoryRepository {
+	r := &memoryRepository{
+		stocks:      make(map[string]domain.Stock, len(stocks)),
+		fixSessions: make(map[string]domain.FIXSession),
+	}
+	now := time.Now().UTC()
+	for _, stock := range stocks {
+		r.nextStockID++
+		stock.StockID = r.nextStockID
+		if stock.LastUpdated.IsZero() {
+			stock.LastUpdated = now
+		}
+		r.stocks[stock.Symbol] = stock
+	}
+	return r
+}
+
+// DemoStocks returns the stocks seeded by sql/mock_data.sql.
+func DemoStocks() []domain.Stock {
+	return []domain.Stock{
+		{Symbol: "AAPL", BidPrice: 169.85, BidVolume: 500, AskPrice: 170.15, AskVolume: 300},
+		{Symbol: "MSFT", BidPrice: 378.92, BidVolume: 250, AskPrice: 379.45, AskVolume: 400},
+		{Symbol: "GOOGL", BidPrice: 147.75, BidVolume: 150, AskPrice: 148.05, AskVolume: 200},
+		{Symbol: "AMZN", BidPrice: 178.25, BidVolume: 300, AskPrice: 178.65, AskVolume: 250},
+		{Symbol: "NVDA", BidPrice: 875.35, BidVolume: 100, AskPrice: 876.20, AskVolume: 150},
+		{Symbol: "META", BidPrice: 505.75, BidVolume: 200, AskPrice: 506.25, AskVolume: 180},
+		{Symbol: "TSLA", BidPrice: 175.85, BidVolume: 400, AskPrice: 176.25, AskVolume: 350},
+		{Symbol: "JPM", BidPrice: 182.45, BidVolume: 250, AskPrice: 182.85, AskVolume: 200},
+		{Symbol: "V", BidPrice: 275.65, BidVolume: 150, AskPrice: 276.15, AskVolume: 175},
+		{Symbol: "WMT", BidPrice: 59.85, BidVolume: 300, AskPrice: 60.15, AskVolume: 280},
+	}
+}
+
+func (r *memoryRepository) GetAllStocks(ctx context.Context) ([]domain.Stock, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stocks := make([]domain.Stock, 0, len(r.stocks))
+	for _, stock := range r.stocks {
+		stocks = append(stocks, stock)
+	}
+	sort.Slice(stocks, func(i, j int) bool { return stocks[i].StockID < stocks[j].StockID })
+	return stocks, nil
+}
+
+func (r *memoryRepository) GetStockBySymbol(ctx context.Context, symbol string) (*domain.Stock, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stock, ok := r.stocks[symbol]
+	if !ok {
+		return nil, domain.NewNotFoundError("stock_not_found", fmt.Sprintf("stock %s not found", symbol))
+	}
+	return &stock, nil
+}
+
+func (r *memoryRepository) UpdateStock(ctx context.Context, stock *domain.Stock) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.stocks[stock.Symbol]
+	if !ok {
+		return nil
+	}
+	current.BidPrice = stock.BidPrice
+	current.BidVolume = stock.BidVolume
+	current.AskPrice = stock.AskPrice
+	current.AskVolume = stock.AskVolume
+	current.LastUpdated = time.Now().UTC()
+	r.stocks[stock.Symbol] = current
+	return nil
+}
+
+func (r *memoryRepository) GetAllTransactions(ctx context.Context) ([]domain.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	transactions := make([]domain.Transaction, len(r.transactions))
+	for i, tx := range r.transactions {
+		tx.Stock = r.stocks[tx.Symbol]
+		transactions[i] = tx
+	}
+	return transactions, nil
+}
+
+func (r *memoryRepository) CreateTransaction(ctx context.Context, tx *domain.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkTransaction(tx); err != nil {
+		return err
+	}
+	return r.insertTransaction(tx)
+}
+
+func (r *memoryRepository) CreateOrderGroup(ctx context.Context, orders []domain.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Every order is checked first, as the database would roll back the
+	// whole group.
+	for i := range orders {
+		if err := r.checkTransaction(&orders[i]); err != nil {
+			return err
+		}
+	}
+	for i := range orders {
+		if err := r.insertTransaction(&orders[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkTransaction applies the constraints of the Transactions table. The
+// caller holds the lock.
+func (r *memoryRepository) checkTransaction(tx *domain.Transaction) error {
+	if _, ok := r.stocks[tx.Symbol]; !ok {
+		return domain.NewValidationError("unknown_symbol", fmt.Sprintf("stock %s does not exist", tx.Symbol))
+	}
+	if tx.Quantity <= 0 || tx.Price <= 0 {
+		return domain.NewValidationError("invalid_transaction", "quantity and price must be positive")
+	}
+	if tx.ClientOrderID != nil {
+		for _, existing := range r.transactions {
+			if existing.ClientOrderID != nil && *existing.ClientOrderID == *tx.ClientOrderID {
+				return domain.NewConflictError("duplicate_client_order_id",
+					fmt.Sprintf("client order ID %s is already in use", *tx.ClientOrderID))
+			}
+		}
+	}
+	return nil
+}
+
+// insertTransaction stores tx with its outbox event. The caller holds the
+// lock.
+func (r *memoryRepository) insertTransaction(tx *domain.Transaction) error {
+	r.nextTxID++
+	tx.TransactionID = r.nextTxID
+	if tx.OrderTime.IsZero() {
+		tx.OrderTime = time.Now().UTC()
+	}
+
+	stored := *tx
+	stored.Stock = domain.Stock{}
+	if err := r.addOrderEvent(domain.OrderCreated, &stored); err != nil {
+		return err
+	}
+	r.transactions = append(r.transactions, stored)
+	return nil
+}
+
+func (r *memoryRepository) UpdateTransactionStatus(ctx context.Context, id int64, status domain.TransactionStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := slices.IndexFunc(r.transactions, func(tx domain.Transaction) bool { return tx.TransactionID == id })
+	if i < 0 {
+		return domain.NewNotFoundError("transaction_not_found", fmt.Sprintf("transaction %d not found", id))
+	}
+	tx := r.transactions[i]
+	if tx.GroupID == nil {
+		return r.setTransactionStatus(i, status)
+	}
+
+	if tx.Status == status {
+		return nil
+	}
+	if err := tx.CheckGroupTransition(status); err != nil {
+		return err
+	}
+
+	group := r.orderGroup(*tx.GroupID)
+	if err := r.setTransactionStatus(i, status); err != nil {
+		return err
+	}
+	changes := domain.GroupTransitions(tx, status, group)
+	for j, other := range r.transactions {
+		if next, ok := changes[other.TransactionID]; ok {
+			if err := r.setTransactionStatus(j, next); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) GetTransactionGroup(ctx context.Context, id int64) ([]domain.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i := slices.IndexFunc(r.transactions, func(tx domain.Transaction) bool { return tx.TransactionID == id })
+	if i < 0 {
+		return nil, domain.NewNotFoundError("transaction_not_found", fmt.Sprintf("transaction %d not found", id))
+	}
+	tx := r.transactions[i]
+	if tx.GroupID == nil {
+		return []domain.Transaction{tx}, nil
+	}
+	return r.orderGroup(*tx.GroupID), nil
+}
+
+// orderGroup returns the orders of a group, which were inserted in
+// transaction ID order. The caller holds the lock.
+func (r *memoryRepository) orderGroup(groupID string) []domain.Transaction {
+	var group []domain.Transaction
+	for _, tx := range r.transactions {
+		if tx.GroupID != nil && *tx.GroupID == groupID {
+			group = append(group, tx)
+		}
+	}
+	return group
+}
+
+// setTransactionStatus updates the status of the i-th transaction with its
+// outbox event. The caller holds the lock.
+func (r *memoryRepository) setTransactionStatus(i int, status domain.TransactionStatus) error {
+	tx := &r.transactions[i]
+	updated := *tx
+	// As the completion trigger does in the database.
+	if status == domain.Completed && tx.Status != domain.Completed {
+		now := time.Now().UTC()
+		updated.ExecutionTime = &now
+	}
+	updated.Status = status
+	event, err := domain.NewStatusChangedEvent(&updated, tx.Status)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", domain.OrderStatusChanged, err)
+	}
+	r.addEvent(event)
+	*tx = updated
+	return nil
+}
+
+// addOrderEvent queues an outbox event for tx. The caller holds the lock.
+func (r *memoryRepository) addOrderEvent(eventType string, tx *domain.Transaction) error {
+	event, err := domain.NewOrderEvent(eventType, tx)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	r.addEvent(event)
+	return nil
+}
+
+// addEvent assigns event the next outbox ID and queues it. The caller holds
+// the lock.
+func (r *memoryRepository) addEvent(event *domain.OutboxEvent) {
+	r.nextEventID++
+	event.OutboxID = r.nextEventID
+	r.outbox = append(r.outbox, *event)
+}
+
+func (r *memoryRepository) GetOutboxEvents(ctx context.Context, afterID int64, limit int) ([]domain.OutboxEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var events []domain.OutboxEvent
+	for _, event := range r.outbox {
+		if len(events) == limit {
+			break
+		}
+		if event.OutboxID > afterID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (r *memoryRepository) DeleteOutboxEvents(ctx context.Context, ids []int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	published := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		published[id] = true
+	}
+	kept := r.outbox[:0]
+	for _, event := range r.outbox {
+		if !published[event.OutboxID] {
+			kept = append(kept, event)
+		}
+	}
+	r.outbox = kept
+	return nil
+}
+
+func (r *memoryRepository) RecordFailedWrite(ctx context.Context, fw *domain.FailedWrite) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextWriteID++
+	fw.FailedWriteID = r.nextWriteID
+	r.failedWrites = append(r.failedWrites, *fw)
+	return nil
+}
+
+// FailedWrites returns the writes recorded by RecordFailedWrite.
+func (r *memoryRepository) FailedWrites() []domain.FailedWrite {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]domain.FailedWrite(nil), r.failedWrites...)
+}
+
+func (r *memoryRepository) GetFIXSession(ctx context.Context, sessionID string) (*domain.FIXSession, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, ok := r.fixSessions[sessionID]
+	if !ok {
+		return nil, domain.NewNotFoundError("fix_session_not_found", fmt.Sprintf("FIX session %s not found", sessionID))
+	}
+	return &session, nil
+}
+
+func (r *memoryRepository) SaveFIXSession(ctx context.Context, session *domain.FIXSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fixSessions[session.SessionID] = *session
+	return nil
+}
+
+func (r *memoryRepository) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
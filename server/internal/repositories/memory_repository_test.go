@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+func TestMemoryRepository_UpdateStock(t *testing.T) {
+	repo := NewMemoryRepository(DemoStocks())
+	ctx := context.Background()
+
+	before, err := repo.GetStockBySymbol(ctx, "MSFT")
+	require.NoError(t, err)
+
+	err = repo.UpdateStock(ctx, &domain.Stock{Symbol: "MSFT", BidPrice: 380, BidVolume: 10, AskPrice: 381, AskVolume: 20})
+	require.NoError(t, err)
+
+	after, err := repo.GetStockBySymbol(ctx, "MSFT")
+	require.NoError(t, err)
+	assert.Equal(t, before.StockID, after.StockID)
+	assert.Equal(t, 381.0, after.AskPrice)
+	assert.False(t, after.LastUpdated.Before(before.LastUpdated))
+
+	stocks, err := repo.GetAllStocks(ctx)
+	require.NoError(t, err)
+	require.Len(t, stocks, len(DemoStocks()))
+	assert.Equal(t, "AAPL", stocks[0].Symbol)
+}
+
+func TestMemoryRepository_RecordFailedWrite(t *testing.T) {
+	repo := NewMemoryRepository(nil)
+
+	fw := &domain.FailedWrite{CacheKey: "pending:create:1", Operation: "create", Payload: "{}", Error: "boom"}
+	require.NoError(t, repo.RecordFailedWrite(context.Background(), fw))
+
+	assert.Equal(t, int64(1), fw.FailedWriteID)
+	assert.Equal(t, []domain.FailedWrite{*fw}, repo.FailedWrites())
+}
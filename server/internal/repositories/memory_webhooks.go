@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+func (r *memoryRepository) CreateWebhookSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextHookID++
+	sub.SubscriptionID = r.nextHookID
+	r.webhooks = append(r.webhooks, cloneSubscription(*sub))
+	return nil
+}
+
+func (r *memoryRepository) GetWebhookSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subs := make([]domain.WebhookSubscription, len(r.webhooks))
+	for i, sub := range r.webhooks {
+		subs[i] = cloneSubscription(sub)
+	}
+	return subs, nil
+}
+
+func (r *memoryRepository) GetWebhookSubscription(ctx context.Context, id int64) (*domain.WebhookSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, sub := range r.webhooks {
+		if sub.SubscriptionID == id {
+			sub = cloneSubscription(sub)
+			return &sub, nil
+		}
+	}
+	return nil, webhookNotFound(id)
+}
+
+func (r *memoryRepository) UpdateWebhookSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.webhooks {
+		stored := &r.webhooks[i]
+		if stored.SubscriptionID != sub.SubscriptionID {
+			continue
+		}
+		stored.URL = sub.URL
+		stored.Secret = sub.Secret
+		stored.Events = append([]string(nil), sub.Events...)
+		stored.Active = sub.Active
+		return nil
+	}
+	return webhookNotFound(sub.SubscriptionID)
+}
+
+func (r *memoryRepository) DeleteWebhookSubscription(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, sub := range r.webhooks {
+		if sub.SubscriptionID != id {
+			continue
+		}
+		r.webhooks = append(r.webhooks[:i], r.webhooks[i+1:]...)
+		kept := r.deliveries[:0]
+		for _, delivery := range r.deliveries {
+			if delivery.SubscriptionID != id {
+				kept = append(kept, delivery)
+			}
+		}
+		r.deliveries = kept
+		return nil
+	}
+	return webhookNotFound(id)
+}
+
+func (r *memoryRepository) CreateWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextDelivery++
+	delivery.DeliveryID = r.nextDelivery
+	r.deliveries = append(r.deliveries, *delivery)
+	return nil
+}
+
+func (r *memoryRepository) CreateWebhookDeliveries(ctx context.Context, deliveries []domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range deliveries {
+		r.nextDelivery++
+		deliveries[i].DeliveryID = r.nextDelivery
+		r.deliveries = append(r.deliveries, deliveries[i])
+	}
+	return nil
+}
+
+func (r *memoryRepository) GetWebhookDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]domain.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var deliveries []domain.WebhookDelivery
+	for i := len(r.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if r.deliveries[i].SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, r.deliveries[i])
+		}
+	}
+	return deliveries, nil
+}
+
+func (r *memoryRepository) GetWebhookDelivery(ctx context.Context, id int64) (*domain.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, delivery := range r.deliveries {
+		if delivery.DeliveryID == id {
+			return &delivery, nil
+		}
+	}
+	return nil, deliveryNotFound(id)
+}
+
+func (r *memoryRepository) GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var deliveries []domain.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if len(deliveries) == limit {
+			break
+		}
+		if delivery.Status == domain.DeliveryPending && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+func (r *memoryRepository) UpdateWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.deliveries {
+		stored := &r.deliveries[i]
+		if stored.DeliveryID != delivery.DeliveryID {
+			continue
+		}
+		stored.Status = delivery.Status
+		stored.Attempts = delivery.Attempts
+		stored.NextAttemptAt = delivery.NextAttemptAt
+		stored.ResponseStatus = delivery.ResponseStatus
+		stored.LastError = delivery.LastError
+		stored.DeliveredAt = delivery.DeliveredAt
+		return nil
+	}
+	return deliveryNotFound(delivery.DeliveryID)
+}
+
+// cloneSubscription copies sub so that callers can't modify the stored
+// events.
+func cloneSubscription(sub domain.WebhookSubscription) domain.WebhookSubscription {
+	sub.Events = append([]string(nil), sub.Events...)
+	return sub
+}
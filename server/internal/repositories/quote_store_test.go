@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+)
+
+// quoteStoreImplementations returns each quote store, empty, for the tests
+// that both must pass.
+func quoteStoreImplementations(t *testing.T) map[string]ports.QuoteStore {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return map[string]ports.QuoteStore{
+		"Redis":  NewRedisQuoteStore(client),
+		"Memory": NewMemoryQuoteStore(),
+	}
+}
+
+var testQuotes = []domain.Stock{
+	{StockID: 2, Symbol: "MSFT", BidPrice: 378.92, BidVolume: 250, AskPrice: 379.45, AskVolume: 400,
+		LastUpdated: time.Date(2024, 1, 1, 14, 30, 0, 123456789, time.UTC)},
+	{StockID: 1, Symbol: "AAPL", BidPrice: 169.85, BidVolume: 500, AskPrice: 170.15, AskVolume: 300,
+		LastUpdated: time.Date(2024, 1, 1, 14, 30, 0, 0, time.UTC)},
+}
+
+func TestQuoteStore_SaveAndGet(t *testing.T) {
+	for name, store := range quoteStoreImplementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			_, err := store.GetQuote(ctx, "AAPL")
+			assert.ErrorIs(t, err, ports.ErrCacheMiss)
+			quotes, err := store.GetQuotes(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, quotes)
+
+			require.NoError(t, store.SaveQuotes(ctx, testQuotes))
+
+			quote, err := store.GetQuote(ctx, "MSFT")
+			require.NoError(t, err)
+			assert.Equal(t, testQuotes[0], *quote)
+
+			quotes, err = store.GetQuotes(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, []domain.Stock{testQuotes[1], testQuotes[0]}, quotes)
+
+			updated := testQuotes[1]
+			updated.AskPrice = 171.00
+			require.NoError(t, store.SaveQuotes(ctx, []domain.Stock{updated}))
+
+			quote, err = store.GetQuote(ctx, "AAPL")
+			require.NoError(t, err)
+			assert.Equal(t, 171.00, quote.AskPrice)
+		})
+	}
+}
+
+func TestQuoteStore_Subscribe(t *testing.T) {
+	for name, store := range quoteStoreImplementations(t) {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			var notifications [][]string
+			received := func() [][]string {
+				mu.Lock()
+				defer mu.Unlock()
+				return append([][]string(nil), notifications...)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() {
+				done <- store.Subscribe(ctx, func(symbols []string) {
+					mu.Lock()
+					notifications = append(notifications, symbols)
+					mu.Unlock()
+				})
+			}()
+
+			// Subscribing reports that anything may have changed.
+			require.Eventually(t, func() bool { return len(received()) == 1 }, time.Second, 10*time.Millisecond)
+			assert.Empty(t, received()[0])
+
+			require.NoError(t, store.SaveQuotes(context.Background(), testQuotes))
+			require.Eventually(t, func() bool { return len(received()) == 2 }, time.Second, 10*time.Millisecond)
+			assert.Equal(t, []string{"MSFT", "AAPL"}, received()[1])
+
+			cancel()
+			select {
+			case err := <-done:
+				assert.NoError(t, err)
+			case <-time.After(time.Second):
+				t.Fatal("Subscribe did not return after its context was cancelled")
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const leaseKeyPrefix = "lease:"
+
+// acquireScript extends the lease if ARGV[1] holds it, or else takes it if
+// it is free. It returns 1 if ARGV[1] holds the lease for ARGV[2] ms.
+var acquireScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0
+`)
+
+// releaseScript deletes the lease only if ARGV[1] still holds it, so a
+// holder whose lease expired can't release its successor's.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// redisLeases implements ports.Leases with a key per lease, holding the
+// holder's name and expiring with the lease.
+type redisLeases struct {
+	client redis.UniversalClient
+}
+
+func NewRedisLeases(client redis.UniversalClient) *redisLeases {
+	return &redisLeases{client: client}
+}
+
+func (l *redisLeases) AcquireLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	held, err := acquireScript.Run(ctx, l.client, []string{leaseKeyPrefix + name}, holder, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease %s: %w", name, err)
+	}
+	return held == 1, nil
+}
+
+func (l *redisLeases) ReleaseLease(ctx context.Context, name string, holder string) error {
+	if err := releaseScript.Run(ctx, l.client, []string{leaseKeyPrefix + name}, holder).Err(); err != nil {
+		return fmt.Errorf("failed to release lease %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+)
+
+const (
+	// quoteKeyPrefix is followed by the symbol of a stock to name the hash
+	// holding its quote.
+	quoteKeyPrefix  = "quote:"
+	quoteSymbolsKey = "quote_symbols"
+	// quoteUpdatesChannel carries the JSON list of symbols saved together.
+	quoteUpdatesChannel = "quote_updates"
+	// subscribeRetryDelay is the wait before receiving again after the
+	// subscription connection fails.
+	subscribeRetryDelay = time.Second
+)
+
+// redisQuoteStore implements ports.QuoteStore with a hash per stock, a set
+// of the stored symbols and a pub/sub channel for changes.
+type redisQuoteStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisQuoteStore(client redis.UniversalClient) *redisQuoteStore {
+	return &redisQuoteStore{client: client}
+}
+
+func (s *redisQuoteStore) SaveQuotes(ctx context.Context, stocks []domain.Stock) error {
+	if len(stocks) == 0 {
+		return nil
+	}
+
+	symbols := make([]string, len(stocks))
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		members := make([]interface{}, len(stocks))
+		for i, stock := range stocks {
+			pipe.HSet(ctx, quoteKeyPrefix+stock.Symbol, quoteFields(stock))
+			symbols[i] = stock.Symbol
+			members[i] = stock.Symbol
+		}
+		pipe.SAdd(ctx, quoteSymbolsKey, members...)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save quotes: %w", err)
+	}
+
+	payload, err := json.Marshal(symbols)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Publish(ctx, quoteUpdatesChannel, payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish quote update: %w", err)
+	}
+	return nil
+}
+
+func (s *redisQuoteStore) GetQuote(ctx context.Context, symbol string) (*domain.Stock, error) {
+	fields, err := s.client.HGetAll(ctx, quoteKeyPrefix+symbol).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, ports.ErrCacheMiss
+	}
+
+	stock, err := parseQuote(symbol, fields)
+	if err != nil {
+		return nil, err
+	}
+	return &stock, nil
+}
+
+func (s *redisQuoteStore) GetQuotes(ctx context.Context) ([]domain.Stock, error) {
+	symbols, err := s.client.SMembers(ctx, quoteSymbolsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := make([]*redis.StringStringMapCmd, len(symbols))
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, symbol := range symbols {
+			cmds[i] = pipe.HGetAll(ctx, quoteKeyPrefix+symbol)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stocks := make([]domain.Stock, 0, len(symbols))
+	for i, cmd := range cmds {
+		if len(cmd.Val()) == 0 {
+			continue
+		}
+		stock, err := parseQuote(symbols[i], cmd.Val())
+		if err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, stock)
+	}
+	sort.Slice(stocks, func(i, j int) bool { return stocks[i].StockID < stocks[j].StockID })
+	return stocks, nil
+}
+
+// Subscribe relies on go-redis to reconnect and resubscribe after a failed
+// receive. Every (re)subscription is confirmed with a subscription message,
+// which is reported as a change to every quote.
+func (s *redisQuoteStore) Subscribe(ctx context.Context, onChange func(symbols []string)) error {
+	pubsub := s.client.Subscribe(ctx, quoteUpdatesChannel)
+	// Receive does not return when ctx is cancelled, but does when the
+	// subscription is closed.
+	stop := context.AfterFunc(ctx, func() { pubsub.Close() })
+	defer func() {
+		if stop() {
+			pubsub.Close()
+		}
+	}()
+
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			select {
+			case <-time.After(subscribeRetryDelay):
+				continue
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			onChange(nil)
+		case *redis.Message:
+			var symbols []string
+			if err := json.Unmarshal([]byte(msg.Payload), &symbols); err != nil {
+				// An unreadable notification could be about any quote.
+				symbols = nil
+			}
+			onChange(symbols)
+		}
+	}
+}
+
+func quoteFields(stock domain.Stock) map[string]interface{} {
+	return map[string]interface{}{
+		"stock_id":     stock.StockID,
+		"bid_price":    strconv.FormatFloat(stock.BidPrice, 'f', -1, 64),
+		"bid_volume":   stock.BidVolume,
+		"ask_price":    strconv.FormatFloat(stock.AskPrice, 'f', -1, 64),
+		"ask_volume":   stock.AskVolume,
+		"last_updated": stock.LastUpdated.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+func parseQuote(symbol string, fields map[string]string) (domain.Stock, error) {
+	stock := domain.Stock{Symbol: symbol}
+
+	var errs [6]error
+	stock.StockID, errs[0] = strconv.ParseInt(fields["stock_id"], 10, 64)
+	stock.BidPrice, errs[1] = strconv.ParseFloat(fields["bid_price"], 64)
+	stock.BidVolume, errs[2] = strconv.Atoi(fields["bid_volume"])
+	stock.AskPrice, errs[3] = strconv.ParseFloat(fields["ask_price"], 64)
+	stock.AskVolume, errs[4] = strconv.Atoi(fields["ask_volume"])
+	stock.LastUpdated, errs[5] = time.Parse(time.RFC3339Nano, fields["last_updated"])
+	for _, err := range errs {
+		if err != nil {
+			return domain.Stock{}, fmt.Errorf("malformed quote for %s: %w", symbol, err)
+		}
+	}
+	return stock, nil
+}
@@ -1,10 +1,18 @@
 package repositories
 
 import (
-	"gorm.io/gorm"
+	"context"
+	"errors"
+	"fmt"
+
 	"github.com/touchsung/maxion-server/internal/core/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// tradingRepository works on every supported database. Conditions are given
+// as maps rather than SQL fragments so that GORM quotes the column names,
+// which PostgreSQL would otherwise fold to lower case.
 type tradingRepository struct {
 	db *gorm.DB
 }
@@ -13,44 +21,214 @@ func NewTradingRepository(db *gorm.DB) *tradingRepository {
 	return &tradingRepository{db: db}
 }
 
-func (r *tradingRepository) GetAllStocks() ([]domain.Stock, error) {
+func (r *tradingRepository) GetAllStocks(ctx context.Context) ([]domain.Stock, error) {
 	var stocks []domain.Stock
-	err := r.db.Find(&stocks).Error
+	err := r.db.WithContext(ctx).Find(&stocks).Error
 	return stocks, err
 }
 
-func (r *tradingRepository) GetStockBySymbol(symbol string) (*domain.Stock, error) {
+func (r *tradingRepository) GetStockBySymbol(ctx context.Context, symbol string) (*domain.Stock, error) {
 	var stock domain.Stock
-	err := r.db.Where("Symbol = ?", symbol).First(&stock).Error
+	err := r.db.WithContext(ctx).Where(map[string]any{"Symbol": symbol}).First(&stock).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.NewNotFoundError("stock_not_found", fmt.Sprintf("stock %s not found", symbol))
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &stock, nil
 }
 
-func (r *tradingRepository) GetAllTransactions() ([]domain.Transaction, error) {
+func (r *tradingRepository) GetAllTransactions(ctx context.Context) ([]domain.Transaction, error) {
 	var transactions []domain.Transaction
-	err := r.db.Preload("Stock").Find(&transactions).Error
+	err := r.db.WithContext(ctx).Preload("Stock").Find(&transactions).Error
 	return transactions, err
 }
 
-func (r *tradingRepository) CreateTransaction(tx *domain.Transaction) error {
-	return r.db.Create(tx).Error
+// CreateTransaction inserts tx along with its order.created outbox event.
+func (r *tradingRepository) CreateTransaction(ctx context.Context, tx *domain.Transaction) error {
+	return r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		if err := db.Create(tx).Error; err != nil {
+			return err
+		}
+		return createOrderEvent(db, domain.OrderCreated, tx)
+	})
+}
+
+// CreateOrderGroup inserts the orders in one transaction, each with its
+// order.created outbox event.
+func (r *tradingRepository) CreateOrderGroup(ctx context.Context, orders []domain.Transaction) error {
+	return r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		for i := range orders {
+			if err := db.Create(&orders[i]).Error; err != nil {
+				return err
+			}
+			if err := createOrderEvent(db, domain.OrderCreated, &orders[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateTransactionStatus updates the status along with an
+// order.status_changed outbox event carrying the updated order, and does
+// the same for every order of its group the change affects.
+func (r *tradingRepository) UpdateTransactionStatus(ctx context.Context, id int64, status domain.TransactionStatus) error {
+	return r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		var tx domain.Transaction
+		err := db.Where(map[string]any{"TransactionId": id}).First(&tx).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return transactionNotFound(id)
+		}
+		if err != nil {
+			return err
+		}
+		if tx.GroupID == nil {
+			return setTransactionStatus(db, id, tx.Status, status)
+		}
+
+		// Repeating a status changes nothing, so a replayed write is
+		// harmless.
+		if tx.Status == status {
+			return nil
+		}
+		if err := tx.CheckGroupTransition(status); err != nil {
+			return err
+		}
+
+		group, err := findOrderGroup(db, *tx.GroupID)
+		if err != nil {
+			return err
+		}
+		if err := setTransactionStatus(db, id, tx.Status, status); err != nil {
+			return err
+		}
+		changes := domain.GroupTransitions(tx, status, group)
+		for _, other := range group {
+			if next, ok := changes[other.TransactionID]; ok {
+				if err := setTransactionStatus(db, other.TransactionID, other.Status, next); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (r *tradingRepository) GetTransactionGroup(ctx context.Context, id int64) ([]domain.Transaction, error) {
+	db := r.db.WithContext(ctx)
+	var tx domain.Transaction
+	err := db.Where(map[string]any{"TransactionId": id}).First(&tx).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, transactionNotFound(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tx.GroupID == nil {
+		return []domain.Transaction{tx}, nil
+	}
+	return findOrderGroup(db, *tx.GroupID)
+}
+
+func findOrderGroup(db *gorm.DB, groupID string) ([]domain.Transaction, error) {
+	var group []domain.Transaction
+	err := db.Where(map[string]any{"GroupId": groupID}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "TransactionId"}}).
+		Find(&group).Error
+	return group, err
 }
 
-func (r *tradingRepository) UpdateTransactionStatus(id int64, status domain.TransactionStatus) error {
-	return r.db.Model(&domain.Transaction{}).
-		Where("TransactionId = ?", id).
-		Update("StatusId", status).Error
+// setTransactionStatus changes the status of a transaction whose status was
+// previous, with its order.status_changed outbox event.
+func setTransactionStatus(db *gorm.DB, id int64, previous domain.TransactionStatus, status domain.TransactionStatus) error {
+	result := db.Model(&domain.Transaction{}).
+		Where(map[string]any{"TransactionId": id}).
+		Update("StatusId", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return transactionNotFound(id)
+	}
+
+	// Read back what the completion trigger set.
+	var tx domain.Transaction
+	if err := db.Where(map[string]any{"TransactionId": id}).First(&tx).Error; err != nil {
+		return err
+	}
+	event, err := domain.NewStatusChangedEvent(&tx, previous)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", domain.OrderStatusChanged, err)
+	}
+	return db.Create(event).Error
 }
 
-func (r *tradingRepository) UpdateStock(stock *domain.Stock) error {
-	return r.db.Model(&domain.Stock{}).
-		Where("Symbol = ?", stock.Symbol).
+func transactionNotFound(id int64) error {
+	return domain.NewNotFoundError("transaction_not_found", fmt.Sprintf("transaction %d not found", id))
+}
+
+func createOrderEvent(db *gorm.DB, eventType string, tx *domain.Transaction) error {
+	event, err := domain.NewOrderEvent(eventType, tx)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	return db.Create(event).Error
+}
+
+func (r *tradingRepository) GetOutboxEvents(ctx context.Context, afterID int64, limit int) ([]domain.OutboxEvent, error) {
+	var events []domain.OutboxEvent
+	err := r.db.WithContext(ctx).
+		Where(clause.Gt{Column: clause.Column{Name: "OutboxId"}, Value: afterID}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "OutboxId"}}).
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *tradingRepository) DeleteOutboxEvents(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Where(map[string]any{"OutboxId": ids}).Delete(&domain.OutboxEvent{}).Error
+}
+
+func (r *tradingRepository) UpdateStock(ctx context.Context, stock *domain.Stock) error {
+	return r.db.WithContext(ctx).Model(&domain.Stock{}).
+		Where(map[string]any{"Symbol": stock.Symbol}).
 		Updates(map[string]interface{}{
 			"BidPrice":  stock.BidPrice,
 			"BidVolume": stock.BidVolume,
 			"AskPrice":  stock.AskPrice,
 			"AskVolume": stock.AskVolume,
 		}).Error
-} 
\ No newline at end of file
+}
+
+func (r *tradingRepository) RecordFailedWrite(ctx context.Context, fw *domain.FailedWrite) error {
+	return r.db.WithContext(ctx).Create(fw).Error
+}
+
+func (r *tradingRepository) GetFIXSession(ctx context.Context, sessionID string) (*domain.FIXSession, error) {
+	var session domain.FIXSession
+	err := r.db.WithContext(ctx).Where(map[string]any{"SessionId": sessionID}).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.NewNotFoundError("fix_session_not_found", fmt.Sprintf("FIX session %s not found", sessionID))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *tradingRepository) SaveFIXSession(ctx context.Context, session *domain.FIXSession) error {
+	return r.db.WithContext(ctx).Save(session).Error
+}
+
+func (r *tradingRepository) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
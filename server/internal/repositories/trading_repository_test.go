@@ -0,0 +1,469 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/config"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/migrations"
+	"gorm.io/gorm"
+)
+
+// setupDatabase opens an in-memory SQLite database, migrated to the latest
+// schema, with one stock.
+func setupDatabase(t *testing.T) *gorm.DB {
+	db, err := config.GetDatabaseConnection(config.DatabaseConfig{
+		Driver:            config.DriverSQLite,
+		Path:              ":memory:",
+		ConnectionTimeout: 5 * time.Second,
+	})
+	require.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := migrations.NewMigrator(db, config.DriverSQLite, logging.Discard())
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	requireQuotedIdentifiers(t, db)
+
+	require.NoError(t, db.Exec(`INSERT INTO Stocks (Symbol, BidPrice, BidVolume, AskPrice, AskVolume, LastUpdated)
+		VALUES ('AAPL', 150.00, 1000, 150.50, 800, '2024-01-01 00:00:00.000')`).Error)
+
+	return db
+}
+
+// unquotedIdentifier matches a column in a condition or sort that GORM
+// didn't quote.
+var unquotedIdentifier = regexp.MustCompile(`(?:WHERE|AND|OR|ORDER BY|,) \(?[A-Za-z_]+\b`)
+
+// requireQuotedIdentifiers fails the test on any query the repository builds
+// with an unquoted column name. PostgreSQL folds those to lower case, so they
+// don't match its quoted schema, while SQLite accepts them.
+func requireQuotedIdentifiers(t *testing.T, db *gorm.DB) {
+	check := func(db *gorm.DB) {
+		if sql := db.Statement.SQL.String(); unquotedIdentifier.MatchString(sql) {
+			t.Errorf("unquoted identifier in %s", sql)
+		}
+	}
+	callbacks := db.Callback()
+	require.NoError(t, callbacks.Query().After("gorm:query").Register("test:quoted_query", check))
+	require.NoError(t, callbacks.Update().After("gorm:update").Register("test:quoted_update", check))
+	require.NoError(t, callbacks.Delete().After("gorm:delete").Register("test:quoted_delete", check))
+}
+
+// repository is the set of ports both implementations provide.
+type repository interface {
+	ports.StockRepository
+	ports.TransactionRepository
+	ports.FailedWriteRepository
+	ports.FIXSessionRepository
+	ports.OutboxRepository
+	ports.WebhookRepository
+	ports.AlertRepository
+	ports.Pinger
+}
+
+// implementations returns each repository seeded with the same AAPL stock,
+// for the tests that both must pass.
+func implementations(t *testing.T) map[string]repository {
+	return map[string]repository{
+		"SQLite": NewTradingRepository(setupDatabase(t)),
+		"Memory": NewMemoryRepository([]domain.Stock{{
+			Symbol:      "AAPL",
+			BidPrice:    150.00,
+			BidVolume:   1000,
+			AskPrice:    150.50,
+			AskVolume:   800,
+			LastUpdated: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		}}),
+	}
+}
+
+func TestRepository_GetStockBySymbol(t *testing.T) {
+	for name, repo := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			testGetStockBySymbol(t, repo)
+		})
+	}
+}
+
+func testGetStockBySymbol(t *testing.T, repo repository) {
+
+	stock, err := repo.GetStockBySymbol(context.Background(), "AAPL")
+	require.NoError(t, err)
+	assert.Equal(t, "AAPL", stock.Symbol)
+	assert.Equal(t, 150.50, stock.AskPrice)
+	assert.Equal(t, 800, stock.AskVolume)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), stock.LastUpdated)
+
+	_, err = repo.GetStockBySymbol(context.Background(), "MSFT")
+	kind, ok := domain.ErrorKindOf(err)
+	assert.True(t, ok)
+	assert.Equal(t, domain.KindNotFound, kind)
+}
+
+func TestTradingRepository_UpdateStockKeepsHistory(t *testing.T) {
+	db := setupDatabase(t)
+	repo := NewTradingRepository(db)
+	ctx := context.Background()
+
+	before, err := repo.GetStockBySymbol(ctx, "AAPL")
+	require.NoError(t, err)
+
+	err = repo.UpdateStock(ctx, &domain.Stock{Symbol: "AAPL", BidPrice: 151, BidVolume: 900, AskPrice: 151.25, AskVolume: 700})
+	require.NoError(t, err)
+
+	after, err := repo.GetStockBySymbol(ctx, "AAPL")
+	require.NoError(t, err)
+	assert.Equal(t, 151.25, after.AskPrice)
+	assert.True(t, after.LastUpdated.After(before.LastUpdated))
+
+	var history []struct {
+		AskPrice    float64   `gorm:"column:AskPrice"`
+		LastUpdated time.Time `gorm:"column:LastUpdated"`
+		ValidTo     time.Time `gorm:"column:ValidTo"`
+	}
+	require.NoError(t, db.Table("StocksHistory").Find(&history).Error)
+	require.Len(t, history, 1)
+	assert.Equal(t, 150.50, history[0].AskPrice)
+	assert.True(t, history[0].LastUpdated.Equal(before.LastUpdated))
+	assert.True(t, history[0].ValidTo.Equal(after.LastUpdated))
+}
+
+func TestRepository_Transactions(t *testing.T) {
+	for name, repo := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			testTransactions(t, repo)
+		})
+	}
+}
+
+func testTransactions(t *testing.T, repo repository) {
+	ctx := context.Background()
+
+	clientOrderID := "desk-1"
+	tx := &domain.Transaction{
+		Symbol:        "AAPL",
+		Type:          domain.Buy,
+		Status:        domain.Pending,
+		Quantity:      100,
+		Price:         150.50,
+		TotalAmount:   15050,
+		OrderTime:     time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC),
+		ClientOrderID: &clientOrderID,
+	}
+	require.NoError(t, repo.CreateTransaction(ctx, tx))
+	assert.NotZero(t, tx.TransactionID)
+
+	duplicate := *tx
+	duplicate.TransactionID = 0
+	assert.Error(t, repo.CreateTransaction(ctx, &duplicate))
+
+	require.NoError(t, repo.UpdateTransactionStatus(ctx, tx.TransactionID, domain.Completed))
+
+	transactions, err := repo.GetAllTransactions(ctx)
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, domain.Completed, transactions[0].Status)
+	assert.Equal(t, "AAPL", transactions[0].Stock.Symbol)
+	assert.True(t, transactions[0].OrderTime.Equal(tx.OrderTime))
+	assert.NotNil(t, transactions[0].ExecutionTime)
+
+	err = repo.UpdateTransactionStatus(ctx, tx.TransactionID+1, domain.Cancelled)
+	kind, _ := domain.ErrorKindOf(err)
+	assert.Equal(t, domain.KindNotFound, kind)
+}
+
+func TestRepository_OutboxEvents(t *testing.T) {
+	for name, repo := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			testOutboxEvents(t, repo)
+		})
+	}
+}
+
+func testOutboxEvents(t *testing.T, repo repository) {
+	ctx := context.Background()
+
+	clientOrderID := "desk-1"
+	tx := &domain.Transaction{
+		Symbol:        "AAPL",
+		Type:          domain.Sell,
+		Status:        domain.Pending,
+		Quantity:      10,
+		Price:         150,
+		TotalAmount:   1500,
+		OrderTime:     time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC),
+		ClientOrderID: &clientOrderID,
+	}
+	require.NoError(t, repo.CreateTransaction(ctx, tx))
+
+	// Writes that fail leave no events behind.
+	duplicate := *tx
+	duplicate.TransactionID = 0
+	require.Error(t, repo.CreateTransaction(ctx, &duplicate))
+	require.Error(t, repo.UpdateTransactionStatus(ctx, tx.TransactionID+1, domain.Cancelled))
+
+	require.NoError(t, repo.UpdateTransactionStatus(ctx, tx.TransactionID, domain.Completed))
+
+	events, err := repo.GetOutboxEvents(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, domain.OrderCreated, events[0].EventType)
+	assert.Equal(t, domain.OrderStatusChanged, events[1].EventType)
+	assert.Less(t, events[0].OutboxID, events[1].OutboxID)
+
+	var created, changed domain.OrderEvent
+	require.NoError(t, json.Unmarshal([]byte(events[0].Payload), &created))
+	require.NoError(t, json.Unmarshal([]byte(events[1].Payload), &changed))
+	assert.Equal(t, tx.TransactionID, events[0].AggregateID)
+	assert.Equal(t, "PENDING", created.Status)
+	assert.Equal(t, "SELL", created.Type)
+	assert.Equal(t, &clientOrderID, created.ClientOrderID)
+	assert.Empty(t, created.PreviousStatus)
+	assert.Equal(t, "COMPLETED", changed.Status)
+	assert.Equal(t, "PENDING", changed.PreviousStatus)
+	assert.NotNil(t, changed.ExecutionTime)
+
+	limited, err := repo.GetOutboxEvents(ctx, 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, events[:1], limited)
+	after, err := repo.GetOutboxEvents(ctx, events[0].OutboxID, 10)
+	require.NoError(t, err)
+	assert.Equal(t, events[1:], after)
+
+	require.NoError(t, repo.DeleteOutboxEvents(ctx, []int64{events[0].OutboxID}))
+	remaining, err := repo.GetOutboxEvents(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, events[1:], remaining)
+}
+
+func TestRepository_CreateTransactionUnknownStock(t *testing.T) {
+	for name, repo := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			err := repo.CreateTransaction(context.Background(), &domain.Transaction{
+				Symbol:      "MSFT",
+				Type:        domain.Buy,
+				Status:      domain.Pending,
+				Quantity:    1,
+				Price:       400,
+				TotalAmount: 400,
+			})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRepository_FIXSession(t *testing.T) {
+	for name, repo := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			testFIXSession(t, repo)
+		})
+	}
+}
+
+func testFIXSession(t *testing.T, repo repository) {
+	ctx := context.Background()
+
+	_, err := repo.GetFIXSession(ctx, "MAXION-DESK1")
+	kind, _ := domain.ErrorKindOf(err)
+	assert.Equal(t, domain.KindNotFound, kind)
+
+	session := &domain.FIXSession{SessionID: "MAXION-DESK1", NextSenderSeqNum: 2, NextTargetSeqNum: 3, UpdatedAt: time.Now()}
+	require.NoError(t, repo.SaveFIXSession(ctx, session))
+	session.NextSenderSeqNum = 5
+	require.NoError(t, repo.SaveFIXSession(ctx, session))
+
+	stored, err := repo.GetFIXSession(ctx, "MAXION-DESK1")
+	require.NoError(t, err)
+	assert.Equal(t, 5, stored.NextSenderSeqNum)
+	assert.Equal(t, 3, stored.NextTargetSeqNum)
+}
+
+func TestTradingRepository_RecordFailedWriteAndPing(t *testing.T) {
+	db := setupDatabase(t)
+	repo := NewTradingRepository(db)
+	ctx := context.Background()
+
+	require.NoError(t, repo.RecordFailedWrite(ctx, &domain.FailedWrite{
+		CacheKey:  "pending:create:1",
+		Operation: "create",
+		Payload:   "{}",
+		Error:     "constraint failed",
+		FailedAt:  time.Now(),
+	}))
+
+	var count int64
+	require.NoError(t, db.Model(&domain.FailedWrite{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	assert.NoError(t, repo.Ping(ctx))
+}
+
+// createBracket inserts a bracket buying 10 AAPL with held take-profit and
+// stop-loss legs.
+func createBracket(t *testing.T, repo repository) []domain.Transaction {
+	groupID := "3f1c9a52-7d1e-4c1a-9b7e-2a4d5c6e7f80"
+	order := func(leg domain.OrderLeg, side domain.TransactionType, status domain.TransactionStatus, price float64) domain.Transaction {
+		return domain.Transaction{
+			Symbol:      "AAPL",
+			Type:        side,
+			Status:      status,
+			Quantity:    10,
+			Price:       price,
+			TotalAmount: 10 * price,
+			OrderTime:   time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC),
+			GroupID:     &groupID,
+			Leg:         &leg,
+		}
+	}
+	orders := []domain.Transaction{
+		order(domain.EntryLeg, domain.Buy, domain.Pending, 150.50),
+		order(domain.TakeProfitLeg, domain.Sell, domain.Held, 160),
+		order(domain.StopLossLeg, domain.Sell, domain.Held, 140),
+	}
+	require.NoError(t, repo.CreateOrderGroup(context.Background(), orders))
+	for _, order := range orders {
+		require.NotZero(t, order.TransactionID)
+	}
+	return orders
+}
+
+// legStatuses returns the status of each leg of the group.
+func legStatuses(t *testing.T, repo repository) map[domain.OrderLeg]domain.TransactionStatus {
+	transactions, err := repo.GetAllTransactions(context.Background())
+	require.NoError(t, err)
+
+	statuses := make(map[domain.OrderLeg]domain.TransactionStatus)
+	for _, tx := range transactions {
+		require.NotNil(t, tx.Leg)
+		statuses[*tx.Leg] = tx.Status
+	}
+	return statuses
+}
+
+func TestRepository_OrderGroupFills(t *testing.T) {
+	for name, repo := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			testOrderGroupFills(t, repo)
+		})
+	}
+}
+
+func testOrderGroupFills(t *testing.T, repo repository) {
+	ctx := context.Background()
+	orders := createBracket(t, repo)
+	entry, takeProfit, stopLoss := orders[0], orders[1], orders[2]
+
+	// A held leg can't fill before its entry.
+	err := repo.UpdateTransactionStatus(ctx, takeProfit.TransactionID, domain.Completed)
+	kind, _ := domain.ErrorKindOf(err)
+	assert.Equal(t, domain.KindConflict, kind)
+
+	require.NoError(t, repo.UpdateTransactionStatus(ctx, entry.TransactionID, domain.Completed))
+	assert.Equal(t, map[domain.OrderLeg]domain.TransactionStatus{
+		domain.EntryLeg:      domain.Completed,
+		domain.TakeProfitLeg: domain.Pending,
+		domain.StopLossLeg:   domain.Pending,
+	}, legStatuses(t, repo))
+
+	require.NoError(t, repo.UpdateTransactionStatus(ctx, takeProfit.TransactionID, domain.Completed))
+	assert.Equal(t, map[domain.OrderLeg]domain.TransactionStatus{
+		domain.EntryLeg:      domain.Completed,
+		domain.TakeProfitLeg: domain.Completed,
+		domain.StopLossLeg:   domain.Cancelled,
+	}, legStatuses(t, repo))
+
+	// The cancelled leg can't fill any more, and repeating a fill changes
+	// nothing.
+	err = repo.UpdateTransactionStatus(ctx, stopLoss.TransactionID, domain.Completed)
+	kind, _ = domain.ErrorKindOf(err)
+	assert.Equal(t, domain.KindConflict, kind)
+	require.NoError(t, repo.UpdateTransactionStatus(ctx, takeProfit.TransactionID, domain.Completed))
+
+	events, err := repo.GetOutboxEvents(ctx, 0, 100)
+	require.NoError(t, err)
+	// Three created, the entry filled, two legs activated, one filled and
+	// one cancelled.
+	require.Len(t, events, 8)
+	var cancelled domain.OrderEvent
+	require.NoError(t, json.Unmarshal([]byte(events[7].Payload), &cancelled))
+	assert.Equal(t, stopLoss.TransactionID, cancelled.TransactionID)
+	assert.Equal(t, "CANCELLED", cancelled.Status)
+	assert.Equal(t, "PENDING", cancelled.PreviousStatus)
+	assert.Equal(t, entry.GroupID, cancelled.GroupID)
+	assert.Equal(t, domain.StopLossLeg, *cancelled.Leg)
+}
+
+func TestRepository_OrderGroupEntryCancelled(t *testing.T) {
+	for name, repo := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			entry := createBracket(t, repo)[0]
+
+			require.NoError(t, repo.UpdateTransactionStatus(context.Background(), entry.TransactionID, domain.Cancelled))
+			assert.Equal(t, map[domain.OrderLeg]domain.TransactionStatus{
+				domain.EntryLeg:      domain.Cancelled,
+				domain.TakeProfitLeg: domain.Cancelled,
+				domain.StopLossLeg:   domain.Cancelled,
+			}, legStatuses(t, repo))
+		})
+	}
+}
+
+func TestRepository_GetTransactionGroup(t *testing.T) {
+	for name, repo := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			orders := createBracket(t, repo)
+			single := &domain.Transaction{Symbol: "AAPL", Type: domain.Buy, Status: domain.Pending, Quantity: 1, Price: 150.50, TotalAmount: 150.50}
+			require.NoError(t, repo.CreateTransaction(ctx, single))
+
+			group, err := repo.GetTransactionGroup(ctx, orders[2].TransactionID)
+			require.NoError(t, err)
+			require.Len(t, group, 3)
+			for i, order := range orders {
+				assert.Equal(t, order.TransactionID, group[i].TransactionID)
+				assert.Equal(t, order.Status, group[i].Status)
+			}
+
+			group, err = repo.GetTransactionGroup(ctx, single.TransactionID)
+			require.NoError(t, err)
+			require.Len(t, group, 1)
+			assert.Nil(t, group[0].GroupID)
+
+			_, err = repo.GetTransactionGroup(ctx, 999)
+			kind, _ := domain.ErrorKindOf(err)
+			assert.Equal(t, domain.KindNotFound, kind)
+		})
+	}
+}
+
+func TestRepository_CreateOrderGroupIsAtomic(t *testing.T) {
+	for name, repo := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			groupID := "group-1"
+			err := repo.CreateOrderGroup(ctx, []domain.Transaction{
+				{Symbol: "AAPL", Type: domain.Buy, Status: domain.Pending, Quantity: 10, Price: 150.50, TotalAmount: 1505, GroupID: &groupID},
+				{Symbol: "AAPL", Type: domain.Sell, Status: domain.Held, Quantity: 10, Price: 0, TotalAmount: 0, GroupID: &groupID},
+			})
+			assert.Error(t, err)
+
+			transactions, err := repo.GetAllTransactions(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, transactions)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func webhookNotFound(id int64) error {
+	return domain.NewNotFoundError("webhook_not_found", fmt.Sprintf("webhook %d not found", id))
+}
+
+func deliveryNotFound(id int64) error {
+	return domain.NewNotFoundError("webhook_delivery_not_found", fmt.Sprintf("webhook delivery %d not found", id))
+}
+
+func (r *tradingRepository) CreateWebhookSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	return r.db.WithContext(ctx).Create(sub).Error
+}
+
+func (r *tradingRepository) GetWebhookSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	var subs []domain.WebhookSubscription
+	err := r.db.WithContext(ctx).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "SubscriptionId"}}).
+		Find(&subs).Error
+	return subs, err
+}
+
+func (r *tradingRepository) GetWebhookSubscription(ctx context.Context, id int64) (*domain.WebhookSubscription, error) {
+	var sub domain.WebhookSubscription
+	err := r.db.WithContext(ctx).Where(map[string]any{"SubscriptionId": id}).First(&sub).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, webhookNotFound(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *tradingRepository) UpdateWebhookSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	result := r.db.WithContext(ctx).Model(&domain.WebhookSubscription{}).
+		Where(map[string]any{"SubscriptionId": sub.SubscriptionID}).
+		Select("Url", "Secret", "Events", "Active").
+		Updates(sub)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return webhookNotFound(sub.SubscriptionID)
+	}
+	return nil
+}
+
+func (r *tradingRepository) DeleteWebhookSubscription(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		if err := db.Where(map[string]any{"SubscriptionId": id}).Delete(&domain.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		result := db.Where(map[string]any{"SubscriptionId": id}).Delete(&domain.WebhookSubscription{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return webhookNotFound(id)
+		}
+		return nil
+	})
+}
+
+func (r *tradingRepository) CreateWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Create(delivery).Error
+}
+
+// CreateWebhookDeliveries inserts deliveries in a single statement.
+func (r *tradingRepository) CreateWebhookDeliveries(ctx context.Context, deliveries []domain.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&deliveries).Error
+}
+
+func (r *tradingRepository) GetWebhookDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	err := r.db.WithContext(ctx).
+		Where(map[string]any{"SubscriptionId": subscriptionID}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "DeliveryId"}, Desc: true}).
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *tradingRepository) GetWebhookDelivery(ctx context.Context, id int64) (*domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	err := r.db.WithContext(ctx).Where(map[string]any{"DeliveryId": id}).First(&delivery).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, deliveryNotFound(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *tradingRepository) GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	// clause.Lte and clause.OrderByColumn quote the column names, as the
+	// condition maps do.
+	err := r.db.WithContext(ctx).
+		Where(map[string]any{"Status": domain.DeliveryPending}).
+		Where(clause.Lte{Column: clause.Column{Name: "NextAttemptAt"}, Value: now.UTC()}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "DeliveryId"}}).
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *tradingRepository) UpdateWebhookDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	result := r.db.WithContext(ctx).Model(&domain.WebhookDelivery{}).
+		Where(map[string]any{"DeliveryId": delivery.DeliveryID}).
+		Select("Status", "Attempts", "NextAttemptAt", "ResponseStatus", "LastError", "DeliveredAt").
+		Updates(delivery)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return deliveryNotFound(delivery.DeliveryID)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+func TestRepository_WebhookSubscriptions(t *testing.T) {
+	for name, repo := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			sub := &domain.WebhookSubscription{
+				URL:       "https://bookkeeping.example.com/hooks",
+				Secret:    "0123456789abcdef",
+				Events:    []string{domain.OrderCompleted},
+				Active:    true,
+				CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			}
+			require.NoError(t, repo.CreateWebhookSubscription(ctx, sub))
+			assert.NotZero(t, sub.SubscriptionID)
+
+			sub.Events = []string{domain.OrderCancelled, domain.OrderFailed}
+			sub.Active = false
+			require.NoError(t, repo.UpdateWebhookSubscription(ctx, sub))
+
+			got, err := repo.GetWebhookSubscription(ctx, sub.SubscriptionID)
+			require.NoError(t, err)
+			assert.Equal(t, sub, got)
+
+			subs, err := repo.GetWebhookSubscriptions(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, []domain.WebhookSubscription{*sub}, subs)
+
+			require.NoError(t, repo.DeleteWebhookSubscription(ctx, sub.SubscriptionID))
+			for _, err := range []error{
+				repo.DeleteWebhookSubscription(ctx, sub.SubscriptionID),
+				repo.UpdateWebhookSubscription(ctx, sub),
+			} {
+				kind, _ := domain.ErrorKindOf(err)
+				assert.Equal(t, domain.KindNotFound, kind)
+			}
+			_, err = repo.GetWebhookSubscription(ctx, sub.SubscriptionID)
+			kind, _ := domain.ErrorKindOf(err)
+			assert.Equal(t, domain.KindNotFound, kind)
+		})
+	}
+}
+
+func TestRepository_WebhookDeliveries(t *testing.T) {
+	for name, repo := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+			sub := &domain.WebhookSubscription{URL: "https://example.com", Secret: "0123456789abcdef", Events: domain.WebhookEvents, Active: true, CreatedAt: now}
+			require.NoError(t, repo.CreateWebhookSubscription(ctx, sub))
+
+			newDelivery := func(nextAttemptAt time.Time) *domain.WebhookDelivery {
+				delivery := &domain.WebhookDelivery{
+					SubscriptionID: sub.SubscriptionID,
+					EventID:        "evt-1",
+					EventType:      domain.OrderCompleted,
+					Payload:        `{}`,
+					Status:         domain.DeliveryPending,
+					NextAttemptAt:  &nextAttemptAt,
+					CreatedAt:      now,
+				}
+				require.NoError(t, repo.CreateWebhookDelivery(ctx, delivery))
+				return delivery
+			}
+			due := newDelivery(now.Add(-time.Second))
+			later := newDelivery(now.Add(time.Minute))
+
+			deliveries, err := repo.GetDueWebhookDeliveries(ctx, now, 10)
+			require.NoError(t, err)
+			require.Len(t, deliveries, 1)
+			assert.Equal(t, due.DeliveryID, deliveries[0].DeliveryID)
+
+			// Delivered ones are no longer due, and keep their outcome.
+			responseStatus := 204
+			due.Status = domain.DeliverySucceeded
+			due.Attempts = 1
+			due.NextAttemptAt = nil
+			due.ResponseStatus = &responseStatus
+			due.DeliveredAt = &now
+			require.NoError(t, repo.UpdateWebhookDelivery(ctx, due))
+
+			deliveries, err = repo.GetDueWebhookDeliveries(ctx, now.Add(time.Hour), 10)
+			require.NoError(t, err)
+			require.Len(t, deliveries, 1)
+			assert.Equal(t, later.DeliveryID, deliveries[0].DeliveryID)
+
+			got, err := repo.GetWebhookDelivery(ctx, due.DeliveryID)
+			require.NoError(t, err)
+			assert.Equal(t, domain.DeliverySucceeded, got.Status)
+			assert.Equal(t, &responseStatus, got.ResponseStatus)
+			assert.Nil(t, got.NextAttemptAt)
+			require.NotNil(t, got.DeliveredAt)
+			assert.True(t, got.DeliveredAt.Equal(now))
+
+			// The log is newest first.
+			log, err := repo.GetWebhookDeliveries(ctx, sub.SubscriptionID, 10)
+			require.NoError(t, err)
+			require.Len(t, log, 2)
+			assert.Equal(t, later.DeliveryID, log[0].DeliveryID)
+
+			// Deliveries queued together get IDs in order.
+			batch := []domain.WebhookDelivery{
+				{SubscriptionID: sub.SubscriptionID, EventID: "evt-2", EventType: domain.OrderCancelled, Payload: `{}`, Status: domain.DeliveryPending, CreatedAt: now},
+				{SubscriptionID: sub.SubscriptionID, EventID: "evt-3", EventType: domain.OrderFailed, Payload: `{}`, Status: domain.DeliveryPending, CreatedAt: now},
+			}
+			require.NoError(t, repo.CreateWebhookDeliveries(ctx, batch))
+			assert.Less(t, later.DeliveryID, batch[0].DeliveryID)
+			assert.Less(t, batch[0].DeliveryID, batch[1].DeliveryID)
+			require.NoError(t, repo.CreateWebhookDeliveries(ctx, nil))
+
+			// Deleting the subscription deletes its deliveries.
+			require.NoError(t, repo.DeleteWebhookSubscription(ctx, sub.SubscriptionID))
+			_, err = repo.GetWebhookDelivery(ctx, due.DeliveryID)
+			kind, _ := domain.ErrorKindOf(err)
+			assert.Equal(t, domain.KindNotFound, kind)
+		})
+	}
+}
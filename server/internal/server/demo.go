@@ -0,0 +1,73 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/touchsung/maxion-server/internal/config"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/repositories"
+)
+
+// NewDemoServer returns a server that needs neither a database nor Redis. It
+// keeps its data, cache and quotes in memory, with the stocks of sql/mock_data.sql,
+// and runs an embedded Redis for the rate limits. Nothing survives a restart.
+func NewDemoServer(cfg *config.Config, calendar ports.MarketCalendar, logger *slog.Logger) (*Server, error) {
+	embedded, err := miniredis.Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start embedded redis: %w", err)
+	}
+	stopClock := runClock(embedded, time.Second)
+
+	redisClient := redis.NewClient(&redis.Options{Addr: embedded.Addr()})
+	repo := repositories.NewMemoryRepository(repositories.DemoStocks())
+
+	return newServer(cfg, storage{
+		repo:   repo,
+		cache:  repositories.NewMemoryCache(),
+		quotes: repositories.NewMemoryQuoteStore(),
+		leases: repositories.NewMemoryLeases(),
+		redis:  redisClient,
+		close: func() error {
+			err := redisClient.Close()
+			stopClock()
+			embedded.Close()
+			if err != nil {
+				return fmt.Errorf("failed to close redis: %w", err)
+			}
+			return nil
+		},
+	}, calendar, logger), nil
+}
+
+// runClock moves the embedded Redis clock along with the wall clock, as it
+// only expires keys when told that time has passed.
+func runClock(embedded *miniredis.Miniredis, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := time.Now()
+		for {
+			select {
+			case now := <-ticker.C:
+				embedded.FastForward(now.Sub(last))
+				last = now
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/config"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/handlers"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/repositories"
+)
+
+type openCalendar struct{}
+
+func (openCalendar) Status(at time.Time) domain.MarketStatus {
+	return domain.MarketStatus{Session: domain.Regular, IsOpen: true, AcceptsOrders: true}
+}
+
+func TestDemoServer(t *testing.T) {
+	cfg := config.Default()
+	cfg.Demo = true
+	cfg.GRPC.Addr = ""
+
+	s, err := NewDemoServer(&cfg, openCalendar{}, logging.Discard())
+	require.NoError(t, err)
+	s.setupRoutes()
+
+	resp, err := s.app.Test(httptest.NewRequest("GET", "/v1/stocks", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	var stocks []handlers.StockV1
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stocks))
+	assert.Len(t, stocks, len(repositories.DemoStocks()))
+
+	body, _ := json.Marshal(map[string]any{"symbol": "AAPL", "type": "BUY", "quantity": 10})
+	req := httptest.NewRequest("POST", "/v1/transactions", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = s.app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, 201, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("RateLimit-Limit"))
+
+	report := s.cacheService.Flush(context.Background())
+	assert.Equal(t, 1, report.Synced)
+	assert.Empty(t, report.Failures)
+
+	resp, err = s.app.Test(httptest.NewRequest("GET", "/v1/transactions", nil))
+	require.NoError(t, err)
+	var transactions []handlers.TransactionV1
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&transactions))
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "AAPL", transactions[0].Symbol)
+
+	resp, err = s.app.Test(httptest.NewRequest("GET", "/readyz", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = s.Shutdown(ctx)
+	assert.NoError(t, err)
+}
+
+func TestDemoServer_RateLimitsClientsBehindProxy(t *testing.T) {
+	cfg := config.Default()
+	cfg.Demo = true
+	cfg.GRPC.Addr = ""
+	cfg.RateLimit.Default = config.RateLimitRule{PerIP: 1, Window: time.Minute}
+	// Requests made with app.Test come from 0.0.0.0.
+	cfg.Server.ProxyHeader = "X-Forwarded-For"
+	cfg.Server.TrustedProxies = []string{"0.0.0.0"}
+
+	s, err := NewDemoServer(&cfg, openCalendar{}, logging.Discard())
+	require.NoError(t, err)
+	s.setupRoutes()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, _ = s.Shutdown(ctx)
+	})
+
+	testCases := []struct {
+		name           string
+		forwardedFor   string
+		expectedStatus int
+	}{
+		{name: "First client", forwardedFor: "203.0.113.7, 0.0.0.0", expectedStatus: 200},
+		{name: "Second client has its own quota", forwardedFor: "203.0.113.8", expectedStatus: 200},
+		{name: "First client again", forwardedFor: "203.0.113.7", expectedStatus: 429},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/stocks", nil)
+			req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+			resp, err := s.app.Test(req)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/touchsung/maxion-server/internal/config"
+	"github.com/touchsung/maxion-server/internal/grpcapi"
+	"github.com/touchsung/maxion-server/internal/grpcapi/maxionv1"
+	"github.com/touchsung/maxion-server/internal/ratelimit"
+	"google.golang.org/grpc"
+)
+
+// requestTimeout bounds each request's user context, so repository and Redis
+// calls made with c.UserContext() are cancelled once the deadline passes.
+func requestTimeout(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
+
+// deprecated announces that a route is being retired in favour of the same
+// path under successorPrefix using the Deprecation and Link response headers.
+// The link names the requested path, so that it can be followed.
+func deprecated(successorPrefix string, next fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set("Deprecation", "true")
+		c.Set(fiber.HeaderLink, fmt.Sprintf("<%s%s>; rel=\"successor-version\"", successorPrefix, c.Path()))
+		return next(c)
+	}
+}
+
+// rateLimit applies the configured quota of r, counted separately for each
+// route.
+func (s *Server) rateLimit(r route) fiber.Handler {
+	return ratelimit.Middleware(s.rateLimiter, r.Method+" "+r.Path,
+		rateLimitRule(s.cfg, r.Method, r.Path), s.cfg.RateLimit.APIKeys, s.logger)
+}
+
+// grpcRateLimit limits gRPC calls, and the opening of streams, with the
+// quotas of the REST routes they mirror, counted in the same buckets so that
+// a client gets no more by using both APIs. Streams draw on the route that
+// lists what they stream.
+func grpcRateLimit(cfg *config.Config, limiter *ratelimit.Limiter, logger *slog.Logger) grpcapi.Interceptors {
+	route := func(method string, path string) ratelimit.Method {
+		return ratelimit.Method{Route: method + " " + path, Rule: rateLimitRule(cfg, method, path)}
+	}
+	methods := map[string]ratelimit.Method{
+		maxionv1.TradingService_ListStocks_FullMethodName:         route(fiber.MethodGet, apiV1Prefix+"/stocks"),
+		maxionv1.TradingService_ListOrders_FullMethodName:         route(fiber.MethodGet, apiV1Prefix+"/transactions"),
+		maxionv1.TradingService_PlaceOrder_FullMethodName:         route(fiber.MethodPost, apiV1Prefix+"/transactions"),
+		maxionv1.TradingService_UpdateOrderStatus_FullMethodName:  route(fiber.MethodPut, apiV1Prefix+"/transactions/:id/status"),
+		maxionv1.TradingService_StreamQuotes_FullMethodName:       route(fiber.MethodGet, apiV1Prefix+"/stocks"),
+		maxionv1.TradingService_StreamOrderUpdates_FullMethodName: route(fiber.MethodGet, apiV1Prefix+"/transactions"),
+		maxionv1.TradingService_StreamAlerts_FullMethodName:       route(fiber.MethodGet, apiV1Prefix+"/alerts"),
+	}
+	proxies := ratelimit.Proxies{
+		Header:  cfg.Server.ProxyHeader,
+		Trusted: cfg.Server.TrustedProxies,
+	}
+	return grpcapi.Interceptors{
+		Unary:  []grpc.UnaryServerInterceptor{ratelimit.UnaryServerInterceptor(limiter, methods, cfg.RateLimit.APIKeys, proxies, logger)},
+		Stream: []grpc.StreamServerInterceptor{ratelimit.StreamServerInterceptor(limiter, methods, cfg.RateLimit.APIKeys, proxies, logger)},
+	}
+}
+
+// fixRateLimit counts FIX orders against the REST order entry quota for
+// API keys, per counterparty.
+func fixRateLimit(cfg *config.Config, limiter *ratelimit.Limiter, logger *slog.Logger) func(ctx context.Context, compID string) error {
+	method, path := fiber.MethodPost, apiV1Prefix+"/transactions"
+	return ratelimit.Func(limiter, method+" "+path, "fix", rateLimitRule(cfg, method, path), logger)
+}
+
+func rateLimitRule(cfg *config.Config, method string, path string) ratelimit.Rule {
+	rule := cfg.RateLimit.Rule(method, path)
+	return ratelimit.Rule{
+		PerIP:     rule.PerIP,
+		PerAPIKey: rule.PerAPIKey,
+		Window:    rule.Window,
+	}
+}
@@ -0,0 +1,499 @@
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+	"github.com/touchsung/maxion-server/internal/handlers"
+	"github.com/touchsung/maxion-server/internal/metrics"
+	"github.com/touchsung/maxion-server/internal/openapi"
+)
+
+const (
+	apiV1Prefix   = "/v1"
+	openAPIPath   = "/openapi.json"
+	docsPath      = "/docs"
+	docsAssetPath = docsPath + "/:asset"
+)
+
+// route pairs a handler with its OpenAPI description, so that every route
+// registered from these tables is documented.
+type route struct {
+	openapi.Route
+	handler fiber.Handler
+}
+
+// probeRoutes are served ahead of the request middleware.
+func (s *Server) probeRoutes() []route {
+	return []route{
+		{
+			Route: openapi.Route{
+				Method:    fiber.MethodGet,
+				Path:      "/healthz",
+				Summary:   "Liveness probe",
+				Tag:       "Health",
+				Responses: map[int]any{200: map[string]domain.HealthState{}},
+			},
+			handler: s.healthHandlers.Liveness,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodGet,
+				Path:    "/readyz",
+				Summary: "Readiness of dependencies and background workers",
+				Tag:     "Health",
+				Responses: map[int]any{
+					200: domain.Readiness{},
+					503: domain.Readiness{},
+				},
+			},
+			handler: s.healthHandlers.Readiness,
+		},
+		{
+			Route: openapi.Route{
+				Method:      fiber.MethodGet,
+				Path:        "/metrics",
+				Summary:     "Prometheus metrics",
+				Tag:         "Metrics",
+				Responses:   map[int]any{200: ""},
+				ContentType: "text/plain",
+			},
+			handler: metrics.Handler(),
+		},
+	}
+}
+
+// apiRoutes are the versioned routes followed by their deprecated,
+// unversioned predecessors.
+func (s *Server) apiRoutes() []route {
+	routes := append(s.v1Routes(), s.legacyRoutes()...)
+	if s.cfg.RateLimit.Enabled {
+		for i := range routes {
+			routes[i].Responses[429] = handlers.Problem{}
+		}
+	}
+	return routes
+}
+
+func (s *Server) v1Routes() []route {
+	problem := handlers.Problem{}
+
+	return []route{
+		{
+			Route: openapi.Route{
+				Method:    fiber.MethodGet,
+				Path:      apiV1Prefix + "/market/status",
+				Summary:   "Current trading session of the exchange",
+				Tag:       "Market",
+				Responses: map[int]any{200: domain.MarketStatus{}},
+			},
+			handler: s.marketHandlers.GetMarketStatus,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodGet,
+				Path:    apiV1Prefix + "/stocks",
+				Summary: "List stock quotes",
+				Tag:     "Stocks",
+				Responses: map[int]any{
+					200: []handlers.StockV1{},
+					500: problem,
+					504: problem,
+				},
+			},
+			handler: s.handlers.GetAllStocksV1,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodGet,
+				Path:    apiV1Prefix + "/transactions",
+				Summary: "List transactions",
+				Tag:     "Transactions",
+				Responses: map[int]any{
+					200: []handlers.TransactionV1{},
+					500: problem,
+					504: problem,
+				},
+			},
+			handler: s.handlers.GetAllTransactionsV1,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodPost,
+				Path:    apiV1Prefix + "/transactions",
+				Summary: "Place an order",
+				Tag:     "Transactions",
+				Request: handlers.CreateTransactionRequestV1{},
+				Responses: map[int]any{
+					201: handlers.TransactionV1{},
+					400: problem,
+					404: problem,
+					422: problem,
+					500: problem,
+					503: problem,
+					504: problem,
+				},
+			},
+			handler: s.handlers.CreateTransactionV1,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodPost,
+				Path:    apiV1Prefix + "/order-groups",
+				Summary: "Place a bracket or OCO order group",
+				Tag:     "Transactions",
+				Request: handlers.CreateOrderGroupRequestV1{},
+				Responses: map[int]any{
+					201: handlers.OrderGroupV1{},
+					400: problem,
+					404: problem,
+					422: problem,
+					500: problem,
+					503: problem,
+					504: problem,
+				},
+			},
+			handler: s.handlers.CreateOrderGroupV1,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodPut,
+				Path:    apiV1Prefix + "/transactions/:id/status",
+				Summary: "Change the status of a transaction",
+				Tag:     "Transactions",
+				Request: handlers.UpdateTransactionStatusRequestV1{},
+				Responses: map[int]any{
+					204: nil,
+					400: problem,
+					500: problem,
+					503: problem,
+					504: problem,
+				},
+			},
+			handler: s.handlers.UpdateTransactionStatusV1,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodPost,
+				Path:    apiV1Prefix + "/webhooks",
+				Summary: "Subscribe a URL to order webhooks",
+				Tag:     "Webhooks",
+				Request: handlers.CreateWebhookRequestV1{},
+				Responses: map[int]any{
+					201: handlers.WebhookV1{},
+					400: problem,
+					500: problem,
+					504: problem,
+				},
+			},
+			handler: s.webhookHandlers.CreateWebhook,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodGet,
+				Path:    apiV1Prefix + "/webhooks",
+				Summary: "List webhook subscriptions",
+				Tag:     "Webhooks",
+				Responses: map[int]any{
+					200: []handlers.WebhookV1{},
+					500: problem,
+					504: problem,
+				},
+			},
+			handler: s.webhookHandlers.GetWebhooks,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodGet,
+				Path:    apiV1Prefix + "/webhooks/:id",
+				Summary: "Get a webhook subscription",
+				Tag:     "Webhooks",
+				Responses: map[int]any{
+					200: handlers.WebhookV1{},
+					400: problem,
+					404: problem,
+					500: problem,
+					504: problem,
+				},
+			},
+			handler: s.webhookHandlers.GetWebhook,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodPut,
+				Path:    apiV1Prefix + "/webhooks/:id",
+				Summary: "Replace a webhook subscription",
+				Tag:     "Webhooks",
+				Request: handlers.UpdateWebhookRequestV1{},
+				Responses: map[int]any{
+					200: handlers.WebhookV1{},
+					400: problem,
+					404: problem,
+					500: problem,
+					504: problem,
+				},
+			},
+			handler: s.webhookHandlers.UpdateWebhook,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodDelete,
+				Path:    apiV1Prefix + "/webhooks/:id",
+				Summary: "Delete a webhook subscription and its deliveries",
+				Tag:     "Webhooks",
+				Responses: map[int]any{
+					204: nil,
+					400: problem,
+					404: problem,
+					500: problem,
+					504: problem,
+				},
+			},
+			handler: s.webhookHandlers.DeleteWebhook,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodGet,
+				Path:    apiV1Prefix + "/webhooks/:id/deliveries",
+				Summary: "List the latest deliveries of a webhook subscription",
+				Tag:     "Webhooks",
+				Responses: map[int]any{
+					200: []handlers.WebhookDeliveryV1{},
+					400: problem,
+					404: problem,
+					500: problem,
+					504: problem,
+				},
+			},
+			handler: s.webhookHandlers.GetDeliveries,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodPost,
+				Path:    apiV1Prefix + "/webhooks/:id/deliveries/:deliveryId/redeliver",
+				Summary: "Send the event of a delivery again",
+				Tag:     "Webhooks",
+				Responses: map[int]any{
+					202: handlers.WebhookDeliveryV1{},
+					400: problem,
+					404: problem,
+					500: problem,
+					504: problem,
+				},
+			},
+			handler: s.webhookHandlers.Redeliver,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodPost,
+				Path:    apiV1Prefix + "/alert-rules",
+				Summary: "Create a price alert rule",
+				Tag:     "Alerts",
+				Request: handlers.CreateAlertRuleRequestV1{},
+				Responses: map[int]any{
+					201: handlers.AlertRuleV1{},
+					400: problem,
+					404: problem,
+					500: problem,
+					504: problem,
+				},
+			},
+			handler: s.alertHandlers.CreateAlertRule,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodGet,
+				Path:    apiV1Prefix + "/alert-rules",
+				Summary: "List price alert rules",
+				Tag:     "Alerts",
+				Responses: map[int]any{
+					200: []handlers.AlertRuleV1{},
+					500: problem,
+					504: problem,
+				},
+			},
+			handler: s.alertHandlers.GetAlertRules,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodGet,
+				Path:    apiV1Prefix + "/alert-rules/:id",
+				Summary: "Get a price alert rule",
+				Tag:     "Alerts",
+				Responses: map[int]any{
+					200: handlers.AlertRuleV1{},
+					400: problem,
+					404: problem,
+					500: problem,
+					504: problem,
+				},
+			},
+			handler: s.alertHandlers.GetAlertRule,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodDelete,
+				Path:    apiV1Prefix + "/alert-rules/:id",
+				Summary: "Delete a price alert rule and its alerts",
+				Tag:     "Alerts",
+				Responses: map[int]any{
+					204: nil,
+					400: problem,
+					404: problem,
+					500: problem,
+					504: problem,
+				},
+			},
+			handler: s.alertHandlers.DeleteAlertRule,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodGet,
+				Path:    apiV1Prefix + "/alert-rules/:id/alerts",
+				Summary: "List the latest alerts of a rule",
+				Tag:     "Alerts",
+				Responses: map[int]any{
+					200: []handlers.AlertV1{},
+					400: problem,
+					404: problem,
+					500: problem,
+					504: problem,
+				},
+			},
+			handler: s.alertHandlers.GetRuleAlerts,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodGet,
+				Path:    apiV1Prefix + "/alerts",
+				Summary: "List the latest alerts",
+				Tag:     "Alerts",
+				Responses: map[int]any{
+					200: []handlers.AlertV1{},
+					500: problem,
+					504: problem,
+				},
+			},
+			handler: s.alertHandlers.GetAlerts,
+		},
+	}
+}
+
+// legacyRoutes serve the original, unversioned shape, which exposes the
+// domain structs directly. They are kept for existing clients until they
+// move to /v1 and answer with Deprecation and Link headers meanwhile.
+func (s *Server) legacyRoutes() []route {
+	problem := handlers.Problem{}
+
+	routes := []route{
+		{
+			Route: openapi.Route{
+				Method:    fiber.MethodGet,
+				Path:      "/market/status",
+				Summary:   "Current trading session of the exchange",
+				Tag:       "Market",
+				Responses: map[int]any{200: domain.MarketStatus{}},
+			},
+			handler: s.marketHandlers.GetMarketStatus,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodGet,
+				Path:    "/stocks",
+				Summary: "List stock quotes",
+				Tag:     "Stocks",
+				Responses: map[int]any{
+					200: []domain.Stock{},
+					500: problem,
+					504: problem,
+				},
+			},
+			handler: s.handlers.GetAllStocks,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodGet,
+				Path:    "/transactions",
+				Summary: "List transactions",
+				Tag:     "Transactions",
+				Responses: map[int]any{
+					200: []domain.Transaction{},
+					500: problem,
+					504: problem,
+				},
+			},
+			handler: s.handlers.GetAllTransactions,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodPost,
+				Path:    "/transactions",
+				Summary: "Place an order",
+				Tag:     "Transactions",
+				Request: handlers.CreateTransactionRequest{},
+				Responses: map[int]any{
+					201: domain.Transaction{},
+					400: problem,
+					404: problem,
+					422: problem,
+					500: problem,
+					503: problem,
+					504: problem,
+				},
+			},
+			handler: s.handlers.CreateTransaction,
+		},
+		{
+			Route: openapi.Route{
+				Method:  fiber.MethodPut,
+				Path:    "/transactions/:id/status",
+				Summary: "Change the status of a transaction",
+				Tag:     "Transactions",
+				Request: handlers.UpdateTransactionStatusRequest{},
+				Responses: map[int]any{
+					200: nil,
+					400: problem,
+					500: problem,
+					503: problem,
+					504: problem,
+				},
+			},
+			handler: s.handlers.UpdateTransactionStatus,
+		},
+	}
+
+	for i := range routes {
+		routes[i].Deprecated = true
+		routes[i].handler = deprecated(apiV1Prefix, routes[i].handler)
+	}
+	return routes
+}
+
+// openAPIDocument describes the probe and API routes.
+func (s *Server) openAPIDocument() *openapi.Document {
+	generator := openapi.NewGenerator(openapi.Info{
+		Title:   "Maxion Trading API",
+		Version: "1.0.0",
+	})
+	generator.Problem(handlers.Problem{})
+	generator.Enum(domain.TransactionType(0), int64(domain.Buy), int64(domain.Sell))
+	generator.Enum(domain.TransactionStatus(0),
+		int64(domain.Pending), int64(domain.Completed), int64(domain.Cancelled), int64(domain.Failed), int64(domain.Held))
+	generator.Enum(domain.MarketSession(""),
+		domain.PreMarket, domain.Regular, domain.PostMarket, domain.Closed)
+	generator.Enum(domain.HealthState(""), domain.Healthy, domain.Degraded, domain.Unavailable)
+	generator.Enum(handlers.TransactionTypeV1(""), domain.Buy.String(), domain.Sell.String())
+	generator.Enum(handlers.TransactionStatusV1(""),
+		domain.Pending.String(), domain.Completed.String(), domain.Cancelled.String(), domain.Failed.String(), domain.Held.String())
+	generator.Enum(handlers.OrderGroupTypeV1(""), domain.BracketGroup, domain.OCOGroup)
+	generator.Enum(handlers.OrderLegV1(""), domain.EntryLeg, domain.TakeProfitLeg, domain.StopLossLeg)
+	generator.Enum(handlers.WebhookDeliveryStatusV1(""),
+		domain.DeliveryPending, domain.DeliverySucceeded, domain.DeliveryFailed)
+	generator.Enum(handlers.AlertRuleTypeV1(""), domain.ThresholdRule, domain.PercentMoveRule, domain.VolumeRule)
+	generator.Enum(handlers.QuoteSideV1(""), domain.BidSide, domain.AskSide)
+	generator.Enum(handlers.AlertDirectionV1(""), domain.Above, domain.Below)
+
+	for _, r := range append(s.probeRoutes(), s.apiRoutes()...) {
+		generator.Add(r.Route)
+	}
+	return generator.Document()
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/config"
+	"github.com/touchsung/maxion-server/internal/handlers"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/openapi"
+)
+
+// undocumented routes are served but intentionally left out of the spec.
+var undocumented = map[string]bool{
+	"GET " + openAPIPath:                    true,
+	"GET " + docsPath:                       true,
+	"GET " + openapi.PathFor(docsAssetPath): true,
+}
+
+func newRoutesTestServer() *Server {
+	cfg := config.Default()
+	cfg.Server.RequestTimeout = time.Second
+
+	return &Server{
+		cfg:             &cfg,
+		logger:          logging.Discard(),
+		app:             fiber.New(fiber.Config{ErrorHandler: handlers.ErrorHandler}),
+		handlers:        &handlers.TradingHandlers{},
+		marketHandlers:  &handlers.MarketHandlers{},
+		healthHandlers:  &handlers.HealthHandlers{},
+		webhookHandlers: &handlers.WebhookHandlers{},
+		alertHandlers:   &handlers.AlertHandlers{},
+	}
+}
+
+func TestOpenAPI_MatchesRegisteredRoutes(t *testing.T) {
+	s := newRoutesTestServer()
+	s.setupRoutes()
+
+	var registered []string
+	for _, r := range s.app.GetRoutes(true) {
+		if r.Method == fiber.MethodHead {
+			continue
+		}
+		key := r.Method + " " + openapi.PathFor(r.Path)
+		if !undocumented[key] {
+			registered = append(registered, key)
+		}
+	}
+
+	var documented []string
+	for path, item := range s.openAPIDocument().Paths {
+		for method := range item {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	sort.Strings(registered)
+	sort.Strings(documented)
+	assert.Equal(t, registered, documented, "routes and OpenAPI spec have diverged")
+}
+
+func TestOpenAPI_Served(t *testing.T) {
+	s := newRoutesTestServer()
+	s.setupRoutes()
+
+	resp, err := s.app.Test(httptest.NewRequest("GET", openAPIPath, nil))
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var doc openapi.Document
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+
+	post := doc.Paths["/transactions"]["post"]
+	require.NotNil(t, post)
+	assert.Equal(t, "#/components/schemas/CreateTransactionRequest", post.RequestBody.Content["application/json"].Schema.Ref)
+	request := doc.Components.Schemas["CreateTransactionRequest"]
+	require.NotNil(t, request)
+	assert.ElementsMatch(t, []string{"quantity", "symbol", "type"}, request.Required)
+	assert.Equal(t, []any{float64(1), float64(2)}, request.Properties["type"].Enum)
+	assert.Equal(t, 500, *request.Properties["notes"].MaxLength)
+
+	update := doc.Paths["/transactions/{id}/status"]["put"]
+	require.NotNil(t, update)
+	if assert.Len(t, update.Parameters, 1) {
+		assert.Equal(t, "id", update.Parameters[0].Name)
+	}
+
+	transaction := doc.Components.Schemas["Transaction"]
+	require.NotNil(t, transaction)
+	assert.Equal(t, "#/components/schemas/Stock", transaction.Properties["Stock"].Ref)
+	assert.Equal(t, "date-time", transaction.Properties["OrderTime"].Format)
+	assert.True(t, transaction.Properties["ExecutionTime"].Nullable)
+
+	v1 := doc.Components.Schemas["TransactionV1"]
+	require.NotNil(t, v1)
+	assert.Equal(t, []any{"BUY", "SELL"}, v1.Properties["type"].Enum)
+	assert.Equal(t, []any{"PENDING", "COMPLETED", "CANCELLED", "FAILED", "HELD"}, v1.Properties["status"].Enum)
+	assert.Equal(t, []any{"ENTRY", "TAKE_PROFIT", "STOP_LOSS"}, v1.Properties["leg"].Enum)
+	assert.NotContains(t, v1.Properties, "stock")
+
+	resp, err = s.app.Test(httptest.NewRequest("GET", docsPath, nil))
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	page, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.NotContains(t, string(page), "https://")
+
+	for path, status := range map[string]int{
+		docsPath + "/swagger-ui.css":       200,
+		docsPath + "/swagger-ui-bundle.js": 200,
+		docsPath + "/index.html":           404,
+	} {
+		resp, err = s.app.Test(httptest.NewRequest("GET", path, nil))
+		require.NoError(t, err)
+		assert.Equal(t, status, resp.StatusCode, path)
+	}
+}
+
+func TestLegacyRoutes_Deprecated(t *testing.T) {
+	s := newRoutesTestServer()
+	doc := s.openAPIDocument()
+
+	for _, r := range s.legacyRoutes() {
+		method := strings.ToLower(r.Method)
+		legacy := doc.Paths[openapi.PathFor(r.Path)][method]
+		require.NotNil(t, legacy, r.Path)
+		assert.True(t, legacy.Deprecated, r.Path)
+
+		successor := doc.Paths[openapi.PathFor(apiV1Prefix+r.Path)][method]
+		if assert.NotNil(t, successor, "no /v1 successor for %s %s", r.Method, r.Path) {
+			assert.False(t, successor.Deprecated)
+		}
+	}
+
+	app := fiber.New()
+	ok := func(c *fiber.Ctx) error {
+		return c.SendStatus(200)
+	}
+	app.Get("/stocks", deprecated(apiV1Prefix, ok))
+	app.Put("/transactions/:id/status", deprecated(apiV1Prefix, ok))
+
+	links := map[string]string{
+		"GET /stocks":                 `</v1/stocks>; rel="successor-version"`,
+		"PUT /transactions/42/status": `</v1/transactions/42/status>; rel="successor-version"`,
+	}
+	for request, link := range links {
+		method, path, _ := strings.Cut(request, " ")
+		resp, err := app.Test(httptest.NewRequest(method, path, nil))
+		require.NoError(t, err)
+		assert.Equal(t, "true", resp.Header.Get("Deprecation"), request)
+		assert.Equal(t, link, resp.Header.Get("Link"), request)
+	}
+}
@@ -1,78 +1,408 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+
+	"github.com/go-redis/redis/v8"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/logger"
-	"gorm.io/gorm"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/google/uuid"
+	"github.com/touchsung/maxion-server/internal/config"
+	"github.com/touchsung/maxion-server/internal/core/ports"
+	"github.com/touchsung/maxion-server/internal/core/services"
+	"github.com/touchsung/maxion-server/internal/events"
+	"github.com/touchsung/maxion-server/internal/fix"
+	"github.com/touchsung/maxion-server/internal/grpcapi"
 	"github.com/touchsung/maxion-server/internal/handlers"
+	"github.com/touchsung/maxion-server/internal/logging"
+	"github.com/touchsung/maxion-server/internal/metrics"
+	"github.com/touchsung/maxion-server/internal/openapi"
+	"github.com/touchsung/maxion-server/internal/ratelimit"
 	"github.com/touchsung/maxion-server/internal/repositories"
-	"github.com/touchsung/maxion-server/internal/core/services"
-	"github.com/go-redis/redis/v8"
-	"os"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"context"
+	"github.com/touchsung/maxion-server/internal/tracing"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
 )
 
+// backgroundJobsLease is the lease held by the instance running the
+// background jobs.
+const backgroundJobsLease = "background_jobs"
+
 type Server struct {
-	app          *fiber.App
-	db           *gorm.DB
-	redis        *redis.Client
-	handlers     *handlers.TradingHandlers
-	cacheService *services.CacheService
-	stockUpdater *services.StockUpdater
+	cfg    *config.Config
+	logger *slog.Logger
+	app    *fiber.App
+	// closeStorage closes the database and Redis connections.
+	closeStorage    func() error
+	handlers        *handlers.TradingHandlers
+	marketHandlers  *handlers.MarketHandlers
+	healthHandlers  *handlers.HealthHandlers
+	webhookHandlers *handlers.WebhookHandlers
+	alertHandlers   *handlers.AlertHandlers
+	cacheService    *services.CacheService
+	quoteCache      *services.QuoteCache
+	// elector runs the stock updater, cache sync, outbox relay and webhook
+	// dispatcher on one instance at a time.
+	elector *services.LeaderElector
+	// orderFeed is nil when the gRPC API and FIX gateway are disabled.
+	orderFeed *services.OrderFeed
+	// grpcServer is nil when the gRPC API is disabled.
+	grpcServer    *grpc.Server
+	tradingServer *grpcapi.TradingServer
+	// fixAcceptor is nil when the FIX gateway is disabled.
+	fixAcceptor *fix.Acceptor
+	// rateLimiter is nil when rate limiting is disabled.
+	rateLimiter *ratelimit.Limiter
+}
+
+// repository is the database, or memory in demo mode.
+type repository interface {
+	ports.StockRepository
+	ports.TransactionRepository
+	ports.FailedWriteRepository
+	ports.FIXSessionRepository
+	ports.OutboxRepository
+	ports.WebhookRepository
+	ports.AlertRepository
+	ports.Pinger
+}
+
+// storage is where the server keeps its state: the database and Redis, or
+// memory in demo mode.
+type storage struct {
+	repo   repository
+	cache  ports.Cache
+	quotes ports.QuoteStore
+	leases ports.Leases
+	// redis backs the rate limits.
+	redis redis.UniversalClient
+	// close closes the database and Redis connections.
+	close func() error
 }
 
-func NewServer(db *gorm.DB) *Server {
+func NewServer(cfg *config.Config, db *gorm.DB, calendar ports.MarketCalendar, logger *slog.Logger) *Server {
 	// Initialize Redis
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: os.Getenv("REDIS_HOST") + ":" + os.Getenv("REDIS_PORT"),
-	})
+	redisClient := config.NewRedisClient(cfg.Redis)
 
 	// Initialize repositories
 	tradingRepo := repositories.NewTradingRepository(db)
 
+	return newServer(cfg, storage{
+		repo:   tradingRepo,
+		cache:  repositories.NewRedisCache(redisClient),
+		quotes: repositories.NewRedisQuoteStore(redisClient),
+		leases: repositories.NewRedisLeases(redisClient),
+		redis:  redisClient,
+		close: func() error {
+			var errs []error
+			if err := redisClient.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close redis: %w", err))
+			}
+			if sqlDB, err := db.DB(); err == nil {
+				if err := sqlDB.Close(); err != nil {
+					errs = append(errs, fmt.Errorf("failed to close database: %w", err))
+				}
+			}
+			return errors.Join(errs...)
+		},
+	}, calendar, logger)
+}
+
+func newServer(cfg *config.Config, store storage, calendar ports.MarketCalendar, logger *slog.Logger) *Server {
+	store.redis.AddHook(tracing.RedisHook())
+	tradingRepo := store.repo
+
 	// Initialize cache service
-	cacheService := services.NewCacheService(redisClient, tradingRepo)
+	syncLogger := logger.With("component", "cache_sync")
+	cacheService := services.NewCacheService(
+		store.cache,
+		tradingRepo,
+		tradingRepo,
+		cfg.Cache.Duration,
+		cfg.Cache.SyncInterval,
+		syncLogger,
+	)
+	cacheSync := services.NewSyncWorker(cacheService, syncLogger)
 
 	// Initialize services
-	tradingService := services.NewTradingService(tradingRepo, tradingRepo, cacheService)
+	webhookService := services.NewWebhookService(tradingRepo, logger.With("component", "webhooks"))
+	quoteCache := services.NewQuoteCache(store.quotes, tradingRepo, logger.With("component", "quote_cache"))
+	tradingService := services.NewTradingService(quoteCache, tradingRepo, cacheService, calendar, logger)
+	alertService := services.NewAlertService(tradingRepo, quoteCache, logger.With("component", "alerts"))
 
 	// Initialize handlers
-	handlers := handlers.NewTradingHandlers(tradingService)
+	tradingHandlers := handlers.NewTradingHandlers(tradingService)
+	marketHandlers := handlers.NewMarketHandlers(calendar)
+	webhookHandlers := handlers.NewWebhookHandlers(webhookService)
+	alertHandlers := handlers.NewAlertHandlers(alertService)
+
+	// Initialize stock updater, which has the quotes it saves checked
+	// against the alert rules
+	alertEvaluator := services.NewAlertEvaluator(tradingRepo, logger.With("component", "alerts"))
+	stockUpdater := services.NewStockUpdater(tradingRepo, store.quotes, calendar, alertEvaluator, cfg.Updater.Interval, logger.With("component", "stock_updater"))
+
+	// Initialize webhook delivery
+	webhookDispatcher := services.NewWebhookDispatcher(
+		tradingRepo,
+		events.NewWebhookSender(cfg.Webhooks.Timeout),
+		cfg.Webhooks.PollInterval,
+		logger.With("component", "webhook_dispatcher"),
+	)
+
+	jobs := []services.Job{stockUpdater, cacheSync, webhookDispatcher}
+
+	// Initialize order event publishing. Webhooks are queued last, once the
+	// other sinks have accepted an event, so that an event retried for
+	// another sink doesn't queue them again.
+	jobs = append(jobs, services.NewOutboxRelay(
+		tradingRepo,
+		append(eventSinks(cfg.Outbox, store.redis), webhookService),
+		cfg.Outbox.PollInterval,
+		cfg.Outbox.BatchSize,
+		logger.With("component", "outbox_relay"),
+	))
+
+	// Only the leader runs the background jobs
+	elector := services.NewLeaderElector(
+		store.leases,
+		backgroundJobsLease,
+		leaseHolder(),
+		cfg.Leader.LeaseTTL,
+		jobs,
+		logger.With("component", "leader"),
+	)
 
-	// Initialize stock updater
-	stockUpdater := services.NewStockUpdater(tradingRepo)
+	// Initialize health checks
+	healthService := services.NewHealthService(tradingRepo, cacheService, cacheSync, stockUpdater, elector, logger)
+	healthHandlers := handlers.NewHealthHandlers(healthService)
+
+	var rateLimiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		rateLimiter = ratelimit.NewLimiter(store.redis)
+	}
+
+	var orderFeed *services.OrderFeed
+	if cfg.GRPC.Addr != "" || cfg.FIX.Addr != "" {
+		// Order updates are followed through the events the relay adds to
+		// the Redis stream, on whichever instance it runs.
+		feedLogger := logger.With("component", "order_feed")
+		orderFeed = services.NewOrderFeed(events.NewRedisStreamSource(store.redis, cfg.Outbox.RedisStream, feedLogger), feedLogger)
+	}
+
+	var grpcServer *grpc.Server
+	var tradingServer *grpcapi.TradingServer
+	if cfg.GRPC.Addr != "" {
+		tradingServer = grpcapi.NewTradingServer(tradingService, alertService, quoteCache, orderFeed, cfg.GRPC.StreamInterval, logger)
+		var interceptors grpcapi.Interceptors
+		if rateLimiter != nil {
+			interceptors = grpcRateLimit(cfg, rateLimiter, logger)
+		}
+		grpcServer = grpcapi.NewServer(tradingServer, cfg.Server.RequestTimeout, cfg.GRPC.Reflection, interceptors, logger)
+	}
+
+	var fixAcceptor *fix.Acceptor
+	if cfg.FIX.Addr != "" {
+		fixCfg := fix.Config{
+			SenderCompID:   cfg.FIX.SenderCompID,
+			TargetCompIDs:  cfg.FIX.TargetCompIDs,
+			PollInterval:   cfg.FIX.PollInterval,
+			RequestTimeout: cfg.Server.RequestTimeout,
+		}
+		if rateLimiter != nil {
+			fixCfg.RateLimit = fixRateLimit(cfg, rateLimiter, logger)
+		}
+		fixAcceptor = fix.NewAcceptor(fixCfg, tradingService, orderFeed, tradingRepo, logger.With("component", "fix"))
+	}
 
 	return &Server{
-		app:          fiber.New(),
-		db:           db,
-		redis:        redisClient,
-		handlers:     handlers,
-		cacheService: cacheService,
-		stockUpdater: stockUpdater,
+		cfg:    cfg,
+		logger: logger,
+		app: fiber.New(fiber.Config{
+			DisableStartupMessage: true,
+			ErrorHandler:          handlers.ErrorHandler,
+			// c.IP() reports the client address from ProxyHeader, but only
+			// for requests from a trusted proxy.
+			ProxyHeader:             cfg.Server.ProxyHeader,
+			EnableTrustedProxyCheck: cfg.Server.ProxyHeader != "",
+			TrustedProxies:          cfg.Server.TrustedProxies,
+			EnableIPValidation:      true,
+		}),
+		closeStorage:    store.close,
+		handlers:        tradingHandlers,
+		marketHandlers:  marketHandlers,
+		healthHandlers:  healthHandlers,
+		webhookHandlers: webhookHandlers,
+		alertHandlers:   alertHandlers,
+		cacheService:    cacheService,
+		quoteCache:      quoteCache,
+		elector:         elector,
+		orderFeed:       orderFeed,
+		grpcServer:      grpcServer,
+		tradingServer:   tradingServer,
+		fixAcceptor:     fixAcceptor,
+		rateLimiter:     rateLimiter,
 	}
 }
 
 func (s *Server) setupRoutes() {
 	s.app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept",
+		AllowOrigins:  "*",
+		AllowHeaders:  "Origin, Content-Type, Accept, X-Request-ID, " + ratelimit.APIKeyHeader,
+		ExposeHeaders: "X-Request-ID, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After",
 	}))
 
-	s.app.Use(logger.New())
-	
-	// Trading routes
-	s.app.Get("/stocks", s.handlers.GetAllStocks)
-	s.app.Get("/transactions", s.handlers.GetAllTransactions)
-	s.app.Post("/transactions", s.handlers.CreateTransaction)
-	s.app.Put("/transactions/:id/status", s.handlers.UpdateTransactionStatus)
+	// Probe, scrape and docs routes are registered before the request logger
+	// and metrics middleware so they don't flood the access log or latency
+	// series.
+	for _, r := range s.probeRoutes() {
+		s.app.Add(r.Method, r.Path, r.handler)
+	}
+	s.app.Get(openAPIPath, openapi.Handler(s.openAPIDocument()))
+	s.app.Get(docsPath, openapi.UIHandler("Maxion Trading API", openAPIPath, docsPath))
+	s.app.Get(docsAssetPath, openapi.UIAssetHandler())
+
+	s.app.Use(tracing.Middleware())
+	s.app.Use(metrics.Middleware())
+	s.app.Use(logging.Middleware(s.logger))
+	s.app.Use(requestTimeout(s.cfg.Server.RequestTimeout))
+
+	for _, r := range s.apiRoutes() {
+		if s.rateLimiter != nil {
+			s.app.Add(r.Method, r.Path, s.rateLimit(r), r.handler)
+			continue
+		}
+		s.app.Add(r.Method, r.Path, r.handler)
+	}
 }
 
-func (s *Server) Start(addr string) error {
-	// Start the stock updater
-	ctx := context.Background()
-	s.stockUpdater.Start(ctx)
-	
+func (s *Server) Start(ctx context.Context) error {
+	// Start the quote subscription, and campaign to run the background jobs
+	s.quoteCache.Start(ctx)
+	s.elector.Start(ctx)
+
 	s.setupRoutes()
-	return s.app.Listen(addr)
-} 
\ No newline at end of file
+
+	errCh := make(chan error, 3)
+	if s.orderFeed != nil {
+		s.orderFeed.Start(ctx)
+	}
+	if s.grpcServer != nil {
+		s.tradingServer.Start(ctx)
+		lis, err := net.Listen("tcp", s.cfg.GRPC.Addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen for grpc: %w", err)
+		}
+		s.logger.Info("grpc server listening", "addr", s.cfg.GRPC.Addr)
+		go func() {
+			errCh <- s.grpcServer.Serve(lis)
+		}()
+	}
+
+	if s.fixAcceptor != nil {
+		lis, err := net.Listen("tcp", s.cfg.FIX.Addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen for fix: %w", err)
+		}
+		s.logger.Info("fix gateway listening", "addr", s.cfg.FIX.Addr)
+		go func() {
+			errCh <- s.fixAcceptor.Serve(lis)
+		}()
+	}
+
+	s.logger.Info("server listening", "addr", s.cfg.Server.Addr)
+	go func() {
+		errCh <- s.app.Listen(s.cfg.Server.Addr)
+	}()
+	return <-errCh
+}
+
+// Shutdown stops accepting requests and waits for in-flight ones, stops the
+// background workers and drains pending cached writes into the database.
+// The returned report lists the writes that could not be flushed. Writes are
+// only drained while holding the leader lease; if another instance holds it,
+// they are left for that instance to sync.
+func (s *Server) Shutdown(ctx context.Context) (services.FlushReport, error) {
+	var errs []error
+
+	if err := s.app.ShutdownWithContext(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to stop http server: %w", err))
+	}
+	if s.grpcServer != nil {
+		s.tradingServer.Close()
+		if err := s.stopGRPC(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if s.fixAcceptor != nil {
+		if err := s.fixAcceptor.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop fix gateway: %w", err))
+		}
+	}
+
+	if s.orderFeed != nil {
+		s.orderFeed.Stop()
+	}
+	s.quoteCache.Stop()
+	s.elector.Stop()
+
+	var report services.FlushReport
+	flushed, err := s.elector.Exclusive(ctx, func(ctx context.Context) {
+		report = s.cacheService.Flush(ctx)
+	})
+	switch {
+	case err != nil:
+		errs = append(errs, fmt.Errorf("pending writes not flushed: failed to take leader lease: %w", err))
+	case !flushed:
+		s.logger.Info("pending writes left to the leader")
+	}
+
+	if err := s.closeStorage(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return report, errors.Join(errs...)
+}
+
+// eventSinks returns the sinks the outbox relay publishes to.
+func eventSinks(cfg config.OutboxConfig, redisClient redis.UniversalClient) []ports.EventSink {
+	var sinks []ports.EventSink
+	if cfg.RedisStream != "" {
+		sinks = append(sinks, events.NewRedisStreamSink(redisClient, cfg.RedisStream))
+	}
+	if cfg.WebhookURL != "" {
+		sinks = append(sinks, events.NewWebhookSink(cfg.WebhookURL))
+	}
+	return sinks
+}
+
+// leaseHolder identifies this instance to the other instances.
+func leaseHolder() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "maxion"
+	}
+	return host + "-" + uuid.NewString()[:8]
+}
+
+// stopGRPC waits for in-flight calls to finish, cancelling whatever is left
+// when ctx expires.
+func (s *Server) stopGRPC(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		return fmt.Errorf("failed to stop grpc server gracefully: %w", ctx.Err())
+	}
+}
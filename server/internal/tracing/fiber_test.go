@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	exporter := UseInMemoryExporter(t)
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/transactions/:id", func(c *fiber.Ctx) error {
+		_, span := Tracer().Start(c.UserContext(), "handler")
+		span.End()
+		return c.SendStatus(200)
+	})
+
+	req := httptest.NewRequest("GET", "/transactions/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+
+	handler, server := spans[0], spans[1]
+	assert.Equal(t, "GET /transactions/:id", server.Name)
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+	assert.Equal(t, server.SpanContext.SpanID(), handler.Parent.SpanID())
+}
+
+func TestInjectAndLinkFrom(t *testing.T) {
+	UseInMemoryExporter(t)
+
+	ctx, span := Tracer().Start(context.Background(), "request")
+	carrier := Inject(ctx)
+	span.End()
+
+	require.Contains(t, carrier, "traceparent")
+	link := LinkFrom(carrier)
+	assert.Equal(t, span.SpanContext().TraceID(), link.SpanContext.TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), link.SpanContext.SpanID())
+
+	assert.False(t, LinkFrom(nil).SpanContext.IsValid())
+}
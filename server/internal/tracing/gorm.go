@@ -0,0 +1,108 @@
+package tracing
+
+import (
+	"errors"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "tracing:span"
+
+type gormPlugin struct{}
+
+// GormPlugin returns a GORM plugin that records a client span for every
+// query, using the context passed to db.WithContext as the parent.
+func GormPlugin() gorm.Plugin {
+	return gormPlugin{}
+}
+
+func (gormPlugin) Name() string {
+	return "tracing"
+}
+
+func (gormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	hooks := []struct {
+		name      string
+		operation string
+		register  func(name string, before, after func(*gorm.DB)) error
+	}{
+		{"create", "INSERT", func(name string, before, after func(*gorm.DB)) error {
+			return errors.Join(
+				callbacks.Create().Before("gorm:create").Register(name+":before", before),
+				callbacks.Create().After("gorm:create").Register(name+":after", after),
+			)
+		}},
+		{"query", "SELECT", func(name string, before, after func(*gorm.DB)) error {
+			return errors.Join(
+				callbacks.Query().Before("gorm:query").Register(name+":before", before),
+				callbacks.Query().After("gorm:query").Register(name+":after", after),
+			)
+		}},
+		{"update", "UPDATE", func(name string, before, after func(*gorm.DB)) error {
+			return errors.Join(
+				callbacks.Update().Before("gorm:update").Register(name+":before", before),
+				callbacks.Update().After("gorm:update").Register(name+":after", after),
+			)
+		}},
+		{"delete", "DELETE", func(name string, before, after func(*gorm.DB)) error {
+			return errors.Join(
+				callbacks.Delete().Before("gorm:delete").Register(name+":before", before),
+				callbacks.Delete().After("gorm:delete").Register(name+":after", after),
+			)
+		}},
+		{"row", "ROW", func(name string, before, after func(*gorm.DB)) error {
+			return errors.Join(
+				callbacks.Row().Before("gorm:row").Register(name+":before", before),
+				callbacks.Row().After("gorm:row").Register(name+":after", after),
+			)
+		}},
+		{"raw", "RAW", func(name string, before, after func(*gorm.DB)) error {
+			return errors.Join(
+				callbacks.Raw().Before("gorm:raw").Register(name+":before", before),
+				callbacks.Raw().After("gorm:raw").Register(name+":after", after),
+			)
+		}},
+	}
+
+	for _, hook := range hooks {
+		if err := hook.register("tracing:"+hook.name, beforeQuery(hook.operation), afterQuery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func beforeQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx, span := Tracer().Start(db.Statement.Context, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemKey.String(db.Dialector.Name()),
+				semconv.DBOperationName(operation),
+			),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func afterQuery(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	if db.Statement.Table != "" {
+		span.SetAttributes(semconv.DBCollectionName(db.Statement.Table))
+	}
+	span.SetAttributes(semconv.DBQueryText(db.Statement.SQL.String()))
+
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		RecordError(span, db.Error)
+	}
+}
@@ -0,0 +1,55 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/touchsung/maxion-server/internal/core/domain"
+)
+
+type testOrder struct {
+	Symbol string    `json:"symbol" validate:"required,max=4"`
+	Legs   []testLeg `json:"legs" validate:"dive"`
+	Meta   *testLeg  `json:"-"`
+}
+
+type testLeg struct {
+	Quantity int `json:"quantity" validate:"gt=0"`
+}
+
+func TestStruct(t *testing.T) {
+	testCases := []struct {
+		name           string
+		input          testOrder
+		expectedFields []domain.FieldError
+	}{
+		{
+			name:  "Valid",
+			input: testOrder{Symbol: "AAPL", Legs: []testLeg{{Quantity: 1}}},
+		},
+		{
+			name:  "Uses JSON names and nested paths",
+			input: testOrder{Symbol: "GOOGLE", Legs: []testLeg{{Quantity: 1}, {Quantity: 0}}},
+			expectedFields: []domain.FieldError{
+				{Field: "symbol", Code: "max", Message: "must be at most 4 characters"},
+				{Field: "legs[1].quantity", Code: "gt", Message: "must be greater than 0"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Struct(tc.input)
+			if tc.expectedFields == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var domainErr *domain.Error
+			require.ErrorAs(t, err, &domainErr)
+			assert.Equal(t, domain.KindValidation, domainErr.Kind)
+			assert.Equal(t, tc.expectedFields, domainErr.Fields)
+		})
+	}
+}
@@ -0,0 +1,4 @@
+-- Create the database; its schema is applied by the server's migrate command
+IF DB_ID(N'TradingBot') IS NULL
+    CREATE DATABASE TradingBot;
+GO